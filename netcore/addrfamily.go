@@ -0,0 +1,84 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Address family preference and filtering.
+//
+
+package netcore
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+)
+
+// AddressFamilyPolicy controls which address families [*Network]
+// uses when dialing the endpoints obtained by resolving a domain name.
+//
+// The zero value is [AddressFamilyAny].
+type AddressFamilyPolicy int
+
+const (
+	// AddressFamilyAny uses the endpoints in the resolved order.
+	AddressFamilyAny = AddressFamilyPolicy(iota)
+
+	// AddressFamilyPreferIPv4 moves the IPv4 endpoints before the IPv6 ones.
+	AddressFamilyPreferIPv4
+
+	// AddressFamilyPreferIPv6 moves the IPv6 endpoints before the IPv4 ones.
+	AddressFamilyPreferIPv6
+
+	// AddressFamilyOnlyIPv4 only uses the IPv4 endpoints.
+	AddressFamilyOnlyIPv4
+
+	// AddressFamilyOnlyIPv6 only uses the IPv6 endpoints.
+	AddressFamilyOnlyIPv6
+)
+
+// errNoEndpointsForFamily indicates that no endpoint matches the address family.
+var errNoEndpointsForFamily = errors.New("no endpoints matching the address family")
+
+// filterEndpoints applies the AddressFamilyPolicy and the address family
+// implied by the network (e.g., "tcp4" or "udp6") to the endpoints.
+func (nx *Network) filterEndpoints(network string, endpoints []string) ([]string, error) {
+	policy := nx.AddressFamilyPolicy
+	switch {
+	case strings.HasSuffix(network, "4"):
+		policy = AddressFamilyOnlyIPv4
+	case strings.HasSuffix(network, "6"):
+		policy = AddressFamilyOnlyIPv6
+	}
+
+	var ipv4, ipv6 []string
+	for _, endpoint := range endpoints {
+		if endpointIsIPv6(endpoint) {
+			ipv6 = append(ipv6, endpoint)
+		} else {
+			ipv4 = append(ipv4, endpoint)
+		}
+	}
+
+	var out []string
+	switch policy {
+	case AddressFamilyPreferIPv4:
+		out = append(ipv4, ipv6...)
+	case AddressFamilyPreferIPv6:
+		out = append(ipv6, ipv4...)
+	case AddressFamilyOnlyIPv4:
+		out = ipv4
+	case AddressFamilyOnlyIPv6:
+		out = ipv6
+	default:
+		out = endpoints
+	}
+	if len(endpoints) > 0 && len(out) <= 0 {
+		return nil, errNoEndpointsForFamily
+	}
+	return out, nil
+}
+
+// endpointIsIPv6 returns whether the given endpoint contains an IPv6 address.
+func endpointIsIPv6(endpoint string) bool {
+	addrport, err := netip.ParseAddrPort(endpoint)
+	return err == nil && addrport.Addr().Unmap().Is6()
+}
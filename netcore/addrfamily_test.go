@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetwork_filterEndpoints(t *testing.T) {
+	endpoints := []string{"[2001:db8::1]:443", "1.1.1.1:443", "[2001:db8::2]:443", "[::ffff:2.2.2.2]:443"}
+
+	tests := []struct {
+		name    string
+		policy  AddressFamilyPolicy
+		network string
+		input   []string
+		want    []string
+		wantErr error
+	}{{
+		name:    "any",
+		policy:  AddressFamilyAny,
+		network: "tcp",
+		input:   endpoints,
+		want:    endpoints,
+	}, {
+		name:    "prefer IPv4",
+		policy:  AddressFamilyPreferIPv4,
+		network: "tcp",
+		input:   endpoints,
+		want:    []string{"1.1.1.1:443", "[::ffff:2.2.2.2]:443", "[2001:db8::1]:443", "[2001:db8::2]:443"},
+	}, {
+		name:    "prefer IPv6",
+		policy:  AddressFamilyPreferIPv6,
+		network: "udp",
+		input:   endpoints,
+		want:    []string{"[2001:db8::1]:443", "[2001:db8::2]:443", "1.1.1.1:443", "[::ffff:2.2.2.2]:443"},
+	}, {
+		name:    "only IPv4",
+		policy:  AddressFamilyOnlyIPv4,
+		network: "tcp",
+		input:   endpoints,
+		want:    []string{"1.1.1.1:443", "[::ffff:2.2.2.2]:443"},
+	}, {
+		name:    "only IPv6",
+		policy:  AddressFamilyOnlyIPv6,
+		network: "tcp",
+		input:   endpoints,
+		want:    []string{"[2001:db8::1]:443", "[2001:db8::2]:443"},
+	}, {
+		name:    "tcp4 overrides the policy",
+		policy:  AddressFamilyPreferIPv6,
+		network: "tcp4",
+		input:   endpoints,
+		want:    []string{"1.1.1.1:443", "[::ffff:2.2.2.2]:443"},
+	}, {
+		name:    "udp6 overrides the policy",
+		policy:  AddressFamilyOnlyIPv4,
+		network: "udp6",
+		input:   endpoints,
+		want:    []string{"[2001:db8::1]:443", "[2001:db8::2]:443"},
+	}, {
+		name:    "no endpoints matching the family",
+		policy:  AddressFamilyAny,
+		network: "tcp6",
+		input:   []string{"1.1.1.1:443"},
+		wantErr: errNoEndpointsForFamily,
+	}, {
+		name:    "no endpoints at all",
+		policy:  AddressFamilyOnlyIPv6,
+		network: "tcp",
+		input:   nil,
+		want:    nil,
+	}}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nx := &Network{AddressFamilyPolicy: tt.policy}
+			got, err := nx.filterEndpoints(tt.network, tt.input)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNetwork_DialContextAddressFamily(t *testing.T) {
+	t.Run("we only dial the endpoints matching the network", func(t *testing.T) {
+		var endpoints []string
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				endpoints = append(endpoints, address)
+				return nil, errors.New("mocked error")
+			},
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"2001:db8::1", "1.1.1.1"}, nil
+			},
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp4", "example.com:80")
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		assert.Equal(t, []string{"1.1.1.1:80"}, endpoints)
+	})
+
+	t.Run("we fail when no endpoint matches the network", func(t *testing.T) {
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"1.1.1.1"}, nil
+			},
+		}
+		conn, err := nx.DialTLSContext(context.Background(), "tcp6", "example.com:443")
+		assert.ErrorIs(t, err, errNoEndpointsForFamily)
+		assert.Nil(t, conn)
+	})
+}
@@ -0,0 +1,171 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Per-endpoint circuit breaker.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen indicates that we did not dial an endpoint because
+// the [*CircuitBreaker] is open for it. The events classify this
+// error as [ErrClassCircuitOpen].
+var ErrCircuitOpen = errors.New("netcore: circuit breaker open for endpoint")
+
+// ErrClassCircuitOpen is the error class (i.e., the "errClass" field
+// of the events) corresponding to [ErrCircuitOpen].
+const ErrClassCircuitOpen = "ECIRCUITOPEN"
+
+// CircuitBreaker skips dialing the endpoints (e.g., "130.192.91.211:443")
+// that failed repeatedly within a time window, which avoids wasting time
+// and traffic on unreachable endpoints during long scanning campaigns.
+//
+// After MaxFailures failed dials within Window, we open the circuit for
+// the endpoint and fail its dials with [ErrCircuitOpen] for Cooldown.
+// Then, we allow a single trial dial: if it fails, we open the circuit
+// again, otherwise we close it. We do not count the dials canceled by
+// the caller (e.g., the losing attempts of a [ParallelDialPolicy]).
+//
+// The zero value is ready to use. A [*CircuitBreaker] is safe for
+// concurrent use by multiple goroutines and may be shared by several
+// [*Network] instances.
+type CircuitBreaker struct {
+	// Cooldown is the optional time during which the circuit stays open.
+	// If this field is zero or negative, we use one minute.
+	Cooldown time.Duration
+
+	// MaxFailures is the optional number of failures within Window causing
+	// us to open the circuit. If this field is zero or negative, we use 3.
+	MaxFailures int
+
+	// Window is the optional time window within which we count the failures.
+	// If this field is zero or negative, we use one minute.
+	Window time.Duration
+
+	// endpoints maps an endpoint to the corresponding state.
+	endpoints map[string]*circuitBreakerState
+
+	// mu protects endpoints.
+	mu sync.Mutex
+}
+
+// circuitBreakerState is the state of an endpoint of the [*CircuitBreaker].
+type circuitBreakerState struct {
+	failures  []time.Time
+	openUntil time.Time
+	trial     bool
+}
+
+// Allow returns whether we should dial the given endpoint at the given time.
+//
+// This method is goroutine safe.
+func (cb *CircuitBreaker) Allow(endpoint string, now time.Time) bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	state, found := cb.endpoints[endpoint]
+	if !found || state.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(state.openUntil) || state.trial {
+		return false
+	}
+	state.trial = true
+	return true
+}
+
+// Record records the result of dialing the given endpoint at the given time.
+//
+// This method is goroutine safe.
+func (cb *CircuitBreaker) Record(endpoint string, err error, now time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	state, found := cb.endpoints[endpoint]
+
+	// a canceled dial does not count but allows another trial dial
+	if errors.Is(err, context.Canceled) {
+		if found {
+			state.trial = false
+		}
+		return
+	}
+
+	if err == nil {
+		delete(cb.endpoints, endpoint)
+		return
+	}
+	if cb.endpoints == nil {
+		cb.endpoints = make(map[string]*circuitBreakerState)
+	}
+	if !found {
+		state = &circuitBreakerState{}
+		cb.endpoints[endpoint] = state
+	}
+
+	// a failed trial dial immediately opens the circuit again
+	if state.trial {
+		state.trial = false
+		state.openUntil = now.Add(cb.cooldown())
+		return
+	}
+
+	// otherwise, only keep the failures within the window
+	since := now.Add(-cb.window())
+	failures := state.failures[:0]
+	for _, t := range state.failures {
+		if t.After(since) {
+			failures = append(failures, t)
+		}
+	}
+	state.failures = append(failures, now)
+	if len(state.failures) >= cb.maxFailures() {
+		state.failures = nil
+		state.openUntil = now.Add(cb.cooldown())
+	}
+}
+
+// cooldown returns the configured cooldown or the default.
+func (cb *CircuitBreaker) cooldown() time.Duration {
+	if cb.Cooldown > 0 {
+		return cb.Cooldown
+	}
+	return time.Minute
+}
+
+// maxFailures returns the configured maximum number of failures or the default.
+func (cb *CircuitBreaker) maxFailures() int {
+	if cb.MaxFailures > 0 {
+		return cb.MaxFailures
+	}
+	return 3
+}
+
+// window returns the configured time window or the default.
+func (cb *CircuitBreaker) window() time.Duration {
+	if cb.Window > 0 {
+		return cb.Window
+	}
+	return time.Minute
+}
+
+// circuitBreakerAllow returns [ErrCircuitOpen] if the CircuitBreaker is
+// open for the given endpoint, and nil otherwise.
+func (nx *Network) circuitBreakerAllow(endpoint string) error {
+	if nx.CircuitBreaker != nil && !nx.CircuitBreaker.Allow(endpoint, nx.timeNow()) {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// circuitBreakerRecord records the result of dialing the given
+// endpoint into the CircuitBreaker, if any.
+func (nx *Network) circuitBreakerRecord(endpoint string, err error) {
+	if nx.CircuitBreaker != nil {
+		nx.CircuitBreaker.Record(endpoint, err, nx.timeNow())
+	}
+}
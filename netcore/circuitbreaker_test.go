@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const endpoint = "130.192.91.211:443"
+	mockedErr := errors.New("mocked error")
+
+	t.Run("we open the circuit after repeated failures", func(t *testing.T) {
+		cb := &CircuitBreaker{}
+		for idx := 0; idx < 3; idx++ {
+			assert.True(t, cb.Allow(endpoint, t0))
+			cb.Record(endpoint, mockedErr, t0)
+		}
+		assert.False(t, cb.Allow(endpoint, t0.Add(59*time.Second)))
+		assert.True(t, cb.Allow("130.192.91.211:80", t0))
+	})
+
+	t.Run("we only count the failures within the window", func(t *testing.T) {
+		cb := &CircuitBreaker{MaxFailures: 2, Window: time.Second}
+		cb.Record(endpoint, mockedErr, t0)
+		cb.Record(endpoint, mockedErr, t0.Add(2*time.Second))
+		assert.True(t, cb.Allow(endpoint, t0.Add(2*time.Second)))
+		cb.Record(endpoint, mockedErr, t0.Add(2500*time.Millisecond))
+		assert.False(t, cb.Allow(endpoint, t0.Add(2500*time.Millisecond)))
+	})
+
+	t.Run("a success resets the failures", func(t *testing.T) {
+		cb := &CircuitBreaker{MaxFailures: 2}
+		cb.Record(endpoint, mockedErr, t0)
+		cb.Record(endpoint, nil, t0)
+		cb.Record(endpoint, mockedErr, t0)
+		assert.True(t, cb.Allow(endpoint, t0))
+	})
+
+	t.Run("we do not count canceled dials", func(t *testing.T) {
+		cb := &CircuitBreaker{MaxFailures: 1}
+		cb.Record(endpoint, context.Canceled, t0)
+		assert.True(t, cb.Allow(endpoint, t0))
+	})
+
+	t.Run("we allow a single trial dial after the cooldown", func(t *testing.T) {
+		cb := &CircuitBreaker{Cooldown: time.Second, MaxFailures: 1}
+		cb.Record(endpoint, mockedErr, t0)
+		assert.False(t, cb.Allow(endpoint, t0))
+
+		// a failed trial opens the circuit again
+		t1 := t0.Add(time.Second)
+		assert.True(t, cb.Allow(endpoint, t1))
+		assert.False(t, cb.Allow(endpoint, t1))
+		cb.Record(endpoint, mockedErr, t1)
+		assert.False(t, cb.Allow(endpoint, t1.Add(999*time.Millisecond)))
+
+		// a canceled trial allows another trial
+		t2 := t1.Add(time.Second)
+		assert.True(t, cb.Allow(endpoint, t2))
+		cb.Record(endpoint, context.Canceled, t2)
+		assert.True(t, cb.Allow(endpoint, t2))
+
+		// a successful trial closes the circuit
+		cb.Record(endpoint, nil, t2)
+		assert.True(t, cb.Allow(endpoint, t2))
+		assert.True(t, cb.Allow(endpoint, t2))
+	})
+}
+
+func TestNetwork_CircuitBreaker(t *testing.T) {
+	var (
+		buf   bytes.Buffer
+		dials int
+	)
+	nx := &Network{
+		CircuitBreaker: &CircuitBreaker{MaxFailures: 2},
+		DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dials++
+			return nil, syscall.ECONNREFUSED
+		},
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+	for idx := 0; idx < 3; idx++ {
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+	}
+	_, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// the circuit is per endpoint
+	nx.DialContextFunc = func(ctx context.Context, network, address string) (net.Conn, error) {
+		dials++
+		return &mocks.Conn{MockLocalAddr: func() net.Addr { return &net.TCPAddr{} }}, nil
+	}
+	conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:443")
+	assert.NoError(t, err)
+	assert.NotNil(t, conn)
+
+	assert.Equal(t, 3, dials)
+	assert.Equal(t, int64(3), nx.Stats().DialsAttempted)
+	errClasses := fmt.Sprintf(`"errClass":"%s"`, ErrClassCircuitOpen)
+	assert.Equal(t, 2, strings.Count(buf.String(), errClasses))
+}
@@ -0,0 +1,95 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Cloning a Network.
+//
+
+package netcore
+
+import (
+	"maps"
+	"slices"
+)
+
+// Clone returns a new [*Network] with the same configuration, to which
+// we apply the given options, which allows deriving variants (e.g., with
+// a different TLS config or resolver) from a base [*Network].
+//
+// We deep copy the configuration that callers may want to modify in the
+// clone, that is, the TLSConfig, QUICConfig, RootCAs, ProxyURL, SocketOptions,
+// and Hosts fields. We share the other fields, including the DNSCache and
+// the CircuitBreaker, which are safe to share, the Logger and EventHook, and
+// the functions. The clone starts with zero Stats and has its own limit
+// on the concurrent dials (see MaxConcurrentDials).
+//
+// This method is goroutine safe.
+func (nx *Network) Clone(options ...Option) *Network {
+	clone := &Network{
+		ALPNForEndpoint:        nx.ALPNForEndpoint,
+		AddressFamilyPolicy:    nx.AddressFamilyPolicy,
+		BindToDevice:           nx.BindToDevice,
+		CircuitBreaker:         nx.CircuitBreaker,
+		ControlFunc:            nx.ControlFunc,
+		DialContextFunc:        nx.DialContextFunc,
+		DialPolicy:             nx.DialPolicy,
+		DNSCache:               nx.DNSCache,
+		EnableMultipathTCP:     nx.EnableMultipathTCP,
+		ErrClassifiers:         slices.Clone(nx.ErrClassifiers),
+		EventHook:              nx.EventHook,
+		Hosts:                  cloneHosts(nx.Hosts),
+		LogIOPayloadBytes:      nx.LogIOPayloadBytes,
+		LogLevelIO:             nx.LogLevelIO,
+		LogKernelTimestamps:    nx.LogKernelTimestamps,
+		LogMaxIOEvents:         nx.LogMaxIOEvents,
+		LogTCPInfo:             nx.LogTCPInfo,
+		Logger:                 nx.Logger,
+		LookupHostFunc:         nx.LookupHostFunc,
+		LookupHostResultFunc:   nx.LookupHostResultFunc,
+		MaxConcurrentDials:     nx.MaxConcurrentDials,
+		NewTLSClientConn:       nx.NewTLSClientConn,
+		TLSKeyLogWriter:        nx.TLSKeyLogWriter,
+		TLSLogRawHandshake:     nx.TLSLogRawHandshake,
+		TimeNow:                nx.TimeNow,
+		WrapConn:               nx.WrapConn,
+		WrapPacketConn:         nx.WrapPacketConn,
+		LookupHostTimeout:      nx.LookupHostTimeout,
+		DialContextTimeout:     nx.DialContextTimeout,
+		TLSHandshakeTimeout:    nx.TLSHandshakeTimeout,
+		NewResolverOrSingleton: nx.NewResolverOrSingleton,
+		NewDialerOrSingleton:   nx.NewDialerOrSingleton,
+		TLSEngine:              nx.TLSEngine,
+	}
+	if nx.ProxyURL != nil {
+		proxyURL := *nx.ProxyURL
+		clone.ProxyURL = &proxyURL
+	}
+	if nx.QUICConfig != nil {
+		clone.QUICConfig = nx.QUICConfig.Clone()
+	}
+	if nx.RootCAs != nil {
+		clone.RootCAs = nx.RootCAs.Clone()
+	}
+	if nx.SocketOptions != nil {
+		socketOptions := *nx.SocketOptions
+		clone.SocketOptions = &socketOptions
+	}
+	if nx.TLSConfig != nil {
+		clone.TLSConfig = nx.TLSConfig.Clone()
+	}
+	for _, option := range options {
+		option(clone)
+	}
+	return clone
+}
+
+// cloneHosts returns a deep copy of the given hosts map.
+func cloneHosts(hosts map[string][]string) map[string][]string {
+	if hosts == nil {
+		return nil
+	}
+	out := maps.Clone(hosts)
+	for domain, addrs := range out {
+		out[domain] = slices.Clone(addrs)
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fillTestNetwork sets all the exported fields of the given network to nonzero values.
+func fillTestNetwork(nx *Network) {
+	value := reflect.ValueOf(nx).Elem()
+	for idx := 0; idx < value.NumField(); idx++ {
+		field, ftype := value.Field(idx), value.Type().Field(idx)
+		if !ftype.IsExported() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int, reflect.Int64:
+			field.SetInt(int64(idx + 1))
+		case reflect.String:
+			field.SetString(ftype.Name)
+		case reflect.Func:
+			field.Set(reflect.MakeFunc(field.Type(), func(args []reflect.Value) []reflect.Value {
+				return nil // never invoked
+			}))
+		case reflect.Map:
+			field.Set(reflect.ValueOf(map[string][]string{"example.com": {"130.192.91.211"}}))
+		case reflect.Pointer:
+			field.Set(reflect.New(field.Type().Elem()))
+		case reflect.Slice:
+			field.Set(reflect.MakeSlice(field.Type(), 1, 1))
+		case reflect.Interface:
+			switch ftype.Name {
+			case "DialPolicy":
+				field.Set(reflect.ValueOf(SequentialDialPolicy{}))
+			case "EventHook":
+				field.Set(reflect.ValueOf(&testEventHook{}))
+			case "TLSEngine":
+				field.Set(reflect.ValueOf(&tlsEngineMock{}))
+			case "TLSKeyLogWriter":
+				field.Set(reflect.ValueOf(&bytes.Buffer{}))
+			default:
+				panic("fillTestNetwork: unhandled interface field")
+			}
+		default:
+			panic("fillTestNetwork: unhandled field type")
+		}
+	}
+}
+
+func TestNetwork_Clone(t *testing.T) {
+	t.Run("we copy all the exported fields", func(t *testing.T) {
+		nx := &Network{}
+		fillTestNetwork(nx)
+		clone := nx.Clone()
+
+		value, cvalue := reflect.ValueOf(nx).Elem(), reflect.ValueOf(clone).Elem()
+		for idx := 0; idx < value.NumField(); idx++ {
+			if !value.Type().Field(idx).IsExported() {
+				continue
+			}
+			name := value.Type().Field(idx).Name
+			require.False(t, value.Field(idx).IsZero(), name)
+			assert.False(t, cvalue.Field(idx).IsZero(), name)
+		}
+	})
+
+	t.Run("we deep copy the mutable configuration", func(t *testing.T) {
+		nx := &Network{
+			DNSCache:      &DNSCache{},
+			Hosts:         map[string][]string{"example.com": {"130.192.91.211"}},
+			ProxyURL:      &url.URL{Scheme: "http", Host: "127.0.0.1:8080"},
+			SocketOptions: &SocketOptions{TTL: 5},
+			TLSConfig:     &tls.Config{ServerName: "example.com"},
+		}
+		clone := nx.Clone()
+
+		clone.Hosts["example.com"][0] = "2001:db8::1"
+		clone.ProxyURL.Host = "127.0.0.1:3128"
+		clone.SocketOptions.TTL = 10
+		clone.TLSConfig.ServerName = "example.org"
+
+		assert.Equal(t, []string{"130.192.91.211"}, nx.Hosts["example.com"])
+		assert.Equal(t, "127.0.0.1:8080", nx.ProxyURL.Host)
+		assert.Equal(t, 5, nx.SocketOptions.TTL)
+		assert.Equal(t, "example.com", nx.TLSConfig.ServerName)
+		assert.Same(t, nx.DNSCache, clone.DNSCache)
+	})
+
+	t.Run("we apply the options and do not share the stats", func(t *testing.T) {
+		nx := &Network{}
+		nx.stats.dialsAttempted.Add(1)
+		config := &tls.Config{ServerName: "example.com"}
+		clone := nx.Clone(WithTLSConfig(config))
+		assert.Same(t, config, clone.TLSConfig)
+		assert.Nil(t, nx.TLSConfig)
+		assert.Equal(t, int64(0), clone.Stats().DialsAttempted)
+	})
+}
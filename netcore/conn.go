@@ -10,12 +10,11 @@ package netcore
 
 import (
 	"context"
-	"log/slog"
+	"encoding/base64"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/rbmk-project/common/errclass"
 )
 
 // connLocalAddr is a safe way to get the local address of a connection.
@@ -43,72 +42,132 @@ func (emptyAddr) Network() string { return "" }
 // String implements [net.Addr].
 func (emptyAddr) String() string { return "" }
 
-// maybeWrapConn wraps a connection when it makes sense to do so.
+// maybeWrapConn wraps a connection using WrapConn when we are emitting
+// events and always wraps it to count the bytes in the [NetworkStats].
 func (nx *Network) maybeWrapConn(ctx context.Context, conn net.Conn) net.Conn {
-	if conn != nil && nx.Logger != nil && nx.WrapConn != nil {
+	if conn == nil {
+		return nil
+	}
+	if nx.emitEnabled() && nx.WrapConn != nil {
 		conn = nx.WrapConn(ctx, nx, conn)
 	}
-	return conn
+	return newStatsConn(conn, &nx.stats)
 }
 
 // WrapConn wraps a given [net.Conn] to emit structured logs.
 //
 // The context argument is only used for logging and does not constrain
-// in any way the lifetime of the wrapped connection.
+// in any way the lifetime of the wrapped connection. When the [*Network]
+// calls this function, the context contains the connection ID that we
+// include as the "connId" field of the events.
 func WrapConn(ctx context.Context, netx *Network, conn net.Conn) net.Conn {
 	laddr := connLocalAddr(conn)
 	conn = &connWrapper{
 		ctx:       ctx,
 		closeonce: sync.Once{},
 		conn:      conn,
+		connID:    connIDFromContext(ctx),
 		laddr:     laddr.String(),
 		netx:      netx,
 		protocol:  laddr.Network(),
 		raddr:     connRemoteAddr(conn).String(),
+		t0:        netx.timeNow(),
 	}
 	return conn
 }
 
 // connWrapper wraps a [net.Conn].
 type connWrapper struct {
-	ctx       context.Context // only used for logging
-	closeonce sync.Once
-	conn      net.Conn
-	laddr     string
-	netx      *Network // may contain nil logger!
-	protocol  string
-	raddr     string
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	ctx          context.Context // only used for logging
+	closeonce    sync.Once
+	conn         net.Conn
+	connID       int64
+	laddr        string
+	netx         *Network // may contain nil logger!
+	protocol     string
+	raddr        string
+	readOps      atomic.Int64
+	t0           time.Time
+	writeOps     atomic.Int64
+}
+
+// ioEventsEnabled counts a new read or write operation using the given
+// counter and returns whether we should emit its events, according to
+// the LogLevelIO and LogMaxIOEvents fields.
+func (nx *Network) ioEventsEnabled(ctx context.Context, ops *atomic.Int64) bool {
+	count := ops.Add(1)
+	limit := int64(nx.LogMaxIOEvents)
+	return (limit <= 0 || count <= limit) && nx.emitEnabledAtLevel(ctx, nx.LogLevelIO)
+}
+
+// payloadPrefix returns the base64 encoded prefix of the first count
+// bytes of the given buffer, according to the LogIOPayloadBytes
+// field, or an empty string when it is disabled.
+func (nx *Network) payloadPrefix(buf []byte, count int) string {
+	limit := nx.LogIOPayloadBytes
+	if limit <= 0 || count <= 0 {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(buf[:min(count, len(buf), limit)])
 }
 
 // Close implements [net.Conn].
 func (c *connWrapper) Close() (err error) {
 	c.closeonce.Do(func() {
 		t0 := c.netx.timeNow()
-		if c.netx.Logger != nil {
-			c.netx.Logger.InfoContext(
-				c.ctx,
-				"closeStart",
-				slog.String("localAddr", c.laddr),
-				slog.String("protocol", c.protocol),
-				slog.String("remoteAddr", c.raddr),
-				slog.Time("t", t0),
-			)
+		if c.netx.emitEnabled() {
+			c.netx.emit(c.ctx, &CloseStartEvent{
+				ConnID:     c.connID,
+				LocalAddr:  c.laddr,
+				Protocol:   c.protocol,
+				RemoteAddr: c.raddr,
+				T:          t0,
+			})
+		}
+
+		// read TCP_INFO before closing the socket
+		var (
+			info   tcpInfo
+			infoOK bool
+		)
+		if c.netx.LogTCPInfo && c.netx.emitEnabled() {
+			info, infoOK = readTCPInfo(c.conn)
 		}
 
 		err = c.conn.Close()
 
-		if c.netx.Logger != nil {
-			c.netx.Logger.InfoContext(
-				c.ctx,
-				"closeDone",
-				slog.Any("err", err),
-				slog.String("errClass", errclass.New(err)),
-				slog.String("localAddr", c.laddr),
-				slog.String("protocol", c.protocol),
-				slog.String("remoteAddr", c.raddr),
-				slog.Time("t0", t0),
-				slog.Time("t", c.netx.timeNow()),
-			)
+		if c.netx.emitEnabled() {
+			c.netx.emit(c.ctx, &CloseDoneEvent{
+				ConnID:              c.connID,
+				Err:                 errString(err),
+				ErrClass:            c.netx.errClass(err),
+				IOBytesReadTotal:    c.bytesRead.Load(),
+				IOBytesWrittenTotal: c.bytesWritten.Load(),
+				LocalAddr:           c.laddr,
+				Protocol:            c.protocol,
+				RemoteAddr:          c.raddr,
+				T0:                  t0,
+				T:                   c.netx.timeNow(),
+			})
+			c.netx.emit(c.ctx, &ConnStatsEvent{
+				ConnID:              c.connID,
+				IOBytesReadTotal:    c.bytesRead.Load(),
+				IOBytesWrittenTotal: c.bytesWritten.Load(),
+				IOReadCount:         c.readOps.Load(),
+				IOWriteCount:        c.writeOps.Load(),
+				LocalAddr:           c.laddr,
+				Protocol:            c.protocol,
+				RemoteAddr:          c.raddr,
+				TCPInfo:             infoOK,
+				TCPDeliveryRate:     info.deliveryRate,
+				TCPRTTUsec:          info.rttUsec,
+				TCPRTTVarUsec:       info.rttVarUsec,
+				TCPTotalRetrans:     info.totalRetrans,
+				T0:                  c.t0,
+				T:                   c.netx.timeNow(),
+			})
 		}
 	})
 	return
@@ -122,33 +181,44 @@ func (c *connWrapper) LocalAddr() net.Addr {
 // Read implements [net.Conn].
 func (c *connWrapper) Read(buf []byte) (int, error) {
 	t0 := c.netx.timeNow()
-	if c.netx.Logger != nil {
-		c.netx.Logger.InfoContext(
-			c.ctx,
-			"readStart",
-			slog.Int("ioBufferSize", len(buf)),
-			slog.String("localAddr", c.laddr),
-			slog.String("protocol", c.protocol),
-			slog.String("remoteAddr", c.raddr),
-			slog.Time("t", t0),
-		)
+	emitting := c.netx.ioEventsEnabled(c.ctx, &c.readOps)
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &ReadStartEvent{
+			ConnID:       c.connID,
+			IOBufferSize: len(buf),
+			LocalAddr:    c.laddr,
+			Protocol:     c.protocol,
+			RemoteAddr:   c.raddr,
+			T:            t0,
+		})
 	}
 
-	count, err := c.conn.Read(buf)
-
-	if c.netx.Logger != nil {
-		c.netx.Logger.InfoContext(
-			c.ctx,
-			"readDone",
-			slog.Int("ioBytesCount", count),
-			slog.Any("err", err),
-			slog.String("errClass", errclass.New(err)),
-			slog.String("localAddr", c.laddr),
-			slog.String("protocol", c.protocol),
-			slog.String("remoteAddr", c.raddr),
-			slog.Time("t0", t0),
-			slog.Time("t", c.netx.timeNow()),
-		)
+	var (
+		count   int
+		err     error
+		tKernel time.Time
+	)
+	if emitting && c.netx.LogKernelTimestamps {
+		count, tKernel, err = readWithKernelTimestamp(c.conn, buf)
+	} else {
+		count, err = c.conn.Read(buf)
+	}
+	c.bytesRead.Add(int64(count))
+
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &ReadDoneEvent{
+			ConnID:          c.connID,
+			IOBytesCount:    count,
+			IOPayloadPrefix: c.netx.payloadPrefix(buf, count),
+			Err:             errString(err),
+			ErrClass:        c.netx.errClass(err),
+			LocalAddr:       c.laddr,
+			Protocol:        c.protocol,
+			RemoteAddr:      c.raddr,
+			TKernel:         tKernel,
+			T0:              t0,
+			T:               c.netx.timeNow(),
+		})
 	}
 
 	return count, err
@@ -177,33 +247,34 @@ func (c *connWrapper) SetWriteDeadline(t time.Time) error {
 // Write implements [net.Conn].
 func (c *connWrapper) Write(data []byte) (n int, err error) {
 	t0 := c.netx.timeNow()
-	if c.netx.Logger != nil {
-		c.netx.Logger.InfoContext(
-			c.ctx,
-			"writeStart",
-			slog.Int("ioBufferSize", len(data)),
-			slog.String("localAddr", c.laddr),
-			slog.String("protocol", c.protocol),
-			slog.String("remoteAddr", c.raddr),
-			slog.Time("t", t0),
-		)
+	emitting := c.netx.ioEventsEnabled(c.ctx, &c.writeOps)
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &WriteStartEvent{
+			ConnID:       c.connID,
+			IOBufferSize: len(data),
+			LocalAddr:    c.laddr,
+			Protocol:     c.protocol,
+			RemoteAddr:   c.raddr,
+			T:            t0,
+		})
 	}
 
 	count, err := c.conn.Write(data)
+	c.bytesWritten.Add(int64(count))
 
-	if c.netx.Logger != nil {
-		c.netx.Logger.InfoContext(
-			c.ctx,
-			"writeDone",
-			slog.Int("ioBytesCount", count),
-			slog.Any("err", err),
-			slog.String("errClass", errclass.New(err)),
-			slog.String("localAddr", c.laddr),
-			slog.String("protocol", c.protocol),
-			slog.String("remoteAddr", c.raddr),
-			slog.Time("t0", t0),
-			slog.Time("t", c.netx.timeNow()),
-		)
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &WriteDoneEvent{
+			ConnID:          c.connID,
+			IOBytesCount:    count,
+			IOPayloadPrefix: c.netx.payloadPrefix(data, count),
+			Err:             errString(err),
+			ErrClass:        c.netx.errClass(err),
+			LocalAddr:       c.laddr,
+			Protocol:        c.protocol,
+			RemoteAddr:      c.raddr,
+			T0:              t0,
+			T:               c.netx.timeNow(),
+		})
 	}
 
 	return count, err
@@ -16,6 +16,7 @@ import (
 
 	"github.com/rbmk-project/common/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_connLocalAddr(t *testing.T) {
@@ -86,7 +87,9 @@ func TestNetwork_maybeWrapConn(t *testing.T) {
 		nx := &Network{}
 		conn := &mocks.Conn{}
 		wrapped := nx.maybeWrapConn(context.Background(), conn)
-		assert.Equal(t, conn, wrapped) // should return unwrapped
+		sconn, ok := wrapped.(*statsConn) // should only count the bytes
+		require.True(t, ok)
+		assert.Equal(t, conn, sconn.Conn)
 	})
 
 	t.Run("no wrapper configured", func(t *testing.T) {
@@ -95,7 +98,9 @@ func TestNetwork_maybeWrapConn(t *testing.T) {
 		}
 		conn := &mocks.Conn{}
 		wrapped := nx.maybeWrapConn(context.Background(), conn)
-		assert.Equal(t, conn, wrapped) // should return unwrapped
+		sconn, ok := wrapped.(*statsConn) // should only count the bytes
+		require.True(t, ok)
+		assert.Equal(t, conn, sconn.Conn)
 	})
 
 	t.Run("full wrapping", func(t *testing.T) {
@@ -113,8 +118,10 @@ func TestNetwork_maybeWrapConn(t *testing.T) {
 		}
 		wrapped := nx.maybeWrapConn(context.Background(), conn)
 		assert.NotEqual(t, conn, wrapped) // should return wrapped
-		inner, ok := wrapped.(*connWrapper)
-		assert.True(t, ok)
+		sconn, ok := wrapped.(*statsConn)
+		require.True(t, ok)
+		inner, ok := sconn.Conn.(*connWrapper)
+		require.True(t, ok)
 		assert.Equal(t, inner.netx, nx)
 	})
 }
@@ -187,10 +194,12 @@ func Test_connWrapper(t *testing.T) {
 			wrapper := &connWrapper{
 				ctx:      context.Background(),
 				conn:     mock,
+				connID:   7,
 				laddr:    "127.0.0.1:1234",
 				netx:     &Network{Logger: logger, TimeNow: timeNow},
 				protocol: "tcp",
 				raddr:    "1.1.1.1:443",
+				t0:       fixedTime,
 			}
 
 			return &buf, mock, wrapper, fixedTime
@@ -210,7 +219,7 @@ func Test_connWrapper(t *testing.T) {
 
 			// Verify logging output
 			logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
-			assert.Len(t, logs, 2)
+			assert.Len(t, logs, 3)
 
 			// Verify closeStart log
 			var startLog map[string]interface{}
@@ -219,6 +228,7 @@ func Test_connWrapper(t *testing.T) {
 			assert.Equal(t, map[string]interface{}{
 				"level":      "INFO",
 				"msg":        "closeStart",
+				"connId":     float64(7),
 				"localAddr":  "127.0.0.1:1234",
 				"protocol":   "tcp",
 				"remoteAddr": "1.1.1.1:443",
@@ -230,16 +240,38 @@ func Test_connWrapper(t *testing.T) {
 			err = json.Unmarshal([]byte(logs[1]), &doneLog)
 			assert.NoError(t, err)
 			assert.Equal(t, map[string]interface{}{
-				"level":      "INFO",
-				"msg":        "closeDone",
-				"err":        nil,
-				"errClass":   "",
-				"localAddr":  "127.0.0.1:1234",
-				"protocol":   "tcp",
-				"remoteAddr": "1.1.1.1:443",
-				"t0":         fixedTime.Format(time.RFC3339Nano),
-				"t":          fixedTime.Format(time.RFC3339Nano),
+				"level":               "INFO",
+				"msg":                 "closeDone",
+				"connId":              float64(7),
+				"err":                 nil,
+				"errClass":            "",
+				"ioBytesReadTotal":    float64(0),
+				"ioBytesWrittenTotal": float64(0),
+				"localAddr":           "127.0.0.1:1234",
+				"protocol":            "tcp",
+				"remoteAddr":          "1.1.1.1:443",
+				"t0":                  fixedTime.Format(time.RFC3339Nano),
+				"t":                   fixedTime.Format(time.RFC3339Nano),
 			}, doneLog)
+
+			// Verify connStats log
+			var statsLog map[string]interface{}
+			err = json.Unmarshal([]byte(logs[2]), &statsLog)
+			assert.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{
+				"level":               "INFO",
+				"msg":                 "connStats",
+				"connId":              float64(7),
+				"ioBytesReadTotal":    float64(0),
+				"ioBytesWrittenTotal": float64(0),
+				"ioReadCount":         float64(0),
+				"ioWriteCount":        float64(0),
+				"localAddr":           "127.0.0.1:1234",
+				"protocol":            "tcp",
+				"remoteAddr":          "1.1.1.1:443",
+				"t0":                  fixedTime.Format(time.RFC3339Nano),
+				"t":                   fixedTime.Format(time.RFC3339Nano),
+			}, statsLog)
 		})
 
 		t.Run("error on close", func(t *testing.T) {
@@ -257,7 +289,7 @@ func Test_connWrapper(t *testing.T) {
 
 			// Verify logging output
 			logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
-			assert.Len(t, logs, 2)
+			assert.Len(t, logs, 3)
 
 			// Verify closeStart log
 			var startLog map[string]interface{}
@@ -266,6 +298,7 @@ func Test_connWrapper(t *testing.T) {
 			assert.Equal(t, map[string]interface{}{
 				"level":      "INFO",
 				"msg":        "closeStart",
+				"connId":     float64(7),
 				"localAddr":  "127.0.0.1:1234",
 				"protocol":   "tcp",
 				"remoteAddr": "1.1.1.1:443",
@@ -277,15 +310,18 @@ func Test_connWrapper(t *testing.T) {
 			err = json.Unmarshal([]byte(logs[1]), &doneLog)
 			assert.NoError(t, err)
 			assert.Equal(t, map[string]interface{}{
-				"level":      "INFO",
-				"msg":        "closeDone",
-				"err":        expectedErr.Error(),
-				"errClass":   "EGENERIC",
-				"localAddr":  "127.0.0.1:1234",
-				"protocol":   "tcp",
-				"remoteAddr": "1.1.1.1:443",
-				"t0":         fixedTime.Format(time.RFC3339Nano),
-				"t":          fixedTime.Format(time.RFC3339Nano),
+				"level":               "INFO",
+				"msg":                 "closeDone",
+				"connId":              float64(7),
+				"err":                 expectedErr.Error(),
+				"errClass":            "EGENERIC",
+				"ioBytesReadTotal":    float64(0),
+				"ioBytesWrittenTotal": float64(0),
+				"localAddr":           "127.0.0.1:1234",
+				"protocol":            "tcp",
+				"remoteAddr":          "1.1.1.1:443",
+				"t0":                  fixedTime.Format(time.RFC3339Nano),
+				"t":                   fixedTime.Format(time.RFC3339Nano),
 			}, doneLog)
 		})
 
@@ -310,7 +346,7 @@ func Test_connWrapper(t *testing.T) {
 
 			// Verify we only logged one close operation
 			logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
-			assert.Len(t, logs, 2, "Should only have one pair of start/done logs")
+			assert.Len(t, logs, 3, "Should only have one set of start/done/stats logs")
 		})
 
 		t.Run("no logger configured", func(t *testing.T) {
@@ -332,6 +368,7 @@ func Test_connWrapper(t *testing.T) {
 			wrapper := &connWrapper{
 				ctx:      context.Background(),
 				conn:     mock,
+				connID:   7,
 				laddr:    "127.0.0.1:1234",
 				netx:     &Network{}, // no logger configured
 				protocol: "tcp",
@@ -374,6 +411,7 @@ func Test_connWrapper(t *testing.T) {
 			wrapper := &connWrapper{
 				ctx:      context.Background(),
 				conn:     mock,
+				connID:   7,
 				laddr:    "127.0.0.1:1234",
 				netx:     &Network{Logger: logger, TimeNow: timeNow},
 				protocol: "tcp",
@@ -411,6 +449,7 @@ func Test_connWrapper(t *testing.T) {
 			assert.Equal(t, map[string]interface{}{
 				"level":        "INFO",
 				"msg":          "readStart",
+				"connId":       float64(7),
 				"ioBufferSize": float64(1024),
 				"localAddr":    "127.0.0.1:1234",
 				"protocol":     "tcp",
@@ -423,16 +462,18 @@ func Test_connWrapper(t *testing.T) {
 			err = json.Unmarshal([]byte(logs[1]), &doneLog)
 			assert.NoError(t, err)
 			assert.Equal(t, map[string]interface{}{
-				"level":        "INFO",
-				"msg":          "readDone",
-				"ioBytesCount": float64(len(expectedData)),
-				"err":          nil,
-				"errClass":     "",
-				"localAddr":    "127.0.0.1:1234",
-				"protocol":     "tcp",
-				"remoteAddr":   "1.1.1.1:443",
-				"t0":           fixedTime.Format(time.RFC3339Nano),
-				"t":            fixedTime.Format(time.RFC3339Nano),
+				"level":           "INFO",
+				"msg":             "readDone",
+				"connId":          float64(7),
+				"ioBytesCount":    float64(len(expectedData)),
+				"ioPayloadPrefix": "",
+				"err":             nil,
+				"errClass":        "",
+				"localAddr":       "127.0.0.1:1234",
+				"protocol":        "tcp",
+				"remoteAddr":      "1.1.1.1:443",
+				"t0":              fixedTime.Format(time.RFC3339Nano),
+				"t":               fixedTime.Format(time.RFC3339Nano),
 			}, doneLog)
 		})
 
@@ -462,6 +503,7 @@ func Test_connWrapper(t *testing.T) {
 			assert.Equal(t, map[string]interface{}{
 				"level":        "INFO",
 				"msg":          "readStart",
+				"connId":       float64(7),
 				"ioBufferSize": float64(1024),
 				"localAddr":    "127.0.0.1:1234",
 				"protocol":     "tcp",
@@ -474,16 +516,18 @@ func Test_connWrapper(t *testing.T) {
 			err = json.Unmarshal([]byte(logs[1]), &doneLog)
 			assert.NoError(t, err)
 			assert.Equal(t, map[string]interface{}{
-				"level":        "INFO",
-				"msg":          "readDone",
-				"ioBytesCount": float64(0),
-				"err":          expectedErr.Error(),
-				"errClass":     "EGENERIC",
-				"localAddr":    "127.0.0.1:1234",
-				"protocol":     "tcp",
-				"remoteAddr":   "1.1.1.1:443",
-				"t0":           fixedTime.Format(time.RFC3339Nano),
-				"t":            fixedTime.Format(time.RFC3339Nano),
+				"level":           "INFO",
+				"msg":             "readDone",
+				"connId":          float64(7),
+				"ioBytesCount":    float64(0),
+				"ioPayloadPrefix": "",
+				"err":             expectedErr.Error(),
+				"errClass":        "EGENERIC",
+				"localAddr":       "127.0.0.1:1234",
+				"protocol":        "tcp",
+				"remoteAddr":      "1.1.1.1:443",
+				"t0":              fixedTime.Format(time.RFC3339Nano),
+				"t":               fixedTime.Format(time.RFC3339Nano),
 			}, doneLog)
 		})
 
@@ -503,6 +547,7 @@ func Test_connWrapper(t *testing.T) {
 			wrapper := &connWrapper{
 				ctx:      context.Background(),
 				conn:     mock,
+				connID:   7,
 				laddr:    "127.0.0.1:1234",
 				netx:     &Network{}, // no logger configured
 				protocol: "tcp",
@@ -547,6 +592,7 @@ func Test_connWrapper(t *testing.T) {
 			wrapper := &connWrapper{
 				ctx:      context.Background(),
 				conn:     mock,
+				connID:   7,
 				laddr:    "127.0.0.1:1234",
 				netx:     &Network{Logger: logger, TimeNow: timeNow},
 				protocol: "tcp",
@@ -581,6 +627,7 @@ func Test_connWrapper(t *testing.T) {
 			assert.Equal(t, map[string]interface{}{
 				"level":        "INFO",
 				"msg":          "writeStart",
+				"connId":       float64(7),
 				"ioBufferSize": float64(len(data)),
 				"localAddr":    "127.0.0.1:1234",
 				"protocol":     "tcp",
@@ -593,16 +640,18 @@ func Test_connWrapper(t *testing.T) {
 			err = json.Unmarshal([]byte(logs[1]), &doneLog)
 			assert.NoError(t, err)
 			assert.Equal(t, map[string]interface{}{
-				"level":        "INFO",
-				"msg":          "writeDone",
-				"ioBytesCount": float64(len(data)),
-				"err":          nil,
-				"errClass":     "",
-				"localAddr":    "127.0.0.1:1234",
-				"protocol":     "tcp",
-				"remoteAddr":   "1.1.1.1:443",
-				"t0":           fixedTime.Format(time.RFC3339Nano),
-				"t":            fixedTime.Format(time.RFC3339Nano),
+				"level":           "INFO",
+				"msg":             "writeDone",
+				"connId":          float64(7),
+				"ioBytesCount":    float64(len(data)),
+				"ioPayloadPrefix": "",
+				"err":             nil,
+				"errClass":        "",
+				"localAddr":       "127.0.0.1:1234",
+				"protocol":        "tcp",
+				"remoteAddr":      "1.1.1.1:443",
+				"t0":              fixedTime.Format(time.RFC3339Nano),
+				"t":               fixedTime.Format(time.RFC3339Nano),
 			}, doneLog)
 		})
 
@@ -632,6 +681,7 @@ func Test_connWrapper(t *testing.T) {
 			assert.Equal(t, map[string]interface{}{
 				"level":        "INFO",
 				"msg":          "writeStart",
+				"connId":       float64(7),
 				"ioBufferSize": float64(len(data)),
 				"localAddr":    "127.0.0.1:1234",
 				"protocol":     "tcp",
@@ -644,16 +694,18 @@ func Test_connWrapper(t *testing.T) {
 			err = json.Unmarshal([]byte(logs[1]), &doneLog)
 			assert.NoError(t, err)
 			assert.Equal(t, map[string]interface{}{
-				"level":        "INFO",
-				"msg":          "writeDone",
-				"ioBytesCount": float64(0),
-				"err":          expectedErr.Error(),
-				"errClass":     "EGENERIC",
-				"localAddr":    "127.0.0.1:1234",
-				"protocol":     "tcp",
-				"remoteAddr":   "1.1.1.1:443",
-				"t0":           fixedTime.Format(time.RFC3339Nano),
-				"t":            fixedTime.Format(time.RFC3339Nano),
+				"level":           "INFO",
+				"msg":             "writeDone",
+				"connId":          float64(7),
+				"ioBytesCount":    float64(0),
+				"ioPayloadPrefix": "",
+				"err":             expectedErr.Error(),
+				"errClass":        "EGENERIC",
+				"localAddr":       "127.0.0.1:1234",
+				"protocol":        "tcp",
+				"remoteAddr":      "1.1.1.1:443",
+				"t0":              fixedTime.Format(time.RFC3339Nano),
+				"t":               fixedTime.Format(time.RFC3339Nano),
 			}, doneLog)
 		})
 
@@ -673,6 +725,7 @@ func Test_connWrapper(t *testing.T) {
 			wrapper := &connWrapper{
 				ctx:      context.Background(),
 				conn:     mock,
+				connID:   7,
 				laddr:    "127.0.0.1:1234",
 				netx:     &Network{}, // no logger configured
 				protocol: "tcp",
@@ -832,3 +885,121 @@ func Test_connWrapper(t *testing.T) {
 		assert.ErrorIs(t, err, expectedErr)
 	})
 }
+
+func Test_connWrapperIOVerbosity(t *testing.T) {
+	// newConn creates a wrapped connection where each read and write
+	// operation transfers four bytes, logging using the given handler.
+	newConn := func(nx *Network) net.Conn {
+		mock := &mocks.Conn{
+			MockClose:      func() error { return nil },
+			MockLocalAddr:  func() net.Addr { return &net.TCPAddr{} },
+			MockRead:       func(b []byte) (int, error) { return 4, nil },
+			MockRemoteAddr: func() net.Addr { return &net.TCPAddr{} },
+			MockWrite:      func(b []byte) (int, error) { return 4, nil },
+		}
+		return WrapConn(context.Background(), nx, mock)
+	}
+
+	// transfer performs three reads and two writes and closes the conn.
+	transfer := func(conn net.Conn) {
+		buf := make([]byte, 4)
+		for idx := 0; idx < 3; idx++ {
+			conn.Read(buf)
+		}
+		for idx := 0; idx < 2; idx++ {
+			conn.Write(buf)
+		}
+		conn.Close()
+	}
+
+	// parse returns the parsed log lines.
+	parse := func(buf *bytes.Buffer) (entries []map[string]any) {
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(line), &entry); err == nil {
+				entries = append(entries, entry)
+			}
+		}
+		return
+	}
+
+	// names returns the names of the events along with their levels.
+	names := func(entries []map[string]any) (out []string) {
+		for _, entry := range entries {
+			out = append(out, entry["level"].(string)+":"+entry["msg"].(string))
+		}
+		return
+	}
+
+	t.Run("we limit the number of I/O events", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			LogMaxIOEvents: 1,
+			Logger:         slog.New(slog.NewJSONHandler(&buf, nil)),
+		}
+		transfer(newConn(nx))
+
+		entries := parse(&buf)
+		assert.Equal(t, []string{
+			"INFO:readStart", "INFO:readDone",
+			"INFO:writeStart", "INFO:writeDone",
+			"INFO:closeStart", "INFO:closeDone", "INFO:connStats",
+		}, names(entries))
+		assert.Equal(t, float64(12), entries[5]["ioBytesReadTotal"])
+		assert.Equal(t, float64(8), entries[5]["ioBytesWrittenTotal"])
+		assert.Equal(t, float64(3), entries[6]["ioReadCount"])
+		assert.Equal(t, float64(2), entries[6]["ioWriteCount"])
+	})
+
+	t.Run("we can demote the I/O events", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			LogLevelIO: slog.LevelDebug,
+			Logger:     slog.New(slog.NewJSONHandler(&buf, nil)),
+		}
+		transfer(newConn(nx))
+
+		entries := parse(&buf)
+		assert.Equal(t, []string{"INFO:closeStart", "INFO:closeDone", "INFO:connStats"}, names(entries))
+		assert.Equal(t, float64(12), entries[1]["ioBytesReadTotal"])
+		assert.Equal(t, float64(8), entries[1]["ioBytesWrittenTotal"])
+	})
+
+	t.Run("the demoted I/O events are emitted at the given level", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			LogLevelIO:     slog.LevelDebug,
+			LogMaxIOEvents: 1,
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				Level: slog.LevelDebug,
+			})),
+		}
+		transfer(newConn(nx))
+
+		assert.Equal(t, []string{
+			"DEBUG:readStart", "DEBUG:readDone",
+			"DEBUG:writeStart", "DEBUG:writeDone",
+			"INFO:closeStart", "INFO:closeDone", "INFO:connStats",
+		}, names(parse(&buf)))
+	})
+
+	t.Run("we include the payload prefix when configured", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			LogIOPayloadBytes: 3,
+			Logger:            slog.New(slog.NewJSONHandler(&buf, nil)),
+		}
+		conn := newConn(nx)
+		conn.Read([]byte("GET /"))
+		conn.Write([]byte("HTTP"))
+		conn.Write(nil)
+
+		var prefixes []any
+		for _, entry := range parse(&buf) {
+			if value, found := entry["ioPayloadPrefix"]; found {
+				prefixes = append(prefixes, value)
+			}
+		}
+		assert.Equal(t, []any{"R0VU", "SFRU", ""}, prefixes)
+	})
+}
@@ -0,0 +1,41 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Connection identifiers.
+//
+
+package netcore
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// connIDCounter is the counter used to generate connection IDs.
+var connIDCounter atomic.Int64
+
+// connIDKey is the context key for the connection ID.
+type connIDKey struct{}
+
+// withConnID returns a context containing a new, process-wide unique
+// connection ID, unless the context already contains one. We use the
+// connection ID to correlate all the events of a logical connection.
+func withConnID(ctx context.Context) context.Context {
+	if connIDFromContext(ctx) != 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, connIDKey{}, connIDCounter.Add(1))
+}
+
+// withNewConnID is like withConnID but always assigns a new connection ID,
+// which we use for the connections accepted by a listener.
+func withNewConnID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connIDKey{}, connIDCounter.Add(1))
+}
+
+// connIDFromContext returns the connection ID inside the
+// context or zero if the context does not contain one.
+func connIDFromContext(ctx context.Context) int64 {
+	id, _ := ctx.Value(connIDKey{}).(int64)
+	return id
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withTestConnID returns a context containing the connection ID 7.
+func withTestConnID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, connIDKey{}, int64(7))
+}
+
+func TestWithConnID(t *testing.T) {
+	t.Run("we create unique IDs", func(t *testing.T) {
+		ctx := context.Background()
+		assert.Equal(t, int64(0), connIDFromContext(ctx))
+		id1 := connIDFromContext(withConnID(ctx))
+		id2 := connIDFromContext(withConnID(ctx))
+		assert.NotZero(t, id1)
+		assert.NotZero(t, id2)
+		assert.NotEqual(t, id1, id2)
+	})
+
+	t.Run("we keep an existing ID", func(t *testing.T) {
+		ctx := withTestConnID(context.Background())
+		assert.Equal(t, int64(7), connIDFromContext(withConnID(ctx)))
+	})
+}
+
+func TestConnIDCorrelation(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	nx := &Network{
+		Logger:   slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+		WrapConn: WrapConn,
+	}
+	nx.RootCAs = x509.NewCertPool()
+	nx.RootCAs.AddCert(srv.Certificate())
+	for range 2 {
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	ids := map[string][]float64{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		id, ok := ev["connId"].(float64)
+		require.True(t, ok, line)
+		ids[ev["msg"].(string)] = append(ids[ev["msg"].(string)], id)
+	}
+
+	// both connections use the same ID for all their events
+	for _, msg := range []string{"connectStart", "connectDone", "tlsHandshakeStart",
+		"tlsHandshakeDone", "writeDone", "readDone", "closeStart", "closeDone"} {
+		require.NotEmpty(t, ids[msg], msg)
+		assert.Equal(t, ids["connectStart"][0], ids[msg][0], msg)
+		assert.Equal(t, ids["connectStart"][len(ids["connectStart"])-1], ids[msg][len(ids[msg])-1], msg)
+	}
+
+	// but each connection has its own ID
+	require.Len(t, ids["connectStart"], 2)
+	assert.NotEqual(t, ids["connectStart"][0], ids["connectStart"][1])
+}
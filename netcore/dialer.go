@@ -10,56 +10,83 @@ package netcore
 
 import (
 	"context"
-	"errors"
-	"log/slog"
 	"net"
 	"time"
-
-	"github.com/rbmk-project/common/errclass"
 )
 
 // DialContext establishes a new TCP/UDP connection.
 func (nx *Network) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	// tunnel TCP connections through the proxy, if configured
+	if nx.usesProxy(network) {
+		return nx.dialProxy(ctx, network, address, nil)
+	}
+
 	// resolve the endpoints to connect to
-	endpoints, err := nx.maybeLookupEndpoint(ctx, address)
+	endpoints, err := nx.LookupEndpoint(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// only keep the endpoints matching the address family
+	endpoints, err = nx.filterEndpoints(network, endpoints)
 	if err != nil {
 		return nil, err
 	}
 
-	// sequentially attempt with each available endpoint
-	return nx.sequentialDial(ctx, network, nx.dialLog, endpoints...)
+	// attempt with the available endpoints according to the dial policy
+	return nx.dialEndpoints(ctx, network, nx.dialLog, endpoints...)
 }
 
-// dialContextFunc is a function used to dial a connection.
-type dialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
-
-// sequentialDial attempts to dial the endpoints in sequence until one
-// of them succeeds. It returns the first successfully established network
-// connection, on success, and the union of all errors, otherwise.
-func (nx *Network) sequentialDial(
-	ctx context.Context,
-	network string,
-	fx dialContextFunc,
-	endpoints ...string,
-) (net.Conn, error) {
-	// TODO(bassosimone): decide whether we want to sort IPv4 before IPv6
-	// here, and whether we want another method for happy eyeballs.
-	var errv []error
-	for _, endpoint := range endpoints {
-		conn, err := fx(ctx, network, endpoint)
-		if conn != nil && err == nil {
-			return conn, nil
-		}
-		errv = append(errv, err)
+// DialContextWithAddrs is like DialContext but connects to the given IP
+// addresses of the domain name using the given port without resolving the
+// domain name again, which is useful when the addresses come from a previous
+// DNS measurement. We still filter the addresses according to the address
+// family, attempt them according to the dial policy, and emit events for
+// each attempted endpoint. This method does not use the ProxyURL, since the
+// proxy would resolve the domain name again.
+//
+// This method is goroutine safe.
+func (nx *Network) DialContextWithAddrs(ctx context.Context,
+	network, domain, port string, addrs []string) (net.Conn, error) {
+	endpoints, err := nx.endpointsWithAddrs(network, domain, port, addrs)
+	if err != nil {
+		return nil, err
 	}
-	if len(errv) <= 0 {
-		return nil, errors.New("no endpoints to dial")
+	return nx.dialEndpoints(ctx, network, nx.dialLog, endpoints...)
+}
+
+// endpointsWithAddrs returns the endpoints to dial given the addresses
+// of a domain name, filtered according to the address family.
+func (nx *Network) endpointsWithAddrs(network, domain, port string, addrs []string) ([]string, error) {
+	if len(addrs) <= 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: domain, IsNotFound: true}
+	}
+	var endpoints []string
+	for _, addr := range addrs {
+		if net.ParseIP(addr) == nil {
+			return nil, &net.AddrError{Err: "not an IP address", Addr: addr}
+		}
+		endpoints = append(endpoints, net.JoinHostPort(addr, port))
 	}
-	return nil, errors.Join(errv...)
+	return nx.filterEndpoints(network, endpoints)
 }
 
 // dialLog dials and emits structured logs.
+//
+// The events use the connection ID inside the context, if any, or a new one.
 func (nx *Network) dialLog(ctx context.Context, network, address string) (net.Conn, error) {
+	// Make sure we have a connection ID for correlating events
+	ctx = withConnID(ctx)
+
+	// Optionally wait for a free slot (see MaxConcurrentDials) and skip
+	// the endpoints that failed repeatedly (see CircuitBreaker)
+	release, queueWait, err := nx.acquireDialSlot(ctx)
+	if err == nil {
+		if err = nx.circuitBreakerAllow(address); err != nil {
+			release()
+		}
+	}
+
 	// Optionally enforce timeout for connection establishment
 	if nx.DialContextTimeout > 0 {
 		var cancel context.CancelFunc
@@ -68,23 +95,31 @@ func (nx *Network) dialLog(ctx context.Context, network, address string) (net.Co
 	}
 
 	// Emit structured event before the dial
-	t0 := nx.emitConnectStart(ctx, network, address)
-
-	// Establish the connection
-	conn, err := nx.dialNet(ctx, network, address)
+	t0 := nx.emitConnectStart(ctx, network, address, queueWait)
+
+	// Establish the connection unless we could not get a slot
+	// or we skipped the endpoint
+	var conn net.Conn
+	if err == nil {
+		conn, err = nx.dialNet(ctx, network, address)
+		release()
+		countOperation(&nx.stats.dialsAttempted, &nx.stats.dialsSucceeded, err)
+		nx.circuitBreakerRecord(address, err)
+	}
 
 	// Emit structured event after the dial
 	nx.emitConnectDone(ctx, network, address, t0, conn, err)
 
-	// Maybe wrap the connection if it's not nil and it makes sense
-	// to wrap it (i.e., we have logging enabled)
+	// Wrap the connection if it's not nil to count its bytes and, when
+	// we have logging enabled, to also emit the I/O events
 	conn = nx.maybeWrapConn(ctx, conn)
 
 	// Return the connection and error to the caller
 	return conn, err
 }
 
-// defaultDialer is the default [*net.Dialer] we use.
+// defaultDialer is the default [*net.Dialer] we use, which disables
+// Multipath TCP unless the [*Network] sets EnableMultipathTCP.
 var defaultDialer = func() *net.Dialer {
 	dialer := &net.Dialer{}
 	dialer.SetMultipathTCP(false)
@@ -103,20 +138,48 @@ func (nx *Network) dialNet(ctx context.Context, network, address string) (net.Co
 	if nx.NewDialerOrSingleton != nil {
 		child = nx.NewDialerOrSingleton()
 	}
+
+	// optionally enable Multipath TCP
+	if nx.EnableMultipathTCP {
+		mptcp := *child
+		mptcp.SetMultipathTCP(true)
+		child = &mptcp
+	}
+
+	// apply the socket options, if any
+	if control := nx.controlFunc(); control != nil {
+		child = dialerWithControl(child, control)
+	}
 	return child.DialContext(ctx, network, address)
 }
 
+// usesMultipathTCP returns whether the connection negotiated Multipath TCP,
+// which we only check when EnableMultipathTCP is true.
+func (nx *Network) usesMultipathTCP(conn net.Conn) bool {
+	if !nx.EnableMultipathTCP {
+		return false
+	}
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return false
+	}
+	mptcp, err := tcpConn.MultipathTCP()
+	return err == nil && mptcp
+}
+
 // emitConnectStart emits a structured event before the dial.
-func (nx *Network) emitConnectStart(ctx context.Context, network, address string) time.Time {
+func (nx *Network) emitConnectStart(ctx context.Context,
+	network, address string, queueWait time.Duration) time.Time {
 	t0 := nx.timeNow()
-	if nx.Logger != nil {
-		nx.Logger.InfoContext(
-			ctx,
-			"connectStart",
-			slog.String("protocol", network),
-			slog.String("remoteAddr", address),
-			slog.Time("t", t0),
-		)
+	if nx.emitEnabled() {
+		nx.emit(ctx, &ConnectStartEvent{
+			AttemptIndex:  attemptIndexFromContext(ctx),
+			ConnID:        connIDFromContext(ctx),
+			Protocol:      network,
+			QueueWaitUsec: queueWait.Microseconds(),
+			RemoteAddr:    address,
+			T:             t0,
+		})
 	}
 	return t0
 }
@@ -124,17 +187,21 @@ func (nx *Network) emitConnectStart(ctx context.Context, network, address string
 // emitConnectDone emits a structured event after the dial.
 func (nx *Network) emitConnectDone(ctx context.Context,
 	network, address string, t0 time.Time, conn net.Conn, err error) {
-	if nx.Logger != nil {
-		nx.Logger.InfoContext(
-			ctx,
-			"connectDone",
-			slog.Any("err", err),
-			slog.String("errClass", errclass.New(err)),
-			slog.String("localAddr", connLocalAddr(conn).String()),
-			slog.String("protocol", network),
-			slog.String("remoteAddr", address),
-			slog.Time("t0", t0),
-			slog.Time("t", nx.timeNow()),
-		)
+	if nx.emitEnabled() {
+		nx.emit(ctx, &ConnectDoneEvent{
+			AttemptIndex:        attemptIndexFromContext(ctx),
+			ConnID:              connIDFromContext(ctx),
+			Device:              nx.boundDevice(),
+			Err:                 errString(err),
+			ErrClass:            nx.errClass(err),
+			LocalAddr:           connLocalAddr(conn).String(),
+			MultipathTCP:        nx.usesMultipathTCP(conn),
+			MultipathTCPEnabled: nx.EnableMultipathTCP,
+			Protocol:            network,
+			RemoteAddr:          address,
+			SocketOptions:       nx.socketOptions(),
+			T0:                  t0,
+			T:                   nx.timeNow(),
+		})
 	}
 }
@@ -68,14 +68,14 @@ func TestNetwork_DialContext(t *testing.T) {
 		}
 		conn, err := nx.DialContext(context.Background(), "tcp", "example.com:80")
 		assert.NoError(t, err)
-		assert.Equal(t, mockConn, conn)
+		assert.Equal(t, mockConn, conn.(*statsConn).Conn)
 	})
 }
 
-func TestNetwork_sequentialDial(t *testing.T) {
+func TestSequentialDialPolicy(t *testing.T) {
 	t.Run("empty endpoints list", func(t *testing.T) {
 		nx := &Network{}
-		conn, err := nx.sequentialDial(context.Background(), "tcp", nx.dialLog)
+		conn, err := SequentialDialPolicy{}.Dial(context.Background(), "tcp", nx.dialLog)
 		assert.Error(t, err)
 		assert.Nil(t, conn)
 	})
@@ -93,7 +93,7 @@ func TestNetwork_sequentialDial(t *testing.T) {
 				return nil, expectedErr2
 			},
 		}
-		conn, err := nx.sequentialDial(context.Background(), "tcp", nx.dialLog, "1.1.1.1:80", "2.2.2.2:80")
+		conn, err := SequentialDialPolicy{}.Dial(context.Background(), "tcp", nx.dialLog, "1.1.1.1:80", "2.2.2.2:80")
 		assert.Error(t, err)
 		assert.Nil(t, conn)
 		assert.Equal(t, 2, dialAttempts)
@@ -108,9 +108,9 @@ func TestNetwork_sequentialDial(t *testing.T) {
 				return mockConn, nil
 			},
 		}
-		conn, err := nx.sequentialDial(context.Background(), "tcp", nx.dialLog, "1.1.1.1:80", "2.2.2.2:80")
+		conn, err := SequentialDialPolicy{}.Dial(context.Background(), "tcp", nx.dialLog, "1.1.1.1:80", "2.2.2.2:80")
 		assert.NoError(t, err)
-		assert.Equal(t, mockConn, conn)
+		assert.Equal(t, mockConn, conn.(*statsConn).Conn)
 	})
 
 	t.Run("second endpoint succeeds", func(t *testing.T) {
@@ -126,9 +126,9 @@ func TestNetwork_sequentialDial(t *testing.T) {
 				return mockConn, nil
 			},
 		}
-		conn, err := nx.sequentialDial(context.Background(), "tcp", nx.dialLog, "1.1.1.1:80", "2.2.2.2:80")
+		conn, err := SequentialDialPolicy{}.Dial(context.Background(), "tcp", nx.dialLog, "1.1.1.1:80", "2.2.2.2:80")
 		assert.NoError(t, err)
-		assert.Equal(t, mockConn, conn)
+		assert.Equal(t, mockConn, conn.(*statsConn).Conn)
 		assert.Equal(t, 2, dialAttempts)
 	})
 }
@@ -169,9 +169,9 @@ func TestNetwork_dialLog(t *testing.T) {
 			},
 		}
 
-		conn, err := nx.dialLog(context.Background(), "tcp", "1.1.1.1:80")
+		conn, err := nx.dialLog(withTestConnID(context.Background()), "tcp", "1.1.1.1:80")
 		assert.NoError(t, err)
-		assert.Equal(t, mockConn, conn)
+		assert.Equal(t, mockConn, conn.(*statsConn).Conn)
 
 		logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
 		assert.Len(t, logs, 2)
@@ -181,11 +181,14 @@ func TestNetwork_dialLog(t *testing.T) {
 		err = json.Unmarshal([]byte(logs[0]), &startLog)
 		assert.NoError(t, err)
 		assert.Equal(t, map[string]interface{}{
-			"level":      "INFO",
-			"msg":        "connectStart",
-			"protocol":   "tcp",
-			"remoteAddr": "1.1.1.1:80",
-			"t":          fixedTime.Format(time.RFC3339Nano),
+			"level":         "INFO",
+			"msg":           "connectStart",
+			"attemptIndex":  float64(0),
+			"connId":        float64(7),
+			"protocol":      "tcp",
+			"queueWaitUsec": float64(0),
+			"remoteAddr":    "1.1.1.1:80",
+			"t":             fixedTime.Format(time.RFC3339Nano),
 		}, startLog)
 
 		// Verify connectDone log
@@ -193,15 +196,17 @@ func TestNetwork_dialLog(t *testing.T) {
 		err = json.Unmarshal([]byte(logs[1]), &doneLog)
 		assert.NoError(t, err)
 		assert.Equal(t, map[string]interface{}{
-			"level":      "INFO",
-			"msg":        "connectDone",
-			"err":        nil,
-			"errClass":   "",
-			"localAddr":  "127.0.0.1:1234",
-			"protocol":   "tcp",
-			"remoteAddr": "1.1.1.1:80",
-			"t0":         fixedTime.Format(time.RFC3339Nano),
-			"t":          fixedTime.Format(time.RFC3339Nano),
+			"level":        "INFO",
+			"msg":          "connectDone",
+			"attemptIndex": float64(0),
+			"connId":       float64(7),
+			"err":          nil,
+			"errClass":     "",
+			"localAddr":    "127.0.0.1:1234",
+			"protocol":     "tcp",
+			"remoteAddr":   "1.1.1.1:80",
+			"t0":           fixedTime.Format(time.RFC3339Nano),
+			"t":            fixedTime.Format(time.RFC3339Nano),
 		}, doneLog)
 	})
 
@@ -229,7 +234,7 @@ func TestNetwork_dialLog(t *testing.T) {
 			},
 		}
 
-		conn, err := nx.dialLog(context.Background(), "tcp", "1.1.1.1:80")
+		conn, err := nx.dialLog(withTestConnID(context.Background()), "tcp", "1.1.1.1:80")
 		assert.ErrorIs(t, err, expectedErr)
 		assert.Nil(t, conn)
 
@@ -241,11 +246,14 @@ func TestNetwork_dialLog(t *testing.T) {
 		err = json.Unmarshal([]byte(logs[0]), &startLog)
 		assert.NoError(t, err)
 		assert.Equal(t, map[string]interface{}{
-			"level":      "INFO",
-			"msg":        "connectStart",
-			"protocol":   "tcp",
-			"remoteAddr": "1.1.1.1:80",
-			"t":          fixedTime.Format(time.RFC3339Nano),
+			"level":         "INFO",
+			"msg":           "connectStart",
+			"attemptIndex":  float64(0),
+			"connId":        float64(7),
+			"protocol":      "tcp",
+			"queueWaitUsec": float64(0),
+			"remoteAddr":    "1.1.1.1:80",
+			"t":             fixedTime.Format(time.RFC3339Nano),
 		}, startLog)
 
 		// Verify connectDone log
@@ -253,15 +261,17 @@ func TestNetwork_dialLog(t *testing.T) {
 		err = json.Unmarshal([]byte(logs[1]), &doneLog)
 		assert.NoError(t, err)
 		assert.Equal(t, map[string]interface{}{
-			"level":      "INFO",
-			"msg":        "connectDone",
-			"err":        expectedErr.Error(),
-			"errClass":   "EGENERIC",
-			"localAddr":  "",
-			"protocol":   "tcp",
-			"remoteAddr": "1.1.1.1:80",
-			"t0":         fixedTime.Format(time.RFC3339Nano),
-			"t":          fixedTime.Format(time.RFC3339Nano),
+			"level":        "INFO",
+			"msg":          "connectDone",
+			"attemptIndex": float64(0),
+			"connId":       float64(7),
+			"err":          expectedErr.Error(),
+			"errClass":     "EGENERIC",
+			"localAddr":    "",
+			"protocol":     "tcp",
+			"remoteAddr":   "1.1.1.1:80",
+			"t0":           fixedTime.Format(time.RFC3339Nano),
+			"t":            fixedTime.Format(time.RFC3339Nano),
 		}, doneLog)
 	})
 
@@ -281,9 +291,9 @@ func TestNetwork_dialLog(t *testing.T) {
 			},
 		}
 
-		conn, err := nx.dialLog(context.Background(), "tcp", "1.1.1.1:80")
+		conn, err := nx.dialLog(withTestConnID(context.Background()), "tcp", "1.1.1.1:80")
 		assert.NoError(t, err)
-		assert.Equal(t, mockConn, conn)
+		assert.Equal(t, mockConn, conn.(*statsConn).Conn)
 	})
 
 	t.Run("dial timeout exceeded", func(t *testing.T) {
@@ -319,7 +329,7 @@ func TestNetwork_dialLog(t *testing.T) {
 			DialContextTimeout: time.Microsecond,
 		}
 
-		conn, err := nx.dialLog(context.Background(), "tcp", "1.1.1.1:80")
+		conn, err := nx.dialLog(withTestConnID(context.Background()), "tcp", "1.1.1.1:80")
 		assert.ErrorIs(t, err, context.DeadlineExceeded)
 		assert.Nil(t, conn)
 
@@ -331,11 +341,14 @@ func TestNetwork_dialLog(t *testing.T) {
 		err = json.Unmarshal([]byte(logs[0]), &startLog)
 		assert.NoError(t, err)
 		assert.Equal(t, map[string]interface{}{
-			"level":      "INFO",
-			"msg":        "connectStart",
-			"protocol":   "tcp",
-			"remoteAddr": "1.1.1.1:80",
-			"t":          fixedTime.Format(time.RFC3339Nano),
+			"level":         "INFO",
+			"msg":           "connectStart",
+			"attemptIndex":  float64(0),
+			"connId":        float64(7),
+			"protocol":      "tcp",
+			"queueWaitUsec": float64(0),
+			"remoteAddr":    "1.1.1.1:80",
+			"t":             fixedTime.Format(time.RFC3339Nano),
 		}, startLog)
 
 		// Verify connectDone log
@@ -343,15 +356,17 @@ func TestNetwork_dialLog(t *testing.T) {
 		err = json.Unmarshal([]byte(logs[1]), &doneLog)
 		assert.NoError(t, err)
 		assert.Equal(t, map[string]interface{}{
-			"level":      "INFO",
-			"msg":        "connectDone",
-			"err":        context.DeadlineExceeded.Error(),
-			"errClass":   "ETIMEDOUT",
-			"localAddr":  "",
-			"protocol":   "tcp",
-			"remoteAddr": "1.1.1.1:80",
-			"t0":         fixedTime.Format(time.RFC3339Nano),
-			"t":          fixedTime.Format(time.RFC3339Nano),
+			"level":        "INFO",
+			"msg":          "connectDone",
+			"attemptIndex": float64(0),
+			"connId":       float64(7),
+			"err":          context.DeadlineExceeded.Error(),
+			"errClass":     "ETIMEDOUT",
+			"localAddr":    "",
+			"protocol":     "tcp",
+			"remoteAddr":   "1.1.1.1:80",
+			"t0":           fixedTime.Format(time.RFC3339Nano),
+			"t":            fixedTime.Format(time.RFC3339Nano),
 		}, doneLog)
 	})
 }
@@ -404,3 +419,92 @@ func TestNetwork_dialNet(t *testing.T) {
 		conn.Close()
 	})
 }
+
+func TestNetwork_DialContextWithAddrs(t *testing.T) {
+	newNetwork := func(dialed *[]string) *Network {
+		return &Network{
+			AddressFamilyPolicy: AddressFamilyPreferIPv6,
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				*dialed = append(*dialed, address)
+				return nil, errors.New("mocked dial error")
+			},
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				panic("should not be called")
+			},
+		}
+	}
+
+	t.Run("we dial the addresses without resolving", func(t *testing.T) {
+		var dialed []string
+		nx := newNetwork(&dialed)
+		conn, err := nx.DialContextWithAddrs(context.Background(),
+			"tcp", "example.com", "80", []string{"130.192.91.211", "2001:db8::1"})
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		assert.Equal(t, []string{"[2001:db8::1]:80", "130.192.91.211:80"}, dialed)
+	})
+
+	t.Run("we honour the address family of the network", func(t *testing.T) {
+		var dialed []string
+		nx := newNetwork(&dialed)
+		_, err := nx.DialContextWithAddrs(context.Background(),
+			"tcp4", "example.com", "80", []string{"130.192.91.211", "2001:db8::1"})
+		assert.Error(t, err)
+		assert.Equal(t, []string{"130.192.91.211:80"}, dialed)
+	})
+
+	t.Run("we reject the empty list of addresses", func(t *testing.T) {
+		var dialed []string
+		nx := newNetwork(&dialed)
+		conn, err := nx.DialContextWithAddrs(context.Background(), "tcp", "example.com", "80", nil)
+		var dnsErr *net.DNSError
+		assert.ErrorAs(t, err, &dnsErr)
+		assert.Equal(t, "example.com", dnsErr.Name)
+		assert.Nil(t, conn)
+		assert.Empty(t, dialed)
+	})
+
+	t.Run("we reject addresses that are not IP addresses", func(t *testing.T) {
+		var dialed []string
+		nx := newNetwork(&dialed)
+		conn, err := nx.DialContextWithAddrs(context.Background(),
+			"tcp", "example.com", "80", []string{"www.example.com"})
+		var addrErr *net.AddrError
+		assert.ErrorAs(t, err, &addrErr)
+		assert.Nil(t, conn)
+		assert.Empty(t, dialed)
+	})
+}
+
+func TestNetwork_EnableMultipathTCP(t *testing.T) {
+	lc := &net.ListenConfig{}
+	lc.SetMultipathTCP(true)
+	listener, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("cannot listen using Multipath TCP", err)
+	}
+	defer listener.Close()
+
+	for _, enabled := range []bool{false, true} {
+		var buf bytes.Buffer
+		nx := &Network{
+			EnableMultipathTCP: enabled,
+			Logger:             slog.New(slog.NewJSONHandler(&buf, nil)),
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", listener.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		mptcp, _ := conn.(*statsConn).Conn.(*net.TCPConn).MultipathTCP()
+		conn.Close()
+		if !enabled {
+			assert.False(t, mptcp)
+		}
+
+		var ev ConnectDoneEvent
+		logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.NoError(t, json.Unmarshal([]byte(logs[1]), &ev))
+		assert.Equal(t, mptcp, ev.MultipathTCP)
+		assert.Equal(t, enabled, ev.MultipathTCPEnabled)
+	}
+}
@@ -0,0 +1,281 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Policies for dialing endpoints.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"time"
+)
+
+// DialFunc is a function dialing a single endpoint.
+//
+// The functions that [*Network] passes to a [DialPolicy] emit
+// the structured logs for each connection attempt.
+type DialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// DialPolicy controls how [*Network] attempts the endpoints obtained
+// by resolving a domain name (e.g., sequentially or in parallel).
+//
+// Dial dials the given endpoints using the given [DialFunc] and returns
+// the first successfully established connection, on success, and the
+// union of all errors, otherwise. Implementations must close the
+// connections they do not return.
+type DialPolicy interface {
+	Dial(ctx context.Context, network string, fx DialFunc, endpoints ...string) (net.Conn, error)
+}
+
+// dialPolicy returns the configured [DialPolicy] or the default one.
+func (nx *Network) dialPolicy() DialPolicy {
+	if nx.DialPolicy != nil {
+		return nx.DialPolicy
+	}
+	return SequentialDialPolicy{}
+}
+
+// dialEndpoints dials the given endpoints using the configured [DialPolicy]
+// and a context containing the errClass method, such that the policy
+// classifies the errors like the events do (see [RetryDialPolicy]).
+func (nx *Network) dialEndpoints(ctx context.Context,
+	network string, fx DialFunc, endpoints ...string) (net.Conn, error) {
+	ctx = withErrClassifier(ctx, nx.errClass)
+	return nx.dialPolicy().Dial(ctx, network, fx, endpoints...)
+}
+
+// errNoEndpoints indicates that there are no endpoints to dial.
+var errNoEndpoints = errors.New("no endpoints to dial")
+
+// SequentialDialPolicy is a [DialPolicy] attempting the endpoints
+// in sequence, in the order in which they were resolved, until one
+// of them succeeds. This is the default [DialPolicy].
+type SequentialDialPolicy struct{}
+
+var _ DialPolicy = SequentialDialPolicy{}
+
+// Dial implements [DialPolicy].
+func (SequentialDialPolicy) Dial(
+	ctx context.Context,
+	network string,
+	fx DialFunc,
+	endpoints ...string,
+) (net.Conn, error) {
+	var errv []error
+	for _, endpoint := range endpoints {
+		conn, err := fx(ctx, network, endpoint)
+		if conn != nil && err == nil {
+			return conn, nil
+		}
+		errv = append(errv, err)
+	}
+	if len(errv) <= 0 {
+		return nil, errNoEndpoints
+	}
+	return nil, errors.Join(errv...)
+}
+
+// ParallelDialPolicy is a [DialPolicy] attempting all the endpoints
+// at the same time. The first successful attempt wins and we cancel
+// and close all the other attempts.
+type ParallelDialPolicy struct{}
+
+var _ DialPolicy = ParallelDialPolicy{}
+
+// Dial implements [DialPolicy].
+func (ParallelDialPolicy) Dial(
+	ctx context.Context,
+	network string,
+	fx DialFunc,
+	endpoints ...string,
+) (net.Conn, error) {
+	return raceDial(ctx, network, fx, 0, endpoints...)
+}
+
+// DefaultHappyEyeballsDelay is the default delay between connection
+// attempts used by [HappyEyeballsDialPolicy] (see RFC 8305).
+const DefaultHappyEyeballsDelay = 250 * time.Millisecond
+
+// HappyEyeballsDialPolicy is a [DialPolicy] implementing the connection
+// attempts algorithm of Happy Eyeballs v2 (see RFC 8305).
+//
+// We interleave the IPv6 and IPv4 endpoints, starting with the family of
+// the first endpoint, and we start a new attempt either when the previous
+// attempt fails or after Delay. The first successful attempt wins and
+// we cancel and close all the other attempts.
+type HappyEyeballsDialPolicy struct {
+	// Delay is the optional delay between starting connection attempts.
+	// If zero or negative, we use [DefaultHappyEyeballsDelay].
+	Delay time.Duration
+}
+
+var _ DialPolicy = HappyEyeballsDialPolicy{}
+
+// Dial implements [DialPolicy].
+func (p HappyEyeballsDialPolicy) Dial(
+	ctx context.Context,
+	network string,
+	fx DialFunc,
+	endpoints ...string,
+) (net.Conn, error) {
+	delay := p.Delay
+	if delay <= 0 {
+		delay = DefaultHappyEyeballsDelay
+	}
+	return raceDial(ctx, network, fx, delay, interleaveFamilies(endpoints)...)
+}
+
+// OrderedDialPolicy is a [DialPolicy] sorting the endpoints before
+// passing them to another [DialPolicy], which allows to customize the
+// order in which we attempt the endpoints.
+type OrderedDialPolicy struct {
+	// Compare is the function comparing endpoints, which we use
+	// to stable sort the endpoints (see [slices.SortStableFunc]).
+	//
+	// If this field is nil, we do not sort the endpoints.
+	Compare func(a, b string) int
+
+	// Policy is the optional [DialPolicy] to which we pass the
+	// sorted endpoints. If nil, we use [SequentialDialPolicy].
+	Policy DialPolicy
+}
+
+var _ DialPolicy = OrderedDialPolicy{}
+
+// Dial implements [DialPolicy].
+func (p OrderedDialPolicy) Dial(
+	ctx context.Context,
+	network string,
+	fx DialFunc,
+	endpoints ...string,
+) (net.Conn, error) {
+	endpoints = slices.Clone(endpoints)
+	if p.Compare != nil {
+		slices.SortStableFunc(endpoints, p.Compare)
+	}
+	policy := p.Policy
+	if policy == nil {
+		policy = SequentialDialPolicy{}
+	}
+	return policy.Dial(ctx, network, fx, endpoints...)
+}
+
+// dialResult is the result of a connection attempt.
+type dialResult struct {
+	conn net.Conn
+	err  error
+}
+
+// raceDial attempts the endpoints in order starting a new attempt either
+// after the given delay or when an attempt fails, and returns the first
+// successfully established connection. A zero delay means we start all
+// the attempts at the same time. Once an attempt succeeds, we cancel
+// the pending attempts and close the connections they may establish.
+func raceDial(
+	ctx context.Context,
+	network string,
+	fx DialFunc,
+	delay time.Duration,
+	endpoints ...string,
+) (net.Conn, error) {
+	if len(endpoints) <= 0 {
+		return nil, errNoEndpoints
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialResult, len(endpoints))
+	var (
+		errv    []error
+		next    int
+		pending int
+	)
+	start := func() {
+		endpoint := endpoints[next]
+		next++
+		pending++
+		go func() {
+			conn, err := fx(ctx, network, endpoint)
+			results <- dialResult{conn, err}
+		}()
+	}
+
+	start()
+	for pending > 0 {
+		if delay <= 0 && next < len(endpoints) {
+			start()
+			continue
+		}
+		var (
+			timer   *time.Timer
+			timerch <-chan time.Time
+		)
+		if next < len(endpoints) {
+			timer = time.NewTimer(delay)
+			timerch = timer.C
+		}
+		select {
+		case <-timerch:
+			start()
+		case res := <-results:
+			pending--
+			if timer != nil {
+				timer.Stop()
+			}
+			if res.conn != nil && res.err == nil {
+				go closeDialResults(results, pending)
+				return res.conn, nil
+			}
+			errv = append(errv, res.err)
+			if next < len(endpoints) {
+				start()
+			}
+		}
+	}
+	return nil, errors.Join(errv...)
+}
+
+// closeDialResults reads the given number of results from the
+// channel and closes the connections they contain.
+func closeDialResults(results <-chan dialResult, count int) {
+	for ; count > 0; count-- {
+		if res := <-results; res.conn != nil {
+			res.conn.Close()
+		}
+	}
+}
+
+// interleaveFamilies reorders the endpoints alternating IPv6 and
+// IPv4 addresses, starting with the family of the first endpoint,
+// and otherwise preserving the order of the endpoints.
+func interleaveFamilies(endpoints []string) []string {
+	var ipv4, ipv6 []string
+	for _, endpoint := range endpoints {
+		if endpointIsIPv6(endpoint) {
+			ipv6 = append(ipv6, endpoint)
+		} else {
+			ipv4 = append(ipv4, endpoint)
+		}
+	}
+	first, second := ipv4, ipv6
+	if len(endpoints) > 0 && endpointIsIPv6(endpoints[0]) {
+		first, second = ipv6, ipv4
+	}
+	out := make([]string, 0, len(endpoints))
+	for len(first) > 0 || len(second) > 0 {
+		if len(first) > 0 {
+			out = append(out, first[0])
+			first = first[1:]
+		}
+		if len(second) > 0 {
+			out = append(out, second[0])
+			second = second[1:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+// newMockConnCloser returns a mock conn recording whether it was closed.
+func newMockConnCloser(closed *sync.WaitGroup) *mocks.Conn {
+	return &mocks.Conn{
+		MockClose: func() error {
+			closed.Done()
+			return nil
+		},
+	}
+}
+
+func TestNetwork_dialPolicy(t *testing.T) {
+	t.Run("default policy", func(t *testing.T) {
+		nx := &Network{}
+		assert.Equal(t, SequentialDialPolicy{}, nx.dialPolicy())
+	})
+
+	t.Run("custom policy", func(t *testing.T) {
+		var endpoints []string
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				endpoints = append(endpoints, address)
+				return nil, errors.New("mocked error")
+			},
+			DialPolicy: OrderedDialPolicy{Compare: strings.Compare},
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"2.2.2.2", "1.1.1.1"}, nil
+			},
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "example.com:80")
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		assert.Equal(t, []string{"1.1.1.1:80", "2.2.2.2:80"}, endpoints)
+	})
+}
+
+func TestParallelDialPolicy(t *testing.T) {
+	t.Run("empty endpoints list", func(t *testing.T) {
+		conn, err := ParallelDialPolicy{}.Dial(context.Background(), "tcp", nil)
+		assert.ErrorIs(t, err, errNoEndpoints)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("all endpoints fail", func(t *testing.T) {
+		expectedErr1 := errors.New("error 1")
+		expectedErr2 := errors.New("error 2")
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "1.1.1.1:80" {
+				return nil, expectedErr1
+			}
+			return nil, expectedErr2
+		}
+		conn, err := ParallelDialPolicy{}.Dial(context.Background(), "tcp", fx, "1.1.1.1:80", "2.2.2.2:80")
+		assert.Nil(t, conn)
+		assert.ErrorIs(t, err, expectedErr1)
+		assert.ErrorIs(t, err, expectedErr2)
+	})
+
+	t.Run("fastest endpoint wins and we close the others", func(t *testing.T) {
+		var closed sync.WaitGroup
+		closed.Add(1)
+		slowConn := newMockConnCloser(&closed)
+		fastConn := &mocks.Conn{}
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "1.1.1.1:80" {
+				time.Sleep(100 * time.Millisecond)
+				return slowConn, nil
+			}
+			return fastConn, nil
+		}
+		conn, err := ParallelDialPolicy{}.Dial(context.Background(), "tcp", fx, "1.1.1.1:80", "2.2.2.2:80")
+		assert.NoError(t, err)
+		assert.Equal(t, fastConn, conn)
+		closed.Wait()
+	})
+}
+
+func TestHappyEyeballsDialPolicy(t *testing.T) {
+	t.Run("empty endpoints list", func(t *testing.T) {
+		conn, err := HappyEyeballsDialPolicy{}.Dial(context.Background(), "tcp", nil)
+		assert.ErrorIs(t, err, errNoEndpoints)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("we interleave families and start the next attempt on failure", func(t *testing.T) {
+		var (
+			endpoints []string
+			mu        sync.Mutex
+		)
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			mu.Lock()
+			endpoints = append(endpoints, address)
+			mu.Unlock()
+			return nil, errors.New("mocked error")
+		}
+		policy := HappyEyeballsDialPolicy{Delay: time.Hour}
+		conn, err := policy.Dial(context.Background(), "tcp", fx,
+			"[2001:db8::1]:443", "[2001:db8::2]:443", "1.1.1.1:443", "2.2.2.2:443")
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		expect := []string{"[2001:db8::1]:443", "1.1.1.1:443", "[2001:db8::2]:443", "2.2.2.2:443"}
+		assert.Equal(t, expect, endpoints)
+	})
+
+	t.Run("we start the next attempt after the delay", func(t *testing.T) {
+		var closed sync.WaitGroup
+		closed.Add(1)
+		hangingConn := newMockConnCloser(&closed)
+		secondConn := &mocks.Conn{}
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			if address == "[2001:db8::1]:443" {
+				<-ctx.Done()
+				return hangingConn, nil
+			}
+			return secondConn, nil
+		}
+		policy := HappyEyeballsDialPolicy{Delay: 10 * time.Millisecond}
+		conn, err := policy.Dial(context.Background(), "tcp", fx, "[2001:db8::1]:443", "1.1.1.1:443")
+		assert.NoError(t, err)
+		assert.Equal(t, secondConn, conn)
+		closed.Wait()
+	})
+}
+
+func TestOrderedDialPolicy(t *testing.T) {
+	t.Run("without compare function", func(t *testing.T) {
+		var endpoints []string
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			endpoints = append(endpoints, address)
+			return nil, errors.New("mocked error")
+		}
+		input := []string{"2.2.2.2:80", "1.1.1.1:80"}
+		conn, err := OrderedDialPolicy{}.Dial(context.Background(), "tcp", fx, input...)
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		assert.Equal(t, input, endpoints)
+	})
+
+	t.Run("with compare function and policy", func(t *testing.T) {
+		var endpoints []string
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			endpoints = append(endpoints, address)
+			return nil, errors.New("mocked error")
+		}
+		input := []string{"2.2.2.2:80", "1.1.1.1:80"}
+		policy := OrderedDialPolicy{Compare: strings.Compare, Policy: SequentialDialPolicy{}}
+		conn, err := policy.Dial(context.Background(), "tcp", fx, input...)
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+		assert.Equal(t, []string{"1.1.1.1:80", "2.2.2.2:80"}, endpoints)
+		assert.Equal(t, []string{"2.2.2.2:80", "1.1.1.1:80"}, input)
+	})
+}
+
+func TestInterleaveFamilies(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []string
+		want  []string
+	}{{
+		name:  "empty",
+		input: nil,
+		want:  []string{},
+	}, {
+		name:  "IPv4 first",
+		input: []string{"1.1.1.1:80", "2.2.2.2:80", "[::1]:80"},
+		want:  []string{"1.1.1.1:80", "[::1]:80", "2.2.2.2:80"},
+	}, {
+		name:  "IPv6 first",
+		input: []string{"[::1]:80", "[::2]:80", "1.1.1.1:80"},
+		want:  []string{"[::1]:80", "1.1.1.1:80", "[::2]:80"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, interleaveFamilies(tt.input))
+		})
+	}
+}
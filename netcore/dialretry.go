@@ -0,0 +1,184 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Retrying connection attempts.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/rbmk-project/common/errclass"
+)
+
+// DefaultRetryAttempts is the default number of attempts per endpoint
+// used by [RetryDialPolicy].
+const DefaultRetryAttempts = 3
+
+// DefaultRetryBackoff is the default delay before the first retry
+// used by [RetryDialPolicy].
+const DefaultRetryBackoff = 250 * time.Millisecond
+
+// DefaultRetryableErrClasses contains the default error classes
+// (see [errclass.New]) for which [RetryDialPolicy] retries.
+var DefaultRetryableErrClasses = []string{
+	errclass.ECONNREFUSED,
+	errclass.ECONNRESET,
+	errclass.ETIMEDOUT,
+}
+
+// RetryDialPolicy is a [DialPolicy] retrying each endpoint when the
+// connection attempt fails with a retryable error, waiting for an
+// exponentially increasing backoff between the attempts, and otherwise
+// delegating to another [DialPolicy] the choice of the endpoints order.
+//
+// The connectStart and connectDone events contain the zero-based index
+// of the attempt for the endpoint in the attemptIndex field, while each
+// attempt uses a distinct connection ID.
+type RetryDialPolicy struct {
+	// Attempts is the optional maximum number of attempts per endpoint.
+	// If zero or negative, we use [DefaultRetryAttempts].
+	Attempts int
+
+	// Backoff is the optional delay before the first retry, which we
+	// double after each retry. If zero or negative, we use
+	// [DefaultRetryBackoff].
+	Backoff time.Duration
+
+	// MaxBackoff is the optional maximum delay between attempts.
+	// If zero or negative, we do not limit the delay.
+	MaxBackoff time.Duration
+
+	// Policy is the optional [DialPolicy] to which we pass the retrying
+	// [DialFunc]. If nil, we use [SequentialDialPolicy].
+	Policy DialPolicy
+
+	// RetryableErrClasses contains the error classes (e.g., "ETIMEDOUT")
+	// for which we retry. If nil, we use [DefaultRetryableErrClasses].
+	// When dialing through a [*Network], the classes include the ones
+	// assigned by its ErrClassifiers (e.g., "EPROXYAUTH").
+	RetryableErrClasses []string
+}
+
+var _ DialPolicy = RetryDialPolicy{}
+
+// Dial implements [DialPolicy].
+func (p RetryDialPolicy) Dial(
+	ctx context.Context,
+	network string,
+	fx DialFunc,
+	endpoints ...string,
+) (net.Conn, error) {
+	policy := p.Policy
+	if policy == nil {
+		policy = SequentialDialPolicy{}
+	}
+	return policy.Dial(ctx, network, p.retryDialFunc(fx), endpoints...)
+}
+
+// retryDialFunc wraps the given [DialFunc] to retry each endpoint.
+func (p RetryDialPolicy) retryDialFunc(fx DialFunc) DialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		backoff := p.backoff()
+		for idx := 0; ; idx++ {
+			conn, err := fx(withAttemptIndex(ctx, idx), network, address)
+			if err == nil || idx+1 >= p.attempts() || !p.retryable(ctx, err) {
+				return conn, err
+			}
+			if werr := sleepContext(ctx, backoff); werr != nil {
+				return nil, err
+			}
+			backoff = p.nextBackoff(backoff)
+		}
+	}
+}
+
+// attempts returns the maximum number of attempts per endpoint.
+func (p RetryDialPolicy) attempts() int {
+	if p.Attempts > 0 {
+		return p.Attempts
+	}
+	return DefaultRetryAttempts
+}
+
+// backoff returns the delay before the first retry.
+func (p RetryDialPolicy) backoff() time.Duration {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = DefaultRetryBackoff
+	}
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, p.MaxBackoff)
+	}
+	return backoff
+}
+
+// nextBackoff returns the delay following the given one.
+func (p RetryDialPolicy) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, p.MaxBackoff)
+	}
+	return backoff
+}
+
+// retryable returns whether we should retry after the given error, which
+// we classify using the ErrClassifiers of the [*Network], if any.
+func (p RetryDialPolicy) retryable(ctx context.Context, err error) bool {
+	classes := p.RetryableErrClasses
+	if classes == nil {
+		classes = DefaultRetryableErrClasses
+	}
+	return slices.Contains(classes, errClassifierFromContext(ctx)(err))
+}
+
+// sleepContext waits for the given delay or until the context is done,
+// in which case we return the context error.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// errClassifierKey is the context key for the error classifier.
+type errClassifierKey struct{}
+
+// withErrClassifier returns a context containing the given function
+// classifying the errors, which is the errClass method of the [*Network].
+func withErrClassifier(ctx context.Context, classifier ErrClassifier) context.Context {
+	return context.WithValue(ctx, errClassifierKey{}, classifier)
+}
+
+// errClassifierFromContext returns the error classifier inside the
+// context, or the errClass function if the context does not contain any.
+func errClassifierFromContext(ctx context.Context) ErrClassifier {
+	if classifier, ok := ctx.Value(errClassifierKey{}).(ErrClassifier); ok {
+		return classifier
+	}
+	return errClass
+}
+
+// attemptIndexKey is the context key for the attempt index.
+type attemptIndexKey struct{}
+
+// withAttemptIndex returns a context containing the given zero-based
+// index of the connection attempt for an endpoint.
+func withAttemptIndex(ctx context.Context, idx int) context.Context {
+	return context.WithValue(ctx, attemptIndexKey{}, idx)
+}
+
+// attemptIndexFromContext returns the attempt index inside the context,
+// or zero if the context does not contain any.
+func attemptIndexFromContext(ctx context.Context) int {
+	idx, _ := ctx.Value(attemptIndexKey{}).(int)
+	return idx
+}
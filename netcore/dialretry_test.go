@@ -0,0 +1,172 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryDialPolicy(t *testing.T) {
+	t.Run("we retry the retryable errors with exponential backoff", func(t *testing.T) {
+		var (
+			attempts []int
+			times    []time.Time
+		)
+		expectConn := &mocks.Conn{}
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			attempts = append(attempts, attemptIndexFromContext(ctx))
+			times = append(times, time.Now())
+			if len(attempts) < 3 {
+				return nil, syscall.ECONNREFUSED
+			}
+			return expectConn, nil
+		}
+		policy := RetryDialPolicy{Backoff: 20 * time.Millisecond}
+		conn, err := policy.Dial(context.Background(), "tcp", fx, "1.1.1.1:80")
+		require.NoError(t, err)
+		assert.Equal(t, expectConn, conn)
+		assert.Equal(t, []int{0, 1, 2}, attempts)
+		assert.GreaterOrEqual(t, times[1].Sub(times[0]), 20*time.Millisecond)
+		assert.GreaterOrEqual(t, times[2].Sub(times[1]), 40*time.Millisecond)
+	})
+
+	t.Run("we give up after the configured attempts and try the next endpoint", func(t *testing.T) {
+		var endpoints []string
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			endpoints = append(endpoints, address)
+			return nil, syscall.ECONNRESET
+		}
+		policy := RetryDialPolicy{Attempts: 2, Backoff: time.Millisecond}
+		conn, err := policy.Dial(context.Background(), "tcp", fx, "1.1.1.1:80", "2.2.2.2:80")
+		assert.ErrorIs(t, err, syscall.ECONNRESET)
+		assert.Nil(t, conn)
+		expect := []string{"1.1.1.1:80", "1.1.1.1:80", "2.2.2.2:80", "2.2.2.2:80"}
+		assert.Equal(t, expect, endpoints)
+	})
+
+	t.Run("we do not retry the other errors", func(t *testing.T) {
+		var count int
+		expectedErr := errors.New("mocked error")
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			count++
+			return nil, expectedErr
+		}
+		conn, err := RetryDialPolicy{}.Dial(context.Background(), "tcp", fx, "1.1.1.1:80")
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, conn)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("we honour the configured error classes", func(t *testing.T) {
+		var count int
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			count++
+			return nil, syscall.ECONNREFUSED
+		}
+		policy := RetryDialPolicy{RetryableErrClasses: []string{"ETIMEDOUT"}}
+		conn, err := policy.Dial(context.Background(), "tcp", fx, "1.1.1.1:80")
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+		assert.Nil(t, conn)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("we stop waiting when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var count int
+		fx := func(ctx context.Context, network, address string) (net.Conn, error) {
+			count++
+			cancel()
+			return nil, syscall.ECONNREFUSED
+		}
+		policy := RetryDialPolicy{Backoff: time.Hour}
+		conn, err := policy.Dial(ctx, "tcp", fx, "1.1.1.1:80")
+		assert.ErrorIs(t, err, syscall.ECONNREFUSED)
+		assert.Nil(t, conn)
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("we cap the backoff", func(t *testing.T) {
+		policy := RetryDialPolicy{Backoff: time.Second, MaxBackoff: 3 * time.Second}
+		backoff := policy.backoff()
+		assert.Equal(t, time.Second, backoff)
+		backoff = policy.nextBackoff(backoff)
+		assert.Equal(t, 2*time.Second, backoff)
+		backoff = policy.nextBackoff(backoff)
+		assert.Equal(t, 3*time.Second, backoff)
+	})
+
+	t.Run("we retry the errors classified by the ErrClassifiers", func(t *testing.T) {
+		var count int
+		expectedErr := errors.New("mocked error")
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				count++
+				return nil, expectedErr
+			},
+			DialPolicy: RetryDialPolicy{
+				Attempts:            2,
+				Backoff:             time.Millisecond,
+				RetryableErrClasses: []string{"EMOCKED"},
+			},
+			ErrClassifiers: []ErrClassifier{func(err error) string {
+				if errors.Is(err, expectedErr) {
+					return "EMOCKED"
+				}
+				return ""
+			}},
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, conn)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("we log the attempt index", func(t *testing.T) {
+		var (
+			buf   bytes.Buffer
+			count int
+		)
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				count++
+				if count < 2 {
+					return nil, syscall.ECONNREFUSED
+				}
+				return &mocks.Conn{
+					MockLocalAddr: func() net.Addr { return &net.TCPAddr{} },
+				}, nil
+			},
+			DialPolicy: RetryDialPolicy{Backoff: time.Millisecond},
+			Logger:     slog.New(slog.NewJSONHandler(&buf, nil)),
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+		require.NoError(t, err)
+		require.NotNil(t, conn)
+
+		var (
+			indexes []float64
+			connIDs = map[float64]bool{}
+		)
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			indexes = append(indexes, ev["attemptIndex"].(float64))
+			connIDs[ev["connId"].(float64)] = true
+		}
+		assert.Equal(t, []float64{0, 0, 1, 1}, indexes)
+		assert.Len(t, connIDs, 2)
+	})
+}
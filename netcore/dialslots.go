@@ -0,0 +1,52 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Limiting the number of concurrent dials.
+//
+
+package netcore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// dialSlots is the semaphore limiting the number of concurrent dials
+// of a [*Network] according to its MaxConcurrentDials field.
+//
+// The zero value is ready to use.
+type dialSlots struct {
+	ch chan struct{}
+	mu sync.Mutex
+}
+
+// acquire waits for a free slot, given the maximum number of slots, and
+// returns the function releasing the slot, which the caller MUST call on
+// success. If the maximum number of slots is zero or negative, we do not
+// wait. On failure, we return the context error.
+func (ds *dialSlots) acquire(ctx context.Context, limit int) (func(), error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	ds.mu.Lock()
+	if ds.ch == nil {
+		ds.ch = make(chan struct{}, limit)
+	}
+	ch := ds.ch
+	ds.mu.Unlock()
+	select {
+	case ch <- struct{}{}:
+		return func() { <-ch }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// acquireDialSlot is like [*dialSlots.acquire] but uses the MaxConcurrentDials
+// field and also returns the time we spent waiting for the slot.
+func (nx *Network) acquireDialSlot(ctx context.Context) (func(), time.Duration, error) {
+	t0 := nx.timeNow()
+	release, err := nx.dialSlots.acquire(ctx, nx.MaxConcurrentDials)
+	return release, nx.timeNow().Sub(t0), err
+}
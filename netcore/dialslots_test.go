@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialSlots(t *testing.T) {
+	t.Run("we do not wait without a limit", func(t *testing.T) {
+		ds := &dialSlots{}
+		for idx := 0; idx < 16; idx++ {
+			release, err := ds.acquire(context.Background(), 0)
+			require.NoError(t, err)
+			require.NotNil(t, release)
+		}
+	})
+
+	t.Run("we wait for a free slot", func(t *testing.T) {
+		ds := &dialSlots{}
+		release, err := ds.acquire(context.Background(), 1)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = ds.acquire(ctx, 1)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		release()
+		release, err = ds.acquire(context.Background(), 1)
+		require.NoError(t, err)
+		release()
+	})
+}
+
+func TestNetwork_MaxConcurrentDials(t *testing.T) {
+	t.Run("we limit the concurrent dials", func(t *testing.T) {
+		var (
+			active  atomic.Int64
+			maximum atomic.Int64
+		)
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				count := active.Add(1)
+				for {
+					prev := maximum.Load()
+					if count <= prev || maximum.CompareAndSwap(prev, count) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				active.Add(-1)
+				return &mocks.Conn{}, nil
+			},
+			MaxConcurrentDials: 2,
+		}
+		var wg sync.WaitGroup
+		for idx := 0; idx < 8; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+				assert.NoError(t, err)
+				assert.NotNil(t, conn)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(2), maximum.Load())
+	})
+
+	t.Run("we log the queue wait and the context error", func(t *testing.T) {
+		var buf bytes.Buffer
+		started, unblock := make(chan struct{}), make(chan struct{})
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				close(started)
+				<-unblock
+				return &mocks.Conn{
+					MockLocalAddr: func() net.Addr { return &net.TCPAddr{} },
+				}, nil
+			},
+			Logger:             slog.New(slog.NewJSONHandler(&buf, nil)),
+			MaxConcurrentDials: 1,
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+			assert.NoError(t, err)
+			assert.NotNil(t, conn)
+		}()
+
+		// wait for the first dial to hold the slot
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		conn, err := nx.DialContext(ctx, "tcp", "127.0.0.1:80")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, conn)
+		close(unblock)
+		<-done
+
+		var found bool
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "connectStart" && ev["queueWaitUsec"].(float64) >= 10000 {
+				found = true
+			}
+		}
+		assert.True(t, found)
+		assert.Contains(t, buf.String(), `"errClass":"ETIMEDOUT"`)
+		assert.Equal(t, int64(1), nx.Stats().DialsAttempted)
+	})
+}
@@ -0,0 +1,156 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// In-memory DNS cache.
+//
+
+package netcore
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DNSCache is an in-memory cache of successful DNS lookups, which avoids
+// resolving again the same domain name during a measurement session, thus
+// preventing repeated lookups from skewing the timing of the fetches.
+//
+// We cache each result for the minimum TTL of the DNS records, when the
+// resolver provides it (see [*LookupResult]), or for DefaultTTL otherwise.
+// We never cache failed lookups.
+//
+// Use [WithDNSCacheBypass] to bypass the cache for a specific call.
+//
+// The zero value is ready to use. A [*DNSCache] is safe for concurrent use
+// by multiple goroutines and may be shared by several [*Network] instances.
+type DNSCache struct {
+	// DefaultTTL is the optional TTL to use when the lookup result does not
+	// contain the TTL (e.g., when using a [*net.Resolver]). If this field is
+	// zero or negative, we use one minute.
+	DefaultTTL time.Duration
+
+	// MaxEntries is the optional maximum number of entries in the cache.
+	// When the cache is full, we evict the expired entries and then the
+	// entry closest to its expiration. If this field is zero or negative,
+	// we use 1024 entries.
+	MaxEntries int
+
+	// entries maps a domain name to the corresponding entry.
+	entries map[string]*dnsCacheEntry
+
+	// mu protects entries.
+	mu sync.Mutex
+}
+
+// dnsCacheEntry is an entry of the [*DNSCache].
+type dnsCacheEntry struct {
+	expires time.Time
+	result  *LookupResult
+}
+
+// Get returns a copy of the cached result for the given domain name and
+// whether we found a result that has not expired at the given time. The
+// returned result has the Cached field set to true, no Queries, and the
+// remaining TTL.
+//
+// This method is goroutine safe.
+func (c *DNSCache) Get(domain string, now time.Time) (*LookupResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := normalizeDomain(domain)
+	entry, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	if !now.Before(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	result := entry.result.clone()
+	result.Cached = true
+	result.TTL = entry.expires.Sub(now)
+	return result, true
+}
+
+// Put adds to the cache the given successful result for the given domain
+// name at the given time, replacing any existing entry.
+//
+// This method is goroutine safe.
+func (c *DNSCache) Put(domain string, result *LookupResult, now time.Time) {
+	ttl := result.TTL
+	if ttl <= 0 {
+		ttl = c.DefaultTTL
+	}
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*dnsCacheEntry)
+	}
+	key := normalizeDomain(domain)
+	if _, found := c.entries[key]; !found {
+		c.makeRoom(now)
+	}
+	c.entries[key] = &dnsCacheEntry{expires: now.Add(ttl), result: result.clone()}
+}
+
+// makeRoom ensures there is room for a new entry. The caller MUST hold the mutex.
+func (c *DNSCache) makeRoom(now time.Time) {
+	maxEntries := c.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 1024
+	}
+	if len(c.entries) < maxEntries {
+		return
+	}
+
+	// first, evict all the expired entries
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+
+	// then, evict the entries closest to their expiration
+	for len(c.entries) >= maxEntries {
+		var victim string
+		var expires time.Time
+		for key, entry := range c.entries {
+			if expires.IsZero() || entry.expires.Before(expires) {
+				victim, expires = key, entry.expires
+			}
+		}
+		delete(c.entries, victim)
+	}
+}
+
+// Len returns the number of entries in the cache, including
+// the expired entries that we have not evicted yet.
+//
+// This method is goroutine safe.
+func (c *DNSCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// dnsCacheBypassKey is the context key for [WithDNSCacheBypass].
+type dnsCacheBypassKey struct{}
+
+// WithDNSCacheBypass returns a context causing the lookups using it to
+// bypass the DNSCache of the [*Network] and always resolve the domain name.
+// We still store the fresh result into the cache on success.
+func WithDNSCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dnsCacheBypassKey{}, true)
+}
+
+// dnsCacheBypassFromContext returns whether the context was
+// created using [WithDNSCacheBypass].
+func dnsCacheBypassFromContext(ctx context.Context) bool {
+	bypass, _ := ctx.Value(dnsCacheBypassKey{}).(bool)
+	return bypass
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSCache(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("we respect the TTL of the result", func(t *testing.T) {
+		cache := &DNSCache{}
+		cache.Put("www.example.com", &LookupResult{
+			Addrs:   []string{"130.192.91.211"},
+			Queries: []LookupQuery{{QueryType: "A"}},
+			TTL:     10 * time.Second,
+		}, t0)
+
+		result, found := cache.Get("WWW.example.com.", t0.Add(4*time.Second))
+		require.True(t, found)
+		assert.Equal(t, &LookupResult{
+			Addrs:  []string{"130.192.91.211"},
+			Cached: true,
+			TTL:    6 * time.Second,
+		}, result)
+
+		result, found = cache.Get("www.example.com", t0.Add(10*time.Second))
+		assert.False(t, found)
+		assert.Nil(t, result)
+		assert.Equal(t, 0, cache.Len())
+	})
+
+	t.Run("we use the DefaultTTL when the TTL is unknown", func(t *testing.T) {
+		cache := &DNSCache{DefaultTTL: 5 * time.Second}
+		cache.Put("www.example.com", &LookupResult{Addrs: []string{"130.192.91.211"}}, t0)
+		_, found := cache.Get("www.example.com", t0.Add(4*time.Second))
+		assert.True(t, found)
+		_, found = cache.Get("www.example.com", t0.Add(5*time.Second))
+		assert.False(t, found)
+	})
+
+	t.Run("we use one minute without DefaultTTL", func(t *testing.T) {
+		cache := &DNSCache{}
+		cache.Put("www.example.com", &LookupResult{Addrs: []string{"130.192.91.211"}}, t0)
+		result, found := cache.Get("www.example.com", t0)
+		require.True(t, found)
+		assert.Equal(t, time.Minute, result.TTL)
+	})
+
+	t.Run("we do not share the addresses with the caller", func(t *testing.T) {
+		cache := &DNSCache{}
+		addrs := []string{"130.192.91.211"}
+		cache.Put("www.example.com", &LookupResult{Addrs: addrs}, t0)
+		addrs[0] = "127.0.0.1"
+		result, found := cache.Get("www.example.com", t0)
+		require.True(t, found)
+		result.Addrs[0] = "127.0.0.2"
+		result, found = cache.Get("www.example.com", t0)
+		require.True(t, found)
+		assert.Equal(t, []string{"130.192.91.211"}, result.Addrs)
+	})
+
+	t.Run("we enforce MaxEntries", func(t *testing.T) {
+		cache := &DNSCache{MaxEntries: 2}
+		cache.Put("a.example.com", &LookupResult{TTL: time.Second}, t0)
+		cache.Put("b.example.com", &LookupResult{TTL: time.Hour}, t0)
+		cache.Put("c.example.com", &LookupResult{TTL: 2 * time.Second}, t0)
+		assert.Equal(t, 2, cache.Len())
+		_, found := cache.Get("a.example.com", t0)
+		assert.False(t, found)
+
+		// the expired entries go first
+		cache.Put("d.example.com", &LookupResult{TTL: time.Minute}, t0.Add(3*time.Second))
+		assert.Equal(t, 2, cache.Len())
+		_, found = cache.Get("b.example.com", t0.Add(3*time.Second))
+		assert.True(t, found)
+		_, found = cache.Get("d.example.com", t0.Add(3*time.Second))
+		assert.True(t, found)
+	})
+}
+
+func TestNetwork_DNSCache(t *testing.T) {
+	var lookups int
+	var buf bytes.Buffer
+	nx := &Network{
+		DNSCache: &DNSCache{},
+		Logger:   slog.New(slog.NewJSONHandler(&buf, nil)),
+		LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+			lookups++
+			return []string{"130.192.91.211"}, nil
+		},
+	}
+
+	for idx := 0; idx < 2; idx++ {
+		addrs, err := nx.LookupHost(context.Background(), "www.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"130.192.91.211"}, addrs)
+	}
+	assert.Equal(t, 1, lookups)
+
+	_, err := nx.LookupHost(WithDNSCacheBypass(context.Background()), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 2, lookups)
+
+	// make sure the events tell the cache hits apart
+	var hits []bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev struct {
+			DNSCacheHit bool   `json:"dnsCacheHit"`
+			Msg         string `json:"msg"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		if ev.Msg == "lookupHostDone" {
+			hits = append(hits, ev.DNSCacheHit)
+		}
+	}
+	assert.Equal(t, []bool{false, true, false}, hits)
+}
@@ -0,0 +1,143 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// DNS lookups using dnscore.
+//
+
+package netcore
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+)
+
+// NewDNSCoreTransport creates a new [*dnscore.Transport] dialing connections
+// using the [*Network] and sharing its Logger, such that dnscore logs the raw
+// DNS queries and responses alongside the network events.
+//
+// The transport uses [*Network.DialContext] and [*Network.DialTLSContext], so
+// the server address MUST be an IP address when the [*Network] is using the
+// transport to resolve domain names, otherwise the lookup would recurse.
+func (nx *Network) NewDNSCoreTransport() *dnscore.Transport {
+	return &dnscore.Transport{
+		DialContext:    nx.DialContext,
+		DialTLSContext: nx.DialTLSContext,
+		HTTPClient:     &http.Client{Transport: NewHTTPTransport(nx)},
+		Logger:         nx.Logger,
+		TimeNow:        nx.TimeNow,
+	}
+}
+
+// NewDNSCoreLookupHostResultFunc returns a function suitable for the
+// LookupHostResultFunc field of the [*Network] resolving domain names using
+// the given DNS server (e.g., UDP, DoT or DoH) and a [*dnscore.Transport]
+// created using [*Network.NewDNSCoreTransport].
+//
+// Like the default resolver, the function performs the A and AAAA queries in
+// parallel, emitting the dnsQueryStart and dnsQueryDone events, and fails only
+// when both queries fail. The result contains the CNAME chain, if any.
+func (nx *Network) NewDNSCoreLookupHostResultFunc(
+	addr *dnscore.ServerAddr) func(ctx context.Context, domain string) (*LookupResult, error) {
+	txp := nx.NewDNSCoreTransport()
+	return func(ctx context.Context, domain string) (*LookupResult, error) {
+		result, err := nx.lookupHostParallel(ctx, domain, func(ctx context.Context, queryType string) (*LookupResult, error) {
+			return dnscoreLookup(ctx, txp, addr, domain, queryType)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Resolver = dnscoreResolverName(addr)
+		return result, nil
+	}
+}
+
+// dnscoreQueryTypes maps the query types to the corresponding DNS types.
+var dnscoreQueryTypes = map[string]uint16{
+	"A":    dns.TypeA,
+	"AAAA": dns.TypeAAAA,
+}
+
+// dnscoreLookup performs a DNS query of the given type using the given transport and
+// server and returns the resolved addresses, the CNAME chain, if any, and the TTL.
+func dnscoreLookup(ctx context.Context, txp *dnscore.Transport,
+	addr *dnscore.ServerAddr, domain, queryType string) (*LookupResult, error) {
+	query, err := dnscore.NewQuery(domain, dnscoreQueryTypes[queryType])
+	if err != nil {
+		return nil, err
+	}
+	resp, err := txp.Query(ctx, addr, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := dnscore.ValidateResponse(query, resp); err != nil {
+		return nil, err
+	}
+	if err := dnscore.RCodeToError(resp); err != nil {
+		return nil, err
+	}
+
+	result := &LookupResult{}
+	for _, rr := range resp.Answer {
+		switch rr := rr.(type) {
+		case *dns.A:
+			result.Addrs = append(result.Addrs, rr.A.String())
+		case *dns.AAAA:
+			result.Addrs = append(result.Addrs, rr.AAAA.String())
+		case *dns.CNAME:
+			if len(result.CNAMEs) <= 0 {
+				result.CNAMEs = append(result.CNAMEs, strings.TrimSuffix(rr.Hdr.Name, "."))
+			}
+			result.CNAMEs = append(result.CNAMEs, strings.TrimSuffix(rr.Target, "."))
+		default:
+			continue
+		}
+		if ttl := time.Duration(rr.Header().Ttl) * time.Second; result.TTL <= 0 || ttl < result.TTL {
+			result.TTL = ttl
+		}
+	}
+	if len(result.Addrs) <= 0 {
+		return nil, dnscore.ErrNoData
+	}
+	return result, nil
+}
+
+// resolverServerKey is the context key for [WithResolverServerAddr].
+type resolverServerKey struct{}
+
+// WithResolverServer is like [WithResolverServerAddr] but takes the
+// address (e.g., "9.9.9.9:53") of a DNS server using UDP.
+func WithResolverServer(ctx context.Context, address string) context.Context {
+	return WithResolverServerAddr(ctx, dnscore.NewServerAddr(dnscore.ProtocolUDP, address))
+}
+
+// WithResolverServerAddr returns a context causing the lookups using it to
+// resolve domain names using the given DNS server, as if LookupHostResultFunc
+// was set using [*Network.NewDNSCoreLookupHostResultFunc], thus overriding
+// the resolver configured in the [*Network]. This allows to compare the
+// results of several resolvers using a single [*Network]. The lookups using
+// this context bypass the DNSCache, since they target a specific resolver.
+//
+// The server address MUST use an IP address, otherwise the lookup would recurse.
+func WithResolverServerAddr(ctx context.Context, addr *dnscore.ServerAddr) context.Context {
+	return context.WithValue(ctx, resolverServerKey{}, addr)
+}
+
+// resolverServerFromContext returns the DNS server inside the context or
+// nil if the context was not created using [WithResolverServerAddr].
+func resolverServerFromContext(ctx context.Context) *dnscore.ServerAddr {
+	addr, _ := ctx.Value(resolverServerKey{}).(*dnscore.ServerAddr)
+	return addr
+}
+
+// dnscoreResolverName returns the name of the resolver used in [*LookupResult].
+func dnscoreResolverName(addr *dnscore.ServerAddr) string {
+	if addr.Protocol == dnscore.ProtocolDoH {
+		return addr.Address // already an URL
+	}
+	return string(addr.Protocol) + "://" + addr.Address
+}
@@ -0,0 +1,119 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_NewDNSCoreLookupHostResultFunc(t *testing.T) {
+	// start a DNS server answering www.example.com with a CNAME
+	// to example.com and refusing the queries for other names
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{
+		PacketConn: pconn,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+			resp := &dns.Msg{}
+			resp.SetReply(query)
+			question := query.Question[0]
+			switch {
+			case question.Name != "www.example.com.":
+				resp.Rcode = dns.RcodeNameError
+			case question.Qtype == dns.TypeA:
+				resp.Answer = append(resp.Answer, &dns.CNAME{
+					Hdr: dns.RR_Header{
+						Name:   question.Name,
+						Rrtype: dns.TypeCNAME,
+						Class:  dns.ClassINET,
+						Ttl:    60,
+					},
+					Target: "example.com.",
+				}, &dns.A{
+					Hdr: dns.RR_Header{
+						Name:   "example.com.",
+						Rrtype: dns.TypeA,
+						Class:  dns.ClassINET,
+						Ttl:    60,
+					},
+					A: net.IPv4(130, 192, 91, 211),
+				})
+			}
+			w.WriteMsg(resp)
+		}),
+	}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	addr := dnscore.NewServerAddr(dnscore.ProtocolUDP, pconn.LocalAddr().String())
+
+	t.Run("we resolve the domain using the DNS server", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+		nx.LookupHostResultFunc = nx.NewDNSCoreLookupHostResultFunc(addr)
+
+		result, err := nx.LookupHostResult(context.Background(), "www.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"130.192.91.211"}, result.Addrs)
+		assert.Equal(t, []string{"www.example.com", "example.com"}, result.CNAMEs)
+		assert.Equal(t, "udp://"+pconn.LocalAddr().String(), result.Resolver)
+		assert.Equal(t, 60*time.Second, result.TTL)
+		require.Len(t, result.Queries, 2)
+		assert.Equal(t, "A", result.Queries[0].QueryType)
+		assert.Empty(t, result.Queries[0].Err)
+		assert.Equal(t, "AAAA", result.Queries[1].QueryType)
+		assert.Equal(t, dnscore.ErrNoData.Error(), result.Queries[1].Err)
+
+		logs := buf.String()
+		assert.Equal(t, 2, strings.Count(logs, `"msg":"dnsQueryDone"`))
+		assert.Equal(t, 2, strings.Count(logs, `"msg":"connectDone"`))
+	})
+
+	t.Run("we fail when both queries fail", func(t *testing.T) {
+		nx := &Network{}
+		nx.LookupHostResultFunc = nx.NewDNSCoreLookupHostResultFunc(addr)
+
+		result, err := nx.LookupHostResult(context.Background(), "www.example.org")
+		assert.ErrorIs(t, err, dnscore.ErrNoName)
+		assert.Nil(t, result)
+	})
+
+	t.Run("the context overrides the resolver", func(t *testing.T) {
+		nx := &Network{
+			DNSCache: &DNSCache{},
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, errors.New("should not be called")
+			},
+		}
+		ctx := WithResolverServer(context.Background(), pconn.LocalAddr().String())
+
+		result, err := nx.LookupHostResult(ctx, "www.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"130.192.91.211"}, result.Addrs)
+		assert.Equal(t, "udp://"+pconn.LocalAddr().String(), result.Resolver)
+		assert.Equal(t, 0, nx.DNSCache.Len())
+
+		// without the override we use the Network resolver
+		_, err = nx.LookupHostResult(context.Background(), "www.example.com")
+		assert.Error(t, err)
+	})
+}
+
+func Test_dnscoreResolverName(t *testing.T) {
+	assert.Equal(t, "dot://8.8.8.8:853",
+		dnscoreResolverName(dnscore.NewServerAddr(dnscore.ProtocolDoT, "8.8.8.8:853")))
+	assert.Equal(t, "https://8.8.8.8/dns-query",
+		dnscoreResolverName(dnscore.NewServerAddr(dnscore.ProtocolDoH, "https://8.8.8.8/dns-query")))
+}
@@ -1,9 +1,9 @@
 // SPDX-License-Identifier: GPL-3.0-or-later
 
 /*
-Package netcore provides a TCP/UDP dialer and a TLS dialer.
+Package netcore provides a TCP/UDP dialer, a TLS dialer, and a QUIC dialer.
 
-This package is designed to facilitate measuring TCP, UDP, and TLS
+This package is designed to facilitate measuring TCP, UDP, TLS, and QUIC
 connection events via the [log/slog] package.
 
 # Features
@@ -12,10 +12,127 @@ connection events via the [log/slog] package.
 
 - TLS [*Network.DialTLSContext] method compatible with [net/http].
 
+- [*Network.DialTLSConnContext] and [TLSConnectionState] to inspect the
+negotiated TLS state (e.g., ALPN, version, and peer certificates).
+
+- QUIC [*Network.DialQUICContext] method built on [github.com/quic-go/quic-go].
+
+- Optional ALPNForEndpoint function choosing the ALPN for custom ports
+and protocols (e.g., "h2" for port 8443) without a full TLSConfig.
+
+- DNS lookups emitting events using [*Network.LookupHost] and [*Network.LookupEndpoint].
+
+- Separate A and AAAA queries, each emitting events, when using a [*net.Resolver].
+
+- Rich [*LookupResult] including the CNAME chain and the DNS queries using LookupHostResultFunc.
+
+- Resolving domain names using UDP, DoT, or DoH servers through [github.com/rbmk-project/dnscore]
+using [*Network.NewDNSCoreLookupHostResultFunc].
+
+- Static mapping of domain names to IP addresses using Hosts.
+
+- Binding to simulated, proxied, or userspace network stacks implementing
+[StackProvider] using [*Network.BindTo].
+
+- Per-call resolver override using [WithResolverServer] and [WithResolverServerAddr].
+
+- Optional in-memory [*DNSCache] respecting the TTLs, when known, bypassable
+per call using [WithDNSCacheBypass].
+
+- Dialing pre-resolved addresses using [*Network.DialContextWithAddrs] and
+[*Network.DialTLSContextWithAddrs].
+
+- Attempting every resolved endpoint using [*Network.MeasureEndpoints].
+
+- Optional Multipath TCP using EnableMultipathTCP, logging whether we negotiated it.
+
+- Binding the sockets to a specific network interface using BindToDevice.
+
+- Setting socket options (e.g., TTL, TOS, and SO_MARK) using [*SocketOptions] and ControlFunc.
+
+- Instrumented listeners emitting events for the accepted connections using [*Network.Listen].
+
+- Packet conns emitting events with the peer address of each datagram using [WrapPacketConn].
+
+- HTTP transport using the TCP/UDP and TLS dialers created by [NewHTTPTransport].
+
+- HTTP/3 transport using the QUIC dialer created by [NewHTTP3Transport].
+
+- STUN binding requests logging the server reflexive endpoint and the RTT
+using [*Network.STUNBinding].
+
+- Traceroute using UDP, TCP, or ICMP probes using [*Network.Traceroute], emitting
+an event for each hop (Linux only).
+
+- ICMP ping using [*Network.Ping], with raw sockets when privileged and
+unprivileged ICMP sockets otherwise, emitting events with the RTTs and the loss.
+
+- Redirect-following [*Network.GetWithRedirects] emitting an event per hop.
+
+- Time-to-first-byte events for HTTP round trips using [WrapHTTPRoundTripper].
+
+- Tunneling TCP connections through HTTP CONNECT proxies using ProxyURL.
+
+- Pluggable [TLSEngine], including a uTLS engine parroting browsers in the
+[github.com/rbmk-project/x/netcore/tlsengineutls] package.
+
+- Pluggable ErrClassifiers assigning stable "errClass" values to domain-specific
+errors that would otherwise be "EGENERIC".
+
+- Optional TLS key logging using TLSKeyLogWriter to decrypt packet captures.
+
+- JA3 and JA4 fingerprints of the ClientHello we sent in the TLS handshake events.
+
+- Verified certificate chains, matched root, and failed verification step
+in the "tlsHandshakeDone" event.
+
+- Stapled OCSP response status and number of valid and invalid signed certificate
+timestamps in the "tlsHandshakeDone" event.
+
+- Optional logging of the raw TLS handshake bytes using TLSLogRawHandshake.
+
+- Pluggable [DialPolicy] controlling how we attempt the resolved endpoints.
+
+- [RetryDialPolicy] retrying the endpoints with exponential backoff on
+retryable errors and logging the attemptIndex of each attempt.
+
+- Optional MaxConcurrentDials limit, logging the time spent waiting for
+a free slot in the "connectStart" event.
+
+- Optional per-endpoint [CircuitBreaker] skipping the endpoints that failed
+repeatedly, which the events classify as ECIRCUITOPEN.
+
+- Throughput-throttled connections using [ThrottleConn] and a token bucket
+[RateLimiter], which [ThrottledWrapConn] combines with [WrapConn].
+
+- Functional options for constructing a [*Network] using [New] and for
+deriving variants of an existing one using [*Network.Clone].
+
 - Optional logging for structured diagnostic events through [log/slog].
 
+- Typed structs documenting each event schema, implementing the [Event] interface.
+
+- Optional [EventHook] receiving the events, including a hook creating OpenTelemetry
+spans in the [github.com/rbmk-project/x/netcore/eventhookotel] package.
+
+- Controlling the volume of read and write events using LogLevelIO and LogMaxIOEvents.
+
+- Optional kernel receive timestamps in the read events using LogKernelTimestamps (Linux only).
+
+- Optional payload prefixes in the read and write events using LogIOPayloadBytes.
+
+- Summary "connStats" event with byte and operation counts when closing a connection.
+
+- Optional TCP_INFO metrics in the "connStats" event using LogTCPInfo (Linux only).
+
+- Per-network counters of lookups, dials, handshakes, and bytes using [*Network.Stats].
+
+- Buffered [*JSONLHandler] writing the events as line-delimited JSON.
+
 - Include error classification into the logging events.
 
+- Include a connection ID into the events to correlate those of the same connection.
+
 # Design Documents
 
 This package is experimental and has no design documents for now.
@@ -0,0 +1,31 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Pluggable error classification.
+//
+
+package netcore
+
+import "github.com/rbmk-project/common/errclass"
+
+// ErrClassifier is a function returning the error class of the given
+// non-nil error (e.g., "EPROXYAUTH"), or an empty string when it does not
+// know how to classify the error. Use the ErrClassifiers field of the
+// [*Network] to assign stable error classes to domain-specific errors
+// (e.g., proxy errors, QUIC errors, or gRPC status codes).
+type ErrClassifier func(err error) string
+
+// errClass is like the errClass function but consults the ErrClassifiers,
+// in order, when the error would otherwise be [errclass.EGENERIC].
+func (nx *Network) errClass(err error) string {
+	class := errClass(err)
+	if class != errclass.EGENERIC {
+		return class
+	}
+	for _, classifier := range nx.ErrClassifiers {
+		if custom := classifier(err); custom != "" {
+			return custom
+		}
+	}
+	return class
+}
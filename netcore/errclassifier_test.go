@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_errClass(t *testing.T) {
+	errProxy := errors.New("mocked proxy error")
+	nx := &Network{
+		ErrClassifiers: []ErrClassifier{
+			func(err error) string {
+				return "" // does not know the error
+			},
+			func(err error) string {
+				if errors.Is(err, errProxy) {
+					return "EPROXY"
+				}
+				return ""
+			},
+			func(err error) string {
+				panic("should not be called after a match")
+			},
+		},
+	}
+
+	t.Run("nil error", func(t *testing.T) {
+		assert.Equal(t, "", nx.errClass(nil))
+	})
+
+	t.Run("we do not override the built-in classes", func(t *testing.T) {
+		assert.Equal(t, "ECONNREFUSED", nx.errClass(syscall.ECONNREFUSED))
+		assert.Equal(t, ErrClassCircuitOpen, nx.errClass(ErrCircuitOpen))
+	})
+
+	t.Run("we consult the classifiers in order", func(t *testing.T) {
+		assert.Equal(t, "EPROXY", nx.errClass(errProxy))
+	})
+
+	t.Run("we fall back to EGENERIC", func(t *testing.T) {
+		nx := &Network{ErrClassifiers: []ErrClassifier{func(err error) string { return "" }}}
+		assert.Equal(t, "EGENERIC", nx.errClass(errors.New("mocked error")))
+	})
+}
+
+func TestNetwork_ErrClassifiers(t *testing.T) {
+	errCustom := errors.New("mocked custom error")
+	var buf bytes.Buffer
+	nx := New(
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+		WithErrClassifiers(func(err error) string {
+			if errors.Is(err, errCustom) {
+				return "ECUSTOM"
+			}
+			return ""
+		}),
+	)
+	nx.DialContextFunc = func(ctx context.Context, network, address string) (net.Conn, error) {
+		return nil, errCustom
+	}
+
+	_, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+	require.ErrorIs(t, err, errCustom)
+
+	var found bool
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var ev map[string]any
+		require.NoError(t, json.Unmarshal(line, &ev))
+		if ev["msg"] == "connectDone" {
+			assert.Equal(t, "ECUSTOM", ev["errClass"])
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
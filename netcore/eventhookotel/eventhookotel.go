@@ -0,0 +1,138 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// OpenTelemetry event hook.
+//
+
+// Package eventhookotel implements a [netcore.EventHook] creating
+// OpenTelemetry spans for the operations of a [*netcore.Network].
+//
+// Use it by setting the EventHook field of [*netcore.Network]:
+//
+//	nx := &netcore.Network{EventHook: eventhookotel.New(otel.GetTracerProvider())}
+//
+// We create a span when a lookup, dial, or handshake operation completes,
+// using the "t0" and "t" fields of the event as the start and end times
+// and the other fields as the span attributes, with the same names used
+// by the structured logs. The span parent is the span, if any, in the
+// context passed to the [*netcore.Network] method. When the operation
+// fails, we set the span status to error using the "err" field.
+package eventhookotel
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"time"
+
+	"github.com/rbmk-project/x/netcore"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the [trace.Tracer] we use.
+const TracerName = "github.com/rbmk-project/x/netcore"
+
+// spanNames maps the names of the events emitted when an
+// operation completes to the names of the corresponding spans.
+var spanNames = map[string]string{
+	"connectDone":       "connect",
+	"httpConnectDone":   "httpConnect",
+	"lookupHostDone":    "lookupHost",
+	"pingDone":          "ping",
+	"quicHandshakeDone": "quicHandshake",
+	"stunBindingDone":   "stunBinding",
+	"tlsHandshakeDone":  "tlsHandshake",
+	"tracerouteHop":     "tracerouteHop",
+}
+
+// Hook is a [netcore.EventHook] creating OpenTelemetry spans.
+//
+// Construct using [New].
+type Hook struct {
+	tracer trace.Tracer
+}
+
+// Ensure that [*Hook] implements [netcore.EventHook].
+var _ netcore.EventHook = &Hook{}
+
+// New creates a new [*Hook] using the given [trace.TracerProvider].
+func New(tp trace.TracerProvider) *Hook {
+	return &Hook{tracer: tp.Tracer(TracerName)}
+}
+
+// OnEvent implements [netcore.EventHook].
+//
+// This method is goroutine safe.
+func (h *Hook) OnEvent(ctx context.Context, ev netcore.Event) {
+	// only create spans for the operations we trace
+	name, found := spanNames[ev.EventName()]
+	if !found {
+		return
+	}
+
+	// convert the event fields to span attributes
+	var (
+		attrs  []attribute.KeyValue
+		errstr string
+		t0, t  time.Time
+	)
+	for _, attr := range ev.LogAttrs() {
+		switch attr.Key {
+		case "err":
+			if attr.Value.Kind() == slog.KindString {
+				errstr = attr.Value.String()
+			}
+		case "t0":
+			t0 = attr.Value.Time()
+		case "t":
+			t = attr.Value.Time()
+		default:
+			if kv, ok := newAttribute(attr); ok {
+				attrs = append(attrs, kv)
+			}
+		}
+	}
+
+	// create a span covering the whole operation
+	_, span := h.tracer.Start(
+		ctx,
+		name,
+		trace.WithAttributes(attrs...),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(t0),
+	)
+	if errstr != "" {
+		span.SetStatus(codes.Error, errstr)
+	}
+	span.End(trace.WithTimestamp(t))
+}
+
+// newAttribute converts a [slog.Attr] to an [attribute.KeyValue].
+func newAttribute(attr slog.Attr) (attribute.KeyValue, bool) {
+	switch attr.Value.Kind() {
+	case slog.KindBool:
+		return attribute.Bool(attr.Key, attr.Value.Bool()), true
+
+	case slog.KindInt64:
+		return attribute.Int64(attr.Key, attr.Value.Int64()), true
+
+	case slog.KindString:
+		return attribute.String(attr.Key, attr.Value.String()), true
+
+	case slog.KindAny:
+		switch value := attr.Value.Any().(type) {
+		case []string:
+			return attribute.StringSlice(attr.Key, value), true
+
+		case [][]byte:
+			encoded := make([]string, 0, len(value))
+			for _, entry := range value {
+				encoded = append(encoded, base64.StdEncoding.EncodeToString(entry))
+			}
+			return attribute.StringSlice(attr.Key, encoded), true
+		}
+	}
+	return attribute.KeyValue{}, false
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package eventhookotel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/x/netcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// testSpan is a finished span recorded by [*testTracerProvider].
+type testSpan struct {
+	name       string
+	attrs      map[attribute.Key]attribute.Value
+	kind       trace.SpanKind
+	start      time.Time
+	end        time.Time
+	statusCode codes.Code
+	statusDesc string
+}
+
+// testTracerProvider is a [trace.TracerProvider] recording the spans.
+type testTracerProvider struct {
+	noop.TracerProvider
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+// Tracer implements [trace.TracerProvider].
+func (tp *testTracerProvider) Tracer(name string, options ...trace.TracerOption) trace.Tracer {
+	return &testTracer{provider: tp}
+}
+
+// testTracer is the [trace.Tracer] returned by [*testTracerProvider].
+type testTracer struct {
+	noop.Tracer
+	provider *testTracerProvider
+}
+
+// Start implements [trace.Tracer].
+func (tr *testTracer) Start(ctx context.Context,
+	name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(options...)
+	span := &testSpan{
+		name:  name,
+		attrs: map[attribute.Key]attribute.Value{},
+		kind:  config.SpanKind(),
+		start: config.Timestamp(),
+	}
+	for _, kv := range config.Attributes() {
+		span.attrs[kv.Key] = kv.Value
+	}
+	return ctx, &testSpanRecorder{provider: tr.provider, span: span}
+}
+
+// testSpanRecorder is the [trace.Span] returned by [*testTracer].
+type testSpanRecorder struct {
+	noop.Span
+	provider *testTracerProvider
+	span     *testSpan
+}
+
+// SetStatus implements [trace.Span].
+func (sr *testSpanRecorder) SetStatus(code codes.Code, description string) {
+	sr.span.statusCode = code
+	sr.span.statusDesc = description
+}
+
+// End implements [trace.Span].
+func (sr *testSpanRecorder) End(options ...trace.SpanEndOption) {
+	config := trace.NewSpanEndConfig(options...)
+	sr.span.end = config.Timestamp()
+	sr.provider.mu.Lock()
+	sr.provider.spans = append(sr.provider.spans, sr.span)
+	sr.provider.mu.Unlock()
+}
+
+func TestHook(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("we create a span when an operation fails", func(t *testing.T) {
+		tp := &testTracerProvider{}
+		hook := New(tp)
+		hook.OnEvent(context.Background(), &netcore.ConnectDoneEvent{
+			ConnID:     7,
+			Err:        "connection refused",
+			ErrClass:   "ECONNREFUSED",
+			Protocol:   "tcp",
+			RemoteAddr: "130.192.91.211:443",
+			T0:         t0,
+			T:          t0.Add(time.Second),
+		})
+
+		require.Len(t, tp.spans, 1)
+		span := tp.spans[0]
+		assert.Equal(t, "connect", span.name)
+		assert.Equal(t, trace.SpanKindClient, span.kind)
+		assert.Equal(t, t0, span.start)
+		assert.Equal(t, t0.Add(time.Second), span.end)
+		assert.Equal(t, codes.Error, span.statusCode)
+		assert.Equal(t, "connection refused", span.statusDesc)
+		assert.Equal(t, int64(7), span.attrs["connId"].AsInt64())
+		assert.Equal(t, "ECONNREFUSED", span.attrs["errClass"].AsString())
+		assert.Equal(t, "130.192.91.211:443", span.attrs["remoteAddr"].AsString())
+		assert.NotContains(t, span.attrs, attribute.Key("err"))
+		assert.NotContains(t, span.attrs, attribute.Key("t0"))
+		assert.NotContains(t, span.attrs, attribute.Key("t"))
+	})
+
+	t.Run("we convert the slice attributes", func(t *testing.T) {
+		tp := &testTracerProvider{}
+		hook := New(tp)
+		hook.OnEvent(context.Background(), &netcore.LookupHostDoneEvent{
+			DNSLookupDomain:  "example.com",
+			DNSResolvedAddrs: []string{"130.192.91.211", "2001:db8::1"},
+			T0:               t0,
+			T:                t0,
+		})
+		hook.OnEvent(context.Background(), &netcore.TLSHandshakeDoneEvent{
+			TLSPeerCerts: [][]byte{{0x30, 0x01}},
+			T0:           t0,
+			T:            t0,
+		})
+
+		require.Len(t, tp.spans, 2)
+		assert.Equal(t, "lookupHost", tp.spans[0].name)
+		assert.Equal(t, codes.Unset, tp.spans[0].statusCode)
+		assert.Equal(t, []string{"130.192.91.211", "2001:db8::1"},
+			tp.spans[0].attrs["dnsResolvedAddrs"].AsStringSlice())
+		assert.Equal(t, "tlsHandshake", tp.spans[1].name)
+		assert.Equal(t, []string{"MAE="}, tp.spans[1].attrs["tlsPeerCerts"].AsStringSlice())
+	})
+
+	t.Run("we ignore the events of the other operations", func(t *testing.T) {
+		tp := &testTracerProvider{}
+		hook := New(tp)
+		hook.OnEvent(context.Background(), &netcore.ConnectStartEvent{})
+		hook.OnEvent(context.Background(), &netcore.ReadDoneEvent{})
+		assert.Empty(t, tp.spans)
+	})
+
+	t.Run("we trace the operations of a network", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		pool := x509.NewCertPool()
+		pool.AddCert(srv.Certificate())
+
+		tp := &testTracerProvider{}
+		nx := &netcore.Network{
+			EventHook: New(tp),
+			TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"},
+		}
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+
+		require.Len(t, tp.spans, 2)
+		assert.Equal(t, "connect", tp.spans[0].name)
+		assert.Equal(t, "tlsHandshake", tp.spans[1].name)
+		for _, span := range tp.spans {
+			assert.Equal(t, codes.Unset, span.statusCode)
+			assert.False(t, span.end.Before(span.start))
+			assert.Equal(t, srv.Listener.Addr().String(), span.attrs["remoteAddr"].AsString())
+		}
+		assert.Equal(t, tp.spans[0].attrs["connId"], tp.spans[1].attrs["connId"])
+		assert.Equal(t, "TLS 1.3", tp.spans[1].attrs["tlsVersion"].AsString())
+	})
+
+	t.Run("we set the error status when dialing fails", func(t *testing.T) {
+		tp := &testTracerProvider{}
+		nx := &netcore.Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return nil, errors.New("mocked error")
+			},
+			EventHook: New(tp),
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:443")
+		require.Error(t, err)
+		assert.Nil(t, conn)
+
+		require.Len(t, tp.spans, 1)
+		assert.Equal(t, codes.Error, tp.spans[0].statusCode)
+		assert.Equal(t, "mocked error", tp.spans[0].statusDesc)
+	})
+}
@@ -0,0 +1,1855 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Structured diagnostic events.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/rbmk-project/common/errclass"
+)
+
+// Event is a structured diagnostic event emitted by a [*Network].
+//
+// We emit each event as a [log/slog] record whose message is the event name
+// and whose attributes are the event fields, in the documented order. The
+// json tags of the event structs match the attribute names, such that it is
+// possible to parse a JSON-serialized event into the corresponding struct.
+type Event interface {
+	// EventName returns the event name (e.g., "connectStart").
+	EventName() string
+
+	// LogAttrs returns the event fields as [slog.Attr] values.
+	LogAttrs() []slog.Attr
+}
+
+// EventHook receives the events emitted by a [*Network].
+//
+// The [github.com/rbmk-project/x/netcore/eventhookotel] package provides
+// a hook creating OpenTelemetry spans from the events.
+type EventHook interface {
+	// OnEvent is called synchronously for each event. The context is the
+	// one of the operation emitting the event. Implementations MUST be
+	// safe for concurrent use by multiple goroutines.
+	OnEvent(ctx context.Context, ev Event)
+}
+
+// emitEnabled returns whether we are emitting events.
+func (nx *Network) emitEnabled() bool {
+	return nx.Logger != nil || nx.EventHook != nil
+}
+
+// emitEnabledAtLevel is like emitEnabled but also takes into account
+// whether the Logger would emit events at the given level.
+func (nx *Network) emitEnabledAtLevel(ctx context.Context, level slog.Level) bool {
+	return (nx.Logger != nil && nx.Logger.Enabled(ctx, level)) || nx.EventHook != nil
+}
+
+// emit emits the given event at [slog.LevelInfo] using the
+// Logger and the EventHook, if any.
+func (nx *Network) emit(ctx context.Context, ev Event) {
+	nx.emitAtLevel(ctx, slog.LevelInfo, ev)
+}
+
+// emitAtLevel is like emit but uses the given level for the Logger.
+func (nx *Network) emitAtLevel(ctx context.Context, level slog.Level, ev Event) {
+	if nx.Logger != nil {
+		nx.Logger.LogAttrs(ctx, level, ev.EventName(), ev.LogAttrs()...)
+	}
+	if nx.EventHook != nil {
+		nx.EventHook.OnEvent(ctx, ev)
+	}
+}
+
+// errAttr returns the "err" attribute, which is null on success.
+func errAttr(err string) slog.Attr {
+	if err == "" {
+		return slog.Any("err", nil)
+	}
+	return slog.String("err", err)
+}
+
+// errString returns the error message or an empty string.
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// errClass returns the error class of the given error, which is
+// empty for a nil error, using [errclass.New] except for the errors
+// specific to this package (e.g., [ErrCircuitOpen]).
+func errClass(err error) string {
+	if errors.Is(err, ErrCircuitOpen) {
+		return ErrClassCircuitOpen
+	}
+	return errclass.New(err)
+}
+
+// LookupHostStartEvent is the "lookupHostStart" event emitted before resolving a domain name.
+type LookupHostStartEvent struct {
+	// DNSLookupDomain is the domain name to resolve.
+	DNSLookupDomain string `json:"dnsLookupDomain"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*LookupHostStartEvent] implements [Event].
+var _ Event = &LookupHostStartEvent{}
+
+// EventName implements [Event].
+func (ev *LookupHostStartEvent) EventName() string {
+	return "lookupHostStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *LookupHostStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("dnsLookupDomain", ev.DNSLookupDomain),
+		slog.Time("t", ev.T),
+	}
+}
+
+// LookupHostDoneEvent is the "lookupHostDone" event emitted after resolving a domain name.
+type LookupHostDoneEvent struct {
+	// DNSCNAMEs is the CNAME chain, if known.
+	DNSCNAMEs []string `json:"dnsCNAMEs"`
+
+	// DNSCacheHit indicates that the result comes from the [*DNSCache].
+	DNSCacheHit bool `json:"dnsCacheHit"`
+
+	// DNSLookupDomain is the domain name to resolve.
+	DNSLookupDomain string `json:"dnsLookupDomain"`
+
+	// DNSQueries contains the DNS queries we performed, if known.
+	DNSQueries []LookupQuery `json:"dnsQueries"`
+
+	// DNSResolvedAddrs is the list of resolved IP addresses.
+	DNSResolvedAddrs []string `json:"dnsResolvedAddrs"`
+
+	// DNSResolver describes the resolver we used, if known.
+	DNSResolver string `json:"dnsResolver"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*LookupHostDoneEvent] implements [Event].
+var _ Event = &LookupHostDoneEvent{}
+
+// EventName implements [Event].
+func (ev *LookupHostDoneEvent) EventName() string {
+	return "lookupHostDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *LookupHostDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Any("dnsCNAMEs", ev.DNSCNAMEs),
+		slog.Bool("dnsCacheHit", ev.DNSCacheHit),
+		slog.String("dnsLookupDomain", ev.DNSLookupDomain),
+		slog.Any("dnsQueries", ev.DNSQueries),
+		slog.Any("dnsResolvedAddrs", ev.DNSResolvedAddrs),
+		slog.String("dnsResolver", ev.DNSResolver),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// DNSQueryStartEvent is the "dnsQueryStart" event emitted before a DNS query
+// performed by the [*net.Resolver] (i.e., when LookupHostFunc is not set) or
+// by [*Network.NewDNSCoreLookupHostResultFunc].
+type DNSQueryStartEvent struct {
+	// DNSLookupDomain is the domain name to resolve.
+	DNSLookupDomain string `json:"dnsLookupDomain"`
+
+	// DNSQueryType is the query type (i.e., "A" or "AAAA").
+	DNSQueryType string `json:"dnsQueryType"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*DNSQueryStartEvent] implements [Event].
+var _ Event = &DNSQueryStartEvent{}
+
+// EventName implements [Event].
+func (ev *DNSQueryStartEvent) EventName() string {
+	return "dnsQueryStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *DNSQueryStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("dnsLookupDomain", ev.DNSLookupDomain),
+		slog.String("dnsQueryType", ev.DNSQueryType),
+		slog.Time("t", ev.T),
+	}
+}
+
+// DNSQueryDoneEvent is the "dnsQueryDone" event emitted after a DNS query.
+type DNSQueryDoneEvent struct {
+	// DNSLookupDomain is the domain name to resolve.
+	DNSLookupDomain string `json:"dnsLookupDomain"`
+
+	// DNSQueryType is the query type (i.e., "A" or "AAAA").
+	DNSQueryType string `json:"dnsQueryType"`
+
+	// DNSResolvedAddrs is the list of resolved IP addresses.
+	DNSResolvedAddrs []string `json:"dnsResolvedAddrs"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*DNSQueryDoneEvent] implements [Event].
+var _ Event = &DNSQueryDoneEvent{}
+
+// EventName implements [Event].
+func (ev *DNSQueryDoneEvent) EventName() string {
+	return "dnsQueryDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *DNSQueryDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("dnsLookupDomain", ev.DNSLookupDomain),
+		slog.String("dnsQueryType", ev.DNSQueryType),
+		slog.Any("dnsResolvedAddrs", ev.DNSResolvedAddrs),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ConnectStartEvent is the "connectStart" event emitted before connecting.
+type ConnectStartEvent struct {
+	// AttemptIndex is the zero-based index of the attempt for the endpoint,
+	// which is nonzero when the [RetryDialPolicy] retries the endpoint.
+	AttemptIndex int `json:"attemptIndex"`
+
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// QueueWaitUsec is the time in microseconds we waited for a free dialing
+	// slot, which is nonzero when the [*Network] sets MaxConcurrentDials.
+	QueueWaitUsec int64 `json:"queueWaitUsec"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ConnectStartEvent] implements [Event].
+var _ Event = &ConnectStartEvent{}
+
+// EventName implements [Event].
+func (ev *ConnectStartEvent) EventName() string {
+	return "connectStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *ConnectStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int("attemptIndex", ev.AttemptIndex),
+		slog.Int64("connId", ev.ConnID),
+		slog.String("protocol", ev.Protocol),
+		slog.Int64("queueWaitUsec", ev.QueueWaitUsec),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ConnectDoneEvent is the "connectDone" event emitted after connecting.
+type ConnectDoneEvent struct {
+	// AttemptIndex is the zero-based index of the attempt for the endpoint,
+	// which is nonzero when the [RetryDialPolicy] retries the endpoint.
+	AttemptIndex int `json:"attemptIndex"`
+
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Device is the device to which we bound the socket (see BindToDevice)
+	// or empty. We only emit this field when it is not empty.
+	Device string `json:"device"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// MultipathTCP indicates whether the connection negotiated Multipath TCP,
+	// which we only check when the [*Network] sets EnableMultipathTCP.
+	MultipathTCP bool `json:"multipathTCP"`
+
+	// MultipathTCPEnabled is true when the [*Network] sets EnableMultipathTCP.
+	// We only emit this field and MultipathTCP when it is true, to distinguish
+	// the connections that did not negotiate Multipath TCP from the default.
+	MultipathTCPEnabled bool `json:"multipathTCPEnabled"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// SocketOptions describes the socket options we set (e.g., "ttl=5").
+	// We only emit this field when we set at least one option.
+	SocketOptions []string `json:"socketOptions"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ConnectDoneEvent] implements [Event].
+var _ Event = &ConnectDoneEvent{}
+
+// EventName implements [Event].
+func (ev *ConnectDoneEvent) EventName() string {
+	return "connectDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *ConnectDoneEvent) LogAttrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int("attemptIndex", ev.AttemptIndex),
+		slog.Int64("connId", ev.ConnID),
+	}
+	if ev.Device != "" {
+		attrs = append(attrs, slog.String("device", ev.Device))
+	}
+	attrs = append(attrs,
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+	)
+	if ev.MultipathTCPEnabled {
+		attrs = append(attrs,
+			slog.Bool("multipathTCP", ev.MultipathTCP),
+			slog.Bool("multipathTCPEnabled", ev.MultipathTCPEnabled),
+		)
+	}
+	attrs = append(attrs,
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+	)
+	if len(ev.SocketOptions) > 0 {
+		attrs = append(attrs, slog.Any("socketOptions", ev.SocketOptions))
+	}
+	return append(attrs, slog.Time("t0", ev.T0), slog.Time("t", ev.T))
+}
+
+// AcceptStartEvent is the "acceptStart" event emitted before accepting
+// a connection using a listener created by [*Network.Listen].
+type AcceptStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// LocalAddr is the local endpoint of the listener.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp").
+	Protocol string `json:"protocol"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*AcceptStartEvent] implements [Event].
+var _ Event = &AcceptStartEvent{}
+
+// EventName implements [Event].
+func (ev *AcceptStartEvent) EventName() string {
+	return "acceptStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *AcceptStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.Time("t", ev.T),
+	}
+}
+
+// AcceptDoneEvent is the "acceptDone" event emitted after accepting a connection.
+type AcceptDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint of the listener.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint or empty on failure.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*AcceptDoneEvent] implements [Event].
+var _ Event = &AcceptDoneEvent{}
+
+// EventName implements [Event].
+func (ev *AcceptDoneEvent) EventName() string {
+	return "acceptDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *AcceptDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ReadStartEvent is the "readStart" event emitted before reading from a connection.
+type ReadStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBufferSize is the size of the buffer passed to the I/O operation.
+	IOBufferSize int `json:"ioBufferSize"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ReadStartEvent] implements [Event].
+var _ Event = &ReadStartEvent{}
+
+// EventName implements [Event].
+func (ev *ReadStartEvent) EventName() string {
+	return "readStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *ReadStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBufferSize", ev.IOBufferSize),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ReadDoneEvent is the "readDone" event emitted after reading from a connection.
+type ReadDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBytesCount is the number of bytes transferred by the I/O operation.
+	IOBytesCount int `json:"ioBytesCount"`
+
+	// IOPayloadPrefix is the base64 encoded prefix of the transferred
+	// bytes, which is empty unless enabled using LogIOPayloadBytes.
+	IOPayloadPrefix string `json:"ioPayloadPrefix"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// TKernel is the kernel receive timestamp of the data, which is
+	// zero unless enabled using LogKernelTimestamps. We only emit
+	// this field when it is not zero.
+	TKernel time.Time `json:"tKernel"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ReadDoneEvent] implements [Event].
+var _ Event = &ReadDoneEvent{}
+
+// EventName implements [Event].
+func (ev *ReadDoneEvent) EventName() string {
+	return "readDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *ReadDoneEvent) LogAttrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBytesCount", ev.IOBytesCount),
+		slog.String("ioPayloadPrefix", ev.IOPayloadPrefix),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+	}
+	if !ev.TKernel.IsZero() {
+		attrs = append(attrs, slog.Time("tKernel", ev.TKernel))
+	}
+	return append(attrs, slog.Time("t0", ev.T0), slog.Time("t", ev.T))
+}
+
+// WriteStartEvent is the "writeStart" event emitted before writing to a connection.
+type WriteStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBufferSize is the size of the buffer passed to the I/O operation.
+	IOBufferSize int `json:"ioBufferSize"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*WriteStartEvent] implements [Event].
+var _ Event = &WriteStartEvent{}
+
+// EventName implements [Event].
+func (ev *WriteStartEvent) EventName() string {
+	return "writeStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *WriteStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBufferSize", ev.IOBufferSize),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// WriteDoneEvent is the "writeDone" event emitted after writing to a connection.
+type WriteDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBytesCount is the number of bytes transferred by the I/O operation.
+	IOBytesCount int `json:"ioBytesCount"`
+
+	// IOPayloadPrefix is the base64 encoded prefix of the transferred
+	// bytes, which is empty unless enabled using LogIOPayloadBytes.
+	IOPayloadPrefix string `json:"ioPayloadPrefix"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*WriteDoneEvent] implements [Event].
+var _ Event = &WriteDoneEvent{}
+
+// EventName implements [Event].
+func (ev *WriteDoneEvent) EventName() string {
+	return "writeDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *WriteDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBytesCount", ev.IOBytesCount),
+		slog.String("ioPayloadPrefix", ev.IOPayloadPrefix),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ReadFromStartEvent is the "readFromStart" event emitted before reading
+// a datagram from a [net.PacketConn] wrapped using [WrapPacketConn].
+type ReadFromStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBufferSize is the size of the buffer passed to the I/O operation.
+	IOBufferSize int `json:"ioBufferSize"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "udp").
+	Protocol string `json:"protocol"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ReadFromStartEvent] implements [Event].
+var _ Event = &ReadFromStartEvent{}
+
+// EventName implements [Event].
+func (ev *ReadFromStartEvent) EventName() string {
+	return "readFromStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *ReadFromStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBufferSize", ev.IOBufferSize),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ReadFromDoneEvent is the "readFromDone" event emitted after reading a datagram.
+type ReadFromDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBytesCount is the number of bytes transferred by the I/O operation.
+	IOBytesCount int `json:"ioBytesCount"`
+
+	// IOPayloadPrefix is the base64 encoded prefix of the transferred
+	// bytes, which is empty unless enabled using LogIOPayloadBytes.
+	IOPayloadPrefix string `json:"ioPayloadPrefix"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the peer that sent the datagram or empty on failure.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ReadFromDoneEvent] implements [Event].
+var _ Event = &ReadFromDoneEvent{}
+
+// EventName implements [Event].
+func (ev *ReadFromDoneEvent) EventName() string {
+	return "readFromDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *ReadFromDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBytesCount", ev.IOBytesCount),
+		slog.String("ioPayloadPrefix", ev.IOPayloadPrefix),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// WriteToStartEvent is the "writeToStart" event emitted before writing
+// a datagram to a [net.PacketConn] wrapped using [WrapPacketConn].
+type WriteToStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBufferSize is the size of the buffer passed to the I/O operation.
+	IOBufferSize int `json:"ioBufferSize"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the destination of the datagram.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*WriteToStartEvent] implements [Event].
+var _ Event = &WriteToStartEvent{}
+
+// EventName implements [Event].
+func (ev *WriteToStartEvent) EventName() string {
+	return "writeToStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *WriteToStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBufferSize", ev.IOBufferSize),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// WriteToDoneEvent is the "writeToDone" event emitted after writing a datagram.
+type WriteToDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBytesCount is the number of bytes transferred by the I/O operation.
+	IOBytesCount int `json:"ioBytesCount"`
+
+	// IOPayloadPrefix is the base64 encoded prefix of the transferred
+	// bytes, which is empty unless enabled using LogIOPayloadBytes.
+	IOPayloadPrefix string `json:"ioPayloadPrefix"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the destination of the datagram.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*WriteToDoneEvent] implements [Event].
+var _ Event = &WriteToDoneEvent{}
+
+// EventName implements [Event].
+func (ev *WriteToDoneEvent) EventName() string {
+	return "writeToDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *WriteToDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int("ioBytesCount", ev.IOBytesCount),
+		slog.String("ioPayloadPrefix", ev.IOPayloadPrefix),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// CloseStartEvent is the "closeStart" event emitted before closing a connection.
+type CloseStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*CloseStartEvent] implements [Event].
+var _ Event = &CloseStartEvent{}
+
+// EventName implements [Event].
+func (ev *CloseStartEvent) EventName() string {
+	return "closeStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *CloseStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// CloseDoneEvent is the "closeDone" event emitted after closing a connection.
+type CloseDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// IOBytesReadTotal is the total number of bytes read from the connection.
+	IOBytesReadTotal int64 `json:"ioBytesReadTotal"`
+
+	// IOBytesWrittenTotal is the total number of bytes written to the connection.
+	IOBytesWrittenTotal int64 `json:"ioBytesWrittenTotal"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*CloseDoneEvent] implements [Event].
+var _ Event = &CloseDoneEvent{}
+
+// EventName implements [Event].
+func (ev *CloseDoneEvent) EventName() string {
+	return "closeDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *CloseDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.Int64("ioBytesReadTotal", ev.IOBytesReadTotal),
+		slog.Int64("ioBytesWrittenTotal", ev.IOBytesWrittenTotal),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// ConnStatsEvent is the "connStats" event emitted after the "closeDone"
+// event, summarizing the whole lifetime of a connection.
+type ConnStatsEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// IOBytesReadTotal is the total number of bytes read from the connection.
+	IOBytesReadTotal int64 `json:"ioBytesReadTotal"`
+
+	// IOBytesWrittenTotal is the total number of bytes written to the connection.
+	IOBytesWrittenTotal int64 `json:"ioBytesWrittenTotal"`
+
+	// IOReadCount is the number of read operations.
+	IOReadCount int64 `json:"ioReadCount"`
+
+	// IOWriteCount is the number of write operations.
+	IOWriteCount int64 `json:"ioWriteCount"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// TCPInfo is true when we sampled the TCP_INFO metrics of the connection
+	// (see LogTCPInfo). We only emit this field and the following TCP_INFO
+	// fields when it is true, to distinguish unknown metrics from zero ones.
+	TCPInfo bool `json:"tcpInfo"`
+
+	// TCPDeliveryRate is the TCP_INFO delivery rate in bytes per second.
+	TCPDeliveryRate int64 `json:"tcpDeliveryRate"`
+
+	// TCPRTTUsec is the TCP_INFO smoothed RTT in microseconds.
+	TCPRTTUsec int64 `json:"tcpRTTUsec"`
+
+	// TCPRTTVarUsec is the TCP_INFO RTT variance in microseconds.
+	TCPRTTVarUsec int64 `json:"tcpRTTVarUsec"`
+
+	// TCPTotalRetrans is the TCP_INFO total number of retransmitted
+	// segments, which may indicate throttling.
+	TCPTotalRetrans int64 `json:"tcpTotalRetrans"`
+
+	// T0 is the time when we started tracking the connection, such
+	// that T minus T0 is the duration of the connection.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*ConnStatsEvent] implements [Event].
+var _ Event = &ConnStatsEvent{}
+
+// EventName implements [Event].
+func (ev *ConnStatsEvent) EventName() string {
+	return "connStats"
+}
+
+// LogAttrs implements [Event].
+func (ev *ConnStatsEvent) LogAttrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.Int64("ioBytesReadTotal", ev.IOBytesReadTotal),
+		slog.Int64("ioBytesWrittenTotal", ev.IOBytesWrittenTotal),
+		slog.Int64("ioReadCount", ev.IOReadCount),
+		slog.Int64("ioWriteCount", ev.IOWriteCount),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+	}
+	if ev.TCPInfo {
+		attrs = append(attrs,
+			slog.Bool("tcpInfo", ev.TCPInfo),
+			slog.Int64("tcpDeliveryRate", ev.TCPDeliveryRate),
+			slog.Int64("tcpRTTUsec", ev.TCPRTTUsec),
+			slog.Int64("tcpRTTVarUsec", ev.TCPRTTVarUsec),
+			slog.Int64("tcpTotalRetrans", ev.TCPTotalRetrans),
+		)
+	}
+	return append(attrs, slog.Time("t0", ev.T0), slog.Time("t", ev.T))
+}
+
+// TLSHandshakeStartEvent is the "tlsHandshakeStart" event emitted before the TLS handshake.
+type TLSHandshakeStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+
+	// TLSEngineName is the TLS engine name (e.g., "stdlib").
+	TLSEngineName string `json:"tlsEngineName"`
+
+	// TLSParrot is the parroted TLS fingerprint or empty.
+	TLSParrot string `json:"tlsParrot"`
+
+	// TLSServerName is the SNI we sent.
+	TLSServerName string `json:"tlsServerName"`
+
+	// TLSSkipVerify is whether we skipped certificate verification.
+	TLSSkipVerify bool `json:"tlsSkipVerify"`
+}
+
+// Ensure that [*TLSHandshakeStartEvent] implements [Event].
+var _ Event = &TLSHandshakeStartEvent{}
+
+// EventName implements [Event].
+func (ev *TLSHandshakeStartEvent) EventName() string {
+	return "tlsHandshakeStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *TLSHandshakeStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+		slog.String("tlsEngineName", ev.TLSEngineName),
+		slog.String("tlsParrot", ev.TLSParrot),
+		slog.String("tlsServerName", ev.TLSServerName),
+		slog.Bool("tlsSkipVerify", ev.TLSSkipVerify),
+	}
+}
+
+// TLSHandshakeDoneEvent is the "tlsHandshakeDone" event emitted after the TLS handshake.
+type TLSHandshakeDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+
+	// TLSCipherSuite is the negotiated cipher suite name.
+	TLSCipherSuite string `json:"tlsCipherSuite"`
+
+	// TLSEngineName is the TLS engine name (e.g., "stdlib").
+	TLSEngineName string `json:"tlsEngineName"`
+
+	// TLSJA3 is the JA3 fingerprint of the ClientHello or empty.
+	TLSJA3 string `json:"tlsJA3"`
+
+	// TLSJA4 is the JA4 fingerprint of the ClientHello or empty.
+	TLSJA4 string `json:"tlsJA4"`
+
+	// TLSParrot is the parroted TLS fingerprint or empty.
+	TLSParrot string `json:"tlsParrot"`
+
+	// TLSNegotiatedProtocol is the protocol negotiated using ALPN.
+	TLSNegotiatedProtocol string `json:"tlsNegotiatedProtocol"`
+
+	// TLSOCSPStapled is whether the server stapled an OCSP response.
+	TLSOCSPStapled bool `json:"tlsOCSPStapled"`
+
+	// TLSOCSPStatus is the status of the stapled OCSP response, that is, "good",
+	// "revoked", "unknown", or "invalid", or empty without a stapled response.
+	TLSOCSPStatus string `json:"tlsOCSPStatus"`
+
+	// TLSPeerCerts is the list of DER-encoded certificates sent by the peer.
+	TLSPeerCerts [][]byte `json:"tlsPeerCerts"`
+
+	// TLSRawClientHello is the base64 encoded ClientHello, if enabled.
+	TLSRawClientHello string `json:"tlsRawClientHello"`
+
+	// TLSRawServerRecords is the base64 encoded first server bytes, if enabled.
+	TLSRawServerRecords string `json:"tlsRawServerRecords"`
+
+	// TLSSCTCount is the number of signed certificate timestamps provided by
+	// the server, either using the TLS extension or embedded into the certificate.
+	TLSSCTCount int `json:"tlsSCTCount"`
+
+	// TLSSCTInvalid is the number of malformed signed certificate timestamps
+	// or with a timestamp in the future. We do not verify their signatures.
+	TLSSCTInvalid int `json:"tlsSCTInvalid"`
+
+	// TLSServerName is the SNI we sent.
+	TLSServerName string `json:"tlsServerName"`
+
+	// TLSSkipVerify is whether we skipped certificate verification.
+	TLSSkipVerify bool `json:"tlsSkipVerify"`
+
+	// TLSVerifiedChains describes the verified certificate chains, which are
+	// empty when the verification fails or we skip it, using the subjects of
+	// the certificates from the leaf to the root separated by " -> ".
+	TLSVerifiedChains []string `json:"tlsVerifiedChains"`
+
+	// TLSVerifiedRoot is the subject of the root of the first verified chain,
+	// that is, the trust anchor we matched, or empty.
+	TLSVerifiedRoot string `json:"tlsVerifiedRoot"`
+
+	// TLSVerifyFailure is the certificate verification step that failed (e.g.,
+	// "expired", "hostname_mismatch", or "unknown_authority") or empty.
+	TLSVerifyFailure string `json:"tlsVerifyFailure"`
+
+	// TLSVersion is the negotiated TLS version (e.g., "TLS 1.3").
+	TLSVersion string `json:"tlsVersion"`
+}
+
+// Ensure that [*TLSHandshakeDoneEvent] implements [Event].
+var _ Event = &TLSHandshakeDoneEvent{}
+
+// EventName implements [Event].
+func (ev *TLSHandshakeDoneEvent) EventName() string {
+	return "tlsHandshakeDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *TLSHandshakeDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+		slog.String("tlsCipherSuite", ev.TLSCipherSuite),
+		slog.String("tlsEngineName", ev.TLSEngineName),
+		slog.String("tlsJA3", ev.TLSJA3),
+		slog.String("tlsJA4", ev.TLSJA4),
+		slog.String("tlsParrot", ev.TLSParrot),
+		slog.String("tlsNegotiatedProtocol", ev.TLSNegotiatedProtocol),
+		slog.Bool("tlsOCSPStapled", ev.TLSOCSPStapled),
+		slog.String("tlsOCSPStatus", ev.TLSOCSPStatus),
+		slog.Any("tlsPeerCerts", ev.TLSPeerCerts),
+		slog.String("tlsRawClientHello", ev.TLSRawClientHello),
+		slog.String("tlsRawServerRecords", ev.TLSRawServerRecords),
+		slog.Int("tlsSCTCount", ev.TLSSCTCount),
+		slog.Int("tlsSCTInvalid", ev.TLSSCTInvalid),
+		slog.String("tlsServerName", ev.TLSServerName),
+		slog.Bool("tlsSkipVerify", ev.TLSSkipVerify),
+		slog.Any("tlsVerifiedChains", ev.TLSVerifiedChains),
+		slog.String("tlsVerifiedRoot", ev.TLSVerifiedRoot),
+		slog.String("tlsVerifyFailure", ev.TLSVerifyFailure),
+		slog.String("tlsVersion", ev.TLSVersion),
+	}
+}
+
+// QUICHandshakeStartEvent is the "quicHandshakeStart" event emitted before the QUIC handshake.
+type QUICHandshakeStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+
+	// TLSServerName is the SNI we sent.
+	TLSServerName string `json:"tlsServerName"`
+
+	// TLSSkipVerify is whether we skipped certificate verification.
+	TLSSkipVerify bool `json:"tlsSkipVerify"`
+}
+
+// Ensure that [*QUICHandshakeStartEvent] implements [Event].
+var _ Event = &QUICHandshakeStartEvent{}
+
+// EventName implements [Event].
+func (ev *QUICHandshakeStartEvent) EventName() string {
+	return "quicHandshakeStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *QUICHandshakeStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+		slog.String("tlsServerName", ev.TLSServerName),
+		slog.Bool("tlsSkipVerify", ev.TLSSkipVerify),
+	}
+}
+
+// QUICHandshakeDoneEvent is the "quicHandshakeDone" event emitted after the QUIC handshake.
+type QUICHandshakeDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// QUICUsed0RTT is whether the QUIC handshake used 0-RTT.
+	QUICUsed0RTT bool `json:"quicUsed0RTT"`
+
+	// QUICVersion is the negotiated QUIC version (e.g., "v1").
+	QUICVersion string `json:"quicVersion"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+
+	// TLSCipherSuite is the negotiated cipher suite name.
+	TLSCipherSuite string `json:"tlsCipherSuite"`
+
+	// TLSNegotiatedProtocol is the protocol negotiated using ALPN.
+	TLSNegotiatedProtocol string `json:"tlsNegotiatedProtocol"`
+
+	// TLSPeerCerts is the list of DER-encoded certificates sent by the peer.
+	TLSPeerCerts [][]byte `json:"tlsPeerCerts"`
+
+	// TLSServerName is the SNI we sent.
+	TLSServerName string `json:"tlsServerName"`
+
+	// TLSSkipVerify is whether we skipped certificate verification.
+	TLSSkipVerify bool `json:"tlsSkipVerify"`
+
+	// TLSVersion is the negotiated TLS version (e.g., "TLS 1.3").
+	TLSVersion string `json:"tlsVersion"`
+}
+
+// Ensure that [*QUICHandshakeDoneEvent] implements [Event].
+var _ Event = &QUICHandshakeDoneEvent{}
+
+// EventName implements [Event].
+func (ev *QUICHandshakeDoneEvent) EventName() string {
+	return "quicHandshakeDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *QUICHandshakeDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.Bool("quicUsed0RTT", ev.QUICUsed0RTT),
+		slog.String("quicVersion", ev.QUICVersion),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+		slog.String("tlsCipherSuite", ev.TLSCipherSuite),
+		slog.String("tlsNegotiatedProtocol", ev.TLSNegotiatedProtocol),
+		slog.Any("tlsPeerCerts", ev.TLSPeerCerts),
+		slog.String("tlsServerName", ev.TLSServerName),
+		slog.Bool("tlsSkipVerify", ev.TLSSkipVerify),
+		slog.String("tlsVersion", ev.TLSVersion),
+	}
+}
+
+// HTTPConnectStartEvent is the "httpConnectStart" event emitted before sending a CONNECT request to a proxy.
+type HTTPConnectStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// HTTPConnectTarget is the endpoint we asked the proxy to connect to.
+	HTTPConnectTarget string `json:"httpConnectTarget"`
+
+	// HTTPProxyURL is the proxy URL with the password redacted.
+	HTTPProxyURL string `json:"httpProxyUrl"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*HTTPConnectStartEvent] implements [Event].
+var _ Event = &HTTPConnectStartEvent{}
+
+// EventName implements [Event].
+func (ev *HTTPConnectStartEvent) EventName() string {
+	return "httpConnectStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *HTTPConnectStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.String("httpConnectTarget", ev.HTTPConnectTarget),
+		slog.String("httpProxyUrl", ev.HTTPProxyURL),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// HTTPConnectDoneEvent is the "httpConnectDone" event emitted after receiving the CONNECT response from a proxy.
+type HTTPConnectDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// HTTPConnectTarget is the endpoint we asked the proxy to connect to.
+	HTTPConnectTarget string `json:"httpConnectTarget"`
+
+	// HTTPProxyURL is the proxy URL with the password redacted.
+	HTTPProxyURL string `json:"httpProxyUrl"`
+
+	// HTTPResponseStatusCode is the HTTP response status code or zero.
+	HTTPResponseStatusCode int `json:"httpResponseStatusCode"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*HTTPConnectDoneEvent] implements [Event].
+var _ Event = &HTTPConnectDoneEvent{}
+
+// EventName implements [Event].
+func (ev *HTTPConnectDoneEvent) EventName() string {
+	return "httpConnectDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *HTTPConnectDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("httpConnectTarget", ev.HTTPConnectTarget),
+		slog.String("httpProxyUrl", ev.HTTPProxyURL),
+		slog.Int("httpResponseStatusCode", ev.HTTPResponseStatusCode),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// HTTPFirstResponseByteEvent is the "httpFirstResponseByte" event emitted when receiving the first response byte,
+// where T0 is the time when we finished writing the request.
+type HTTPFirstResponseByteEvent struct {
+	// HTTPMethod is the HTTP request method.
+	HTTPMethod string `json:"httpMethod"`
+
+	// HTTPURL is the HTTP request URL.
+	HTTPURL string `json:"httpUrl"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*HTTPFirstResponseByteEvent] implements [Event].
+var _ Event = &HTTPFirstResponseByteEvent{}
+
+// EventName implements [Event].
+func (ev *HTTPFirstResponseByteEvent) EventName() string {
+	return "httpFirstResponseByte"
+}
+
+// LogAttrs implements [Event].
+func (ev *HTTPFirstResponseByteEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.String("httpMethod", ev.HTTPMethod),
+		slog.String("httpUrl", ev.HTTPURL),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// HTTPRedirectHopEvent is the "httpRedirectHop" event emitted after each hop when following redirects.
+type HTTPRedirectHopEvent struct {
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// HTTPHasCookies is whether we sent cookies with the request.
+	HTTPHasCookies bool `json:"httpHasCookies"`
+
+	// HTTPLocation is the Location header of the response, if any.
+	HTTPLocation string `json:"httpLocation"`
+
+	// HTTPResponseStatusCode is the HTTP response status code or zero.
+	HTTPResponseStatusCode int `json:"httpResponseStatusCode"`
+
+	// HTTPURL is the HTTP request URL.
+	HTTPURL string `json:"httpUrl"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*HTTPRedirectHopEvent] implements [Event].
+var _ Event = &HTTPRedirectHopEvent{}
+
+// EventName implements [Event].
+func (ev *HTTPRedirectHopEvent) EventName() string {
+	return "httpRedirectHop"
+}
+
+// LogAttrs implements [Event].
+func (ev *HTTPRedirectHopEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.Bool("httpHasCookies", ev.HTTPHasCookies),
+		slog.String("httpLocation", ev.HTTPLocation),
+		slog.Int("httpResponseStatusCode", ev.HTTPResponseStatusCode),
+		slog.String("httpUrl", ev.HTTPURL),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// STUNBindingStartEvent is the "stunBindingStart" event emitted before sending a STUN binding request.
+type STUNBindingStartEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*STUNBindingStartEvent] implements [Event].
+var _ Event = &STUNBindingStartEvent{}
+
+// EventName implements [Event].
+func (ev *STUNBindingStartEvent) EventName() string {
+	return "stunBindingStart"
+}
+
+// LogAttrs implements [Event].
+func (ev *STUNBindingStartEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// STUNBindingDoneEvent is the "stunBindingDone" event emitted after receiving the STUN binding response.
+type STUNBindingDoneEvent struct {
+	// ConnID is the connection ID correlating the events of the same connection.
+	ConnID int64 `json:"connId"`
+
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// LocalAddr is the local endpoint or empty if unknown.
+	LocalAddr string `json:"localAddr"`
+
+	// Protocol is the protocol (e.g., "tcp" or "udp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the remote endpoint.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// STUNMappedAddr is the server reflexive endpoint or empty on failure.
+	STUNMappedAddr string `json:"stunMappedAddr"`
+
+	// STUNRTTUsec is the round trip time in microseconds.
+	STUNRTTUsec int64 `json:"stunRttUsec"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*STUNBindingDoneEvent] implements [Event].
+var _ Event = &STUNBindingDoneEvent{}
+
+// EventName implements [Event].
+func (ev *STUNBindingDoneEvent) EventName() string {
+	return "stunBindingDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *STUNBindingDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int64("connId", ev.ConnID),
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("localAddr", ev.LocalAddr),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.String("stunMappedAddr", ev.STUNMappedAddr),
+		slog.Int64("stunRttUsec", ev.STUNRTTUsec),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// TracerouteHopEvent is the "tracerouteHop" event emitted after each traceroute probe.
+type TracerouteHopEvent struct {
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// Protocol is the probe protocol (i.e., "udp", "tcp", or "icmp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the endpoint of the traceroute target.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// TracerouteHopAddr is the IP address that responded to the probe or empty.
+	TracerouteHopAddr string `json:"tracerouteHopAddr"`
+
+	// TracerouteReached indicates that the response comes from the target.
+	TracerouteReached bool `json:"tracerouteReached"`
+
+	// TracerouteRTTUsec is the round trip time in microseconds or zero without a response.
+	TracerouteRTTUsec int64 `json:"tracerouteRttUsec"`
+
+	// TracerouteTTL is the TTL of the probe.
+	TracerouteTTL int `json:"tracerouteTtl"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*TracerouteHopEvent] implements [Event].
+var _ Event = &TracerouteHopEvent{}
+
+// EventName implements [Event].
+func (ev *TracerouteHopEvent) EventName() string {
+	return "tracerouteHop"
+}
+
+// LogAttrs implements [Event].
+func (ev *TracerouteHopEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.String("tracerouteHopAddr", ev.TracerouteHopAddr),
+		slog.Bool("tracerouteReached", ev.TracerouteReached),
+		slog.Int64("tracerouteRttUsec", ev.TracerouteRTTUsec),
+		slog.Int("tracerouteTtl", ev.TracerouteTTL),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// PingRequestEvent is the "pingRequest" event emitted before sending an ICMP echo request.
+type PingRequestEvent struct {
+	// PingSeq is the sequence number of the echo request.
+	PingSeq int `json:"pingSeq"`
+
+	// Protocol is the protocol (i.e., "icmp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the IP address we ping.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*PingRequestEvent] implements [Event].
+var _ Event = &PingRequestEvent{}
+
+// EventName implements [Event].
+func (ev *PingRequestEvent) EventName() string {
+	return "pingRequest"
+}
+
+// LogAttrs implements [Event].
+func (ev *PingRequestEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		slog.Int("pingSeq", ev.PingSeq),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t", ev.T),
+	}
+}
+
+// PingReplyEvent is the "pingReply" event emitted after receiving the ICMP echo reply or timing out.
+type PingReplyEvent struct {
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// PingRTTUsec is the round trip time in microseconds or zero on failure.
+	PingRTTUsec int64 `json:"pingRttUsec"`
+
+	// PingSeq is the sequence number of the echo request.
+	PingSeq int `json:"pingSeq"`
+
+	// Protocol is the protocol (i.e., "icmp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the IP address we ping.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*PingReplyEvent] implements [Event].
+var _ Event = &PingReplyEvent{}
+
+// EventName implements [Event].
+func (ev *PingReplyEvent) EventName() string {
+	return "pingReply"
+}
+
+// LogAttrs implements [Event].
+func (ev *PingReplyEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.Int64("pingRttUsec", ev.PingRTTUsec),
+		slog.Int("pingSeq", ev.PingSeq),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
+
+// PingDoneEvent is the "pingDone" event emitted after all the ICMP echo requests,
+// summarizing the packet loss and the round trip times.
+type PingDoneEvent struct {
+	// Err is the error message or empty on success, which we emit as null.
+	Err string `json:"err"`
+
+	// ErrClass is the error class (e.g., "ETIMEDOUT") or empty on success.
+	ErrClass string `json:"errClass"`
+
+	// PingPrivileged indicates whether we used a raw ICMP socket.
+	PingPrivileged bool `json:"pingPrivileged"`
+
+	// PingReceived is the number of echo replies we received.
+	PingReceived int `json:"pingReceived"`
+
+	// PingRTTAvgUsec is the average round trip time in microseconds or zero.
+	PingRTTAvgUsec int64 `json:"pingRttAvgUsec"`
+
+	// PingRTTMaxUsec is the maximum round trip time in microseconds or zero.
+	PingRTTMaxUsec int64 `json:"pingRttMaxUsec"`
+
+	// PingRTTMinUsec is the minimum round trip time in microseconds or zero.
+	PingRTTMinUsec int64 `json:"pingRttMinUsec"`
+
+	// PingSent is the number of echo requests we sent.
+	PingSent int `json:"pingSent"`
+
+	// Protocol is the protocol (i.e., "icmp").
+	Protocol string `json:"protocol"`
+
+	// RemoteAddr is the IP address we ping.
+	RemoteAddr string `json:"remoteAddr"`
+
+	// T0 is the time when the operation started.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when the event occurred.
+	T time.Time `json:"t"`
+}
+
+// Ensure that [*PingDoneEvent] implements [Event].
+var _ Event = &PingDoneEvent{}
+
+// EventName implements [Event].
+func (ev *PingDoneEvent) EventName() string {
+	return "pingDone"
+}
+
+// LogAttrs implements [Event].
+func (ev *PingDoneEvent) LogAttrs() []slog.Attr {
+	return []slog.Attr{
+		errAttr(ev.Err),
+		slog.String("errClass", ev.ErrClass),
+		slog.Bool("pingPrivileged", ev.PingPrivileged),
+		slog.Int("pingReceived", ev.PingReceived),
+		slog.Int64("pingRttAvgUsec", ev.PingRTTAvgUsec),
+		slog.Int64("pingRttMaxUsec", ev.PingRTTMaxUsec),
+		slog.Int64("pingRttMinUsec", ev.PingRTTMinUsec),
+		slog.Int("pingSent", ev.PingSent),
+		slog.String("protocol", ev.Protocol),
+		slog.String("remoteAddr", ev.RemoteAddr),
+		slog.Time("t0", ev.T0),
+		slog.Time("t", ev.T),
+	}
+}
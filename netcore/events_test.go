@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testEventHook is an [EventHook] recording the events.
+type testEventHook struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+// OnEvent implements [EventHook].
+func (h *testEventHook) OnEvent(ctx context.Context, ev Event) {
+	h.mu.Lock()
+	h.events = append(h.events, ev)
+	h.mu.Unlock()
+}
+
+// fillTestEvent sets all the fields of the given event to nonzero values.
+func fillTestEvent(ev Event) {
+	value := reflect.ValueOf(ev).Elem()
+	for idx := 0; idx < value.NumField(); idx++ {
+		field := value.Field(idx)
+		switch field.Interface().(type) {
+		case string:
+			field.SetString(value.Type().Field(idx).Name)
+		case int, int64:
+			field.SetInt(int64(idx + 1))
+		case bool:
+			field.SetBool(true)
+		case time.Time:
+			field.Set(reflect.ValueOf(time.Date(2024, 1, 1, 0, 0, idx, 0, time.UTC)))
+		case []string:
+			field.Set(reflect.ValueOf([]string{"130.192.91.211", "2001:db8::1"}))
+		case []LookupQuery:
+			field.Set(reflect.ValueOf([]LookupQuery{{
+				Err:       "no answer from DNS server",
+				QueryType: "AAAA",
+				T0:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				T:         time.Date(2024, 1, 1, 0, 0, 1, 0, time.UTC),
+			}}))
+		case [][]byte:
+			field.Set(reflect.ValueOf([][]byte{{0x30, 0x01}, {0x30, 0x02}}))
+		default:
+			panic("fillTestEvent: unhandled field type")
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	events := []Event{
+		&LookupHostStartEvent{},
+		&LookupHostDoneEvent{},
+		&DNSQueryStartEvent{},
+		&DNSQueryDoneEvent{},
+		&ConnectStartEvent{},
+		&ConnectDoneEvent{},
+		&AcceptStartEvent{},
+		&AcceptDoneEvent{},
+		&ReadStartEvent{},
+		&ReadDoneEvent{},
+		&WriteStartEvent{},
+		&WriteDoneEvent{},
+		&ReadFromStartEvent{},
+		&ReadFromDoneEvent{},
+		&WriteToStartEvent{},
+		&WriteToDoneEvent{},
+		&CloseStartEvent{},
+		&CloseDoneEvent{},
+		&ConnStatsEvent{},
+		&TLSHandshakeStartEvent{},
+		&TLSHandshakeDoneEvent{},
+		&QUICHandshakeStartEvent{},
+		&QUICHandshakeDoneEvent{},
+		&HTTPConnectStartEvent{},
+		&HTTPConnectDoneEvent{},
+		&HTTPFirstResponseByteEvent{},
+		&HTTPRedirectHopEvent{},
+		&STUNBindingStartEvent{},
+		&STUNBindingDoneEvent{},
+		&TracerouteHopEvent{},
+		&PingRequestEvent{},
+		&PingReplyEvent{},
+		&PingDoneEvent{},
+	}
+
+	for _, ev := range events {
+		t.Run(ev.EventName(), func(t *testing.T) {
+			fillTestEvent(ev)
+
+			// make sure the JSON log entry parses back into the event
+			var buf bytes.Buffer
+			nx := &Network{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+			nx.emit(context.Background(), ev)
+
+			var entry struct {
+				Msg string `json:"msg"`
+			}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+			assert.Equal(t, ev.EventName(), entry.Msg)
+
+			got := reflect.New(reflect.TypeOf(ev).Elem()).Interface()
+			require.NoError(t, json.Unmarshal(buf.Bytes(), got))
+			assert.Equal(t, ev, got)
+
+			// make sure there is a json tag for each attribute
+			var fields map[string]any
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+			assert.Len(t, fields, reflect.TypeOf(ev).Elem().NumField()+3)
+		})
+	}
+
+	t.Run("we emit a null error on success", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+		nx.emit(context.Background(), &CloseDoneEvent{})
+
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &fields))
+		value, found := fields["err"]
+		assert.True(t, found)
+		assert.Nil(t, value)
+	})
+
+	t.Run("we invoke the EventHook without a logger", func(t *testing.T) {
+		hook := &testEventHook{}
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return &mocks.Conn{
+					MockClose:      func() error { return nil },
+					MockLocalAddr:  func() net.Addr { return &net.TCPAddr{} },
+					MockRemoteAddr: func() net.Addr { return &net.TCPAddr{} },
+				}, nil
+			},
+			EventHook: hook,
+			WrapConn:  WrapConn,
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:443")
+		require.NoError(t, err)
+		conn.Close()
+
+		var names []string
+		for _, ev := range hook.events {
+			names = append(names, ev.EventName())
+		}
+		assert.Equal(t, []string{"connectStart", "connectDone", "closeStart", "closeDone", "connStats"}, names)
+	})
+
+	t.Run("we do not emit without a logger or hook", func(t *testing.T) {
+		nx := &Network{}
+		nx.emit(context.Background(), &CloseDoneEvent{})
+	})
+}
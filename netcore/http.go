@@ -0,0 +1,38 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// HTTP transport.
+//
+
+package netcore
+
+import "net/http"
+
+// NewHTTPTransport returns an [*http.Transport] dialing cleartext and TLS
+// connections using the DialContext and DialTLSContext methods of the
+// given [*Network], with defaults suitable for measuring:
+//
+// 1. we disable keep-alives, such that each request uses a new
+// connection and we observe its connect and handshake events;
+//
+// 2. we disable proxies, including the ones configured using
+// environment variables, to measure the direct network path, unless
+// the ProxyURL field of the [*Network] is set, in which case
+// the dialers tunnel connections through the proxy;
+//
+// 3. we set ForceAttemptHTTP2, to negotiate HTTP/2 using ALPN
+// despite using a custom TLS dialer.
+//
+// The caller may modify the returned transport before using it (e.g., by
+// setting DisableKeepAlives to false to reuse idle connections). To only use
+// HTTP/1.1, set ForceAttemptHTTP2 to false and set the TLSConfig field of the
+// [*Network] such that the ALPN does not include "h2".
+func NewHTTPTransport(nx *Network) *http.Transport {
+	return &http.Transport{
+		DialContext:       nx.DialContext,
+		DialTLSContext:    nx.DialTLSContext,
+		DisableKeepAlives: true,
+		ForceAttemptHTTP2: true,
+		Proxy:             nil,
+	}
+}
@@ -0,0 +1,38 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// HTTP/3 transport.
+//
+
+package netcore
+
+import (
+	"crypto/tls"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// NewHTTP3Transport returns an [*http3.Transport] establishing QUIC
+// connections using the given [*Network], such that HTTP/3 round trips
+// emit the same structured logs as [*Network.DialQUICContext].
+//
+// The transport uses a clone of the TLSConfig field of the [*Network],
+// if set, or a config using its RootCAs field, otherwise. In both cases,
+// the transport overrides the ALPN to "h3" and, unless already set,
+// uses the host of the request URL as the TLS server name.
+//
+// The transport uses the QUICConfig field of the [*Network], if set.
+//
+// The caller should invoke the Close method of the returned
+// transport when done using it to close the idle connections.
+func NewHTTP3Transport(nx *Network) *http3.Transport {
+	tlsConfig := &tls.Config{RootCAs: nx.RootCAs}
+	if nx.TLSConfig != nil {
+		tlsConfig = nx.TLSConfig.Clone()
+	}
+	return &http3.Transport{
+		Dial:            nx.dialQUIC,
+		QUICConfig:      nx.QUICConfig,
+		TLSClientConfig: tlsConfig,
+	}
+}
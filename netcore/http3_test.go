@@ -0,0 +1,78 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rbmk-project/common/runtimex"
+	"github.com/rbmk-project/common/selfsignedcert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTP3Transport(t *testing.T) {
+	t.Run("TLS config", func(t *testing.T) {
+		pool := x509.NewCertPool()
+
+		nx := &Network{RootCAs: pool}
+		txp := NewHTTP3Transport(nx)
+		assert.Same(t, pool, txp.TLSClientConfig.RootCAs)
+
+		nx = &Network{TLSConfig: &tls.Config{ServerName: "www.example.com"}}
+		txp = NewHTTP3Transport(nx)
+		assert.NotSame(t, nx.TLSConfig, txp.TLSClientConfig)
+		assert.Equal(t, "www.example.com", txp.TLSClientConfig.ServerName)
+	})
+
+	t.Run("round trip with logging", func(t *testing.T) {
+		// start an HTTP/3 server on localhost
+		cert := selfsignedcert.New(selfsignedcert.NewConfigExampleCom())
+		tlsCert := runtimex.Try1(tls.X509KeyPair(cert.CertPEM, cert.KeyPEM))
+		pool := x509.NewCertPool()
+		runtimex.Assert(pool.AppendCertsFromPEM(cert.CertPEM), "cannot append cert")
+		pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer pconn.Close()
+		srv := &http3.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("Hello, HTTP/3!\n"))
+			}),
+			TLSConfig: http3.ConfigureTLSConfig(&tls.Config{
+				Certificates: []tls.Certificate{tlsCert},
+			}),
+		}
+		go srv.Serve(pconn)
+		defer srv.Close()
+
+		// perform the round trip
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger:  slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+			RootCAs: pool,
+		}
+		txp := NewHTTP3Transport(nx)
+		defer txp.Close()
+		client := &http.Client{Transport: txp}
+		resp, err := client.Get("https://" + pconn.LocalAddr().String() + "/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		// check the results
+		assert.Equal(t, "HTTP/3.0", resp.Proto)
+		assert.Equal(t, "Hello, HTTP/3!\n", string(body))
+		assert.True(t, strings.Contains(buf.String(), `"msg":"quicHandshakeDone"`))
+		assert.True(t, strings.Contains(buf.String(), `"tlsNegotiatedProtocol":"h3"`))
+	})
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPTransport(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		txp := NewHTTPTransport(&Network{})
+		assert.NotNil(t, txp.DialContext)
+		assert.NotNil(t, txp.DialTLSContext)
+		assert.True(t, txp.DisableKeepAlives)
+		assert.True(t, txp.ForceAttemptHTTP2)
+		assert.Nil(t, txp.Proxy)
+	})
+
+	for _, proto := range []string{"HTTP/1.1", "HTTP/2.0"} {
+		t.Run("round trip using "+proto, func(t *testing.T) {
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(r.Proto))
+			}))
+			srv.EnableHTTP2 = proto == "HTTP/2.0"
+			srv.StartTLS()
+			defer srv.Close()
+
+			var buf bytes.Buffer
+			nx := &Network{
+				Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+				TLSConfig: &tls.Config{
+					NextProtos: []string{"h2", "http/1.1"},
+					RootCAs:    srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs,
+					ServerName: "example.com",
+				},
+			}
+			txp := NewHTTPTransport(nx)
+			defer txp.CloseIdleConnections()
+			client := &http.Client{Transport: txp}
+			resp, err := client.Get(srv.URL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, proto, resp.Proto)
+			assert.Equal(t, proto, string(body))
+			assert.True(t, strings.Contains(buf.String(), `"msg":"tlsHandshakeDone"`))
+		})
+	}
+}
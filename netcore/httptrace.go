@@ -0,0 +1,102 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// HTTP round trip tracing.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// WrapHTTPRoundTripper wraps a given [http.RoundTripper] to emit an
+// "httpFirstResponseByte" structured event for each round trip, whose t0
+// and t fields contain the time when we finished writing the request
+// and the time when we received the first response byte, such that their
+// difference is the time to first byte (TTFB).
+//
+// The wrapped round tripper uses [net/http/httptrace] to observe the round
+// trip, so we only emit the event when the underlying round tripper
+// supports it (e.g., [*http.Transport], which [NewHTTPTransport] returns).
+func WrapHTTPRoundTripper(nx *Network, txp http.RoundTripper) http.RoundTripper {
+	return &httpRoundTripper{netx: nx, txp: txp}
+}
+
+// httpRoundTripper wraps an [http.RoundTripper].
+type httpRoundTripper struct {
+	netx *Network
+	txp  http.RoundTripper
+}
+
+// RoundTrip implements [http.RoundTripper].
+func (rt *httpRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !rt.netx.emitEnabled() {
+		return rt.txp.RoundTrip(req)
+	}
+	ht := &httpTracer{netx: rt.netx, req: req}
+	ctx := httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn:              ht.gotConn,
+		WroteRequest:         ht.wroteRequest,
+		GotFirstResponseByte: ht.gotFirstResponseByte,
+	})
+	return rt.txp.RoundTrip(req.WithContext(ctx))
+}
+
+// httpTracer traces a single HTTP round trip.
+type httpTracer struct {
+	conn        net.Conn
+	mu          sync.Mutex
+	netx        *Network
+	req         *http.Request
+	wroteReqErr error
+	wroteReqT   time.Time
+}
+
+// gotConn records the connection used by the round trip.
+func (ht *httpTracer) gotConn(info httptrace.GotConnInfo) {
+	ht.mu.Lock()
+	ht.conn = info.Conn
+	ht.mu.Unlock()
+}
+
+// wroteRequest records when we finished writing the request.
+func (ht *httpTracer) wroteRequest(info httptrace.WroteRequestInfo) {
+	t := ht.netx.timeNow()
+	ht.mu.Lock()
+	ht.wroteReqErr = info.Err
+	ht.wroteReqT = t
+	ht.mu.Unlock()
+}
+
+// gotFirstResponseByte emits the structured event.
+func (ht *httpTracer) gotFirstResponseByte() {
+	t := ht.netx.timeNow()
+	ht.mu.Lock()
+	conn, t0, err := ht.conn, ht.wroteReqT, ht.wroteReqErr
+	ht.mu.Unlock()
+	if t0.IsZero() || err != nil {
+		return // we did not write the request successfully
+	}
+	ht.emitHTTPFirstResponseByte(ht.req.Context(), conn, t0, t)
+}
+
+// emitHTTPFirstResponseByte emits the "httpFirstResponseByte" event.
+func (ht *httpTracer) emitHTTPFirstResponseByte(
+	ctx context.Context, conn net.Conn, t0, t time.Time) {
+	laddr := connLocalAddr(conn)
+	ht.netx.emit(ctx, &HTTPFirstResponseByteEvent{
+		HTTPMethod: ht.req.Method,
+		HTTPURL:    ht.req.URL.String(),
+		LocalAddr:  laddr.String(),
+		Protocol:   laddr.Network(),
+		RemoteAddr: connRemoteAddr(conn).String(),
+		T0:         t0,
+		T:          t,
+	})
+}
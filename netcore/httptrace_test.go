@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapHTTPRoundTripper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte("hello"))
+	}))
+	defer srv.Close()
+
+	t.Run("we emit the first response byte event", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+		}
+		txp := WrapHTTPRoundTripper(nx, NewHTTPTransport(nx))
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := txp.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		var events []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "httpFirstResponseByte" {
+				events = append(events, ev)
+			}
+		}
+		require.Len(t, events, 1)
+		ev := events[0]
+		assert.Equal(t, "GET", ev["httpMethod"])
+		assert.Equal(t, srv.URL, ev["httpUrl"])
+		assert.Equal(t, "tcp", ev["protocol"])
+		assert.Equal(t, srv.Listener.Addr().String(), ev["remoteAddr"])
+		t0, err := time.Parse(time.RFC3339Nano, ev["t0"].(string))
+		require.NoError(t, err)
+		t1, err := time.Parse(time.RFC3339Nano, ev["t"].(string))
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, t1.Sub(t0), 50*time.Millisecond)
+	})
+
+	t.Run("we do not trace without a logger", func(t *testing.T) {
+		nx := &Network{}
+		txp := WrapHTTPRoundTripper(nx, NewHTTPTransport(nx))
+		req, err := http.NewRequest("GET", srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := txp.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})
+}
@@ -0,0 +1,131 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// JSONL event sink.
+//
+
+package netcore
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// JSONLHandler is a [slog.Handler] writing the structured diagnostic
+// events as line-delimited JSON objects (JSONL).
+//
+// Each line contains the event name as the "msg" field followed by the
+// event fields in the documented order. We omit the "time" and "level"
+// fields added by [slog.JSONHandler], since the events already contain
+// the "t" field and we emit them all at the same level.
+//
+// We buffer the output and serialize writes, so the handler is safe for
+// concurrent use and lines never interleave. You MUST call Flush or Close
+// to make sure all the buffered events reach the underlying writer.
+//
+// Construct using [NewJSONLHandler].
+type JSONLHandler struct {
+	slog.Handler
+	w *jsonlWriter
+}
+
+// Ensure that [*JSONLHandler] implements [slog.Handler].
+var _ slog.Handler = &JSONLHandler{}
+
+// NewJSONLHandler creates a new [*JSONLHandler] writing to the given
+// [io.Writer] the records whose level is at least the given level. If
+// the level is nil, we use [slog.LevelInfo]. When the writer is also an
+// [io.Closer], the Close method of the handler closes it.
+func NewJSONLHandler(w io.Writer, level slog.Leveler) *JSONLHandler {
+	jw := &jsonlWriter{bw: bufio.NewWriter(w), w: w}
+	return &JSONLHandler{
+		Handler: slog.NewJSONHandler(jw, &slog.HandlerOptions{
+			Level:       level,
+			ReplaceAttr: jsonlReplaceAttr,
+		}),
+		w: jw,
+	}
+}
+
+// jsonlReplaceAttr removes the "time" and "level" fields.
+func jsonlReplaceAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) == 0 && (attr.Key == slog.TimeKey || attr.Key == slog.LevelKey) {
+		return slog.Attr{}
+	}
+	return attr
+}
+
+// WithAttrs implements [slog.Handler].
+func (h *JSONLHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &JSONLHandler{Handler: h.Handler.WithAttrs(attrs), w: h.w}
+}
+
+// WithGroup implements [slog.Handler].
+func (h *JSONLHandler) WithGroup(name string) slog.Handler {
+	return &JSONLHandler{Handler: h.Handler.WithGroup(name), w: h.w}
+}
+
+// Flush writes the buffered events to the underlying writer.
+//
+// This method is goroutine safe.
+func (h *JSONLHandler) Flush() error {
+	return h.w.Flush()
+}
+
+// Close flushes the buffered events and closes the underlying writer
+// when it is an [io.Closer]. After Close, handling a record fails with
+// [net.ErrClosed]. The handlers returned by WithAttrs and WithGroup
+// share the same writer, so closing any of them closes them all.
+//
+// This method is goroutine safe.
+func (h *JSONLHandler) Close() error {
+	return h.w.Close()
+}
+
+// jsonlWriter is the buffered, goroutine safe writer used by [*JSONLHandler].
+type jsonlWriter struct {
+	bw     *bufio.Writer
+	closed bool
+	mu     sync.Mutex
+	w      io.Writer
+}
+
+// Write implements [io.Writer].
+func (jw *jsonlWriter) Write(data []byte) (int, error) {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	if jw.closed {
+		return 0, net.ErrClosed
+	}
+	return jw.bw.Write(data)
+}
+
+// Flush flushes the buffered data.
+func (jw *jsonlWriter) Flush() error {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	if jw.closed {
+		return net.ErrClosed
+	}
+	return jw.bw.Flush()
+}
+
+// Close flushes the buffered data and closes the writer, if possible.
+func (jw *jsonlWriter) Close() error {
+	jw.mu.Lock()
+	defer jw.mu.Unlock()
+	if jw.closed {
+		return net.ErrClosed
+	}
+	jw.closed = true
+	err := jw.bw.Flush()
+	if closer, ok := jw.w.(io.Closer); ok {
+		if cerr := closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
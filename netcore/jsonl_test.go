@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testWriteCloser is a [bytes.Buffer] with a Close method.
+type testWriteCloser struct {
+	bytes.Buffer
+	closed bool
+	err    error
+}
+
+// Close implements [io.Closer].
+func (wc *testWriteCloser) Close() error {
+	wc.closed = true
+	return wc.err
+}
+
+func TestJSONLHandler(t *testing.T) {
+	t.Run("we write the events in order without time and level", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := NewJSONLHandler(&buf, nil)
+		nx := &Network{Logger: slog.New(handler)}
+		nx.emit(context.Background(), &ConnectStartEvent{
+			ConnID:     7,
+			Protocol:   "tcp",
+			RemoteAddr: "130.192.91.211:443",
+			T:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		})
+
+		// nothing is written before flushing
+		assert.Empty(t, buf.String())
+		require.NoError(t, handler.Flush())
+
+		expect := `{"msg":"connectStart","attemptIndex":0,"connId":7,"protocol":"tcp",` +
+			`"queueWaitUsec":0,"remoteAddr":"130.192.91.211:443","t":"2024-01-01T00:00:00Z"}` + "\n"
+		assert.Equal(t, expect, buf.String())
+	})
+
+	t.Run("we filter records by level", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := NewJSONLHandler(&buf, slog.LevelWarn)
+		logger := slog.New(handler)
+		logger.Info("connectStart")
+		logger.Warn("connectDone")
+		require.NoError(t, handler.Flush())
+		assert.Equal(t, `{"msg":"connectDone"}`+"\n", buf.String())
+	})
+
+	t.Run("we keep the attrs and groups", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := NewJSONLHandler(&buf, nil)
+		logger := slog.New(handler).With("measurement", "web").WithGroup("g")
+		logger.Info("connectStart", "time", "kept")
+		require.NoError(t, handler.Flush())
+		assert.Equal(t, `{"msg":"connectStart","measurement":"web","g":{"time":"kept"}}`+"\n", buf.String())
+	})
+
+	t.Run("lines do not interleave with concurrent writers", func(t *testing.T) {
+		var buf bytes.Buffer
+		handler := NewJSONLHandler(&buf, nil)
+		nx := &Network{Logger: slog.New(handler)}
+		var wg sync.WaitGroup
+		for idx := 0; idx < 16; idx++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for count := 0; count < 64; count++ {
+					nx.emit(context.Background(), &ReadDoneEvent{IOBytesCount: count})
+				}
+			}()
+		}
+		wg.Wait()
+		require.NoError(t, handler.Flush())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, lines, 16*64)
+		for _, line := range lines {
+			var ev ReadDoneEvent
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		}
+	})
+
+	t.Run("Close flushes and closes the writer", func(t *testing.T) {
+		wc := &testWriteCloser{}
+		handler := NewJSONLHandler(wc, nil)
+		logger := slog.New(handler)
+		logger.Info("closeStart")
+		require.NoError(t, handler.Close())
+		assert.True(t, wc.closed)
+		assert.Equal(t, `{"msg":"closeStart"}`+"\n", wc.String())
+
+		// once closed, we cannot use the handler anymore
+		assert.ErrorIs(t, handler.Handle(context.Background(), slog.Record{}), net.ErrClosed)
+		assert.ErrorIs(t, handler.Flush(), net.ErrClosed)
+		assert.ErrorIs(t, handler.Close(), net.ErrClosed)
+	})
+
+	t.Run("Close returns the error closing the writer", func(t *testing.T) {
+		expected := errors.New("mocked error")
+		wc := &testWriteCloser{err: expected}
+		handler := NewJSONLHandler(wc, nil)
+		assert.ErrorIs(t, handler.Close(), expected)
+	})
+}
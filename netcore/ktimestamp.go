@@ -0,0 +1,47 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Kernel receive timestamps.
+//
+
+package netcore
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// errKernelTimestampUnsupported indicates that we cannot read the
+// kernel timestamps for the connection, so we fallback to Read.
+var errKernelTimestampUnsupported = errors.New("netcore: kernel timestamps not supported")
+
+// readWithKernelTimestamp reads from the given connection and returns the kernel
+// receive timestamp, when available, or the zero time otherwise.
+func readWithKernelTimestamp(conn net.Conn, buf []byte) (int, time.Time, error) {
+	count, ts, err := recvWithKernelTimestamp(conn, buf)
+	switch {
+	case err == errKernelTimestampUnsupported:
+		count, err = conn.Read(buf)
+		return count, time.Time{}, err
+	case err != nil:
+		return 0, time.Time{}, &net.OpError{
+			Op:     "read",
+			Net:    connLocalAddr(conn).Network(),
+			Source: conn.LocalAddr(),
+			Addr:   conn.RemoteAddr(),
+			Err:    err,
+		}
+	case count == 0 && len(buf) > 0 && isStreamConn(conn):
+		return 0, time.Time{}, io.EOF
+	default:
+		return count, ts, nil
+	}
+}
+
+// isStreamConn returns whether the connection is a TCP connection.
+func isStreamConn(conn net.Conn) bool {
+	_, ok := conn.(*net.TCPConn)
+	return ok
+}
@@ -0,0 +1,71 @@
+//go:build linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Linux kernel receive timestamps.
+//
+
+package netcore
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// enableKernelTimestamps enables SO_TIMESTAMPNS on the socket.
+func enableKernelTimestamps(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TIMESTAMPNS, 1)
+}
+
+// recvWithKernelTimestamp uses recvmsg to read from the TCP or UDP
+// connection and parses the SCM_TIMESTAMPNS control message.
+func recvWithKernelTimestamp(conn net.Conn, buf []byte) (int, time.Time, error) {
+	var sc syscall.Conn
+	switch conn := conn.(type) {
+	case *net.TCPConn:
+		sc = conn
+	case *net.UDPConn:
+		sc = conn
+	default:
+		return 0, time.Time{}, errKernelTimestampUnsupported
+	}
+	rawConn, err := sc.SyscallConn()
+	if err != nil {
+		return 0, time.Time{}, errKernelTimestampUnsupported
+	}
+
+	var (
+		count int
+		oob   = make([]byte, unix.CmsgSpace(int(unsafe.Sizeof(unix.Timespec{}))))
+		oobn  int
+		rerr  error
+	)
+	if err := rawConn.Read(func(fd uintptr) bool {
+		count, oobn, _, _, rerr = unix.Recvmsg(int(fd), buf, oob, 0)
+		return rerr != unix.EAGAIN
+	}); err != nil {
+		return 0, time.Time{}, err
+	}
+	if rerr != nil {
+		return 0, time.Time{}, os.NewSyscallError("recvmsg", rerr)
+	}
+
+	messages, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return count, time.Time{}, nil
+	}
+	for _, msg := range messages {
+		if msg.Header.Level == unix.SOL_SOCKET && msg.Header.Type == unix.SCM_TIMESTAMPNS &&
+			len(msg.Data) >= int(unsafe.Sizeof(unix.Timespec{})) {
+			ts := (*unix.Timespec)(unsafe.Pointer(&msg.Data[0]))
+			return count, time.Unix(ts.Unix()), nil
+		}
+	}
+	return count, time.Time{}, nil
+}
@@ -0,0 +1,114 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_LogKernelTimestamps(t *testing.T) {
+	// start a TCP and a UDP echo servers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			io.Copy(conn, conn)
+			conn.Close()
+		}
+	}()
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pconn.Close()
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			count, addr, err := pconn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			pconn.WriteTo(buf[:count], addr)
+		}
+	}()
+
+	servers := map[string]string{
+		"tcp": listener.Addr().String(),
+		"udp": pconn.LocalAddr().String(),
+	}
+	for network, address := range servers {
+		t.Run(network, func(t *testing.T) {
+			var buf bytes.Buffer
+			nx := &Network{
+				LogKernelTimestamps: true,
+				Logger:              slog.New(slog.NewJSONHandler(&buf, nil)),
+				WrapConn:            WrapConn,
+			}
+			conn, err := nx.DialContext(context.Background(), network, address)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			// the kernel may enable the timestamps asynchronously, so the
+			// first packets could lack them and we need several attempts
+			data := make([]byte, 5)
+			for idx := 0; idx < 10; idx++ {
+				_, err = conn.Write([]byte("hello"))
+				require.NoError(t, err)
+				_, err = io.ReadFull(conn, data)
+				require.NoError(t, err)
+				assert.Equal(t, "hello", string(data))
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			// make sure we honor the deadlines
+			conn.SetReadDeadline(time.Now())
+			_, err = conn.Read(data)
+			assert.True(t, errors.Is(err, os.ErrDeadlineExceeded))
+
+			var found bool
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if !strings.Contains(line, `"msg":"readDone"`) {
+					continue
+				}
+				var ev ReadDoneEvent
+				require.NoError(t, json.Unmarshal([]byte(line), &ev))
+				if ev.TKernel.IsZero() {
+					continue
+				}
+				found = true
+				assert.False(t, ev.TKernel.After(ev.T))
+			}
+			assert.True(t, found)
+		})
+	}
+
+	t.Run("we return io.EOF when the peer closes a TCP connection", func(t *testing.T) {
+		nx := &Network{
+			LogKernelTimestamps: true,
+			Logger:              slog.New(slog.NewTextHandler(io.Discard, nil)),
+			WrapConn:            WrapConn,
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.(*statsConn).Conn.(*connWrapper).conn.(*net.TCPConn).CloseWrite())
+		_, err = conn.Read(make([]byte, 5))
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
@@ -0,0 +1,24 @@
+//go:build !linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Kernel receive timestamps on other platforms.
+//
+
+package netcore
+
+import (
+	"net"
+	"time"
+)
+
+// enableKernelTimestamps does nothing because we only support kernel timestamps on Linux.
+func enableKernelTimestamps(fd uintptr) error {
+	return nil
+}
+
+// recvWithKernelTimestamp fails with [errKernelTimestampUnsupported].
+func recvWithKernelTimestamp(conn net.Conn, buf []byte) (int, time.Time, error) {
+	return 0, time.Time{}, errKernelTimestampUnsupported
+}
@@ -0,0 +1,126 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Instrumented listeners.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Listen creates a new listener (e.g., a TCP listener), which is useful
+// to run local servers (e.g., reflectors or test servers) with the same
+// observability as the dialing side. The listener emits the "acceptStart"
+// and "acceptDone" events and wraps the accepted connections using WrapConn,
+// with a new connection ID for each of them. We apply BindToDevice,
+// SocketOptions, and ControlFunc to the listening socket.
+//
+// The context argument is used for creating the listener and for logging,
+// but does not constrain the lifetime of the listener.
+//
+// This method is goroutine safe.
+func (nx *Network) Listen(ctx context.Context, network, address string) (net.Listener, error) {
+	listener, err := nx.listenConfig().Listen(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if nx.emitEnabled() {
+		addr := listener.Addr()
+		listener = &listenerWrapper{
+			ctx:      ctx,
+			laddr:    addr.String(),
+			listener: listener,
+			netx:     nx,
+			protocol: addr.Network(),
+		}
+	}
+	return listener, nil
+}
+
+// ListenPacket creates a new [net.PacketConn] (e.g., a UDP socket) applying
+// BindToDevice, SocketOptions, and ControlFunc to the socket. We wrap the
+// [net.PacketConn] using WrapPacketConn, to emit events for each datagram.
+//
+// The context argument is used for creating the socket and for logging,
+// but does not constrain the lifetime of the socket.
+//
+// This method is goroutine safe.
+func (nx *Network) ListenPacket(ctx context.Context, network, address string) (net.PacketConn, error) {
+	pconn, err := nx.listenConfig().ListenPacket(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return nx.maybeWrapPacketConn(withConnID(ctx), pconn), nil
+}
+
+// listenConfig returns the [*net.ListenConfig] to use.
+func (nx *Network) listenConfig() *net.ListenConfig {
+	lc := &net.ListenConfig{Control: nx.controlFunc()}
+	lc.SetMultipathTCP(nx.EnableMultipathTCP)
+	return lc
+}
+
+// listenerWrapper wraps a [net.Listener].
+type listenerWrapper struct {
+	ctx      context.Context // only used for logging
+	laddr    string
+	listener net.Listener
+	netx     *Network
+	protocol string
+}
+
+// Accept implements [net.Listener].
+func (lw *listenerWrapper) Accept() (net.Conn, error) {
+	// Make sure we have a new connection ID for correlating events
+	ctx := withNewConnID(lw.ctx)
+
+	// Emit structured event before accepting
+	t0 := lw.netx.timeNow()
+	if lw.netx.emitEnabled() {
+		lw.netx.emit(ctx, &AcceptStartEvent{
+			ConnID:    connIDFromContext(ctx),
+			LocalAddr: lw.laddr,
+			Protocol:  lw.protocol,
+			T:         t0,
+		})
+	}
+
+	// Accept the connection
+	conn, err := lw.listener.Accept()
+
+	// Emit structured event after accepting
+	lw.emitAcceptDone(ctx, t0, conn, err)
+
+	// Maybe wrap the connection
+	return lw.netx.maybeWrapConn(ctx, conn), err
+}
+
+// emitAcceptDone emits a structured event after accepting.
+func (lw *listenerWrapper) emitAcceptDone(ctx context.Context, t0 time.Time, conn net.Conn, err error) {
+	if lw.netx.emitEnabled() {
+		lw.netx.emit(ctx, &AcceptDoneEvent{
+			ConnID:     connIDFromContext(ctx),
+			Err:        errString(err),
+			ErrClass:   lw.netx.errClass(err),
+			LocalAddr:  lw.laddr,
+			Protocol:   lw.protocol,
+			RemoteAddr: connRemoteAddr(conn).String(),
+			T0:         t0,
+			T:          lw.netx.timeNow(),
+		})
+	}
+}
+
+// Addr implements [net.Listener].
+func (lw *listenerWrapper) Addr() net.Addr {
+	return lw.listener.Addr()
+}
+
+// Close implements [net.Listener].
+func (lw *listenerWrapper) Close() error {
+	return lw.listener.Close()
+}
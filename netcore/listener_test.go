@@ -0,0 +1,98 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_Listen(t *testing.T) {
+	t.Run("we emit events for the accepted connections", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger:   slog.New(slog.NewJSONHandler(&buf, nil)),
+			WrapConn: WrapConn,
+		}
+		listener, err := nx.Listen(context.Background(), "tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		client, err := net.Dial("tcp", listener.Addr().String())
+		require.NoError(t, err)
+		defer client.Close()
+		_, err = client.Write([]byte("hello"))
+		require.NoError(t, err)
+
+		conn, err := listener.Accept()
+		require.NoError(t, err)
+		_, err = io.ReadFull(conn, make([]byte, 5))
+		require.NoError(t, err)
+		conn.Close()
+
+		var names []string
+		connIDs := map[float64]bool{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			names = append(names, ev["msg"].(string))
+			connIDs[ev["connId"].(float64)] = true
+			if ev["msg"] == "acceptDone" {
+				assert.Equal(t, listener.Addr().String(), ev["localAddr"])
+				assert.Equal(t, client.LocalAddr().String(), ev["remoteAddr"])
+				assert.Nil(t, ev["err"])
+			}
+		}
+		assert.Equal(t, []string{
+			"acceptStart", "acceptDone", "readStart", "readDone",
+			"closeStart", "closeDone", "connStats",
+		}, names)
+		assert.Len(t, connIDs, 1)
+	})
+
+	t.Run("we emit acceptDone on failure", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+		listener, err := nx.Listen(context.Background(), "tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listener.Close()
+
+		conn, err := listener.Accept()
+		assert.ErrorIs(t, err, net.ErrClosed)
+		assert.Nil(t, conn)
+		assert.Contains(t, buf.String(), `"msg":"acceptDone"`)
+	})
+
+	t.Run("we do not wrap the listener without logging", func(t *testing.T) {
+		nx := &Network{}
+		listener, err := nx.Listen(context.Background(), "tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		_, ok := listener.(*net.TCPListener)
+		assert.True(t, ok)
+	})
+
+	t.Run("we fail with an invalid address", func(t *testing.T) {
+		nx := &Network{}
+		listener, err := nx.Listen(context.Background(), "tcp", "127.0.0.1")
+		assert.Error(t, err)
+		assert.Nil(t, listener)
+	})
+}
+
+func TestNetwork_ListenPacket(t *testing.T) {
+	nx := &Network{}
+	pconn, err := nx.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pconn.Close()
+	assert.Equal(t, "udp", pconn.LocalAddr().Network())
+}
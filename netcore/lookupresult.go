@@ -0,0 +1,66 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Rich DNS lookup results.
+//
+
+package netcore
+
+import "time"
+
+// LookupResult is the result of a DNS lookup.
+type LookupResult struct {
+	// Addrs contains the resolved IP addresses.
+	Addrs []string
+
+	// CNAMEs contains the CNAME chain, if known, starting from the
+	// queried domain name and ending with the canonical name.
+	CNAMEs []string
+
+	// Cached indicates that the result comes from the [*DNSCache],
+	// in which case we did not perform any DNS query.
+	Cached bool
+
+	// Queries contains information about each DNS query we performed
+	// to resolve the domain name (e.g., the A and AAAA queries), if known.
+	Queries []LookupQuery
+
+	// Resolver describes the resolver we used (e.g., "udp://8.8.8.8:53"),
+	// if known, or is empty otherwise.
+	Resolver string
+
+	// TTL is the minimum TTL of the DNS records, if known, or zero
+	// otherwise. When Cached is true, this is the remaining TTL.
+	TTL time.Duration
+}
+
+// clone returns a copy of the result without the queries, which
+// we use for storing results into and loading them from the cache.
+func (r *LookupResult) clone() *LookupResult {
+	return &LookupResult{
+		Addrs:    append([]string{}, r.Addrs...),
+		CNAMEs:   append([]string(nil), r.CNAMEs...),
+		Resolver: r.Resolver,
+		TTL:      r.TTL,
+	}
+}
+
+// LookupQuery describes a DNS query performed during a lookup.
+type LookupQuery struct {
+	// Err is the error message or empty on success.
+	Err string `json:"err"`
+
+	// QueryType is the query type (e.g., "A" or "AAAA").
+	QueryType string `json:"dnsQueryType"`
+
+	// T0 is the time when we sent the query.
+	T0 time.Time `json:"t0"`
+
+	// T is the time when we received the response or gave up.
+	T time.Time `json:"t"`
+}
+
+// RTT returns the round trip time of the query.
+func (q LookupQuery) RTT() time.Duration {
+	return q.T.Sub(q.T0)
+}
@@ -0,0 +1,69 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Measuring all the resolved endpoints.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// EndpointResult is the result of dialing a single endpoint
+// using [*Network.MeasureEndpoints].
+type EndpointResult struct {
+	// Conn is the established connection or nil on failure. The
+	// caller owns the connection and is responsible for closing it.
+	Conn net.Conn
+
+	// Endpoint is the endpoint we dialed (e.g., "8.8.8.8:443").
+	Endpoint string
+
+	// Err is the error that occurred or nil on success.
+	Err error
+}
+
+// MeasureEndpoints resolves the domain name in the given address and
+// attempts to connect to every resolved endpoint, regardless of whether
+// earlier attempts succeeded, returning a result for each endpoint in
+// the order in which they were resolved. Contrary to [*Network.DialContext],
+// which stops at the first working endpoint, this method is useful to
+// measure the reachability of all the endpoints.
+//
+// We attempt all the endpoints at the same time, which you can limit
+// using MaxConcurrentDials, and emit events for each attempt. We do not
+// use the DialPolicy or the ProxyURL. The returned error is non-nil only
+// when we cannot obtain the endpoints to dial, in which case the results
+// are nil. Otherwise, the caller must close the successful connections.
+//
+// This method is goroutine safe.
+func (nx *Network) MeasureEndpoints(ctx context.Context, network, address string) ([]EndpointResult, error) {
+	// resolve the endpoints to connect to
+	endpoints, err := nx.LookupEndpoint(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// only keep the endpoints matching the address family
+	endpoints, err = nx.filterEndpoints(network, endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// attempt all the endpoints and collect the results
+	results := make([]EndpointResult, len(endpoints))
+	var wg sync.WaitGroup
+	for idx, endpoint := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := nx.dialLog(ctx, network, endpoint)
+			results[idx] = EndpointResult{Conn: conn, Endpoint: endpoint, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
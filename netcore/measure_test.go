@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_MeasureEndpoints(t *testing.T) {
+	t.Run("lookup failure", func(t *testing.T) {
+		expectedErr := errors.New("mocked lookup error")
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expectedErr
+			},
+		}
+		results, err := nx.MeasureEndpoints(context.Background(), "tcp", "example.com:80")
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, results)
+	})
+
+	t.Run("no endpoints matching the address family", func(t *testing.T) {
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"1.2.3.4"}, nil
+			},
+		}
+		results, err := nx.MeasureEndpoints(context.Background(), "tcp6", "example.com:80")
+		assert.Error(t, err)
+		assert.Nil(t, results)
+	})
+
+	t.Run("we attempt every endpoint", func(t *testing.T) {
+		expectedErr := errors.New("mocked dial error")
+		var (
+			dialed []string
+			mu     sync.Mutex
+		)
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"1.2.3.4", "::1", "5.6.7.8"}, nil
+			},
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				mu.Lock()
+				dialed = append(dialed, address)
+				mu.Unlock()
+				if address == "[::1]:80" {
+					return nil, expectedErr
+				}
+				return &mocks.Conn{MockClose: func() error { return nil }}, nil
+			},
+		}
+		results, err := nx.MeasureEndpoints(context.Background(), "tcp", "example.com:80")
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.ElementsMatch(t, []string{"1.2.3.4:80", "[::1]:80", "5.6.7.8:80"}, dialed)
+
+		assert.Equal(t, "1.2.3.4:80", results[0].Endpoint)
+		assert.NotNil(t, results[0].Conn)
+		assert.NoError(t, results[0].Err)
+
+		assert.Equal(t, "[::1]:80", results[1].Endpoint)
+		assert.Nil(t, results[1].Conn)
+		assert.ErrorIs(t, results[1].Err, expectedErr)
+
+		assert.Equal(t, "5.6.7.8:80", results[2].Endpoint)
+		assert.NotNil(t, results[2].Conn)
+		assert.NoError(t, results[2].Err)
+
+		for _, result := range results {
+			if result.Conn != nil {
+				result.Conn.Close()
+			}
+		}
+	})
+}
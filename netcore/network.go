@@ -10,9 +10,14 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"io"
 	"log/slog"
 	"net"
+	"net/url"
+	"syscall"
 	"time"
+
+	"github.com/quic-go/quic-go"
 )
 
 // Network allows dialing and measuring TCP/UDP/TLS connections.
@@ -21,13 +26,130 @@ import (
 //
 // A [*Network] is safe for concurrent use by multiple goroutines as long as
 // you don't modify its fields after construction and the underlying fields you
-// may set (e.g., DialContextFunc) are also safe.
+// may set (e.g., DialContextFunc) are also safe. A [*Network] must not
+// be copied after first use, since it contains the counters of Stats
+// and the semaphore implementing MaxConcurrentDials.
 type Network struct {
+	// ALPNForEndpoint is the optional function returning the ALPN protocols
+	// to use for the given network and address (e.g., "h2" for "tcp" and
+	// "example.com:8443") when we create the TLS config, that is, when the
+	// TLSConfig field is nil. If this field is nil or the function returns
+	// nil, we use the default ALPN, which depends on the network and the
+	// port (e.g., "h2" and "http/1.1" for "tcp" and port 443, "h3" for
+	// "udp" and port 443, and no ALPN for unknown ports).
+	ALPNForEndpoint func(network, address string) []string
+
+	// AddressFamilyPolicy is the optional [AddressFamilyPolicy] to apply
+	// to the endpoints obtained by resolving a domain name. If this field is
+	// zero, we use the endpoints in the resolved order. The "tcp4", "tcp6",
+	// "udp4", and "udp6" networks passed to DialContext and DialTLSContext
+	// override this field and only use the corresponding address family.
+	AddressFamilyPolicy AddressFamilyPolicy
+
+	// BindToDevice is the optional name of the network interface (e.g.,
+	// "wlan0") to which we bind the sockets we create, using SO_BINDTODEVICE
+	// on Linux and IP_BOUND_IF on macOS, so that measurements use a specific
+	// interface. On other platforms, dialing fails when this field is set. The
+	// "connectDone" event contains the device in the "device" field. Binding
+	// may require privileges on Linux and has no effect with DialContextFunc.
+	BindToDevice string
+
+	// CircuitBreaker is the optional [*CircuitBreaker] skipping the dials
+	// to the endpoints that failed repeatedly. The skipped dials still emit
+	// the "connectStart" and "connectDone" events, the latter with the
+	// [ErrClassCircuitOpen] error class. If this field is nil, we always dial.
+	CircuitBreaker *CircuitBreaker
+
+	// ControlFunc is the optional function invoked on the sockets we
+	// create before connecting, after setting BindToDevice and SocketOptions,
+	// to set additional options using the raw connection. Like those fields,
+	// this field has no effect with DialContextFunc.
+	ControlFunc func(network, address string, c syscall.RawConn) error
+
 	// DialContextFunc is the optional dialer for creating new
 	// TCP and UDP connections. If this field is nil, the default
 	// dialer from the [net] package will be used.
 	DialContextFunc func(ctx context.Context, network, address string) (net.Conn, error)
 
+	// DialPolicy is the optional [DialPolicy] controlling how we attempt
+	// the endpoints obtained by resolving a domain name. If this field
+	// is nil, we use a [SequentialDialPolicy].
+	DialPolicy DialPolicy
+
+	// DNSCache is the optional [*DNSCache] used by the lookups. If this
+	// field is nil, we resolve domain names every time. Cache hits still
+	// emit the "lookupHostStart" and "lookupHostDone" events, the latter
+	// with "dnsCacheHit" set to true and no DNS queries.
+	DNSCache *DNSCache
+
+	// EnableMultipathTCP optionally enables Multipath TCP for the TCP
+	// connections we create, which we otherwise disable because we focus
+	// on precise measurements, so that it is possible to study Multipath TCP
+	// reachability. The "connectDone" event tells whether the connection
+	// actually negotiated Multipath TCP in the "multipathTCP" field. This
+	// field has no effect with DialContextFunc.
+	EnableMultipathTCP bool
+
+	// ErrClassifiers contains optional [ErrClassifier] functions assigning
+	// the "errClass" of the errors that we would otherwise classify as
+	// "EGENERIC". We call them in order and use the first nonempty class.
+	// The RetryDialPolicy also uses them to decide whether to retry.
+	ErrClassifiers []ErrClassifier
+
+	// EventHook is the optional [EventHook] receiving the structured
+	// diagnostic events along with the Logger, for example to create
+	// tracing spans. If this field is nil, we only use the Logger.
+	EventHook EventHook
+
+	// Hosts optionally maps domain names to IP addresses like /etc/hosts,
+	// which we consult before any lookup, including the DNSCache. On match,
+	// we still emit the "lookupHostStart" and "lookupHostDone" events, the
+	// latter with "dnsResolver" set to "hosts" and no DNS queries. We ignore
+	// the case and the trailing dot of the domain names, as well as the
+	// entries without addresses.
+	Hosts map[string][]string
+
+	// LogIOPayloadBytes is the optional number of bytes of each read and
+	// write operation to include, base64 encoded, in the "ioPayloadPrefix"
+	// field of the "readDone" and "writeDone" events, to allow detecting
+	// injected blockpages or malformed responses. If this field is zero or
+	// negative, we redact the payload and the field is empty. Note that
+	// the payload may contain sensitive information (e.g., cookies) unless
+	// the connection uses TLS, in which case we only see ciphertext.
+	LogIOPayloadBytes int
+
+	// LogLevelIO is the level at which the Logger emits the read
+	// and write events, which dominate the log volume of bulk transfers.
+	// The zero value is [slog.LevelInfo], which is the level of all the
+	// other events. Setting this field to [slog.LevelDebug] demotes the
+	// I/O events, which a Logger with the default level then discards.
+	// The EventHook, if any, receives the I/O events regardless.
+	LogLevelIO slog.Level
+
+	// LogKernelTimestamps optionally enables SO_TIMESTAMPNS on the sockets
+	// we create, to include the kernel receive timestamp in the "tKernel"
+	// field of the "readDone" events, in addition to the userspace time. This
+	// improves attributing the latency, for example when racing injected
+	// and legitimate responses. This field only has effect on Linux, when
+	// we emit the read events, and without DialContextFunc. Since the kernel
+	// may enable timestamping asynchronously, the first reads of the process
+	// may lack the kernel timestamp.
+	LogKernelTimestamps bool
+
+	// LogMaxIOEvents is the optional maximum number of read operations
+	// and of write operations for which a connection emits events. If this
+	// field is zero or negative, we emit events for all the operations.
+	// In any case, the "closeDone" event contains the total number of bytes
+	// read from and written to the connection.
+	LogMaxIOEvents int
+
+	// LogTCPInfo optionally enables reading the TCP_INFO metrics (i.e., RTT,
+	// RTT variance, total retransmissions, and delivery rate) of the TCP
+	// connections we create when closing them, to include them into the
+	// "connStats" event. Kernel-level retransmissions are strong evidence of
+	// throttling. This field only has effect on Linux.
+	LogTCPInfo bool
+
 	// Logger is the optional structured logger for emitting
 	// structured diagnostic events. If this field is nil, we
 	// will not be emitting structured logs.
@@ -38,6 +160,22 @@ type Network struct {
 	// default [*net.Resolver] from the [net] package.
 	LookupHostFunc func(ctx context.Context, domain string) ([]string, error)
 
+	// LookupHostResultFunc is like LookupHostFunc but returns a [*LookupResult]
+	// containing additional information (e.g., the CNAME chain), which we
+	// include into the "lookupHostDone" event. On success, the function MUST
+	// return a non-nil result. If this field is not nil, it takes precedence
+	// over the LookupHostFunc field. A context created using
+	// [WithResolverServerAddr] takes precedence over both fields.
+	LookupHostResultFunc func(ctx context.Context, domain string) (*LookupResult, error)
+
+	// MaxConcurrentDials is the optional maximum number of TCP/UDP dials
+	// in progress at the same time, to avoid exhausting file descriptors
+	// when measuring many endpoints. Further dials wait for a free slot,
+	// before the DialContextTimeout starts, and the "connectStart" event
+	// contains the time spent waiting in the "queueWaitUsec" field. If this
+	// field is zero or negative, we do not limit the concurrent dials.
+	MaxConcurrentDials int
+
 	// NewTLSClientConn is the optional function to create a new TLS client
 	// connection. If this field is nil, we use the [crypto/tls] package.
 	//
@@ -50,16 +188,50 @@ type Network struct {
 	// Deprecated: use the TLSEngine field instead.
 	NewTLSClientConn func(conn net.Conn, config *tls.Config) TLSConn
 
+	// ProxyURL is the optional URL of an HTTP proxy through which we
+	// tunnel TCP connections using the CONNECT method. The scheme must be
+	// either "http" or "https", in which case we also use TLS with the
+	// proxy. When this field is set, DialContext and DialTLSContext do not
+	// resolve the target domain name, which is resolved by the proxy, and
+	// the address family policy only applies to the proxy endpoints.
+	ProxyURL *url.URL
+
+	// QUICConfig is the optional [*quic.Config] used by DialQUICContext.
+	// If this field is nil, we use the quic-go defaults.
+	QUICConfig *quic.Config
+
 	// RootCAs contains the optional [*x509.CertPool] used when
 	// creating TLS connections. If it is not set, we use the system's
 	// root CAs. This field is only used when the TLSConfig field is nil.
 	RootCAs *x509.CertPool
 
+	// SocketOptions contains the optional [*SocketOptions] (e.g., the TTL)
+	// to set on the sockets we create before connecting. On platforms not
+	// supporting an option, dialing fails. The "connectDone" event describes
+	// the options in the "socketOptions" field. This field has no effect
+	// with DialContextFunc.
+	SocketOptions *SocketOptions
+
 	// TLSConfig is the TLS client config to use. If this field is nil, we
 	// will try to create a suitable config based on the network and address
 	// that are passed to the DialTLSContext method.
 	TLSConfig *tls.Config
 
+	// TLSKeyLogWriter is the optional [io.Writer] where we write the TLS
+	// secrets using the NSS key log format (i.e., the format used by
+	// the SSLKEYLOGFILE environment variable), so that packet captures
+	// of the measurements can be decrypted later. When this field is
+	// set, it overrides the KeyLogWriter of the TLS config we use
+	// for TLS and QUIC connections, including the TLSConfig field.
+	TLSKeyLogWriter io.Writer
+
+	// TLSLogRawHandshake optionally enables logging the raw ClientHello
+	// and the first bytes sent by the server (up to 16 KiB) as base64
+	// encoded strings in the "tlsHandshakeDone" event, even when the
+	// handshake fails, to diagnose middlebox tampering. This field
+	// only has effect when the Logger or EventHook field is not nil.
+	TLSLogRawHandshake bool
+
 	// TimeNow is an optional function that returns the current time.
 	// If this field is nil, the [time.Now] function will be used.
 	TimeNow func() time.Time
@@ -68,6 +240,11 @@ type Network struct {
 	// structured logs. [WrapConn] is the default wrapper to use.
 	WrapConn func(ctx context.Context, netx *Network, conn net.Conn) net.Conn
 
+	// WrapPacketConn is an optional function to wrap the packet conns
+	// created by ListenPacket to emit structured logs for each datagram.
+	// [WrapPacketConn] is the default wrapper to use.
+	WrapPacketConn func(ctx context.Context, netx *Network, pconn net.PacketConn) net.PacketConn
+
 	// LookupHostTimeout is the optional timeout to use for limiting
 	// the maximum time spent resolving a domain name.
 	LookupHostTimeout time.Duration
@@ -76,11 +253,20 @@ type Network struct {
 	// the maximum time spent creating a single connection.
 	DialContextTimeout time.Duration
 
+	// TLSHandshakeTimeout is the optional timeout to use for limiting
+	// the maximum time spent in a single TLS or QUIC handshake, which
+	// is separate from DialContextTimeout, so that we bound handshakes
+	// hanging after connecting (e.g., when a middlebox blackholes the
+	// flow after the SYN-ACK) and classify them as ETIMEDOUT.
+	TLSHandshakeTimeout time.Duration
+
 	// NewResolverOrSingleton is the optional function that returns
 	// the [*net.Resolver] to use when LookupHostFunc is not set. As the
 	// name suggests, this function may either create a new [*net.Resolver]
 	// for each call or just return a singleton instance. When this method
 	// is not set, we use an internal zero-initialized, static [*net.Resolver].
+	// In both cases, we perform the A and AAAA queries as distinct operations
+	// emitting "dnsQueryStart" and "dnsQueryDone" events.
 	NewResolverOrSingleton func() *net.Resolver
 
 	// NewDialerOrSingleton is the optional function that returns
@@ -89,13 +275,21 @@ type Network struct {
 	// for each call or just return a singleton instance. When this method
 	// is not set, we use an internal, static [*net.Dialer] where
 	// support for Multipath TCP has been disabled. We disable Multipath
-	// TCP because we focus on precise internet measurements.
+	// TCP because we focus on precise internet measurements. In both cases,
+	// EnableMultipathTCP enables Multipath TCP on a copy of the dialer.
 	NewDialerOrSingleton func() *net.Dialer
 
 	// TLSEngine is the optional [TLSEngine] to use for creating a new
 	// instance of [TLSConn]. If this field is nil, we create on the fly
-	// and use an instance of [TLSEngineStdlib].
+	// and use an instance of [TLSEngineStdlib]. The tlsengineutls
+	// package provides an alternative engine parroting browsers.
 	TLSEngine TLSEngine
+
+	// dialSlots limits the concurrent dials (see MaxConcurrentDials).
+	dialSlots dialSlots
+
+	// stats contains the counters returned by the Stats method.
+	stats networkStats
 }
 
 // DefaultNetwork is the default [*Network] used by this package.
@@ -0,0 +1,114 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Functional options for constructing a Network.
+//
+
+package netcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/rbmk-project/dnscore"
+)
+
+// Option configures a [*Network] constructed using [New] or [*Network.Clone].
+//
+// An Option is just a function modifying the [*Network], so it is possible
+// to write custom options setting the fields not covered by this package.
+type Option func(nx *Network)
+
+// New creates a new [*Network] and applies the given options. Without
+// options, the returned [*Network] is equivalent to the zero value.
+func New(options ...Option) *Network {
+	nx := &Network{}
+	for _, option := range options {
+		option(nx)
+	}
+	return nx
+}
+
+// WithDialPolicy returns an [Option] setting the DialPolicy field.
+func WithDialPolicy(policy DialPolicy) Option {
+	return func(nx *Network) {
+		nx.DialPolicy = policy
+	}
+}
+
+// WithDNSCache returns an [Option] setting the DNSCache field.
+func WithDNSCache(cache *DNSCache) Option {
+	return func(nx *Network) {
+		nx.DNSCache = cache
+	}
+}
+
+// WithDNSCoreResolver returns an [Option] setting the LookupHostResultFunc
+// field to resolve domain names using the given DNS server (see
+// [*Network.NewDNSCoreLookupHostResultFunc]).
+func WithDNSCoreResolver(addr *dnscore.ServerAddr) Option {
+	return func(nx *Network) {
+		nx.LookupHostResultFunc = nx.NewDNSCoreLookupHostResultFunc(addr)
+	}
+}
+
+// WithErrClassifiers returns an [Option] appending the given
+// [ErrClassifier] functions to the ErrClassifiers field.
+func WithErrClassifiers(classifiers ...ErrClassifier) Option {
+	return func(nx *Network) {
+		nx.ErrClassifiers = append(nx.ErrClassifiers, classifiers...)
+	}
+}
+
+// WithEventHook returns an [Option] setting the EventHook field.
+func WithEventHook(hook EventHook) Option {
+	return func(nx *Network) {
+		nx.EventHook = hook
+	}
+}
+
+// WithLogger returns an [Option] setting the Logger field.
+func WithLogger(logger *slog.Logger) Option {
+	return func(nx *Network) {
+		nx.Logger = logger
+	}
+}
+
+// WithProxyURL returns an [Option] setting the ProxyURL field.
+func WithProxyURL(proxyURL *url.URL) Option {
+	return func(nx *Network) {
+		nx.ProxyURL = proxyURL
+	}
+}
+
+// WithRootCAs returns an [Option] setting the RootCAs field.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(nx *Network) {
+		nx.RootCAs = pool
+	}
+}
+
+// WithStackProvider returns an [Option] binding the [*Network] to
+// the given [StackProvider] (see [*Network.BindTo]).
+func WithStackProvider(provider StackProvider) Option {
+	return func(nx *Network) {
+		nx.BindTo(provider)
+	}
+}
+
+// WithTimeNow returns an [Option] setting the TimeNow field.
+func WithTimeNow(timeNow func() time.Time) Option {
+	return func(nx *Network) {
+		nx.TimeNow = timeNow
+	}
+}
+
+// WithTLSConfig returns an [Option] setting the TLSConfig field.
+func WithTLSConfig(config *tls.Config) Option {
+	return func(nx *Network) {
+		nx.TLSConfig = config
+	}
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log/slog"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/dnscore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("without options", func(t *testing.T) {
+		assert.Equal(t, &Network{}, New())
+	})
+
+	t.Run("with options", func(t *testing.T) {
+		var (
+			cache    = &DNSCache{}
+			config   = &tls.Config{}
+			hook     = &testEventHook{}
+			logger   = slog.Default()
+			policy   = ParallelDialPolicy{}
+			pool     = x509.NewCertPool()
+			proxyURL = &url.URL{Scheme: "http", Host: "127.0.0.1:8080"}
+			t0       = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		)
+		nx := New(
+			WithDialPolicy(policy),
+			WithDNSCache(cache),
+			WithDNSCoreResolver(dnscore.NewServerAddr(dnscore.ProtocolUDP, "8.8.8.8:53")),
+			WithEventHook(hook),
+			WithLogger(logger),
+			WithProxyURL(proxyURL),
+			WithRootCAs(pool),
+			WithTimeNow(func() time.Time { return t0 }),
+			WithTLSConfig(config),
+			func(nx *Network) { nx.LogTCPInfo = true },
+		)
+		assert.Equal(t, policy, nx.DialPolicy)
+		assert.Same(t, cache, nx.DNSCache)
+		assert.NotNil(t, nx.LookupHostResultFunc)
+		assert.Same(t, hook, nx.EventHook)
+		assert.Same(t, logger, nx.Logger)
+		assert.Same(t, proxyURL, nx.ProxyURL)
+		assert.Same(t, pool, nx.RootCAs)
+		assert.Equal(t, t0, nx.timeNow())
+		assert.Same(t, config, nx.TLSConfig)
+		assert.True(t, nx.LogTCPInfo)
+	})
+
+	t.Run("with a stack provider", func(t *testing.T) {
+		provider := &testStackProvider{}
+		nx := New(WithStackProvider(provider))
+		assert.NotNil(t, nx.DialContextFunc)
+		assert.NotNil(t, nx.LookupHostFunc)
+	})
+}
@@ -0,0 +1,212 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// PacketConn wrapper.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maybeWrapPacketConn wraps a packet conn using WrapPacketConn when we are
+// emitting events and always wraps it to count the bytes in the [NetworkStats].
+func (nx *Network) maybeWrapPacketConn(ctx context.Context, pconn net.PacketConn) net.PacketConn {
+	if pconn == nil {
+		return nil
+	}
+	if nx.emitEnabled() && nx.WrapPacketConn != nil {
+		pconn = nx.WrapPacketConn(ctx, nx, pconn)
+	}
+	return &statsPacketConn{PacketConn: pconn, stats: &nx.stats}
+}
+
+// WrapPacketConn wraps a given [net.PacketConn] to emit structured logs
+// including the peer address of each datagram, like [WrapConn] does
+// for a [net.Conn]. The read and write events are named "readFromStart",
+// "readFromDone", "writeToStart", and "writeToDone", and we also emit the
+// "closeStart", "closeDone", and "connStats" events when closing.
+//
+// The context argument is only used for logging and does not constrain
+// in any way the lifetime of the wrapped packet conn. When the [*Network]
+// calls this function, the context contains the connection ID that we
+// include as the "connId" field of the events.
+func WrapPacketConn(ctx context.Context, netx *Network, pconn net.PacketConn) net.PacketConn {
+	laddr := pconn.LocalAddr()
+	if laddr == nil {
+		laddr = emptyAddr{}
+	}
+	return &packetConnWrapper{
+		ctx:      ctx,
+		connID:   connIDFromContext(ctx),
+		laddr:    laddr.String(),
+		netx:     netx,
+		pconn:    pconn,
+		protocol: laddr.Network(),
+		t0:       netx.timeNow(),
+	}
+}
+
+// packetConnWrapper wraps a [net.PacketConn].
+type packetConnWrapper struct {
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	ctx          context.Context // only used for logging
+	closeonce    sync.Once
+	connID       int64
+	laddr        string
+	netx         *Network // may contain nil logger!
+	pconn        net.PacketConn
+	protocol     string
+	readOps      atomic.Int64
+	t0           time.Time
+	writeOps     atomic.Int64
+}
+
+// addrString is a safe way to get the string representation of an address.
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+// Close implements [net.PacketConn].
+func (c *packetConnWrapper) Close() (err error) {
+	c.closeonce.Do(func() {
+		t0 := c.netx.timeNow()
+		if c.netx.emitEnabled() {
+			c.netx.emit(c.ctx, &CloseStartEvent{
+				ConnID:    c.connID,
+				LocalAddr: c.laddr,
+				Protocol:  c.protocol,
+				T:         t0,
+			})
+		}
+
+		err = c.pconn.Close()
+
+		if c.netx.emitEnabled() {
+			c.netx.emit(c.ctx, &CloseDoneEvent{
+				ConnID:              c.connID,
+				Err:                 errString(err),
+				ErrClass:            c.netx.errClass(err),
+				IOBytesReadTotal:    c.bytesRead.Load(),
+				IOBytesWrittenTotal: c.bytesWritten.Load(),
+				LocalAddr:           c.laddr,
+				Protocol:            c.protocol,
+				T0:                  t0,
+				T:                   c.netx.timeNow(),
+			})
+			c.netx.emit(c.ctx, &ConnStatsEvent{
+				ConnID:              c.connID,
+				IOBytesReadTotal:    c.bytesRead.Load(),
+				IOBytesWrittenTotal: c.bytesWritten.Load(),
+				IOReadCount:         c.readOps.Load(),
+				IOWriteCount:        c.writeOps.Load(),
+				LocalAddr:           c.laddr,
+				Protocol:            c.protocol,
+				T0:                  c.t0,
+				T:                   c.netx.timeNow(),
+			})
+		}
+	})
+	return
+}
+
+// LocalAddr implements [net.PacketConn].
+func (c *packetConnWrapper) LocalAddr() net.Addr {
+	return c.pconn.LocalAddr()
+}
+
+// ReadFrom implements [net.PacketConn].
+func (c *packetConnWrapper) ReadFrom(buf []byte) (int, net.Addr, error) {
+	t0 := c.netx.timeNow()
+	emitting := c.netx.ioEventsEnabled(c.ctx, &c.readOps)
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &ReadFromStartEvent{
+			ConnID:       c.connID,
+			IOBufferSize: len(buf),
+			LocalAddr:    c.laddr,
+			Protocol:     c.protocol,
+			T:            t0,
+		})
+	}
+
+	count, addr, err := c.pconn.ReadFrom(buf)
+	c.bytesRead.Add(int64(count))
+
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &ReadFromDoneEvent{
+			ConnID:          c.connID,
+			IOBytesCount:    count,
+			IOPayloadPrefix: c.netx.payloadPrefix(buf, count),
+			Err:             errString(err),
+			ErrClass:        c.netx.errClass(err),
+			LocalAddr:       c.laddr,
+			Protocol:        c.protocol,
+			RemoteAddr:      addrString(addr),
+			T0:              t0,
+			T:               c.netx.timeNow(),
+		})
+	}
+
+	return count, addr, err
+}
+
+// SetDeadline implements [net.PacketConn].
+func (c *packetConnWrapper) SetDeadline(t time.Time) error {
+	return c.pconn.SetDeadline(t)
+}
+
+// SetReadDeadline implements [net.PacketConn].
+func (c *packetConnWrapper) SetReadDeadline(t time.Time) error {
+	return c.pconn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements [net.PacketConn].
+func (c *packetConnWrapper) SetWriteDeadline(t time.Time) error {
+	return c.pconn.SetWriteDeadline(t)
+}
+
+// WriteTo implements [net.PacketConn].
+func (c *packetConnWrapper) WriteTo(data []byte, addr net.Addr) (int, error) {
+	t0 := c.netx.timeNow()
+	raddr := addrString(addr)
+	emitting := c.netx.ioEventsEnabled(c.ctx, &c.writeOps)
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &WriteToStartEvent{
+			ConnID:       c.connID,
+			IOBufferSize: len(data),
+			LocalAddr:    c.laddr,
+			Protocol:     c.protocol,
+			RemoteAddr:   raddr,
+			T:            t0,
+		})
+	}
+
+	count, err := c.pconn.WriteTo(data, addr)
+	c.bytesWritten.Add(int64(count))
+
+	if emitting {
+		c.netx.emitAtLevel(c.ctx, c.netx.LogLevelIO, &WriteToDoneEvent{
+			ConnID:          c.connID,
+			IOBytesCount:    count,
+			IOPayloadPrefix: c.netx.payloadPrefix(data, count),
+			Err:             errString(err),
+			ErrClass:        c.netx.errClass(err),
+			LocalAddr:       c.laddr,
+			Protocol:        c.protocol,
+			RemoteAddr:      raddr,
+			T0:              t0,
+			T:               c.netx.timeNow(),
+		})
+	}
+
+	return count, err
+}
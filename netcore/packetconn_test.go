@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapPacketConn(t *testing.T) {
+	t.Run("we log each datagram with the peer address", func(t *testing.T) {
+		peer, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer peer.Close()
+
+		var buf bytes.Buffer
+		nx := &Network{
+			LogIOPayloadBytes: 2,
+			Logger:            slog.New(slog.NewJSONHandler(&buf, nil)),
+			WrapPacketConn:    WrapPacketConn,
+		}
+		pconn, err := nx.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		sconn, ok := pconn.(*statsPacketConn)
+		require.True(t, ok)
+		_, ok = sconn.PacketConn.(*packetConnWrapper)
+		require.True(t, ok)
+
+		_, err = pconn.WriteTo([]byte("hello"), peer.LocalAddr())
+		require.NoError(t, err)
+		data := make([]byte, 16)
+		count, addr, err := peer.ReadFrom(data)
+		require.NoError(t, err)
+		_, err = peer.WriteTo(data[:count], addr)
+		require.NoError(t, err)
+		_, addr, err = pconn.ReadFrom(data)
+		require.NoError(t, err)
+		assert.Equal(t, peer.LocalAddr().String(), addr.String())
+		require.NoError(t, pconn.Close())
+
+		var names []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			names = append(names, ev["msg"].(string))
+			switch ev["msg"] {
+			case "writeToDone", "readFromDone":
+				assert.Equal(t, peer.LocalAddr().String(), ev["remoteAddr"])
+				assert.Equal(t, pconn.LocalAddr().String(), ev["localAddr"])
+				assert.Equal(t, float64(5), ev["ioBytesCount"])
+				assert.Equal(t, "aGU=", ev["ioPayloadPrefix"])
+			case "connStats":
+				assert.Equal(t, float64(5), ev["ioBytesReadTotal"])
+				assert.Equal(t, float64(5), ev["ioBytesWrittenTotal"])
+			}
+		}
+		assert.Equal(t, []string{
+			"writeToStart", "writeToDone", "readFromStart", "readFromDone",
+			"closeStart", "closeDone", "connStats",
+		}, names)
+		assert.Equal(t, int64(5), nx.Stats().BytesRead)
+	})
+
+	t.Run("we log the errors", func(t *testing.T) {
+		var buf bytes.Buffer
+		fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		expectedErr := errors.New("mocked error")
+		nx := &Network{
+			Logger:  slog.New(slog.NewJSONHandler(&buf, nil)),
+			TimeNow: func() time.Time { return fixedTime },
+		}
+		pconn := WrapPacketConn(context.Background(), nx, &mocks.PacketConn{
+			MockLocalAddr: func() net.Addr {
+				return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234}
+			},
+			MockReadFrom: func(p []byte) (int, net.Addr, error) {
+				return 0, nil, expectedErr
+			},
+		})
+		_, addr, err := pconn.ReadFrom(make([]byte, 4))
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, addr)
+
+		logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, logs, 2)
+		var ev ReadFromDoneEvent
+		require.NoError(t, json.Unmarshal([]byte(logs[1]), &ev))
+		assert.Equal(t, ReadFromDoneEvent{
+			Err:        "mocked error",
+			ErrClass:   "EGENERIC",
+			LocalAddr:  "127.0.0.1:1234",
+			Protocol:   "udp",
+			RemoteAddr: "",
+			T0:         fixedTime,
+			T:          fixedTime,
+		}, ev)
+	})
+
+	t.Run("we only count the bytes without WrapPacketConn", func(t *testing.T) {
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(&bytes.Buffer{}, nil))}
+		pconn, err := nx.ListenPacket(context.Background(), "udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer pconn.Close()
+		sconn, ok := pconn.(*statsPacketConn)
+		require.True(t, ok)
+		_, ok = sconn.PacketConn.(*net.UDPConn)
+		assert.True(t, ok)
+	})
+}
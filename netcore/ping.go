@@ -0,0 +1,263 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// ICMP ping measurements.
+//
+
+package netcore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/netip"
+	"os"
+	"slices"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// DefaultPingInterval is the interval between the echo requests sent by
+// [*Network.Ping], which is also the time we wait for each reply.
+const DefaultPingInterval = time.Second
+
+// PingResult is the result of [*Network.Ping].
+type PingResult struct {
+	// Addr is the IP address we pinged.
+	Addr string
+
+	// Privileged indicates whether we used a raw ICMP socket, which
+	// requires privileges, rather than an unprivileged ICMP socket.
+	Privileged bool
+
+	// RTTs contains the round trip times of the received replies.
+	RTTs []time.Duration
+
+	// Received is the number of echo replies we received.
+	Received int
+
+	// Sent is the number of echo requests we sent.
+	Sent int
+}
+
+// Loss returns the fraction of echo requests without a reply.
+func (r *PingResult) Loss() float64 {
+	if r.Sent <= 0 {
+		return 0
+	}
+	return float64(r.Sent-r.Received) / float64(r.Sent)
+}
+
+// Ping sends count ICMP echo requests to the given host, which is a
+// domain name or an IP address, one every [DefaultPingInterval], and
+// returns the RTTs and the number of replies. We emit a "pingRequest"
+// event for each request, a "pingReply" event when we receive the reply
+// or time out, and a "pingDone" event summarizing the packet loss and
+// the RTTs. A count lower than one is equivalent to one.
+//
+// We use the first address of the host matching the AddressFamilyPolicy
+// and a raw ICMP socket, when we have the required privileges, falling
+// back to an unprivileged ICMP datagram socket otherwise (see the
+// net.ipv4.ping_group_range sysctl on Linux). We ignore DialContextFunc,
+// ProxyURL, and SocketOptions. When the context is done, we stop and
+// return the partial result along with the context error.
+//
+// This method is goroutine safe.
+func (nx *Network) Ping(ctx context.Context, host string, count int) (*PingResult, error) {
+	// resolve the host and pick the first usable address
+	endpoint, err := nx.lookupFirstAddr(ctx, host, "0")
+	if err != nil {
+		return nil, err
+	}
+	addr := endpoint.Addr()
+
+	// create the ICMP socket
+	pconn, privileged, err := pingListen(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer pconn.Close()
+
+	// make sure the context interrupts waiting for the replies
+	stop := context.AfterFunc(ctx, func() {
+		pconn.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	// send the echo requests and wait for the replies
+	result := &PingResult{Addr: addr.String(), Privileged: privileged}
+	var id [2]byte
+	rand.Read(id[:])
+	start, t0 := nx.timeNow(), time.Time{}
+	for seq := 1; seq <= max(count, 1); seq++ {
+		if err = ctx.Err(); err == nil && seq > 1 {
+			err = sleepContext(ctx, DefaultPingInterval-nx.timeNow().Sub(t0))
+		}
+		if err != nil {
+			break
+		}
+		t0 = nx.emitPingRequest(ctx, addr, seq)
+		var rtt time.Duration
+		rtt, err = pingOnce(pconn, privileged, addr, binary.BigEndian.Uint16(id[:]), seq)
+		if err != nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		result.Sent++
+		if err == nil {
+			result.Received++
+			result.RTTs = append(result.RTTs, rtt)
+		}
+		nx.emitPingReply(ctx, addr, seq, t0, rtt, err)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			err = nil // a lost reply is a measurement result
+		}
+		if err != nil {
+			break
+		}
+	}
+	nx.emitPingDone(ctx, start, result, err)
+	return result, err
+}
+
+// pingListen creates a raw ICMP socket for the family of the given
+// address or, when this fails, an unprivileged ICMP datagram socket.
+func pingListen(addr netip.Addr) (*icmp.PacketConn, bool, error) {
+	rawNetwork, dgramNetwork, laddr := "ip4:icmp", "udp4", "0.0.0.0"
+	if addr.Is6() {
+		rawNetwork, dgramNetwork, laddr = "ip6:ipv6-icmp", "udp6", "::"
+	}
+	if pconn, err := icmp.ListenPacket(rawNetwork, laddr); err == nil {
+		return pconn, true, nil
+	}
+	pconn, err := icmp.ListenPacket(dgramNetwork, laddr)
+	if err != nil {
+		return nil, false, err
+	}
+	return pconn, false, nil
+}
+
+// pingOnce sends an echo request and waits for the corresponding reply for
+// [DefaultPingInterval], returning the RTT on success. With unprivileged
+// sockets, the kernel replaces the identifier and filters the replies.
+func pingOnce(pconn *icmp.PacketConn,
+	privileged bool, addr netip.Addr, id uint16, seq int) (time.Duration, error) {
+	var requestType, replyType icmp.Type = ipv4.ICMPTypeEcho, ipv4.ICMPTypeEchoReply
+	if addr.Is6() {
+		requestType, replyType = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply
+	}
+	request := icmp.Message{
+		Type: requestType,
+		Body: &icmp.Echo{ID: int(id), Seq: seq, Data: make([]byte, 32)},
+	}
+	data, err := request.Marshal(nil) // the kernel computes the ICMPv6 checksum
+	if err != nil {
+		return 0, err
+	}
+
+	var dst net.Addr = &net.IPAddr{IP: addr.AsSlice()}
+	if !privileged {
+		dst = &net.UDPAddr{IP: addr.AsSlice()}
+	}
+	t0 := time.Now()
+	if _, err := pconn.WriteTo(data, dst); err != nil {
+		return 0, err
+	}
+
+	if err := pconn.SetReadDeadline(t0.Add(DefaultPingInterval)); err != nil {
+		return 0, err
+	}
+	buffer := make([]byte, 1500)
+	for {
+		count, peer, err := pconn.ReadFrom(buffer)
+		if err != nil {
+			return 0, err
+		}
+		rtt := time.Since(t0)
+		reply, err := icmp.ParseMessage(replyType.Protocol(), buffer[:count])
+		if err != nil || reply.Type != replyType {
+			continue
+		}
+		echo, ok := reply.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq || (privileged && (echo.ID != int(id) || !pingPeerIs(peer, addr))) {
+			continue // not the reply to this request
+		}
+		return rtt, nil
+	}
+}
+
+// pingPeerIs returns whether the given peer address has the given IP address.
+func pingPeerIs(peer net.Addr, addr netip.Addr) bool {
+	var ip net.IP
+	switch peer := peer.(type) {
+	case *net.IPAddr:
+		ip = peer.IP
+	case *net.UDPAddr:
+		ip = peer.IP
+	}
+	other, ok := netip.AddrFromSlice(ip)
+	return ok && other.Unmap() == addr
+}
+
+// emitPingRequest emits a ping request event.
+func (nx *Network) emitPingRequest(ctx context.Context, addr netip.Addr, seq int) time.Time {
+	t0 := nx.timeNow()
+	if nx.emitEnabled() {
+		nx.emit(ctx, &PingRequestEvent{
+			PingSeq:    seq,
+			Protocol:   "icmp",
+			RemoteAddr: addr.String(),
+			T:          t0,
+		})
+	}
+	return t0
+}
+
+// emitPingReply emits a ping reply event.
+func (nx *Network) emitPingReply(ctx context.Context,
+	addr netip.Addr, seq int, t0 time.Time, rtt time.Duration, err error) {
+	if nx.emitEnabled() {
+		nx.emit(ctx, &PingReplyEvent{
+			Err:         errString(err),
+			ErrClass:    nx.errClass(err),
+			PingRTTUsec: rtt.Microseconds(),
+			PingSeq:     seq,
+			Protocol:    "icmp",
+			RemoteAddr:  addr.String(),
+			T0:          t0,
+			T:           nx.timeNow(),
+		})
+	}
+}
+
+// emitPingDone emits a ping done event summarizing the result.
+func (nx *Network) emitPingDone(ctx context.Context, t0 time.Time, result *PingResult, err error) {
+	if nx.emitEnabled() {
+		var avg, lowest, highest time.Duration
+		if len(result.RTTs) > 0 {
+			for _, rtt := range result.RTTs {
+				avg += rtt
+			}
+			avg /= time.Duration(len(result.RTTs))
+			lowest, highest = slices.Min(result.RTTs), slices.Max(result.RTTs)
+		}
+		nx.emit(ctx, &PingDoneEvent{
+			Err:            errString(err),
+			ErrClass:       nx.errClass(err),
+			PingPrivileged: result.Privileged,
+			PingReceived:   result.Received,
+			PingRTTAvgUsec: avg.Microseconds(),
+			PingRTTMaxUsec: highest.Microseconds(),
+			PingRTTMinUsec: lowest.Microseconds(),
+			PingSent:       result.Sent,
+			Protocol:       "icmp",
+			RemoteAddr:     result.Addr,
+			T0:             t0,
+			T:              nx.timeNow(),
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPingResult_Loss(t *testing.T) {
+	assert.Equal(t, float64(0), (&PingResult{}).Loss())
+	assert.Equal(t, 0.25, (&PingResult{Received: 3, Sent: 4}).Loss())
+}
+
+func TestNetwork_Ping(t *testing.T) {
+	t.Run("lookup failure", func(t *testing.T) {
+		expectedErr := errors.New("mocked lookup error")
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expectedErr
+			},
+		}
+		result, err := nx.Ping(context.Background(), "example.com", 1)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, result)
+	})
+
+	t.Run("lookup without addresses", func(t *testing.T) {
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{}, nil
+			},
+		}
+		result, err := nx.Ping(context.Background(), "example.com", 1)
+		assert.ErrorIs(t, err, errNoAddresses)
+		assert.Nil(t, result)
+	})
+
+	// ping creates a network logging to the given buffer and pings the
+	// localhost, skipping the test when we cannot create ICMP sockets
+	ping := func(t *testing.T, ctx context.Context, buf *bytes.Buffer, count int) (*PingResult, error) {
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(buf, nil))}
+		result, err := nx.Ping(ctx, "127.0.0.1", count)
+		if result == nil && err != nil {
+			t.Skipf("cannot create ICMP sockets on this system: %s", err)
+		}
+		return result, err
+	}
+
+	t.Run("successful ping", func(t *testing.T) {
+		var buf bytes.Buffer
+		result, err := ping(t, context.Background(), &buf, 2)
+		require.NoError(t, err)
+		assert.Equal(t, "127.0.0.1", result.Addr)
+		assert.Equal(t, 2, result.Sent)
+		assert.Equal(t, 2, result.Received)
+		assert.Len(t, result.RTTs, 2)
+		assert.Equal(t, float64(0), result.Loss())
+
+		var names []string
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			names = append(names, ev["msg"].(string))
+			switch ev["msg"] {
+			case "pingReply":
+				assert.Nil(t, ev["err"])
+				assert.Equal(t, "127.0.0.1", ev["remoteAddr"])
+			case "pingDone":
+				assert.Equal(t, float64(2), ev["pingSent"])
+				assert.Equal(t, float64(2), ev["pingReceived"])
+				assert.Equal(t, result.Privileged, ev["pingPrivileged"])
+			}
+		}
+		expect := []string{"pingRequest", "pingReply", "pingRequest", "pingReply", "pingDone"}
+		assert.Equal(t, expect, names[len(names)-len(expect):])
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		var buf bytes.Buffer
+		result, err := ping(t, ctx, &buf, 10)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 1, result.Sent)
+	})
+}
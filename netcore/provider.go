@@ -0,0 +1,40 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Binding a Network to a custom network stack.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+)
+
+// StackProvider is the minimal interface of a network stack to which we
+// can bind a [*Network] using [*Network.BindTo] (e.g., a simulated stack,
+// a proxy, or a userspace network stack).
+type StackProvider interface {
+	// DialContext dials the given endpoint, which contains an IP
+	// address when the [*Network] resolved the domain name.
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+
+	// LookupHost resolves the given domain name to IP addresses.
+	LookupHost(ctx context.Context, domain string) ([]string, error)
+}
+
+var _ StackProvider = &Network{}
+
+// BindTo configures the [*Network] to dial and resolve domain names
+// using the given [StackProvider], by setting the DialContextFunc and
+// LookupHostFunc fields and clearing the LookupHostResultFunc field,
+// which would otherwise take precedence over LookupHostFunc. We
+// still emit the structured logs for the dials and the lookups.
+//
+// Call this method before using the [*Network], since
+// it modifies the fields of the [*Network].
+func (nx *Network) BindTo(provider StackProvider) {
+	nx.DialContextFunc = provider.DialContext
+	nx.LookupHostFunc = provider.LookupHost
+	nx.LookupHostResultFunc = nil
+}
@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testStackProvider is a [StackProvider] for testing.
+type testStackProvider struct {
+	dialed   []string
+	resolved []string
+}
+
+var _ StackProvider = &testStackProvider{}
+
+func (p *testStackProvider) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	p.dialed = append(p.dialed, address)
+	return &mocks.Conn{MockClose: func() error { return nil }}, nil
+}
+
+func (p *testStackProvider) LookupHost(ctx context.Context, domain string) ([]string, error) {
+	p.resolved = append(p.resolved, domain)
+	return []string{"10.0.0.1"}, nil
+}
+
+func TestNetwork_BindTo(t *testing.T) {
+	provider := &testStackProvider{}
+	nx := &Network{
+		LookupHostResultFunc: func(ctx context.Context, domain string) (*LookupResult, error) {
+			panic("should not be called")
+		},
+	}
+	nx.BindTo(provider)
+	assert.Nil(t, nx.LookupHostResultFunc)
+
+	conn, err := nx.DialContext(context.Background(), "tcp", "example.com:80")
+	require.NoError(t, err)
+	conn.Close()
+
+	assert.Equal(t, []string{"example.com"}, provider.resolved)
+	assert.Equal(t, []string{"10.0.0.1:80"}, provider.dialed)
+}
@@ -0,0 +1,200 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// HTTP CONNECT proxy support.
+//
+
+package netcore
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// errUnsupportedProxyScheme indicates that the proxy URL scheme is
+// neither "http" nor "https".
+var errUnsupportedProxyScheme = errors.New("netcore: unsupported proxy URL scheme")
+
+// errProxyConnect indicates that the proxy refused the CONNECT request.
+var errProxyConnect = errors.New("netcore: proxy CONNECT failed")
+
+// usesProxy returns whether we should tunnel a connection using the
+// given network through the configured proxy.
+func (nx *Network) usesProxy(network string) bool {
+	return nx.ProxyURL != nil && strings.HasPrefix(network, "tcp")
+}
+
+// proxyEndpoint returns the TCP endpoint of the given proxy URL.
+func proxyEndpoint(URL *url.URL) (string, error) {
+	port := URL.Port()
+	switch URL.Scheme {
+	case "http":
+		if port == "" {
+			port = "80"
+		}
+	case "https":
+		if port == "" {
+			port = "443"
+		}
+	default:
+		return "", errUnsupportedProxyScheme
+	}
+	return net.JoinHostPort(URL.Hostname(), port), nil
+}
+
+// dialProxy connects to the configured proxy, establishes a TLS
+// session with it when its URL scheme is "https", and then uses
+// CONNECT to tunnel a connection to the given address.
+//
+// The optional next function continues setting up the tunnel (e.g., by
+// performing a TLS handshake with the given address) and, like the rest
+// of each attempt, uses the same connection ID of the connection to the
+// proxy. On failure, next must close the connection.
+func (nx *Network) dialProxy(ctx context.Context, network, address string,
+	next func(ctx context.Context, conn net.Conn) (net.Conn, error)) (net.Conn, error) {
+	// resolve the endpoints of the proxy
+	endpoint, err := proxyEndpoint(nx.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := nx.LookupEndpoint(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// only keep the endpoints matching the address family
+	endpoints, err = nx.filterEndpoints(network, endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// use TLS with the proxy when the URL scheme is "https"
+	dial := nx.dialLog
+	if nx.ProxyURL.Scheme == "https" {
+		config, err := newTLSConfig("tcp", endpoint, nx.RootCAs)
+		if err != nil {
+			return nil, err
+		}
+		config.NextProtos = []string{"http/1.1"}
+		nx.maybeSetKeyLogWriter(config)
+		dial = (&tlsDialer{config: config, netx: nx}).dial
+	}
+
+	// connect to the proxy and establish the tunnel according to the dial policy
+	fx := func(ctx context.Context, network, endpoint string) (net.Conn, error) {
+		ctx = withConnID(ctx)
+		conn, err := dial(ctx, network, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		conn, err = nx.proxyConnect(ctx, conn, address)
+		if err != nil || next == nil {
+			return conn, err
+		}
+		return next(ctx, conn)
+	}
+	return nx.dialEndpoints(ctx, network, fx, endpoints...)
+}
+
+// proxyConnect sends the CONNECT request for the given address using
+// the given connection to the proxy and returns the tunnel. On failure,
+// this method closes the connection to the proxy.
+func (nx *Network) proxyConnect(ctx context.Context, conn net.Conn, address string) (net.Conn, error) {
+	// make sure the context interrupts talking with the proxy
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+
+	// create the CONNECT request
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+	if user := nx.ProxyURL.User; user != nil {
+		password, _ := user.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+
+	// emit event before the CONNECT
+	laddr := connLocalAddr(conn)
+	raddr := connRemoteAddr(conn)
+	t0 := nx.emitHTTPConnectStart(ctx, laddr, raddr, address)
+
+	// send the request and read the response; it is okay to discard the
+	// buffered reader because the server will not speak until spoken to
+	var statusCode int
+	err := req.Write(conn)
+	if err == nil {
+		var resp *http.Response
+		resp, err = http.ReadResponse(bufio.NewReader(conn), req)
+		if err == nil {
+			resp.Body.Close()
+			statusCode = resp.StatusCode
+			if statusCode != http.StatusOK {
+				err = fmt.Errorf("%w: %s", errProxyConnect, resp.Status)
+			}
+		}
+	}
+	if !stop() && err == nil {
+		err = ctx.Err()
+	}
+
+	// emit event after the CONNECT
+	nx.emitHTTPConnectDone(ctx, laddr, raddr, address, t0, statusCode, err)
+
+	// process the results
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// emitHTTPConnectStart emits an HTTP CONNECT start event.
+func (nx *Network) emitHTTPConnectStart(ctx context.Context,
+	laddr, raddr net.Addr, target string) time.Time {
+	t0 := nx.timeNow()
+	if nx.emitEnabled() {
+		nx.emit(ctx, &HTTPConnectStartEvent{
+			ConnID:            connIDFromContext(ctx),
+			HTTPConnectTarget: target,
+			HTTPProxyURL:      nx.ProxyURL.Redacted(),
+			LocalAddr:         laddr.String(),
+			Protocol:          laddr.Network(),
+			RemoteAddr:        raddr.String(),
+			T:                 t0,
+		})
+	}
+	return t0
+}
+
+// emitHTTPConnectDone emits an HTTP CONNECT done event.
+func (nx *Network) emitHTTPConnectDone(ctx context.Context,
+	laddr, raddr net.Addr, target string, t0 time.Time, statusCode int, err error) {
+	if nx.emitEnabled() {
+		nx.emit(ctx, &HTTPConnectDoneEvent{
+			ConnID:                 connIDFromContext(ctx),
+			Err:                    errString(err),
+			ErrClass:               nx.errClass(err),
+			HTTPConnectTarget:      target,
+			HTTPProxyURL:           nx.ProxyURL.Redacted(),
+			HTTPResponseStatusCode: statusCode,
+			LocalAddr:              laddr.String(),
+			Protocol:               laddr.Network(),
+			RemoteAddr:             raddr.String(),
+			T0:                     t0,
+			T:                      nx.timeNow(),
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connectProxy is a minimal HTTP CONNECT proxy for testing.
+type connectProxy struct {
+	auth []string
+	mu   sync.Mutex
+}
+
+// ServeHTTP implements [http.Handler].
+func (p *connectProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.auth = append(p.auth, r.Header.Get("Proxy-Authorization"))
+	p.mu.Unlock()
+	if r.Method != http.MethodConnect {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if strings.HasPrefix(r.Host, "forbidden.example:") {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	target, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer target.Close()
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+	go io.Copy(target, conn)
+	io.Copy(conn, target)
+}
+
+// proxyEvents returns the "httpConnectDone" events in the given logs.
+func proxyEvents(t *testing.T, logs string) (events []map[string]any) {
+	for _, line := range strings.Split(strings.TrimSpace(logs), "\n") {
+		var ev map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		if ev["msg"] == "httpConnectDone" {
+			events = append(events, ev)
+		}
+	}
+	return
+}
+
+func TestNetwork_ProxyURL(t *testing.T) {
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+	target := httptest.NewServer(hello)
+	defer target.Close()
+	tlsTarget := httptest.NewTLSServer(hello)
+	defer tlsTarget.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(tlsTarget.Certificate())
+
+	fetch := func(nx *Network, URL string) (string, error) {
+		clnt := &http.Client{Transport: NewHTTPTransport(nx)}
+		resp, err := clnt.Get(URL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	t.Run("we tunnel cleartext connections through an http proxy", func(t *testing.T) {
+		proxy := &connectProxy{}
+		psrv := httptest.NewServer(proxy)
+		defer psrv.Close()
+
+		var buf bytes.Buffer
+		proxyURL, err := url.Parse(psrv.URL)
+		require.NoError(t, err)
+		proxyURL.User = url.UserPassword("user", "pass")
+		nx := &Network{
+			Logger:   slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+			ProxyURL: proxyURL,
+		}
+		body, err := fetch(nx, target.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", body)
+
+		assert.Equal(t, []string{"Basic dXNlcjpwYXNz"}, proxy.auth)
+		events := proxyEvents(t, buf.String())
+		require.Len(t, events, 1)
+		assert.Equal(t, strings.TrimPrefix(target.URL, "http://"), events[0]["httpConnectTarget"])
+		assert.Equal(t, proxyURL.Redacted(), events[0]["httpProxyUrl"])
+		assert.Equal(t, float64(http.StatusOK), events[0]["httpResponseStatusCode"])
+		assert.Equal(t, psrv.Listener.Addr().String(), events[0]["remoteAddr"])
+		assert.Equal(t, "", events[0]["errClass"])
+	})
+
+	t.Run("we tunnel TLS connections through an https proxy", func(t *testing.T) {
+		psrv := httptest.NewTLSServer(&connectProxy{})
+		defer psrv.Close()
+
+		var buf bytes.Buffer
+		proxyURL, err := url.Parse(psrv.URL)
+		require.NoError(t, err)
+		nx := &Network{
+			Logger:   slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+			ProxyURL: proxyURL,
+			RootCAs:  pool,
+		}
+		body, err := fetch(nx, tlsTarget.URL)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", body)
+
+		var handshakes int
+		connIDs := map[float64]bool{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "tlsHandshakeDone" {
+				handshakes++
+			}
+			if id, ok := ev["connId"].(float64); ok {
+				connIDs[id] = true
+			}
+		}
+		assert.Equal(t, 2, handshakes) // first with the proxy, then with the target
+		assert.Len(t, connIDs, 1)      // all the events belong to the same connection
+		assert.Len(t, proxyEvents(t, buf.String()), 1)
+	})
+
+	t.Run("we return an error when the proxy refuses the CONNECT", func(t *testing.T) {
+		psrv := httptest.NewServer(&connectProxy{})
+		defer psrv.Close()
+
+		var buf bytes.Buffer
+		proxyURL, err := url.Parse(psrv.URL)
+		require.NoError(t, err)
+		nx := &Network{
+			Logger:   slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+			ProxyURL: proxyURL,
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "forbidden.example:443")
+		assert.ErrorIs(t, err, errProxyConnect)
+		assert.Nil(t, conn)
+
+		events := proxyEvents(t, buf.String())
+		require.Len(t, events, 1)
+		assert.Equal(t, float64(http.StatusForbidden), events[0]["httpResponseStatusCode"])
+	})
+
+	t.Run("we reject unsupported proxy URL schemes", func(t *testing.T) {
+		nx := &Network{
+			ProxyURL: &url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"},
+		}
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", "example.com:443")
+		assert.ErrorIs(t, err, errUnsupportedProxyScheme)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("we do not tunnel UDP connections", func(t *testing.T) {
+		nx := &Network{
+			ProxyURL: &url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"},
+		}
+		conn, err := nx.DialContext(context.Background(), "udp", "127.0.0.1:53")
+		require.NoError(t, err)
+		conn.Close()
+	})
+}
@@ -0,0 +1,229 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// QUIC dialing code.
+//
+
+package netcore
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// DialQUICContext establishes a new QUIC connection.
+//
+// The config argument is the optional TLS config to use. If nil, we use
+// the same TLS config that DialTLSContext would use for the "udp" network
+// and the given address (e.g., using the "h3" ALPN for port 443).
+//
+// The returned [*quic.Conn] owns the underlying UDP connection,
+// which we close when the QUIC connection is closed.
+func (nx *Network) DialQUICContext(ctx context.Context, address string, config *tls.Config) (*quic.Conn, error) {
+	// obtain the TLS config to use
+	if config == nil {
+		var err error
+		config, err = nx.tlsConfig("udp", address)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nx.dialQUIC(ctx, address, config, nx.QUICConfig)
+}
+
+// dialQUIC implements DialQUICContext using the given TLS and QUIC configs
+// and is compatible with the Dial field of [*http3.Transport].
+func (nx *Network) dialQUIC(ctx context.Context,
+	address string, config *tls.Config, quicConfig *quic.Config) (*quic.Conn, error) {
+	// make sure we log the TLS secrets also with caller-provided configs
+	if nx.TLSKeyLogWriter != nil {
+		config = config.Clone()
+		nx.maybeSetKeyLogWriter(config)
+	}
+
+	// resolve the endpoints to connect to
+	endpoints, err := nx.LookupEndpoint(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// only keep the endpoints matching the address family
+	endpoints, err = nx.filterEndpoints("udp", endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// build a QUIC dialer
+	qd := &quicDialer{config: config, netx: nx, quicConfig: quicConfig}
+
+	// attempt with the available endpoints according to the dial policy
+	conn, err := nx.dialEndpoints(ctx, "udp", qd.dial, endpoints...)
+	if err != nil {
+		return nil, err
+	}
+	qconn := conn.(*quicDialerConn)
+	go func() {
+		<-qconn.qconn.Context().Done()
+		qconn.Conn.Close()
+	}()
+	return qconn.qconn, nil
+}
+
+// quicDialer dials QUIC connections.
+type quicDialer struct {
+	config     *tls.Config
+	netx       *Network
+	quicConfig *quic.Config
+}
+
+// quicDialerConn allows to return a [*quic.Conn] from a [DialFunc], such
+// that we can use a [DialPolicy], and closes both the QUIC connection
+// and the underlying UDP connection when the policy closes it.
+type quicDialerConn struct {
+	net.Conn
+	qconn *quic.Conn
+}
+
+// Close implements [net.Conn].
+func (c *quicDialerConn) Close() error {
+	c.qconn.CloseWithError(0, "")
+	return c.Conn.Close()
+}
+
+func (qd *quicDialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	// use the same connection ID for dialing and handshaking
+	ctx = withConnID(ctx)
+
+	// dial and log the results of dialing
+	conn, err := qd.netx.dialLog(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// emit event before the QUIC handshake
+	laddr := connLocalAddr(conn).String()
+	t0 := qd.emitQUICHandshakeStart(ctx, laddr, network, address)
+
+	// perform the QUIC handshake, optionally enforcing a timeout
+	hsctx, cancel := qd.netx.withTLSHandshakeTimeout(ctx)
+	qconn, err := qd.handshake(hsctx, conn)
+	cancel()
+	countOperation(&qd.netx.stats.handshakesAttempted, &qd.netx.stats.handshakesSucceeded, err)
+
+	// emit event after the QUIC handshake
+	var state quic.ConnectionState
+	if qconn != nil {
+		state = qconn.ConnectionState()
+	}
+	qd.emitQUICHandshakeDone(ctx, laddr, network, address, t0, err, state)
+
+	// process the results
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &quicDialerConn{Conn: conn, qconn: qconn}, nil
+}
+
+// handshake performs the QUIC handshake over the given connection.
+func (qd *quicDialer) handshake(ctx context.Context, conn net.Conn) (*quic.Conn, error) {
+	pconn := &connectedPacketConn{conn}
+	qconn, err := quic.DialEarly(ctx, pconn, conn.RemoteAddr(), qd.config, qd.quicConfig)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case <-qconn.HandshakeComplete():
+		return qconn, nil
+	case <-qconn.Context().Done():
+		err := context.Cause(qconn.Context())
+		qconn.CloseWithError(0, "")
+		return nil, err
+	case <-ctx.Done():
+		qconn.CloseWithError(0, "")
+		return nil, ctx.Err()
+	}
+}
+
+// connectedPacketConn adapts a connected [net.Conn] to
+// the [net.PacketConn] interface required by QUIC.
+type connectedPacketConn struct {
+	net.Conn
+}
+
+var _ net.PacketConn = &connectedPacketConn{}
+
+// ReadFrom implements [net.PacketConn].
+func (c *connectedPacketConn) ReadFrom(buf []byte) (int, net.Addr, error) {
+	count, err := c.Conn.Read(buf)
+	return count, c.Conn.RemoteAddr(), err
+}
+
+// errWrongPeer indicates that we tried to write to an address
+// that differs from the address of the connected peer.
+var errWrongPeer = errors.New("cannot write to a peer other than the connected one")
+
+// WriteTo implements [net.PacketConn].
+func (c *connectedPacketConn) WriteTo(buf []byte, addr net.Addr) (int, error) {
+	if addr.String() != c.Conn.RemoteAddr().String() {
+		return 0, errWrongPeer
+	}
+	return c.Conn.Write(buf)
+}
+
+// emitQUICHandshakeStart emits a QUIC handshake start event.
+func (qd *quicDialer) emitQUICHandshakeStart(ctx context.Context,
+	localAddr, network, remoteAddr string) time.Time {
+	t0 := qd.netx.timeNow()
+	if qd.netx.emitEnabled() {
+		qd.netx.emit(ctx, &QUICHandshakeStartEvent{
+			ConnID:        connIDFromContext(ctx),
+			LocalAddr:     localAddr,
+			Protocol:      network,
+			RemoteAddr:    remoteAddr,
+			T:             t0,
+			TLSServerName: qd.config.ServerName,
+			TLSSkipVerify: qd.config.InsecureSkipVerify,
+		})
+	}
+	return t0
+}
+
+// emitQUICHandshakeDone emits a QUIC handshake done event.
+func (qd *quicDialer) emitQUICHandshakeDone(ctx context.Context,
+	localAddr, network, remoteAddr string, t0 time.Time,
+	err error, state quic.ConnectionState) {
+	if qd.netx.emitEnabled() {
+		qd.netx.emit(ctx, &QUICHandshakeDoneEvent{
+			ConnID:                connIDFromContext(ctx),
+			Err:                   errString(err),
+			ErrClass:              qd.netx.errClass(err),
+			LocalAddr:             localAddr,
+			Protocol:              network,
+			QUICUsed0RTT:          state.Used0RTT,
+			QUICVersion:           quicVersionName(state.Version),
+			RemoteAddr:            remoteAddr,
+			T0:                    t0,
+			T:                     qd.netx.timeNow(),
+			TLSCipherSuite:        tls.CipherSuiteName(state.TLS.CipherSuite),
+			TLSNegotiatedProtocol: state.TLS.NegotiatedProtocol,
+			TLSPeerCerts:          tlsPeerCerts(state.TLS, err),
+			TLSServerName:         qd.config.ServerName,
+			TLSSkipVerify:         qd.config.InsecureSkipVerify,
+			TLSVersion:            tls.VersionName(state.TLS.Version),
+		})
+	}
+}
+
+// quicVersionName returns the name of the given QUIC version.
+func quicVersionName(version quic.Version) string {
+	if version == 0 {
+		return ""
+	}
+	return version.String()
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/rbmk-project/common/mocks"
+	"github.com/rbmk-project/common/runtimex"
+	"github.com/rbmk-project/common/selfsignedcert"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newQUICTestServer starts a QUIC server on localhost accepting
+// connections using the "h3" ALPN and returns its address and the
+// pool containing its certificate.
+func newQUICTestServer(t *testing.T) (string, *x509.CertPool) {
+	cert := selfsignedcert.New(selfsignedcert.NewConfigExampleCom())
+	tlsCert := runtimex.Try1(tls.X509KeyPair(cert.CertPEM, cert.KeyPEM))
+	pool := x509.NewCertPool()
+	runtimex.Assert(pool.AppendCertsFromPEM(cert.CertPEM), "cannot append cert")
+	listener, err := quic.ListenAddr("127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{tlsCert},
+		NextProtos:   []string{"h3"},
+	}, &quic.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept(context.Background())
+			if err != nil {
+				return
+			}
+			<-conn.Context().Done()
+		}
+	}()
+	return listener.Addr().String(), pool
+}
+
+func TestNetwork_DialQUICContext(t *testing.T) {
+	t.Run("tls config failure", func(t *testing.T) {
+		nx := &Network{}
+		conn, err := nx.DialQUICContext(context.Background(), "invalid:address:format", nil)
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		expectedErr := errors.New("mocked lookup error")
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expectedErr
+			},
+		}
+		conn, err := nx.DialQUICContext(context.Background(), "example.com:443", nil)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("dial failure", func(t *testing.T) {
+		expectedErr := errors.New("mocked dial error")
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				assert.Equal(t, "udp", network)
+				return nil, expectedErr
+			},
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{"1.2.3.4"}, nil
+			},
+		}
+		conn, err := nx.DialQUICContext(context.Background(), "example.com:443", nil)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("handshake failure", func(t *testing.T) {
+		address, _ := newQUICTestServer(t)
+		nx := &Network{}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		conn, err := nx.DialQUICContext(ctx, address, &tls.Config{
+			NextProtos: []string{"h3"},
+			RootCAs:    x509.NewCertPool(), // does not contain the server cert
+			ServerName: "www.example.com",
+		})
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+	})
+
+	t.Run("successful dial and handshake with logging", func(t *testing.T) {
+		address, pool := newQUICTestServer(t)
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		conn, err := nx.DialQUICContext(ctx, address, &tls.Config{
+			NextProtos: []string{"h3"},
+			RootCAs:    pool,
+			ServerName: "www.example.com",
+		})
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		assert.Equal(t, "h3", conn.ConnectionState().TLS.NegotiatedProtocol)
+
+		var events []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var event map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			events = append(events, event)
+		}
+		require.Len(t, events, 4)
+		assert.Equal(t, "connectStart", events[0]["msg"])
+		assert.Equal(t, "connectDone", events[1]["msg"])
+		assert.Equal(t, "quicHandshakeStart", events[2]["msg"])
+		assert.Equal(t, "www.example.com", events[2]["tlsServerName"])
+		assert.Equal(t, "quicHandshakeDone", events[3]["msg"])
+		assert.Equal(t, "", events[3]["errClass"])
+		assert.Equal(t, "udp", events[3]["protocol"])
+		assert.Equal(t, address, events[3]["remoteAddr"])
+		assert.Equal(t, false, events[3]["quicUsed0RTT"])
+		assert.Equal(t, "v1", events[3]["quicVersion"])
+		assert.Equal(t, "h3", events[3]["tlsNegotiatedProtocol"])
+		assert.Equal(t, "TLS 1.3", events[3]["tlsVersion"])
+		assert.Len(t, events[3]["tlsPeerCerts"], 1)
+	})
+
+	t.Run("we write the TLS secrets with a caller-provided config", func(t *testing.T) {
+		address, pool := newQUICTestServer(t)
+		var buf bytes.Buffer
+		nx := &Network{TLSKeyLogWriter: &buf}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		config := &tls.Config{
+			NextProtos: []string{"h3"},
+			RootCAs:    pool,
+			ServerName: "www.example.com",
+		}
+		conn, err := nx.DialQUICContext(ctx, address, config)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		assert.Contains(t, buf.String(), "CLIENT_HANDSHAKE_TRAFFIC_SECRET ")
+		assert.Nil(t, config.KeyLogWriter) // we did not modify the caller's config
+	})
+}
+
+func TestConnectedPacketConn(t *testing.T) {
+	remoteAddr := &net.UDPAddr{IP: net.ParseIP("1.2.3.4"), Port: 443}
+	conn := &connectedPacketConn{&mocks.Conn{
+		MockRead: func(b []byte) (int, error) {
+			return copy(b, "abc"), nil
+		},
+		MockRemoteAddr: func() net.Addr {
+			return remoteAddr
+		},
+		MockWrite: func(b []byte) (int, error) {
+			return len(b), nil
+		},
+	}}
+
+	t.Run("ReadFrom", func(t *testing.T) {
+		buf := make([]byte, 8)
+		count, addr, err := conn.ReadFrom(buf)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+		assert.Equal(t, remoteAddr, addr)
+	})
+
+	t.Run("WriteTo the connected peer", func(t *testing.T) {
+		count, err := conn.WriteTo([]byte("abc"), remoteAddr)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, count)
+	})
+
+	t.Run("WriteTo another peer", func(t *testing.T) {
+		otherAddr := &net.UDPAddr{IP: net.ParseIP("4.3.2.1"), Port: 443}
+		count, err := conn.WriteTo([]byte("abc"), otherAddr)
+		assert.ErrorIs(t, err, errWrongPeer)
+		assert.Equal(t, 0, count)
+	})
+}
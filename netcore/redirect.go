@@ -0,0 +1,139 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Redirect-following fetch helper.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"time"
+)
+
+// RedirectHop describes a request in a redirect chain.
+type RedirectHop struct {
+	// HasCookies indicates whether the response set cookies.
+	HasCookies bool
+
+	// Location is the resolved URL of the Location header, which is
+	// empty when the response is not a redirect or lacks a Location.
+	Location string
+
+	// StatusCode is the response status code.
+	StatusCode int
+
+	// URL is the request URL.
+	URL string
+}
+
+// errTooManyRedirects indicates that we exceeded the maximum number of redirects.
+var errTooManyRedirects = errors.New("too many redirects")
+
+// GetWithRedirects performs a GET request for the given URL following
+// at most maxRedirects redirects, using a transport created with
+// [NewHTTPTransport] and wrapped with [WrapHTTPRoundTripper], and
+// a cookie jar to carry cookies across hops.
+//
+// For each hop, we emit an "httpRedirectHop" structured event and we append
+// a [RedirectHop] to the returned chain. On success, we return the final
+// response, whose body the caller must close, and the chain including
+// the final response. On failure, we return the chain so far, which is
+// empty when the request for the given URL fails.
+func (nx *Network) GetWithRedirects(
+	ctx context.Context, URL string, maxRedirects int) (*http.Response, []RedirectHop, error) {
+	// create the client
+	txp := NewHTTPTransport(nx)
+	defer txp.CloseIdleConnections()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // we follow redirects manually
+		},
+		Jar:       jar,
+		Transport: WrapHTTPRoundTripper(nx, txp),
+	}
+
+	// follow the redirect chain
+	var chain []RedirectHop
+	for {
+		resp, hop, err := nx.getHop(ctx, client, URL)
+		if err != nil {
+			return nil, chain, err
+		}
+		chain = append(chain, hop)
+		if hop.Location == "" {
+			return resp, chain, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if len(chain) > maxRedirects {
+			return nil, chain, errTooManyRedirects
+		}
+		URL = hop.Location
+	}
+}
+
+// getHop performs a single GET request and emits the corresponding event.
+func (nx *Network) getHop(ctx context.Context,
+	client *http.Client, URL string) (*http.Response, RedirectHop, error) {
+	t0 := nx.timeNow()
+	hop := RedirectHop{URL: URL}
+	req, err := http.NewRequestWithContext(ctx, "GET", URL, nil)
+	if err != nil {
+		nx.emitRedirectHop(ctx, t0, hop, err)
+		return nil, hop, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		nx.emitRedirectHop(ctx, t0, hop, err)
+		return nil, hop, err
+	}
+	hop.HasCookies = len(resp.Cookies()) > 0
+	hop.StatusCode = resp.StatusCode
+	if isRedirect(resp.StatusCode) && resp.Header.Get("Location") != "" {
+		location, err := resp.Location()
+		if err != nil {
+			resp.Body.Close()
+			nx.emitRedirectHop(ctx, t0, hop, err)
+			return nil, hop, err
+		}
+		hop.Location = location.String()
+	}
+	nx.emitRedirectHop(ctx, t0, hop, nil)
+	return resp, hop, nil
+}
+
+// isRedirect returns whether the status code is a redirect we should follow.
+func isRedirect(statusCode int) bool {
+	switch statusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
+}
+
+// emitRedirectHop emits a structured event describing a redirect hop.
+func (nx *Network) emitRedirectHop(ctx context.Context, t0 time.Time, hop RedirectHop, err error) {
+	if nx.emitEnabled() {
+		nx.emit(ctx, &HTTPRedirectHopEvent{
+			Err:                    errString(err),
+			ErrClass:               nx.errClass(err),
+			HTTPHasCookies:         hop.HasCookies,
+			HTTPLocation:           hop.Location,
+			HTTPResponseStatusCode: hop.StatusCode,
+			HTTPURL:                hop.URL,
+			T0:                     t0,
+			T:                      nx.timeNow(),
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRedirectTestServer returns a server redirecting /a to /b, setting
+// a cookie, and redirecting /b to /c, which requires the cookie.
+func newRedirectTestServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/a", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "deadbeef"})
+		http.Redirect(w, r, "/b", http.StatusFound)
+	})
+	mux.HandleFunc("/b", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/c", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/c", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("final"))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusTemporaryRedirect)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestNetwork_GetWithRedirects(t *testing.T) {
+	t.Run("we follow the chain and emit an event per hop", func(t *testing.T) {
+		srv := newRedirectTestServer()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+		}
+		resp, chain, err := nx.GetWithRedirects(context.Background(), srv.URL+"/a", 10)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "final", string(body))
+
+		expect := []RedirectHop{{
+			HasCookies: true,
+			Location:   srv.URL + "/b",
+			StatusCode: http.StatusFound,
+			URL:        srv.URL + "/a",
+		}, {
+			Location:   srv.URL + "/c",
+			StatusCode: http.StatusMovedPermanently,
+			URL:        srv.URL + "/b",
+		}, {
+			StatusCode: http.StatusOK,
+			URL:        srv.URL + "/c",
+		}}
+		assert.Equal(t, expect, chain)
+
+		var hops []map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var event map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &event))
+			if event["msg"] == "httpRedirectHop" {
+				hops = append(hops, event)
+			}
+		}
+		require.Len(t, hops, 3)
+		assert.Equal(t, srv.URL+"/a", hops[0]["httpUrl"])
+		assert.Equal(t, true, hops[0]["httpHasCookies"])
+		assert.Equal(t, srv.URL+"/b", hops[0]["httpLocation"])
+		assert.Equal(t, float64(http.StatusFound), hops[0]["httpResponseStatusCode"])
+		assert.Equal(t, "", hops[2]["httpLocation"])
+		assert.Equal(t, float64(http.StatusOK), hops[2]["httpResponseStatusCode"])
+	})
+
+	t.Run("we stop after too many redirects", func(t *testing.T) {
+		srv := newRedirectTestServer()
+		defer srv.Close()
+
+		nx := &Network{}
+		resp, chain, err := nx.GetWithRedirects(context.Background(), srv.URL+"/loop", 3)
+		assert.ErrorIs(t, err, errTooManyRedirects)
+		assert.Nil(t, resp)
+		assert.Len(t, chain, 4)
+	})
+
+	t.Run("we return the chain on failure", func(t *testing.T) {
+		nx := &Network{}
+		resp, chain, err := nx.GetWithRedirects(context.Background(), "\t", 3)
+		assert.Error(t, err)
+		assert.Nil(t, resp)
+		assert.Empty(t, chain)
+	})
+}
@@ -3,30 +3,34 @@
 //
 // Adapted from: https://github.com/ooni/probe-cli/blob/v3.20.1/internal/netxlite/dialer.go
 //
-// Internal code for DNS lookups.
+// Code for DNS lookups.
 //
 
 package netcore
 
 import (
 	"context"
-	"log/slog"
+	"errors"
 	"net"
+	"net/netip"
+	"strings"
+	"sync"
 	"time"
-
-	"github.com/rbmk-project/common/errclass"
 )
 
-// maybeLookupEndpoint resolves the domain name inside an endpoint into
-// a list of TCP/UDP endpoints. If the domain name is already an IP
-// address, we short circuit the lookup.
-func (nx *Network) maybeLookupEndpoint(ctx context.Context, endpoint string) ([]string, error) {
+// LookupEndpoint resolves the domain name inside an endpoint (e.g.,
+// "example.com:443") into a list of TCP/UDP endpoints, emitting the
+// same events as [*Network.LookupHost]. If the domain name is already
+// an IP address, we short circuit the lookup.
+//
+// This method is goroutine safe.
+func (nx *Network) LookupEndpoint(ctx context.Context, endpoint string) ([]string, error) {
 	domain, port, err := net.SplitHostPort(endpoint)
 	if err != nil {
 		return nil, err
 	}
 
-	addrs, err := nx.maybeLookupHost(ctx, domain)
+	addrs, err := nx.LookupHost(ctx, domain)
 	if err != nil {
 		return nil, err
 	}
@@ -38,12 +42,55 @@ func (nx *Network) maybeLookupEndpoint(ctx context.Context, endpoint string) ([]
 	return endpoints, nil
 }
 
-// maybeLookupHost resolves a domain name to IP addresses unless the domain
+// errNoAddresses indicates that the lookup succeeded without returning any address.
+var errNoAddresses = errors.New("netcore: no addresses for host")
+
+// lookupFirstAddr resolves the given host, which is a domain name or an
+// IP address, and returns the endpoint using the first address matching
+// the AddressFamilyPolicy and the given port.
+func (nx *Network) lookupFirstAddr(ctx context.Context, host, port string) (netip.AddrPort, error) {
+	endpoints, err := nx.LookupEndpoint(ctx, net.JoinHostPort(host, port))
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	endpoints, err = nx.filterEndpoints("ip", endpoints)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	if len(endpoints) <= 0 {
+		return netip.AddrPort{}, errNoAddresses
+	}
+	endpoint, err := netip.ParseAddrPort(endpoints[0])
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(endpoint.Addr().Unmap(), endpoint.Port()), nil
+}
+
+// LookupHost resolves a domain name to IP addresses unless the domain
 // is already an IP address, in which case we short circuit the lookup.
-func (nx *Network) maybeLookupHost(ctx context.Context, domain string) ([]string, error) {
+//
+// This method emits the "lookupHostStart" and "lookupHostDone" events
+// and honors the LookupHostTimeout field, so that it is possible to
+// perform DNS-only measurements using the same logging conventions.
+//
+// This method is goroutine safe.
+func (nx *Network) LookupHost(ctx context.Context, domain string) ([]string, error) {
+	result, err := nx.LookupHostResult(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	return result.Addrs, nil
+}
+
+// LookupHostResult is like [*Network.LookupHost] but returns a [*LookupResult]
+// including, when known, the CNAME chain, the resolver, and the DNS queries.
+//
+// This method is goroutine safe.
+func (nx *Network) LookupHostResult(ctx context.Context, domain string) (*LookupResult, error) {
 	// handle the case where domain is already an IP address
 	if net.ParseIP(domain) != nil {
-		return []string{domain}, nil
+		return &LookupResult{Addrs: []string{domain}}, nil
 	}
 
 	// Optionally enforce a timeout for the lookup
@@ -56,24 +103,93 @@ func (nx *Network) maybeLookupHost(ctx context.Context, domain string) ([]string
 	// Emit structured event before the lookup
 	t0 := nx.emitLookupHostStart(ctx, domain)
 
-	// Perform the actual lookup
-	addrs, err := nx.doLookupHost(ctx, domain)
+	// Perform the actual lookup, possibly using the hosts or the cache
+	result, err := nx.lookupHostHosts(ctx, domain)
+	countOperation(&nx.stats.lookupsAttempted, &nx.stats.lookupsSucceeded, err)
 
 	// Emit structured event after the lookup
-	nx.emitLookupHostDone(ctx, domain, t0, addrs, err)
+	nx.emitLookupHostDone(ctx, domain, t0, result, err)
 
 	// Returns results to the caller
-	return addrs, err
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 // defaultResolver is the [*net.Resolver] we use by default.
 var defaultResolver = &net.Resolver{}
 
+// errNoLookupResult indicates that a LookupHostResultFunc returned
+// neither a result nor an error.
+var errNoLookupResult = errors.New("netcore: no lookup result")
+
+// lookupHostHosts uses the Hosts, if any, before calling lookupHostCached.
+func (nx *Network) lookupHostHosts(ctx context.Context, domain string) (*LookupResult, error) {
+	if addrs := nx.lookupHosts(domain); len(addrs) > 0 {
+		return &LookupResult{Addrs: append([]string{}, addrs...), Resolver: "hosts"}, nil
+	}
+	return nx.lookupHostCached(ctx, domain)
+}
+
+// lookupHosts returns the addresses of the domain inside Hosts, if any.
+func (nx *Network) lookupHosts(domain string) []string {
+	if len(nx.Hosts) <= 0 {
+		return nil
+	}
+	if addrs := nx.Hosts[domain]; len(addrs) > 0 {
+		return addrs
+	}
+	domain = normalizeDomain(domain)
+	for name, addrs := range nx.Hosts {
+		if normalizeDomain(name) == domain && len(addrs) > 0 {
+			return addrs
+		}
+	}
+	return nil
+}
+
+// normalizeDomain lowercases the domain name and removes the trailing dot.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
+// lookupHostCached uses the DNSCache, if any, before calling doLookupHost.
+func (nx *Network) lookupHostCached(ctx context.Context, domain string) (*LookupResult, error) {
+	if nx.DNSCache == nil || resolverServerFromContext(ctx) != nil {
+		return nx.doLookupHost(ctx, domain)
+	}
+	if !dnsCacheBypassFromContext(ctx) {
+		if result, found := nx.DNSCache.Get(domain, nx.timeNow()); found {
+			return result, nil
+		}
+	}
+	result, err := nx.doLookupHost(ctx, domain)
+	if err == nil {
+		nx.DNSCache.Put(domain, result, nx.timeNow())
+	}
+	return result, err
+}
+
 // doLookupHost performs the DNS lookup.
-func (nx *Network) doLookupHost(ctx context.Context, domain string) ([]string, error) {
-	// if there is a custom LookupHostFunc, use it
+func (nx *Network) doLookupHost(ctx context.Context, domain string) (*LookupResult, error) {
+	// if the context overrides the resolver, use the given DNS server
+	if addr := resolverServerFromContext(ctx); addr != nil {
+		return nx.NewDNSCoreLookupHostResultFunc(addr)(ctx, domain)
+	}
+
+	// if there is a custom LookupHostResultFunc, use it
+	if nx.LookupHostResultFunc != nil {
+		result, err := nx.LookupHostResultFunc(ctx, domain)
+		if err == nil && result == nil {
+			err = errNoLookupResult
+		}
+		return result, err
+	}
+
+	// otherwise, if there is a custom LookupHostFunc, use it
 	if nx.LookupHostFunc != nil {
-		return nx.LookupHostFunc(ctx, domain)
+		return newLookupResult(nx.LookupHostFunc(ctx, domain))
 	}
 
 	// otherwise either use the default [*net.Resolver] or the
@@ -82,36 +198,171 @@ func (nx *Network) doLookupHost(ctx context.Context, domain string) ([]string, e
 	if nx.NewResolverOrSingleton != nil {
 		reso = nx.NewResolverOrSingleton()
 	}
-	return reso.LookupHost(ctx, domain)
+	return nx.lookupHostParallel(ctx, domain, func(ctx context.Context, queryType string) (*LookupResult, error) {
+		addrs, err := lookupIP(ctx, reso, queryTypeToIPNetwork[queryType], domain)
+		if err != nil {
+			return nil, err
+		}
+		return &LookupResult{Addrs: addrs}, nil
+	})
+}
+
+// queryTypeToIPNetwork maps the query types to the networks used by [*net.Resolver].
+var queryTypeToIPNetwork = map[string]string{
+	"A":    "ip4",
+	"AAAA": "ip6",
+}
+
+// dnsQueryFunc performs a DNS query of the given type (i.e., "A" or "AAAA") and
+// returns the resolved addresses and, if known, the CNAME chain and the TTL.
+type dnsQueryFunc func(ctx context.Context, queryType string) (*LookupResult, error)
+
+// lookupHostParallel resolves a domain name by performing the A and AAAA queries
+// in parallel as distinct operations, each emitting its own events, such that
+// IPv6-only failures are visible. The result contains the IPv4 addresses followed
+// by the IPv6 addresses, and the lookup fails only when both queries fail.
+func (nx *Network) lookupHostParallel(
+	ctx context.Context, domain string, fx dnsQueryFunc) (*LookupResult, error) {
+	// perform the queries in parallel
+	queries := []struct {
+		queryType string
+		result    *LookupResult
+		err       error
+		info      LookupQuery
+	}{
+		{queryType: "A"},
+		{queryType: "AAAA"},
+	}
+	wg := &sync.WaitGroup{}
+	for idx := range queries {
+		query := &queries[idx]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t0 := nx.emitDNSQueryStart(ctx, domain, query.queryType)
+			query.result, query.err = fx(ctx, query.queryType)
+			if query.result == nil {
+				query.result = &LookupResult{}
+			}
+			query.info = nx.emitDNSQueryDone(ctx, domain, query.queryType, t0, query.result.Addrs, query.err)
+		}()
+	}
+	wg.Wait()
+
+	// merge the results
+	var errs []error
+	result := &LookupResult{}
+	for _, query := range queries {
+		result.Addrs = append(result.Addrs, query.result.Addrs...)
+		if len(result.CNAMEs) <= 0 {
+			result.CNAMEs = query.result.CNAMEs
+		}
+		if ttl := query.result.TTL; ttl > 0 && (result.TTL <= 0 || ttl < result.TTL) {
+			result.TTL = ttl
+		}
+		result.Queries = append(result.Queries, query.info)
+		if query.err != nil {
+			errs = append(errs, query.err)
+		}
+	}
+	switch {
+	case len(result.Addrs) > 0:
+		return result, nil
+	case len(errs) > 0:
+		return nil, errs[0]
+	default:
+		return nil, &net.DNSError{Err: "no such host", Name: domain, IsNotFound: true}
+	}
+}
+
+// lookupIP resolves the IP addresses of the given network ("ip4" or "ip6").
+func lookupIP(ctx context.Context, reso *net.Resolver, network, domain string) ([]string, error) {
+	ipAddrs, err := reso.LookupIP(ctx, network, domain)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []string
+	for _, ipAddr := range ipAddrs {
+		addrs = append(addrs, ipAddr.String())
+	}
+	return addrs, nil
+}
+
+// newLookupResult wraps the addresses returned by a lookup into a [*LookupResult].
+func newLookupResult(addrs []string, err error) (*LookupResult, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &LookupResult{Addrs: addrs}, nil
+}
+
+// emitDNSQueryStart emits a structured event before a DNS query.
+func (nx *Network) emitDNSQueryStart(ctx context.Context, domain, queryType string) time.Time {
+	t0 := nx.timeNow()
+	if nx.emitEnabled() {
+		nx.emit(ctx, &DNSQueryStartEvent{
+			DNSLookupDomain: domain,
+			DNSQueryType:    queryType,
+			T:               t0,
+		})
+	}
+	return t0
+}
+
+// emitDNSQueryDone emits a structured event after a DNS query
+// and returns the corresponding [LookupQuery].
+func (nx *Network) emitDNSQueryDone(ctx context.Context, domain,
+	queryType string, t0 time.Time, addrs []string, err error) LookupQuery {
+	info := LookupQuery{
+		Err:       errString(err),
+		QueryType: queryType,
+		T0:        t0,
+		T:         nx.timeNow(),
+	}
+	if nx.emitEnabled() {
+		nx.emit(ctx, &DNSQueryDoneEvent{
+			DNSLookupDomain:  domain,
+			DNSQueryType:     queryType,
+			DNSResolvedAddrs: addrs,
+			Err:              info.Err,
+			ErrClass:         nx.errClass(err),
+			T0:               t0,
+			T:                info.T,
+		})
+	}
+	return info
 }
 
 // emitLookupHostStart emits a structured event before the lookup.
 func (nx *Network) emitLookupHostStart(ctx context.Context, domain string) time.Time {
 	t0 := nx.timeNow()
-	if nx.Logger != nil {
-		nx.Logger.InfoContext(
-			ctx,
-			"lookupHostStart",
-			slog.String("dnsLookupDomain", domain),
-			slog.Time("t", t0),
-		)
+	if nx.emitEnabled() {
+		nx.emit(ctx, &LookupHostStartEvent{
+			DNSLookupDomain: domain,
+			T:               t0,
+		})
 	}
 	return t0
 }
 
 // emitLookupHostDone emits a structured event after the lookup.
 func (nx *Network) emitLookupHostDone(ctx context.Context,
-	domain string, t0 time.Time, addrs []string, err error) {
-	if nx.Logger != nil {
-		nx.Logger.InfoContext(
-			ctx,
-			"lookupHostDone",
-			slog.String("dnsLookupDomain", domain),
-			slog.Any("dnsResolvedAddrs", addrs),
-			slog.Any("err", err),
-			slog.String("errClass", errclass.New(err)),
-			slog.Time("t0", t0),
-			slog.Time("t", nx.timeNow()),
-		)
+	domain string, t0 time.Time, result *LookupResult, err error) {
+	if nx.emitEnabled() {
+		if result == nil {
+			result = &LookupResult{}
+		}
+		nx.emit(ctx, &LookupHostDoneEvent{
+			DNSCNAMEs:        result.CNAMEs,
+			DNSCacheHit:      result.Cached,
+			DNSLookupDomain:  domain,
+			DNSQueries:       result.Queries,
+			DNSResolvedAddrs: result.Addrs,
+			DNSResolver:      result.Resolver,
+			Err:              errString(err),
+			ErrClass:         nx.errClass(err),
+			T0:               t0,
+			T:                nx.timeNow(),
+		})
 	}
 }
@@ -13,13 +13,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/miekg/dns"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestNetwork_maybeLookupEndpoint(t *testing.T) {
+func TestNetwork_LookupEndpoint(t *testing.T) {
 	t.Run("invalid endpoint format", func(t *testing.T) {
 		nx := &Network{}
-		_, err := nx.maybeLookupEndpoint(context.Background(), "invalid:endpoint:format")
+		_, err := nx.LookupEndpoint(context.Background(), "invalid:endpoint:format")
 		assert.Error(t, err)
 	})
 
@@ -30,7 +32,7 @@ func TestNetwork_maybeLookupEndpoint(t *testing.T) {
 				return nil, expectedErr
 			},
 		}
-		_, err := nx.maybeLookupEndpoint(context.Background(), "example.com:80")
+		_, err := nx.LookupEndpoint(context.Background(), "example.com:80")
 		assert.ErrorIs(t, err, expectedErr)
 	})
 
@@ -40,20 +42,20 @@ func TestNetwork_maybeLookupEndpoint(t *testing.T) {
 				return []string{"1.2.3.4", "5.6.7.8"}, nil
 			},
 		}
-		endpoints, err := nx.maybeLookupEndpoint(context.Background(), "example.com:80")
+		endpoints, err := nx.LookupEndpoint(context.Background(), "example.com:80")
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"1.2.3.4:80", "5.6.7.8:80"}, endpoints)
 	})
 }
 
-func TestNetwork_maybeLookupHost(t *testing.T) {
+func TestNetwork_LookupHost(t *testing.T) {
 	t.Run("IP address short circuit", func(t *testing.T) {
 		nx := &Network{
 			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
 				return nil, errors.New("should not be called")
 			},
 		}
-		addrs, err := nx.maybeLookupHost(context.Background(), "1.1.1.1")
+		addrs, err := nx.LookupHost(context.Background(), "1.1.1.1")
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"1.1.1.1"}, addrs)
 	})
@@ -64,7 +66,7 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 				return []string{"1.2.3.4", "5.6.7.8"}, nil
 			},
 		}
-		addrs, err := nx.maybeLookupHost(context.Background(), "example.com")
+		addrs, err := nx.LookupHost(context.Background(), "example.com")
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"1.2.3.4", "5.6.7.8"}, addrs)
 	})
@@ -76,7 +78,7 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 				return nil, expectedErr
 			},
 		}
-		_, err := nx.maybeLookupHost(context.Background(), "example.com")
+		_, err := nx.LookupHost(context.Background(), "example.com")
 		assert.ErrorIs(t, err, expectedErr)
 	})
 
@@ -96,7 +98,7 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 			},
 			LookupHostTimeout: time.Microsecond,
 		}
-		_, err := nx.maybeLookupHost(context.Background(), "example.com")
+		_, err := nx.LookupHost(context.Background(), "example.com")
 		assert.ErrorIs(t, err, expectedErr)
 	})
 
@@ -111,7 +113,7 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 				return reso
 			},
 		}
-		_, err := nx.maybeLookupHost(context.Background(), "example.com")
+		_, err := nx.LookupHost(context.Background(), "example.com")
 		assert.Error(t, err)
 	})
 
@@ -138,7 +140,7 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 			},
 		}
 
-		addrs, err := nx.maybeLookupHost(context.Background(), "example.com")
+		addrs, err := nx.LookupHost(context.Background(), "example.com")
 		assert.NoError(t, err)
 		assert.Equal(t, []string{"1.2.3.4", "5.6.7.8"}, addrs)
 
@@ -163,8 +165,12 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 		assert.Equal(t, map[string]interface{}{
 			"level":            "INFO",
 			"msg":              "lookupHostDone",
+			"dnsCNAMEs":        nil,
+			"dnsCacheHit":      false,
 			"dnsLookupDomain":  "example.com",
+			"dnsQueries":       nil,
 			"dnsResolvedAddrs": []interface{}{"1.2.3.4", "5.6.7.8"},
+			"dnsResolver":      "",
 			"err":              nil,
 			"errClass":         "",
 			"t0":               fixedTime.Format(time.RFC3339Nano),
@@ -196,7 +202,7 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 			},
 		}
 
-		addrs, err := nx.maybeLookupHost(context.Background(), "example.com")
+		addrs, err := nx.LookupHost(context.Background(), "example.com")
 		assert.ErrorIs(t, err, expectedErr)
 		assert.Nil(t, addrs)
 
@@ -221,8 +227,12 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 		assert.Equal(t, map[string]interface{}{
 			"level":            "INFO",
 			"msg":              "lookupHostDone",
+			"dnsCNAMEs":        nil,
+			"dnsCacheHit":      false,
 			"dnsLookupDomain":  "example.com",
+			"dnsQueries":       nil,
 			"dnsResolvedAddrs": nil,
+			"dnsResolver":      "",
 			"err":              expectedErr.Error(),
 			"errClass":         "EGENERIC",
 			"t0":               fixedTime.Format(time.RFC3339Nano),
@@ -230,3 +240,207 @@ func TestNetwork_maybeLookupHost(t *testing.T) {
 		}, doneLog)
 	})
 }
+
+func TestNetwork_LookupHostResult(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	expectResult := &LookupResult{
+		Addrs:  []string{"130.192.91.211"},
+		CNAMEs: []string{"www.example.com", "example.com"},
+		Queries: []LookupQuery{{
+			QueryType: "A",
+			T0:        fixedTime,
+			T:         fixedTime.Add(20 * time.Millisecond),
+		}},
+		Resolver: "udp://8.8.8.8:53",
+	}
+
+	t.Run("IP address short circuit", func(t *testing.T) {
+		nx := &Network{}
+		result, err := nx.LookupHostResult(context.Background(), "::1")
+		assert.NoError(t, err)
+		assert.Equal(t, &LookupResult{Addrs: []string{"::1"}}, result)
+	})
+
+	t.Run("LookupHostResultFunc takes precedence", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, errors.New("should not be called")
+			},
+			LookupHostResultFunc: func(ctx context.Context, domain string) (*LookupResult, error) {
+				return expectResult, nil
+			},
+		}
+
+		result, err := nx.LookupHostResult(context.Background(), "www.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, expectResult, result)
+		assert.Equal(t, 20*time.Millisecond, result.Queries[0].RTT())
+
+		addrs, err := nx.LookupHost(context.Background(), "www.example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"130.192.91.211"}, addrs)
+
+		// make sure we log the additional information
+		logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		assert.Len(t, logs, 4)
+		var ev LookupHostDoneEvent
+		assert.NoError(t, json.Unmarshal([]byte(logs[1]), &ev))
+		assert.Equal(t, expectResult.CNAMEs, ev.DNSCNAMEs)
+		assert.Equal(t, expectResult.Queries, ev.DNSQueries)
+		assert.Equal(t, expectResult.Resolver, ev.DNSResolver)
+	})
+
+	t.Run("we reject a nil result without an error", func(t *testing.T) {
+		nx := &Network{
+			LookupHostResultFunc: func(ctx context.Context, domain string) (*LookupResult, error) {
+				return nil, nil
+			},
+		}
+		result, err := nx.LookupHostResult(context.Background(), "www.example.com")
+		assert.ErrorIs(t, err, errNoLookupResult)
+		assert.Nil(t, result)
+	})
+}
+
+func TestNetwork_lookupHostNetResolver(t *testing.T) {
+	// start a DNS server answering the A queries for www.example.com
+	// and refusing the AAAA queries, as IPv6-broken servers may do
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &dns.Server{
+		PacketConn: pconn,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, query *dns.Msg) {
+			resp := &dns.Msg{}
+			resp.SetReply(query)
+			switch {
+			case query.Question[0].Name != "www.example.com.":
+				resp.Rcode = dns.RcodeNameError
+			case query.Question[0].Qtype == dns.TypeA:
+				resp.Answer = append(resp.Answer, &dns.A{
+					Hdr: dns.RR_Header{
+						Name:   query.Question[0].Name,
+						Rrtype: dns.TypeA,
+						Class:  dns.ClassINET,
+						Ttl:    60,
+					},
+					A: net.IPv4(130, 192, 91, 211),
+				})
+			default:
+				resp.Rcode = dns.RcodeRefused
+			}
+			w.WriteMsg(resp)
+		}),
+	}
+	go srv.ActivateAndServe()
+	defer srv.Shutdown()
+
+	newNetwork := func(buf *bytes.Buffer) *Network {
+		return &Network{
+			Logger: slog.New(slog.NewJSONHandler(buf, nil)),
+			NewResolverOrSingleton: func() *net.Resolver {
+				return &net.Resolver{
+					PreferGo: true,
+					Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+						return (&net.Dialer{}).DialContext(ctx, "udp", pconn.LocalAddr().String())
+					},
+				}
+			},
+		}
+	}
+
+	// parse returns the events by name.
+	parse := func(buf *bytes.Buffer) map[string][]map[string]any {
+		events := map[string][]map[string]any{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			name := ev["msg"].(string)
+			events[name] = append(events[name], ev)
+		}
+		return events
+	}
+
+	t.Run("we log the A and AAAA queries separately", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := newNetwork(&buf)
+		result, err := nx.LookupHostResult(context.Background(), "www.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"130.192.91.211"}, result.Addrs)
+		require.Len(t, result.Queries, 2)
+		assert.Equal(t, "A", result.Queries[0].QueryType)
+		assert.Empty(t, result.Queries[0].Err)
+		assert.Equal(t, "AAAA", result.Queries[1].QueryType)
+		assert.NotEmpty(t, result.Queries[1].Err)
+
+		events := parse(&buf)
+		assert.Len(t, events["dnsQueryStart"], 2)
+		require.Len(t, events["dnsQueryDone"], 2)
+		byType := map[any]map[string]any{}
+		for _, ev := range events["dnsQueryDone"] {
+			byType[ev["dnsQueryType"]] = ev
+		}
+		assert.Nil(t, byType["A"]["err"])
+		assert.Equal(t, []any{"130.192.91.211"}, byType["A"]["dnsResolvedAddrs"])
+		assert.NotNil(t, byType["AAAA"]["err"])
+		assert.NotEmpty(t, byType["AAAA"]["errClass"])
+		require.Len(t, events["lookupHostDone"], 1)
+		assert.Nil(t, events["lookupHostDone"][0]["err"])
+	})
+
+	t.Run("we fail when both queries fail", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := newNetwork(&buf)
+		addrs, err := nx.LookupHost(context.Background(), "nxdomain.example.com")
+		assert.Error(t, err)
+		assert.Nil(t, addrs)
+
+		events := parse(&buf)
+		assert.Len(t, events["dnsQueryDone"], 2)
+		require.Len(t, events["lookupHostDone"], 1)
+		assert.NotNil(t, events["lookupHostDone"][0]["err"])
+	})
+}
+
+func TestNetwork_Hosts(t *testing.T) {
+	var buf bytes.Buffer
+	nx := &Network{
+		DNSCache: &DNSCache{},
+		Hosts: map[string][]string{
+			"www.Example.com.": {"130.192.91.211", "2001:db8::1"},
+			"example.org":      {},
+		},
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+			return nil, errors.New("mocked lookup error")
+		},
+	}
+
+	result, err := nx.LookupHostResult(context.Background(), "WWW.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &LookupResult{
+		Addrs:    []string{"130.192.91.211", "2001:db8::1"},
+		Resolver: "hosts",
+	}, result)
+	assert.Equal(t, 0, nx.DNSCache.Len())
+
+	// we do not share the addresses with the caller
+	result.Addrs[0] = "127.0.0.1"
+	addrs, err := nx.LookupHost(context.Background(), "www.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"130.192.91.211", "2001:db8::1"}, addrs)
+
+	// we ignore the entries without addresses
+	_, err = nx.LookupHost(context.Background(), "example.org")
+	assert.Error(t, err)
+
+	// make sure we emit the synthetic lookup events
+	logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, logs, 6)
+	var ev LookupHostDoneEvent
+	require.NoError(t, json.Unmarshal([]byte(logs[1]), &ev))
+	assert.Equal(t, "WWW.example.com", ev.DNSLookupDomain)
+	assert.Equal(t, []string{"130.192.91.211", "2001:db8::1"}, ev.DNSResolvedAddrs)
+	assert.Equal(t, "hosts", ev.DNSResolver)
+}
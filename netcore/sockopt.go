@@ -0,0 +1,134 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Socket options applied when dialing.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// SocketOptions contains the options to set on the sockets we create
+// before connecting, for example for traceroute-like probing or for
+// policy routing. The zero value of each field means not setting the
+// corresponding option.
+type SocketOptions struct {
+	// Mark is the firewall mark (SO_MARK), which is only supported on Linux
+	// and requires the CAP_NET_ADMIN capability.
+	Mark int
+
+	// TOS is the IPv4 type of service or the IPv6 traffic class, which
+	// contain the DSCP bits (e.g., 0xb8 for Expedited Forwarding).
+	TOS int
+
+	// TTL is the IPv4 time to live or the IPv6 hop limit.
+	TTL int
+}
+
+// strings returns the description of the options, which we log.
+func (so *SocketOptions) strings() []string {
+	var out []string
+	if so.Mark != 0 {
+		out = append(out, fmt.Sprintf("mark=%d", so.Mark))
+	}
+	if so.TOS != 0 {
+		out = append(out, fmt.Sprintf("tos=%#x", so.TOS))
+	}
+	if so.TTL != 0 {
+		out = append(out, fmt.Sprintf("ttl=%d", so.TTL))
+	}
+	return out
+}
+
+// errBindToDeviceUnsupported indicates that BindToDevice is not supported.
+var errBindToDeviceUnsupported = errors.New("netcore: BindToDevice not supported on this platform")
+
+// errSocketOptionUnsupported indicates that a socket option is not supported.
+var errSocketOptionUnsupported = errors.New("netcore: socket option not supported on this platform")
+
+// boundDevice returns the device to which we bind the sockets, if any.
+//
+// We only bind the sockets we create, so the DialContextFunc disables this feature.
+func (nx *Network) boundDevice() string {
+	if nx.DialContextFunc != nil {
+		return ""
+	}
+	return nx.BindToDevice
+}
+
+// socketOptions returns the description of the socket options we set, if any.
+//
+// We only set the options of the sockets we create, so the DialContextFunc
+// disables this feature.
+func (nx *Network) socketOptions() []string {
+	if nx.DialContextFunc != nil || nx.SocketOptions == nil {
+		return nil
+	}
+	return nx.SocketOptions.strings()
+}
+
+// controlFunc returns the function to apply the socket options
+// before connecting or nil if there are no options to apply.
+func (nx *Network) controlFunc() func(network, address string, c syscall.RawConn) error {
+	device, options, control := nx.BindToDevice, nx.SocketOptions, nx.ControlFunc
+	timestamps := nx.LogKernelTimestamps
+	if device == "" && options == nil && control == nil && !timestamps {
+		return nil
+	}
+	return func(network, address string, c syscall.RawConn) error {
+		var err error
+		if cerr := c.Control(func(fd uintptr) {
+			if device != "" {
+				if err = bindToDevice(fd, network, device); err != nil {
+					return
+				}
+			}
+			if options != nil {
+				if err = setSocketOptions(fd, network, options); err != nil {
+					return
+				}
+			}
+			if timestamps {
+				err = enableKernelTimestamps(fd)
+			}
+		}); cerr != nil {
+			return cerr
+		}
+		if err != nil {
+			return err
+		}
+		if control != nil {
+			return control(network, address, c)
+		}
+		return nil
+	}
+}
+
+// dialerWithControl returns a copy of the dialer also invoking the given
+// control function after the Control or ControlContext of the dialer.
+func dialerWithControl(dialer *net.Dialer,
+	control func(network, address string, c syscall.RawConn) error) *net.Dialer {
+	child := *dialer
+	prevControl, prevControlContext := child.Control, child.ControlContext
+	child.Control = nil
+	child.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		var err error
+		switch {
+		case prevControlContext != nil:
+			err = prevControlContext(ctx, network, address, c)
+		case prevControl != nil:
+			err = prevControl(network, address, c)
+		}
+		if err != nil {
+			return err
+		}
+		return control(network, address, c)
+	}
+	return &child
+}
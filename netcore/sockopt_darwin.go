@@ -0,0 +1,38 @@
+//go:build darwin
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Darwin socket options.
+//
+
+package netcore
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// bindToDevice binds the socket to the given device using IP_BOUND_IF
+// or IPV6_BOUND_IF depending on the address family of the network.
+func bindToDevice(fd uintptr, network, device string) error {
+	iface, err := net.InterfaceByName(device)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(network, "6") {
+		return unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_BOUND_IF, iface.Index)
+	}
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_BOUND_IF, iface.Index)
+}
+
+// setSocketOptions sets the given options, failing with
+// [errSocketOptionUnsupported] when Mark is set.
+func setSocketOptions(fd uintptr, network string, options *SocketOptions) error {
+	if options.Mark != 0 {
+		return errSocketOptionUnsupported
+	}
+	return setIPSocketOptions(int(fd), network, options)
+}
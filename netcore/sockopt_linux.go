@@ -0,0 +1,27 @@
+//go:build linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Linux socket options.
+//
+
+package netcore
+
+import "golang.org/x/sys/unix"
+
+// bindToDevice binds the socket to the given device using SO_BINDTODEVICE.
+func bindToDevice(fd uintptr, network, device string) error {
+	return unix.BindToDevice(int(fd), device)
+}
+
+// setSocketOptions sets the given options including SO_MARK.
+func setSocketOptions(fd uintptr, network string, options *SocketOptions) error {
+	if err := setIPSocketOptions(int(fd), network, options); err != nil {
+		return err
+	}
+	if options.Mark != 0 {
+		return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MARK, options.Mark)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestNetwork_SocketOptionsLinux(t *testing.T) {
+	for _, network := range []string{"udp4", "udp6"} {
+		t.Run(network, func(t *testing.T) {
+			address := "127.0.0.1:9"
+			if network == "udp6" {
+				address = "[::1]:9"
+			}
+
+			var buf bytes.Buffer
+			var tos, ttl int
+			nx := &Network{
+				ControlFunc: func(network, address string, c syscall.RawConn) error {
+					level, tosOpt, ttlOpt := unix.IPPROTO_IP, unix.IP_TOS, unix.IP_TTL
+					if strings.HasSuffix(network, "6") {
+						level, tosOpt, ttlOpt = unix.IPPROTO_IPV6, unix.IPV6_TCLASS, unix.IPV6_UNICAST_HOPS
+					}
+					var err error
+					c.Control(func(fd uintptr) {
+						if tos, err = unix.GetsockoptInt(int(fd), level, tosOpt); err != nil {
+							return
+						}
+						ttl, err = unix.GetsockoptInt(int(fd), level, ttlOpt)
+					})
+					return err
+				},
+				Logger:        slog.New(slog.NewJSONHandler(&buf, nil)),
+				SocketOptions: &SocketOptions{TOS: 0xb8, TTL: 5},
+			}
+			conn, err := nx.DialContext(context.Background(), network, address)
+			if network == "udp6" && err != nil {
+				t.Skip("IPv6 not available")
+			}
+			require.NoError(t, err)
+			conn.Close()
+			assert.Equal(t, 0xb8, tos)
+			assert.Equal(t, 5, ttl)
+
+			logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			var ev ConnectDoneEvent
+			require.NoError(t, json.Unmarshal([]byte(logs[1]), &ev))
+			assert.Equal(t, []string{"tos=0xb8", "ttl=5"}, ev.SocketOptions)
+		})
+	}
+
+	t.Run("we fail when we cannot set an option", func(t *testing.T) {
+		nx := &Network{SocketOptions: &SocketOptions{TTL: 1024}}
+		conn, err := nx.DialContext(context.Background(), "udp4", net.JoinHostPort("127.0.0.1", "9"))
+		assert.ErrorIs(t, err, unix.EINVAL)
+		assert.Nil(t, conn)
+	})
+}
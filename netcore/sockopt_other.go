@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Socket options on other platforms.
+//
+
+package netcore
+
+// bindToDevice fails with [errBindToDeviceUnsupported].
+func bindToDevice(fd uintptr, network, device string) error {
+	return errBindToDeviceUnsupported
+}
+
+// setSocketOptions fails with [errSocketOptionUnsupported].
+func setSocketOptions(fd uintptr, network string, options *SocketOptions) error {
+	return errSocketOptionUnsupported
+}
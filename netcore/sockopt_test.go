@@ -0,0 +1,122 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_dialerWithControl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	t.Run("we invoke the existing control function first", func(t *testing.T) {
+		var calls []string
+		dialer := &net.Dialer{
+			Control: func(network, address string, c syscall.RawConn) error {
+				calls = append(calls, "prev")
+				return nil
+			},
+		}
+		child := dialerWithControl(dialer, func(network, address string, c syscall.RawConn) error {
+			calls = append(calls, "next")
+			return nil
+		})
+		conn, err := child.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+		assert.Equal(t, []string{"prev", "next"}, calls)
+		assert.Nil(t, child.Control)
+		assert.NotNil(t, dialer.Control)
+		assert.Nil(t, dialer.ControlContext)
+	})
+
+	t.Run("we stop at the first error", func(t *testing.T) {
+		expectedErr := errors.New("mocked error")
+		dialer := &net.Dialer{
+			ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+				return expectedErr
+			},
+		}
+		child := dialerWithControl(dialer, func(network, address string, c syscall.RawConn) error {
+			panic("should not be called")
+		})
+		conn, err := child.DialContext(context.Background(), "tcp", listener.Addr().String())
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, conn)
+	})
+}
+
+func TestNetwork_BindToDevice(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	var buf bytes.Buffer
+	nx := &Network{
+		BindToDevice: "nonexistent0",
+		Logger:       slog.New(slog.NewJSONHandler(&buf, nil)),
+	}
+	conn, err := nx.DialContext(context.Background(), "tcp", listener.Addr().String())
+	assert.Error(t, err)
+	assert.Nil(t, conn)
+
+	logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, logs, 2)
+	var ev ConnectDoneEvent
+	require.NoError(t, json.Unmarshal([]byte(logs[1]), &ev))
+	assert.Equal(t, "nonexistent0", ev.Device)
+	assert.NotEmpty(t, ev.Err)
+}
+
+func TestSocketOptions(t *testing.T) {
+	t.Run("we describe the options that are set", func(t *testing.T) {
+		assert.Nil(t, (&SocketOptions{}).strings())
+		options := &SocketOptions{Mark: 7, TOS: 0xb8, TTL: 5}
+		assert.Equal(t, []string{"mark=7", "tos=0xb8", "ttl=5"}, options.strings())
+	})
+
+	t.Run("we invoke the ControlFunc", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		var buf bytes.Buffer
+		var called bool
+		nx := &Network{
+			ControlFunc: func(network, address string, c syscall.RawConn) error {
+				called = true
+				return nil
+			},
+			Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+		assert.True(t, called)
+	})
+
+	t.Run("the DialContextFunc disables the options", func(t *testing.T) {
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return nil, errors.New("mocked error")
+			},
+			SocketOptions: &SocketOptions{TTL: 5},
+		}
+		assert.Nil(t, nx.socketOptions())
+		nx.DialContextFunc = nil
+		assert.Equal(t, []string{"ttl=5"}, nx.socketOptions())
+	})
+}
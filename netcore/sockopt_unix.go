@@ -0,0 +1,35 @@
+//go:build linux || darwin
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// IP socket options on Linux and Darwin.
+//
+
+package netcore
+
+import (
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// setIPSocketOptions sets the TOS and TTL options depending
+// on the address family of the network.
+func setIPSocketOptions(fd int, network string, options *SocketOptions) error {
+	level, tos, ttl := unix.IPPROTO_IP, unix.IP_TOS, unix.IP_TTL
+	if strings.HasSuffix(network, "6") {
+		level, tos, ttl = unix.IPPROTO_IPV6, unix.IPV6_TCLASS, unix.IPV6_UNICAST_HOPS
+	}
+	if options.TOS != 0 {
+		if err := unix.SetsockoptInt(fd, level, tos, options.TOS); err != nil {
+			return err
+		}
+	}
+	if options.TTL != 0 {
+		if err := unix.SetsockoptInt(fd, level, ttl, options.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
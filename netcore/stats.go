@@ -0,0 +1,154 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Per-network statistics.
+//
+
+package netcore
+
+import (
+	"net"
+	"sync/atomic"
+)
+
+// NetworkStats is a snapshot of the statistics of a [*Network].
+type NetworkStats struct {
+	// BytesRead is the number of bytes read from the connections and
+	// the packet conns created by the [*Network], regardless of whether
+	// we are emitting events, including the TLS records.
+	BytesRead int64
+
+	// BytesWritten is the number of bytes written to the connections
+	// and the packet conns created by the [*Network].
+	BytesWritten int64
+
+	// DialsAttempted is the number of TCP/UDP dials we attempted.
+	DialsAttempted int64
+
+	// DialsSucceeded is the number of TCP/UDP dials that succeeded.
+	DialsSucceeded int64
+
+	// HandshakesAttempted is the number of TLS and QUIC handshakes we attempted.
+	HandshakesAttempted int64
+
+	// HandshakesSucceeded is the number of TLS and QUIC handshakes that succeeded.
+	HandshakesSucceeded int64
+
+	// LookupsAttempted is the number of DNS lookups we attempted, excluding
+	// the cases where the domain is already an IP address.
+	LookupsAttempted int64
+
+	// LookupsSucceeded is the number of DNS lookups that succeeded.
+	LookupsSucceeded int64
+}
+
+// networkStats contains the atomic counters of a [*Network].
+type networkStats struct {
+	bytesRead           atomic.Int64
+	bytesWritten        atomic.Int64
+	dialsAttempted      atomic.Int64
+	dialsSucceeded      atomic.Int64
+	handshakesAttempted atomic.Int64
+	handshakesSucceeded atomic.Int64
+	lookupsAttempted    atomic.Int64
+	lookupsSucceeded    atomic.Int64
+}
+
+// countOperation counts an operation attempt and, if the
+// error is nil, also counts the operation as successful.
+func countOperation(attempted, succeeded *atomic.Int64, err error) {
+	attempted.Add(1)
+	if err == nil {
+		succeeded.Add(1)
+	}
+}
+
+// Stats returns a snapshot of the statistics of the [*Network], which
+// count the operations since the [*Network] was created. Because we do
+// not read the counters atomically as a whole, a snapshot taken while
+// operations are in progress may be slightly inconsistent (e.g., the
+// dials that succeeded may momentarily exceed those attempted).
+//
+// This method is goroutine safe.
+func (nx *Network) Stats() NetworkStats {
+	return NetworkStats{
+		BytesRead:           nx.stats.bytesRead.Load(),
+		BytesWritten:        nx.stats.bytesWritten.Load(),
+		DialsAttempted:      nx.stats.dialsAttempted.Load(),
+		DialsSucceeded:      nx.stats.dialsSucceeded.Load(),
+		HandshakesAttempted: nx.stats.handshakesAttempted.Load(),
+		HandshakesSucceeded: nx.stats.handshakesSucceeded.Load(),
+		LookupsAttempted:    nx.stats.lookupsAttempted.Load(),
+		LookupsSucceeded:    nx.stats.lookupsSucceeded.Load(),
+	}
+}
+
+// statsConn wraps a [net.Conn] to count the bytes in the [networkStats].
+type statsConn struct {
+	net.Conn
+	stats *networkStats
+}
+
+// Read implements [net.Conn].
+func (c *statsConn) Read(buf []byte) (int, error) {
+	count, err := c.Conn.Read(buf)
+	c.stats.bytesRead.Add(int64(count))
+	return count, err
+}
+
+// Write implements [net.Conn].
+func (c *statsConn) Write(data []byte) (int, error) {
+	count, err := c.Conn.Write(data)
+	c.stats.bytesWritten.Add(int64(count))
+	return count, err
+}
+
+// newStatsConn wraps a [net.Conn] to count the bytes in the [networkStats],
+// preserving the [net.PacketConn] methods of connected UDP sockets.
+func newStatsConn(conn net.Conn, stats *networkStats) net.Conn {
+	sconn := &statsConn{Conn: conn, stats: stats}
+	if pconn, ok := conn.(net.PacketConn); ok {
+		return &statsConnPacketConn{statsConn: sconn, pconn: pconn}
+	}
+	return sconn
+}
+
+// statsConnPacketConn is a [*statsConn] that is also a [net.PacketConn].
+type statsConnPacketConn struct {
+	*statsConn
+	pconn net.PacketConn
+}
+
+// ReadFrom implements [net.PacketConn].
+func (c *statsConnPacketConn) ReadFrom(buf []byte) (int, net.Addr, error) {
+	count, addr, err := c.pconn.ReadFrom(buf)
+	c.stats.bytesRead.Add(int64(count))
+	return count, addr, err
+}
+
+// WriteTo implements [net.PacketConn].
+func (c *statsConnPacketConn) WriteTo(data []byte, addr net.Addr) (int, error) {
+	count, err := c.pconn.WriteTo(data, addr)
+	c.stats.bytesWritten.Add(int64(count))
+	return count, err
+}
+
+// statsPacketConn wraps a [net.PacketConn] to count the bytes in the [networkStats].
+type statsPacketConn struct {
+	net.PacketConn
+	stats *networkStats
+}
+
+// ReadFrom implements [net.PacketConn].
+func (c *statsPacketConn) ReadFrom(buf []byte) (int, net.Addr, error) {
+	count, addr, err := c.PacketConn.ReadFrom(buf)
+	c.stats.bytesRead.Add(int64(count))
+	return count, addr, err
+}
+
+// WriteTo implements [net.PacketConn].
+func (c *statsPacketConn) WriteTo(data []byte, addr net.Addr) (int, error) {
+	count, err := c.PacketConn.WriteTo(data, addr)
+	c.stats.bytesWritten.Add(int64(count))
+	return count, err
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetworkStats(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	t.Run("the zero value has zero stats", func(t *testing.T) {
+		nx := &Network{}
+		assert.Equal(t, NetworkStats{}, nx.Stats())
+	})
+
+	t.Run("we count the operations", func(t *testing.T) {
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				if domain == "nxdomain.example.com" {
+					return nil, errors.New("no such host")
+				}
+				return []string{"127.0.0.1"}, nil
+			},
+			Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			TLSConfig: &tls.Config{RootCAs: pool, ServerName: "example.com"},
+			WrapConn:  WrapConn,
+		}
+
+		// a successful TLS dial including a lookup
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", net.JoinHostPort("www.example.com", port))
+		require.NoError(t, err)
+		conn.Close()
+
+		// a failed TLS handshake
+		nx.TLSConfig = &tls.Config{ServerName: "example.com"}
+		conn, err = nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		require.Error(t, err)
+		assert.Nil(t, conn)
+
+		// a failed lookup
+		conn, err = nx.DialContext(context.Background(), "tcp", "nxdomain.example.com:443")
+		require.Error(t, err)
+		assert.Nil(t, conn)
+
+		stats := nx.Stats()
+		assert.Equal(t, int64(2), stats.DialsAttempted)
+		assert.Equal(t, int64(2), stats.DialsSucceeded)
+		assert.Equal(t, int64(2), stats.HandshakesAttempted)
+		assert.Equal(t, int64(1), stats.HandshakesSucceeded)
+		assert.Equal(t, int64(2), stats.LookupsAttempted)
+		assert.Equal(t, int64(1), stats.LookupsSucceeded)
+		assert.Greater(t, stats.BytesRead, int64(0))
+		assert.Greater(t, stats.BytesWritten, int64(0))
+	})
+
+	t.Run("we count the bytes without logging", func(t *testing.T) {
+		server, client := net.Pipe()
+		defer server.Close()
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return client, nil
+			},
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:443")
+		require.NoError(t, err)
+		defer conn.Close()
+
+		go server.Write([]byte("abcdef"))
+		buf := make([]byte, 6)
+		_, err = io.ReadFull(conn, buf)
+		require.NoError(t, err)
+
+		go io.ReadAll(server)
+		_, err = conn.Write([]byte("abc"))
+		require.NoError(t, err)
+
+		stats := nx.Stats()
+		assert.Equal(t, int64(6), stats.BytesRead)
+		assert.Equal(t, int64(3), stats.BytesWritten)
+	})
+}
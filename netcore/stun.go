@@ -0,0 +1,283 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// STUN binding requests (see RFC 5389).
+//
+
+package netcore
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// DefaultSTUNTimeout is the default time we wait for the response to
+// the STUN binding request when the context has no deadline.
+const DefaultSTUNTimeout = 5 * time.Second
+
+// STUNBindingResult is the result of [*Network.STUNBinding].
+type STUNBindingResult struct {
+	// LocalAddr is the local endpoint of the UDP socket.
+	LocalAddr string
+
+	// MappedAddr is the server reflexive endpoint, that is, the
+	// endpoint from which the STUN server saw the request coming.
+	MappedAddr string
+
+	// RemoteAddr is the endpoint of the STUN server.
+	RemoteAddr string
+
+	// RTT is the time between sending the request and receiving the response.
+	RTT time.Duration
+}
+
+// STUNBinding sends a STUN binding request to the STUN server at the
+// given address (e.g., "stun.l.google.com:19302") using a UDP socket
+// created by [*Network.DialContext] and returns the server reflexive
+// endpoint, which, compared to the local endpoint, tells whether we
+// are behind a NAT. We emit the "stunBindingStart" and "stunBindingDone"
+// events around the request, with the latter containing the reflexive
+// endpoint in "stunMappedAddr" and the RTT in "stunRttUsec".
+//
+// We send a single request without retransmissions and wait for the
+// response until the context is done or, when the context has no
+// deadline, for [DefaultSTUNTimeout].
+//
+// This method is goroutine safe.
+func (nx *Network) STUNBinding(ctx context.Context, address string) (*STUNBindingResult, error) {
+	// make sure all the events of the socket share the same connection ID
+	ctx = withConnID(ctx)
+
+	// make sure we do not wait forever for the response
+	if _, found := ctx.Deadline(); !found {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultSTUNTimeout)
+		defer cancel()
+	}
+
+	// create the UDP socket
+	conn, err := nx.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// make sure the context interrupts waiting for the response
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+
+	// send the request and wait for the response
+	laddr := connLocalAddr(conn)
+	raddr := connRemoteAddr(conn)
+	t0 := nx.emitSTUNBindingStart(ctx, laddr, raddr)
+	mapped, err := stunRoundTrip(conn)
+	if !stop() && err != nil {
+		err = ctx.Err()
+	}
+	rtt := nx.emitSTUNBindingDone(ctx, laddr, raddr, t0, mapped, err)
+
+	// process the results
+	if err != nil {
+		return nil, err
+	}
+	result := &STUNBindingResult{
+		LocalAddr:  laddr.String(),
+		MappedAddr: mapped,
+		RemoteAddr: raddr.String(),
+		RTT:        rtt,
+	}
+	return result, nil
+}
+
+// These are the STUN protocol constants we use.
+const (
+	stunAttrErrorCode        = 0x0009
+	stunAttrMappedAddress    = 0x0001
+	stunAttrXORMappedAddress = 0x0020
+	stunBindingError         = 0x0111
+	stunBindingRequest       = 0x0001
+	stunBindingSuccess       = 0x0101
+	stunHeaderSize           = 20
+	stunMagicCookie          = 0x2112A442
+)
+
+var (
+	// errSTUNErrorResponse indicates that the STUN server returned an error response.
+	errSTUNErrorResponse = errors.New("netcore: STUN error response")
+
+	// errSTUNInvalidResponse indicates that we cannot parse the STUN response.
+	errSTUNInvalidResponse = errors.New("netcore: invalid STUN response")
+
+	// errSTUNNoMappedAddress indicates that the STUN response lacks the mapped address.
+	errSTUNNoMappedAddress = errors.New("netcore: STUN response without mapped address")
+)
+
+// stunRoundTrip sends a STUN binding request using the given connection
+// and returns the mapped address contained in the response. We ignore
+// the datagrams that are not responses to our request.
+func stunRoundTrip(conn net.Conn) (string, error) {
+	request := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(request[0:2], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	rand.Read(request[8:20])
+	if _, err := conn.Write(request); err != nil {
+		return "", err
+	}
+
+	buffer := make([]byte, 1500)
+	for {
+		count, err := conn.Read(buffer)
+		if err != nil {
+			return "", err
+		}
+		mapped, found, err := stunParseResponse(buffer[:count], request[8:20])
+		if found {
+			return mapped, err
+		}
+	}
+}
+
+// stunParseResponse parses the given STUN response to the request with
+// the given transaction ID and returns the mapped address, whether the
+// datagram is a response to the request, and the error that occurred.
+func stunParseResponse(data, txid []byte) (string, bool, error) {
+	// make sure this is a response to our request
+	if len(data) < stunHeaderSize ||
+		binary.BigEndian.Uint32(data[4:8]) != stunMagicCookie ||
+		string(data[8:20]) != string(txid) {
+		return "", false, nil
+	}
+	msgType := binary.BigEndian.Uint16(data[0:2])
+	if msgType != stunBindingSuccess && msgType != stunBindingError {
+		return "", false, nil
+	}
+	length := int(binary.BigEndian.Uint16(data[2:4]))
+	if length > len(data)-stunHeaderSize {
+		return "", true, errSTUNInvalidResponse
+	}
+
+	// walk through the attributes, which are padded to four bytes
+	var mapped string
+	attrs := data[stunHeaderSize : stunHeaderSize+length]
+	for len(attrs) > 0 {
+		if len(attrs) < 4 {
+			return "", true, errSTUNInvalidResponse
+		}
+		attrType := binary.BigEndian.Uint16(attrs[0:2])
+		attrLen := int(binary.BigEndian.Uint16(attrs[2:4]))
+		if attrLen > len(attrs)-4 {
+			return "", true, errSTUNInvalidResponse
+		}
+		value := attrs[4 : 4+attrLen]
+		attrs = attrs[min(len(attrs), 4+(attrLen+3)&^3):]
+
+		switch attrType {
+		case stunAttrErrorCode:
+			if msgType == stunBindingError && len(value) >= 4 {
+				code := int(value[2]&0x07)*100 + int(value[3])
+				return "", true, fmt.Errorf("%w: %d %s", errSTUNErrorResponse, code, value[4:])
+			}
+
+		case stunAttrXORMappedAddress:
+			endpoint, ok := stunParseAddress(value, data[4:20])
+			if !ok {
+				return "", true, errSTUNInvalidResponse
+			}
+			mapped = endpoint
+
+		case stunAttrMappedAddress:
+			if mapped != "" {
+				continue // prefer the XOR-MAPPED-ADDRESS
+			}
+			endpoint, ok := stunParseAddress(value, nil)
+			if !ok {
+				return "", true, errSTUNInvalidResponse
+			}
+			mapped = endpoint
+		}
+	}
+	if msgType == stunBindingError {
+		return "", true, errSTUNErrorResponse
+	}
+	if mapped == "" {
+		return "", true, errSTUNNoMappedAddress
+	}
+	return mapped, true, nil
+}
+
+// stunParseAddress parses the value of a (XOR-)MAPPED-ADDRESS attribute
+// and returns the corresponding endpoint. When mask is not nil, it
+// contains the magic cookie followed by the transaction ID, which we
+// use to decode the XOR-MAPPED-ADDRESS attribute.
+func stunParseAddress(value, mask []byte) (string, bool) {
+	if len(value) < 4 {
+		return "", false
+	}
+	var size int
+	switch value[1] {
+	case 0x01:
+		size = net.IPv4len
+	case 0x02:
+		size = net.IPv6len
+	default:
+		return "", false
+	}
+	if len(value) != 4+size {
+		return "", false
+	}
+	port := binary.BigEndian.Uint16(value[2:4])
+	ip := make([]byte, size)
+	copy(ip, value[4:])
+	if mask != nil {
+		port ^= uint16(stunMagicCookie >> 16)
+		for idx := range ip {
+			ip[idx] ^= mask[idx]
+		}
+	}
+	addr, _ := netip.AddrFromSlice(ip)
+	return netip.AddrPortFrom(addr, port).String(), true
+}
+
+// emitSTUNBindingStart emits a STUN binding start event.
+func (nx *Network) emitSTUNBindingStart(ctx context.Context, laddr, raddr net.Addr) time.Time {
+	t0 := nx.timeNow()
+	if nx.emitEnabled() {
+		nx.emit(ctx, &STUNBindingStartEvent{
+			ConnID:     connIDFromContext(ctx),
+			LocalAddr:  laddr.String(),
+			Protocol:   laddr.Network(),
+			RemoteAddr: raddr.String(),
+			T:          t0,
+		})
+	}
+	return t0
+}
+
+// emitSTUNBindingDone emits a STUN binding done event and returns the RTT.
+func (nx *Network) emitSTUNBindingDone(ctx context.Context,
+	laddr, raddr net.Addr, t0 time.Time, mapped string, err error) time.Duration {
+	t := nx.timeNow()
+	rtt := t.Sub(t0)
+	if nx.emitEnabled() {
+		nx.emit(ctx, &STUNBindingDoneEvent{
+			ConnID:         connIDFromContext(ctx),
+			Err:            errString(err),
+			ErrClass:       nx.errClass(err),
+			LocalAddr:      laddr.String(),
+			Protocol:       laddr.Network(),
+			RemoteAddr:     raddr.String(),
+			STUNMappedAddr: mapped,
+			STUNRTTUsec:    rtt.Microseconds(),
+			T0:             t0,
+			T:              t,
+		})
+	}
+	return rtt
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stunTestResponse returns the STUN response to the given request.
+func stunTestResponse(request []byte, msgType uint16, attrs ...[]byte) []byte {
+	response := make([]byte, stunHeaderSize)
+	binary.BigEndian.PutUint16(response[0:2], msgType)
+	copy(response[4:20], request[4:20])
+	for _, attr := range attrs {
+		response = append(response, attr...)
+	}
+	binary.BigEndian.PutUint16(response[2:4], uint16(len(response)-stunHeaderSize))
+	return response
+}
+
+// stunTestAttr returns a STUN attribute padded to four bytes.
+func stunTestAttr(attrType uint16, value []byte) []byte {
+	attr := binary.BigEndian.AppendUint16(nil, attrType)
+	attr = binary.BigEndian.AppendUint16(attr, uint16(len(value)))
+	attr = append(attr, value...)
+	for len(attr)%4 != 0 {
+		attr = append(attr, 0)
+	}
+	return attr
+}
+
+// stunTestXORMappedAddress returns the XOR-MAPPED-ADDRESS attribute for the given IPv4 endpoint.
+func stunTestXORMappedAddress(addr *net.UDPAddr) []byte {
+	value := []byte{0x00, 0x01}
+	value = binary.BigEndian.AppendUint16(value, uint16(addr.Port)^uint16(stunMagicCookie>>16))
+	value = binary.BigEndian.AppendUint32(value, binary.BigEndian.Uint32(addr.IP.To4())^stunMagicCookie)
+	return stunTestAttr(stunAttrXORMappedAddress, value)
+}
+
+// startSTUNTestServer starts a UDP server answering each request using the
+// given function, which may return nil to ignore the request.
+func startSTUNTestServer(t *testing.T, respond func(request []byte, addr *net.UDPAddr) [][]byte) string {
+	pconn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { pconn.Close() })
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			count, addr, err := pconn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			for _, response := range respond(buffer[:count], addr.(*net.UDPAddr)) {
+				pconn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return pconn.LocalAddr().String()
+}
+
+func TestNetwork_STUNBinding(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		address := startSTUNTestServer(t, func(request []byte, addr *net.UDPAddr) [][]byte {
+			stray := stunTestResponse(make([]byte, stunHeaderSize), stunBindingSuccess)
+			return [][]byte{stray, stunTestResponse(request, stunBindingSuccess, stunTestXORMappedAddress(addr))}
+		})
+
+		var buf bytes.Buffer
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+		result, err := nx.STUNBinding(context.Background(), address)
+		require.NoError(t, err)
+		assert.Equal(t, result.LocalAddr, result.MappedAddr)
+		assert.Equal(t, address, result.RemoteAddr)
+		assert.True(t, result.RTT > 0)
+
+		var found bool
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "stunBindingDone" {
+				assert.Equal(t, result.MappedAddr, ev["stunMappedAddr"])
+				assert.Equal(t, float64(result.RTT.Microseconds()), ev["stunRttUsec"])
+				assert.Nil(t, ev["err"])
+				found = true
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("error response", func(t *testing.T) {
+		address := startSTUNTestServer(t, func(request []byte, addr *net.UDPAddr) [][]byte {
+			value := append([]byte{0x00, 0x00, 4, 20}, "Unknown Attribute"...)
+			return [][]byte{stunTestResponse(request, stunBindingError, stunTestAttr(stunAttrErrorCode, value))}
+		})
+		nx := &Network{}
+		result, err := nx.STUNBinding(context.Background(), address)
+		assert.ErrorIs(t, err, errSTUNErrorResponse)
+		assert.ErrorContains(t, err, "420 Unknown Attribute")
+		assert.Nil(t, result)
+	})
+
+	t.Run("response without mapped address", func(t *testing.T) {
+		address := startSTUNTestServer(t, func(request []byte, addr *net.UDPAddr) [][]byte {
+			return [][]byte{stunTestResponse(request, stunBindingSuccess)}
+		})
+		nx := &Network{}
+		_, err := nx.STUNBinding(context.Background(), address)
+		assert.ErrorIs(t, err, errSTUNNoMappedAddress)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		address := startSTUNTestServer(t, func(request []byte, addr *net.UDPAddr) [][]byte {
+			return nil
+		})
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		nx := &Network{}
+		_, err := nx.STUNBinding(ctx, address)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func Test_stunParseResponse(t *testing.T) {
+	request := make([]byte, stunHeaderSize)
+	copy(request[8:20], "0123456789ab")
+	binary.BigEndian.PutUint32(request[4:8], stunMagicCookie)
+	txid := request[8:20]
+
+	t.Run("we prefer the XOR-MAPPED-ADDRESS", func(t *testing.T) {
+		mapped := stunTestAttr(stunAttrMappedAddress, []byte{0x00, 0x01, 0x00, 0x50, 10, 0, 0, 1})
+		xored := stunTestXORMappedAddress(&net.UDPAddr{IP: net.IPv4(130, 192, 91, 211), Port: 443})
+		addr, found, err := stunParseResponse(stunTestResponse(request, stunBindingSuccess, xored, mapped), txid)
+		require.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, "130.192.91.211:443", addr)
+	})
+
+	t.Run("we fall back to the MAPPED-ADDRESS", func(t *testing.T) {
+		mapped := stunTestAttr(stunAttrMappedAddress, []byte{0x00, 0x01, 0x00, 0x50, 10, 0, 0, 1})
+		addr, _, err := stunParseResponse(stunTestResponse(request, stunBindingSuccess, mapped), txid)
+		require.NoError(t, err)
+		assert.Equal(t, "10.0.0.1:80", addr)
+	})
+
+	t.Run("we ignore responses to other requests", func(t *testing.T) {
+		_, found, err := stunParseResponse(stunTestResponse(request, stunBindingSuccess), []byte("ba9876543210"))
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("truncated attribute", func(t *testing.T) {
+		response := stunTestResponse(request, stunBindingSuccess, []byte{0x00, 0x20, 0x00, 0x08, 0x00})
+		_, found, err := stunParseResponse(response, txid)
+		assert.True(t, found)
+		assert.ErrorIs(t, err, errSTUNInvalidResponse)
+	})
+
+	t.Run("invalid address family", func(t *testing.T) {
+		attr := stunTestAttr(stunAttrXORMappedAddress, []byte{0x00, 0x03, 0x00, 0x50, 10, 0, 0, 1})
+		_, _, err := stunParseResponse(stunTestResponse(request, stunBindingSuccess, attr), txid)
+		assert.ErrorIs(t, err, errSTUNInvalidResponse)
+	})
+}
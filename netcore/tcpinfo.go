@@ -0,0 +1,39 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// TCP_INFO metrics.
+//
+
+package netcore
+
+import "net"
+
+// tcpInfo contains the TCP_INFO metrics we include into the "connStats" event.
+type tcpInfo struct {
+	// deliveryRate is the delivery rate in bytes per second.
+	deliveryRate int64
+
+	// rttUsec is the smoothed RTT in microseconds.
+	rttUsec int64
+
+	// rttVarUsec is the RTT variance in microseconds.
+	rttVarUsec int64
+
+	// totalRetrans is the total number of retransmitted segments.
+	totalRetrans int64
+}
+
+// readTCPInfo reads the TCP_INFO metrics of the given connection, returning
+// false when the connection is not a TCP connection we can inspect (e.g., when
+// using a DialContextFunc) or the platform does not support TCP_INFO.
+func readTCPInfo(conn net.Conn) (tcpInfo, bool) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return tcpInfo{}, false
+	}
+	rawConn, err := tcpConn.SyscallConn()
+	if err != nil {
+		return tcpInfo{}, false
+	}
+	return readTCPInfoRawConn(rawConn)
+}
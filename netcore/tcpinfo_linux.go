@@ -0,0 +1,34 @@
+//go:build linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Linux TCP_INFO metrics.
+//
+
+package netcore
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// readTCPInfoRawConn reads the TCP_INFO metrics using getsockopt.
+func readTCPInfoRawConn(rawConn syscall.RawConn) (tcpInfo, bool) {
+	var (
+		info *unix.TCPInfo
+		err  error
+	)
+	if cerr := rawConn.Control(func(fd uintptr) {
+		info, err = unix.GetsockoptTCPInfo(int(fd), unix.IPPROTO_TCP, unix.TCP_INFO)
+	}); cerr != nil || err != nil {
+		return tcpInfo{}, false
+	}
+	return tcpInfo{
+		deliveryRate: int64(info.Delivery_rate),
+		rttUsec:      int64(info.Rtt),
+		rttVarUsec:   int64(info.Rttvar),
+		totalRetrans: int64(info.Total_retrans),
+	}, true
+}
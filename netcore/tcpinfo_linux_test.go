@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_LogTCPInfo(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			io.Copy(conn, conn)
+			conn.Close()
+		}
+	}()
+
+	// connStats returns the "connStats" event after echoing some data.
+	connStats := func(t *testing.T, logTCPInfo bool) *ConnStatsEvent {
+		var buf bytes.Buffer
+		nx := &Network{
+			LogTCPInfo: logTCPInfo,
+			Logger:     slog.New(slog.NewJSONHandler(&buf, nil)),
+			WrapConn:   WrapConn,
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", listener.Addr().String())
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("hello"))
+		require.NoError(t, err)
+		_, err = io.ReadFull(conn, make([]byte, 5))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+
+		logs := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		ev := &ConnStatsEvent{}
+		require.NoError(t, json.Unmarshal([]byte(logs[len(logs)-1]), ev))
+		return ev
+	}
+
+	t.Run("we include TCP_INFO when enabled", func(t *testing.T) {
+		ev := connStats(t, true)
+		assert.True(t, ev.TCPInfo)
+		assert.Greater(t, ev.TCPRTTUsec, int64(0))
+	})
+
+	t.Run("we do not include TCP_INFO by default", func(t *testing.T) {
+		ev := connStats(t, false)
+		assert.False(t, ev.TCPInfo)
+		assert.Equal(t, int64(0), ev.TCPRTTUsec)
+		assert.Equal(t, int64(0), ev.TCPDeliveryRate)
+	})
+
+	t.Run("we skip connections that are not TCP connections", func(t *testing.T) {
+		_, ok := readTCPInfo(&mocks.Conn{})
+		assert.False(t, ok)
+	})
+}
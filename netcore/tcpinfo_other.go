@@ -0,0 +1,16 @@
+//go:build !linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// TCP_INFO metrics on other platforms.
+//
+
+package netcore
+
+import "syscall"
+
+// readTCPInfoRawConn returns false because we only support TCP_INFO on Linux.
+func readTCPInfoRawConn(rawConn syscall.RawConn) (tcpInfo, bool) {
+	return tcpInfo{}, false
+}
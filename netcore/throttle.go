@@ -0,0 +1,293 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Throughput-throttled connections.
+//
+
+package netcore
+
+import (
+	"context"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting the throughput in bytes per
+// second, which allows measurement tools to cap their bandwidth usage on
+// metered links. Sharing a [*RateLimiter] among several connections
+// limits their aggregate throughput.
+//
+// The zero value does not limit the throughput. A [*RateLimiter] is
+// safe for concurrent use by multiple goroutines.
+type RateLimiter struct {
+	// BytesPerSecond is the rate at which we refill the bucket. If this
+	// field is zero or negative, we do not limit the throughput.
+	BytesPerSecond int64
+
+	// Burst is the optional capacity of the bucket, that is, the number
+	// of bytes we may transfer without waiting after being idle. If this
+	// field is zero or negative, we use BytesPerSecond.
+	Burst int64
+
+	// last is the last time we refilled the bucket.
+	last time.Time
+
+	// mu protects last and tokens.
+	mu sync.Mutex
+
+	// tokens is the number of tokens in the bucket, which is negative
+	// when the operations consumed tokens in advance.
+	tokens float64
+}
+
+// burst returns the capacity of the bucket.
+func (rl *RateLimiter) burst() int64 {
+	if rl.Burst > 0 {
+		return rl.Burst
+	}
+	return rl.BytesPerSecond
+}
+
+// enabled returns whether the rate limiter limits the throughput.
+func (rl *RateLimiter) enabled() bool {
+	return rl != nil && rl.BytesPerSecond > 0
+}
+
+// reserve consumes the given number of tokens at the given time and
+// returns how long to wait before the tokens are available.
+func (rl *RateLimiter) reserve(count int, now time.Time) time.Duration {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rate, burst := float64(rl.BytesPerSecond), float64(rl.burst())
+	if rl.last.IsZero() {
+		rl.last, rl.tokens = now, burst
+	}
+	if elapsed := now.Sub(rl.last); elapsed > 0 {
+		rl.tokens = min(burst, rl.tokens+elapsed.Seconds()*rate)
+		rl.last = now
+	}
+	rl.tokens -= float64(count)
+	if rl.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rl.tokens / rate * float64(time.Second))
+}
+
+// Wait blocks until the given number of bytes is available or the context
+// is done, in which case we return the context error. The bytes count as
+// consumed in any case. If the rate limiter is nil or does not limit the
+// throughput, we return immediately.
+//
+// This method is goroutine safe.
+func (rl *RateLimiter) Wait(ctx context.Context, count int) error {
+	if !rl.enabled() || count <= 0 {
+		return nil
+	}
+	if delay := rl.reserve(count, time.Now()); delay > 0 {
+		return sleepContext(ctx, delay)
+	}
+	return nil
+}
+
+// ThrottleConn wraps a [net.Conn] to limit the throughput of its reads
+// and writes using the given, optional, [*RateLimiter] instances.
+//
+// We wait for the tokens after each read, since we only know the number of
+// bytes afterwards, and before each write. For stream connections, we split
+// the writes into chunks not larger than the Burst, such that we transfer
+// the data at the configured rate rather than waiting for the whole write
+// upfront. We never split the writes of UDP connections, to keep the
+// datagram boundaries, so a datagram may consume more tokens than the Burst.
+// The waits honour the deadlines of the connection and stop when we close
+// the connection, in which case we return [os.ErrDeadlineExceeded] or
+// [net.ErrClosed], respectively.
+//
+// Use [ThrottledWrapConn] to also emit the structured logs.
+func ThrottleConn(conn net.Conn, readLimiter, writeLimiter *RateLimiter) net.Conn {
+	if !readLimiter.enabled() && !writeLimiter.enabled() {
+		return conn
+	}
+	return &throttledConn{
+		Conn:         conn,
+		changed:      make(chan struct{}),
+		closed:       make(chan struct{}),
+		datagram:     throttleIsDatagram(conn),
+		readLimiter:  readLimiter,
+		writeLimiter: writeLimiter,
+	}
+}
+
+// throttleIsDatagram returns whether the connection uses UDP.
+func throttleIsDatagram(conn net.Conn) bool {
+	addr := conn.LocalAddr()
+	return addr != nil && strings.HasPrefix(addr.Network(), "udp")
+}
+
+// ThrottledWrapConn returns a function suitable for the WrapConn field of
+// a [*Network] that throttles the connections using [ThrottleConn] on top
+// of the structured logs emitted by [WrapConn], so that the read and write
+// events measure the network operations without the throttling delays.
+//
+// Since the [*Network] only invokes WrapConn when emitting events, use
+// [ThrottleConn] directly to throttle the connections without events.
+func ThrottledWrapConn(readLimiter, writeLimiter *RateLimiter) func(
+	ctx context.Context, netx *Network, conn net.Conn) net.Conn {
+	return func(ctx context.Context, netx *Network, conn net.Conn) net.Conn {
+		return ThrottleConn(WrapConn(ctx, netx, conn), readLimiter, writeLimiter)
+	}
+}
+
+// throttledConn is the [net.Conn] returned by [ThrottleConn].
+type throttledConn struct {
+	net.Conn
+
+	// changed is closed and replaced when the deadlines change.
+	changed chan struct{}
+
+	// closed is closed by Close.
+	closed chan struct{}
+
+	// closeOnce ensures we close closed just once.
+	closeOnce sync.Once
+
+	// datagram indicates that we must not split the writes.
+	datagram bool
+
+	// mu protects changed, readDeadline, and writeDeadline.
+	mu sync.Mutex
+
+	// readDeadline is the read deadline or zero.
+	readDeadline time.Time
+
+	// readLimiter is the optional read [*RateLimiter].
+	readLimiter *RateLimiter
+
+	// writeDeadline is the write deadline or zero.
+	writeDeadline time.Time
+
+	// writeLimiter is the optional write [*RateLimiter].
+	writeLimiter *RateLimiter
+}
+
+// Read implements [net.Conn].
+func (c *throttledConn) Read(buf []byte) (int, error) {
+	count, err := c.Conn.Read(buf)
+	if werr := c.wait(c.readLimiter, count, c.deadline(true)); werr != nil && err == nil {
+		err = werr
+	}
+	return count, err
+}
+
+// Write implements [net.Conn].
+func (c *throttledConn) Write(data []byte) (int, error) {
+	chunkSize := len(data)
+	if c.writeLimiter.enabled() && !c.datagram {
+		chunkSize = int(min(int64(chunkSize), c.writeLimiter.burst()))
+	}
+	var total int
+	for {
+		chunk := data[total:min(total+chunkSize, len(data))]
+		if err := c.wait(c.writeLimiter, len(chunk), c.deadline(false)); err != nil {
+			return total, err
+		}
+		count, err := c.Conn.Write(chunk)
+		total += count
+		if err != nil || total >= len(data) {
+			return total, err
+		}
+	}
+}
+
+// Close implements [net.Conn].
+func (c *throttledConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+// SetDeadline implements [net.Conn].
+func (c *throttledConn) SetDeadline(t time.Time) error {
+	c.setDeadlines(t, true, true)
+	return c.Conn.SetDeadline(t)
+}
+
+// SetReadDeadline implements [net.Conn].
+func (c *throttledConn) SetReadDeadline(t time.Time) error {
+	c.setDeadlines(t, true, false)
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline implements [net.Conn].
+func (c *throttledConn) SetWriteDeadline(t time.Time) error {
+	c.setDeadlines(t, false, true)
+	return c.Conn.SetWriteDeadline(t)
+}
+
+// setDeadlines updates the given deadlines and wakes up the pending waits.
+func (c *throttledConn) setDeadlines(t time.Time, read, write bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if read {
+		c.readDeadline = t
+	}
+	if write {
+		c.writeDeadline = t
+	}
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// deadline returns a function returning the read or write deadline
+// and a channel closed when the deadlines change.
+func (c *throttledConn) deadline(read bool) func() (time.Time, <-chan struct{}) {
+	return func() (time.Time, <-chan struct{}) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if read {
+			return c.readDeadline, c.changed
+		}
+		return c.writeDeadline, c.changed
+	}
+}
+
+// wait waits for the given number of bytes using the given [*RateLimiter]
+// until the connection is closed or the deadline returned by the given
+// function expires, which we check again whenever the deadlines change.
+func (c *throttledConn) wait(rl *RateLimiter,
+	count int, deadline func() (time.Time, <-chan struct{})) error {
+	if !rl.enabled() || count <= 0 {
+		return nil
+	}
+	delay := rl.reserve(count, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	dtimer := time.NewTimer(time.Hour)
+	defer dtimer.Stop()
+	for {
+		var expired <-chan time.Time
+		t, changed := deadline()
+		if !t.IsZero() {
+			remaining := time.Until(t)
+			if remaining <= 0 {
+				return os.ErrDeadlineExceeded
+			}
+			dtimer.Reset(remaining) // since go1.23, no need to drain the channel
+			expired = dtimer.C
+		}
+		select {
+		case <-timer.C:
+			return nil
+		case <-c.closed:
+			return net.ErrClosed
+		case <-expired:
+			return os.ErrDeadlineExceeded
+		case <-changed:
+			// loop and check the new deadline
+		}
+	}
+}
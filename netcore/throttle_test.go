@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("we allow the burst and then wait", func(t *testing.T) {
+		rl := &RateLimiter{BytesPerSecond: 1000, Burst: 500}
+		assert.Equal(t, time.Duration(0), rl.reserve(500, t0))
+		assert.Equal(t, 100*time.Millisecond, rl.reserve(100, t0))
+		assert.Equal(t, 200*time.Millisecond, rl.reserve(100, t0))
+	})
+
+	t.Run("we refill the bucket up to the burst", func(t *testing.T) {
+		rl := &RateLimiter{BytesPerSecond: 1000}
+		assert.Equal(t, time.Duration(0), rl.reserve(1000, t0))
+		assert.Equal(t, time.Duration(0), rl.reserve(500, t0.Add(500*time.Millisecond)))
+		assert.Equal(t, time.Duration(0), rl.reserve(1000, t0.Add(time.Hour)))
+		assert.Equal(t, time.Second, rl.reserve(1000, t0.Add(time.Hour)))
+	})
+
+	t.Run("we do not wait without a rate", func(t *testing.T) {
+		var rl *RateLimiter
+		assert.NoError(t, rl.Wait(context.Background(), 1<<20))
+		assert.NoError(t, (&RateLimiter{}).Wait(context.Background(), 1<<20))
+	})
+
+	t.Run("we stop waiting when the context is done", func(t *testing.T) {
+		rl := &RateLimiter{BytesPerSecond: 1}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, rl.Wait(ctx, 10), context.Canceled)
+	})
+}
+
+func TestThrottleConn(t *testing.T) {
+	newMockConn := func() *mocks.Conn {
+		return &mocks.Conn{
+			MockRead:       func(b []byte) (int, error) { return len(b), nil },
+			MockWrite:      func(b []byte) (int, error) { return len(b), nil },
+			MockClose:      func() error { return nil },
+			MockLocalAddr:  func() net.Addr { return &net.TCPAddr{} },
+			MockRemoteAddr: func() net.Addr { return &net.TCPAddr{} },
+		}
+	}
+
+	t.Run("we do not wrap without rate limiters", func(t *testing.T) {
+		conn := newMockConn()
+		assert.Same(t, conn, ThrottleConn(conn, nil, &RateLimiter{}))
+	})
+
+	t.Run("we throttle reads and writes", func(t *testing.T) {
+		limiter := &RateLimiter{BytesPerSecond: 10000, Burst: 100}
+		conn := ThrottleConn(newMockConn(), limiter, limiter)
+		buf := make([]byte, 100)
+
+		t0 := time.Now()
+		count, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, 100, count)
+		count, err = conn.Write(buf)
+		require.NoError(t, err)
+		assert.Equal(t, 100, count)
+		count, err = conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, 100, count)
+		assert.GreaterOrEqual(t, time.Since(t0), 15*time.Millisecond)
+	})
+
+	t.Run("we split the writes of stream connections", func(t *testing.T) {
+		var sizes []int
+		conn := newMockConn()
+		conn.MockWrite = func(b []byte) (int, error) {
+			sizes = append(sizes, len(b))
+			return len(b), nil
+		}
+		limiter := &RateLimiter{BytesPerSecond: 1 << 20, Burst: 100}
+		count, err := ThrottleConn(conn, nil, limiter).Write(make([]byte, 250))
+		require.NoError(t, err)
+		assert.Equal(t, 250, count)
+		assert.Equal(t, []int{100, 100, 50}, sizes)
+	})
+
+	t.Run("we do not split the writes of UDP connections", func(t *testing.T) {
+		var sizes []int
+		conn := newMockConn()
+		conn.MockLocalAddr = func() net.Addr { return &net.UDPAddr{} }
+		conn.MockWrite = func(b []byte) (int, error) {
+			sizes = append(sizes, len(b))
+			return len(b), nil
+		}
+		limiter := &RateLimiter{BytesPerSecond: 1 << 20, Burst: 100}
+		count, err := ThrottleConn(conn, nil, limiter).Write(make([]byte, 250))
+		require.NoError(t, err)
+		assert.Equal(t, 250, count)
+		assert.Equal(t, []int{250}, sizes)
+	})
+
+	t.Run("we stop waiting when closing the connection", func(t *testing.T) {
+		limiter := &RateLimiter{BytesPerSecond: 100}
+		conn := ThrottleConn(newMockConn(), nil, limiter)
+		time.AfterFunc(50*time.Millisecond, func() { conn.Close() })
+		count, err := conn.Write(make([]byte, 1000))
+		assert.ErrorIs(t, err, net.ErrClosed)
+		assert.Equal(t, 100, count)
+	})
+
+	t.Run("we stop waiting when the deadline expires", func(t *testing.T) {
+		conn := newMockConn()
+		conn.MockSetDeadline = func(t time.Time) error { return nil }
+		limiter := &RateLimiter{BytesPerSecond: 100}
+		tconn := ThrottleConn(conn, limiter, limiter)
+		require.NoError(t, tconn.SetDeadline(time.Now().Add(50*time.Millisecond)))
+		count, err := tconn.Read(make([]byte, 1000))
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		assert.Equal(t, 1000, count)
+		count, err = tconn.Write(make([]byte, 1000))
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		assert.Equal(t, 0, count)
+	})
+
+	t.Run("we honour the deadlines set while waiting", func(t *testing.T) {
+		conn := newMockConn()
+		conn.MockSetWriteDeadline = func(t time.Time) error { return nil }
+		limiter := &RateLimiter{BytesPerSecond: 100}
+		tconn := ThrottleConn(conn, nil, limiter)
+		time.AfterFunc(50*time.Millisecond, func() { tconn.SetWriteDeadline(time.Now()) })
+		t0 := time.Now()
+		_, err := tconn.Write(make([]byte, 1000))
+		assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+		assert.Less(t, time.Since(t0), 5*time.Second)
+	})
+
+	t.Run("we keep the standard event stream", func(t *testing.T) {
+		var buf bytes.Buffer
+		limiter := &RateLimiter{BytesPerSecond: 1 << 20}
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return newMockConn(), nil
+			},
+			Logger:   slog.New(slog.NewJSONHandler(&buf, nil)),
+			WrapConn: ThrottledWrapConn(limiter, limiter),
+		}
+		conn, err := nx.DialContext(context.Background(), "tcp", "127.0.0.1:80")
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("GET / HTTP/1.0\r\n\r\n"))
+		require.NoError(t, err)
+		require.NoError(t, conn.Close())
+
+		for _, name := range []string{"writeStart", "writeDone", "closeDone", "connStats"} {
+			assert.True(t, strings.Contains(buf.String(), `"msg":"`+name+`"`), name)
+		}
+	})
+}
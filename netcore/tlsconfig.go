@@ -13,13 +13,33 @@ import (
 )
 
 // tlsConfig either returns the (cloned) [*tls.Config] from the [Network] or
-// creates a new one by invoking the [newTLSConfig] function.
+// creates a new one by invoking the [newTLSConfig] function, in which case we
+// override the ALPN using the ALPNForEndpoint field, if set.
 func (nx *Network) tlsConfig(network, address string) (*tls.Config, error) {
+	var config *tls.Config
 	if nx.TLSConfig != nil {
-		config := nx.TLSConfig.Clone() // make sure we return a cloned config
-		return config, nil
+		config = nx.TLSConfig.Clone() // make sure we return a cloned config
+	} else {
+		var err error
+		if config, err = newTLSConfig(network, address, nx.RootCAs); err != nil {
+			return nil, err
+		}
+		if nx.ALPNForEndpoint != nil {
+			if protos := nx.ALPNForEndpoint(network, address); protos != nil {
+				config.NextProtos = protos
+			}
+		}
+	}
+	nx.maybeSetKeyLogWriter(config)
+	return config, nil
+}
+
+// maybeSetKeyLogWriter sets the KeyLogWriter of the given config
+// when the TLSKeyLogWriter field of the [Network] is not nil.
+func (nx *Network) maybeSetKeyLogWriter(config *tls.Config) {
+	if nx.TLSKeyLogWriter != nil {
+		config.KeyLogWriter = nx.TLSKeyLogWriter
 	}
-	return newTLSConfig(network, address, nx.RootCAs)
 }
 
 // newTLSConfig is a best-effort attempt at creating a suitable TLS config
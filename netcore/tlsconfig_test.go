@@ -3,8 +3,12 @@
 package netcore
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,6 +52,47 @@ func TestNetwork_tlsConfig(t *testing.T) {
 		assert.Contains(t, config.NextProtos, "http/1.1")
 	})
 
+	t.Run("uses ALPNForEndpoint when set", func(t *testing.T) {
+		nx := &Network{
+			ALPNForEndpoint: func(network, address string) []string {
+				switch {
+				case network == "tcp" && address == "example.com:8443":
+					return []string{"h2"}
+				case network == "udp" && address == "dns.example.com:4443":
+					return []string{"doq"}
+				default:
+					return nil
+				}
+			},
+		}
+
+		config, err := nx.tlsConfig("tcp", "example.com:8443")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"h2"}, config.NextProtos)
+
+		config, err = nx.tlsConfig("udp", "dns.example.com:4443")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"doq"}, config.NextProtos)
+
+		// returning nil means using the default ALPN
+		config, err = nx.tlsConfig("udp", "example.com:443")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"h3"}, config.NextProtos)
+	})
+
+	t.Run("ignores ALPNForEndpoint with TLSConfig", func(t *testing.T) {
+		nx := &Network{
+			ALPNForEndpoint: func(network, address string) []string {
+				return []string{"h2"}
+			},
+			TLSConfig: &tls.Config{NextProtos: []string{"http/1.1"}},
+		}
+
+		config, err := nx.tlsConfig("tcp", "example.com:8443")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"http/1.1"}, config.NextProtos)
+	})
+
 	t.Run("passes root CAs to newTLSConfig", func(t *testing.T) {
 		// Create a mock cert pool
 		pool := x509.NewCertPool()
@@ -62,6 +107,33 @@ func TestNetwork_tlsConfig(t *testing.T) {
 		// Verify the root CAs were passed through
 		assert.Same(t, pool, config.RootCAs)
 	})
+
+	t.Run("sets the key log writer", func(t *testing.T) {
+		var buf bytes.Buffer
+		for _, nx := range []*Network{
+			{TLSKeyLogWriter: &buf},
+			{TLSKeyLogWriter: &buf, TLSConfig: &tls.Config{ServerName: "example.com"}},
+		} {
+			config, err := nx.tlsConfig("tcp", "example.com:443")
+			require.NoError(t, err)
+			assert.Same(t, &buf, config.KeyLogWriter)
+		}
+	})
+
+	t.Run("we write the TLS secrets when handshaking", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		nx := &Network{
+			TLSConfig:       &tls.Config{InsecureSkipVerify: true},
+			TLSKeyLogWriter: &buf,
+		}
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+		assert.Contains(t, buf.String(), "CLIENT_HANDSHAKE_TRAFFIC_SECRET ")
+	})
 }
 
 func TestNewTLSConfig(t *testing.T) {
@@ -12,12 +12,10 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"errors"
-	"log/slog"
 	"net"
 	"time"
-
-	"github.com/rbmk-project/common/errclass"
 )
 
 // TLSConn is the interface implementing [*tls.Conn] as well as
@@ -29,6 +27,9 @@ type TLSConn interface {
 }
 
 // DialTLSContext establishes a new TLS connection.
+//
+// The returned [net.Conn] is a [TLSConn]. See also [*Network.DialTLSConnContext]
+// and [TLSConnectionState] for inspecting the negotiated TLS state.
 func (nx *Network) DialTLSContext(ctx context.Context, network, address string) (net.Conn, error) {
 	// obtain the TLS config to use
 	config, err := nx.tlsConfig(network, address)
@@ -36,17 +37,79 @@ func (nx *Network) DialTLSContext(ctx context.Context, network, address string)
 		return nil, err
 	}
 
+	// build a TLS dialer
+	td := &tlsDialer{config: config, netx: nx}
+
+	// tunnel through the proxy, if configured, and handshake with the target
+	if nx.usesProxy(network) {
+		return nx.dialProxy(ctx, network, address, func(ctx context.Context, conn net.Conn) (net.Conn, error) {
+			return td.handshake(ctx, conn, network, address)
+		})
+	}
+
 	// resolve the endpoints to connect to
-	endpoints, err := nx.maybeLookupEndpoint(ctx, address)
+	endpoints, err := nx.LookupEndpoint(ctx, address)
 	if err != nil {
 		return nil, err
 	}
 
-	// build a TLS dialer
+	// only keep the endpoints matching the address family
+	endpoints, err = nx.filterEndpoints(network, endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	// attempt with the available endpoints according to the dial policy
+	return nx.dialEndpoints(ctx, network, td.dial, endpoints...)
+}
+
+// DialTLSContextWithAddrs is like [*Network.DialContextWithAddrs] but
+// establishes a new TLS connection, using the domain name for the TLS
+// config (e.g., for the SNI) unless TLSConfig is set.
+//
+// This method is goroutine safe.
+func (nx *Network) DialTLSContextWithAddrs(ctx context.Context,
+	network, domain, port string, addrs []string) (net.Conn, error) {
+	// obtain the TLS config to use
+	address := net.JoinHostPort(domain, port)
+	config, err := nx.tlsConfig(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// obtain the endpoints to connect to
+	endpoints, err := nx.endpointsWithAddrs(network, domain, port, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	// attempt with the available endpoints according to the dial policy
 	td := &tlsDialer{config: config, netx: nx}
+	return nx.dialEndpoints(ctx, network, td.dial, endpoints...)
+}
 
-	// sequentially attempt with each available endpoint
-	return nx.sequentialDial(ctx, network, td.dial, endpoints...)
+// DialTLSConnContext is like [*Network.DialTLSContext] but returns a [TLSConn],
+// which allows to programmatically inspect the negotiated TLS state (e.g.,
+// the ALPN, the version, and the peer certificates) using ConnectionState.
+//
+// This method is goroutine safe.
+func (nx *Network) DialTLSConnContext(ctx context.Context, network, address string) (TLSConn, error) {
+	conn, err := nx.DialTLSContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return conn.(TLSConn), nil // the TLS dialer always returns a TLSConn
+}
+
+// TLSConnectionState returns the [tls.ConnectionState] of the given
+// connection, if it is a [TLSConn], and whether it is a [TLSConn]. This
+// function is useful to inspect the connections returned by DialTLSContext.
+func TLSConnectionState(conn net.Conn) (tls.ConnectionState, bool) {
+	tconn, ok := conn.(TLSConn)
+	if !ok {
+		return tls.ConnectionState{}, false
+	}
+	return tconn.ConnectionState(), true
 }
 
 type tlsDialer struct {
@@ -55,26 +118,49 @@ type tlsDialer struct {
 }
 
 func (td *tlsDialer) dial(ctx context.Context, network, address string) (net.Conn, error) {
+	// use the same connection ID for dialing and handshaking
+	ctx = withConnID(ctx)
+
 	// dial and log the results of dialing
 	conn, err := td.netx.dialLog(ctx, network, address)
 	if err != nil {
 		return nil, err
 	}
+	return td.handshake(ctx, conn, network, address)
+}
+
+// handshake performs the TLS handshake over the given connection to the
+// given address. On failure, this method closes the connection.
+func (td *tlsDialer) handshake(ctx context.Context, conn net.Conn, network, address string) (net.Conn, error) {
+	// when emitting events, record the ClientHello to fingerprint it and, if
+	// configured, the first bytes sent by the server
+	var rec *handshakeRecorder
+	engineConn := conn
+	if td.netx.emitEnabled() {
+		rec = newHandshakeRecorder(conn, td.netx.TLSLogRawHandshake)
+		engineConn = rec
+	}
 
 	// create TLS client connection
 	engine := td.netx.newTLSEngine()
-	tconn := engine.NewClientConn(conn, td.config)
+	tconn := engine.NewClientConn(engineConn, td.config)
 
 	// emit event before the TLS handshake
 	laddr := connLocalAddr(conn).String()
 	t0 := td.emitTLSHandshakeStart(ctx, laddr, network, address, engine)
 
-	// perform the TLS handshake
-	err = tconn.HandshakeContext(ctx)
+	// perform the TLS handshake, optionally enforcing a timeout
+	hsctx, cancel := td.netx.withTLSHandshakeTimeout(ctx)
+	err := tconn.HandshakeContext(hsctx)
+	cancel()
+	countOperation(&td.netx.stats.handshakesAttempted, &td.netx.stats.handshakesSucceeded, err)
+	if rec != nil {
+		rec.stop()
+	}
 
 	// emit event after the TLS handshake
 	td.emitTLSHandshakeDone(
-		ctx, laddr, network, address, engine, t0, err, tconn.ConnectionState())
+		ctx, laddr, network, address, engine, rec, t0, err, tconn.ConnectionState())
 
 	// process the results
 	if err != nil {
@@ -84,51 +170,85 @@ func (td *tlsDialer) dial(ctx context.Context, network, address string) (net.Con
 	return tconn, nil
 }
 
+// withTLSHandshakeTimeout returns a context bounded by the TLSHandshakeTimeout
+// field of the [*Network], if set, or the context itself otherwise.
+func (nx *Network) withTLSHandshakeTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if nx.TLSHandshakeTimeout > 0 {
+		return context.WithTimeout(ctx, nx.TLSHandshakeTimeout)
+	}
+	return ctx, func() {}
+}
+
 // emitTLSHandshakeStart emits a TLS handshake start event.
 func (td *tlsDialer) emitTLSHandshakeStart(ctx context.Context,
 	localAddr, network, remoteAddr string, engine TLSEngine) time.Time {
 	t0 := td.netx.timeNow()
-	if td.netx.Logger != nil {
-		td.netx.Logger.InfoContext(
-			ctx,
-			"tlsHandshakeStart",
-			slog.String("localAddr", localAddr),
-			slog.String("protocol", network),
-			slog.String("remoteAddr", remoteAddr),
-			slog.Time("t", t0),
-			slog.String("tlsEngineName", engine.Name()),
-			slog.String("tlsParrot", engine.Parrot()),
-			slog.String("tlsServerName", td.config.ServerName),
-			slog.Bool("tlsSkipVerify", td.config.InsecureSkipVerify),
-		)
+	if td.netx.emitEnabled() {
+		td.netx.emit(ctx, &TLSHandshakeStartEvent{
+			ConnID:        connIDFromContext(ctx),
+			LocalAddr:     localAddr,
+			Protocol:      network,
+			RemoteAddr:    remoteAddr,
+			T:             t0,
+			TLSEngineName: engine.Name(),
+			TLSParrot:     engine.Parrot(),
+			TLSServerName: td.config.ServerName,
+			TLSSkipVerify: td.config.InsecureSkipVerify,
+		})
 	}
 	return t0
 }
 
 // emitTLSHandshakeDone emits a TLS handshake done event.
+//
+// The event includes the JA3 and JA4 fingerprints of the ClientHello we
+// sent, if any, which we cannot include into the start event because the
+// engine only creates the ClientHello when handshaking. When the
+// TLSLogRawHandshake field of [*Network] is true, the event also includes
+// the base64 encoded ClientHello and first bytes sent by the server. The
+// event also describes the verified chains or the verification failure,
+// the stapled OCSP response, and the signed certificate timestamps.
 func (td *tlsDialer) emitTLSHandshakeDone(ctx context.Context,
-	localAddr, network, remoteAddr string, engine TLSEngine,
+	localAddr, network, remoteAddr string, engine TLSEngine, rec *handshakeRecorder,
 	t0 time.Time, err error, state tls.ConnectionState) {
-	if td.netx.Logger != nil {
-		td.netx.Logger.InfoContext(
-			ctx,
-			"tlsHandshakeDone",
-			slog.Any("err", err),
-			slog.String("errClass", errclass.New(err)),
-			slog.String("localAddr", localAddr),
-			slog.String("protocol", network),
-			slog.String("remoteAddr", remoteAddr),
-			slog.Time("t0", t0),
-			slog.Time("t", td.netx.timeNow()),
-			slog.String("tlsCipherSuite", tls.CipherSuiteName(state.CipherSuite)),
-			slog.String("tlsEngineName", engine.Name()),
-			slog.String("tlsParrot", engine.Parrot()),
-			slog.String("tlsNegotiatedProtocol", state.NegotiatedProtocol),
-			slog.Any("tlsPeerCerts", tlsPeerCerts(state, err)),
-			slog.String("tlsServerName", td.config.ServerName),
-			slog.Bool("tlsSkipVerify", td.config.InsecureSkipVerify),
-			slog.String("tlsVersion", tls.VersionName(state.Version)),
-		)
+	if td.netx.emitEnabled() {
+		ja3, ja4 := rec.fingerprints()
+		var rawClientHello, rawServerRecords string
+		if td.netx.TLSLogRawHandshake {
+			rawClientHello = base64.StdEncoding.EncodeToString(rec.clientHello())
+			rawServerRecords = base64.StdEncoding.EncodeToString(rec.serverRecords())
+		}
+		t := td.netx.timeNow()
+		sctCount, sctInvalid := tlsSCTs(state, t)
+		td.netx.emit(ctx, &TLSHandshakeDoneEvent{
+			ConnID:                connIDFromContext(ctx),
+			Err:                   errString(err),
+			ErrClass:              td.netx.errClass(err),
+			LocalAddr:             localAddr,
+			Protocol:              network,
+			RemoteAddr:            remoteAddr,
+			T0:                    t0,
+			T:                     t,
+			TLSCipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+			TLSEngineName:         engine.Name(),
+			TLSJA3:                ja3,
+			TLSJA4:                ja4,
+			TLSParrot:             engine.Parrot(),
+			TLSNegotiatedProtocol: state.NegotiatedProtocol,
+			TLSOCSPStapled:        len(state.OCSPResponse) > 0,
+			TLSOCSPStatus:         tlsOCSPStatus(state),
+			TLSPeerCerts:          tlsPeerCerts(state, err),
+			TLSRawClientHello:     rawClientHello,
+			TLSRawServerRecords:   rawServerRecords,
+			TLSSCTCount:           sctCount,
+			TLSSCTInvalid:         sctInvalid,
+			TLSServerName:         td.config.ServerName,
+			TLSSkipVerify:         td.config.InsecureSkipVerify,
+			TLSVerifiedChains:     tlsVerifiedChains(state),
+			TLSVerifiedRoot:       tlsVerifiedRoot(state),
+			TLSVerifyFailure:      tlsVerifyFailure(err),
+			TLSVersion:            tls.VersionName(state.Version),
+		})
 	}
 }
 
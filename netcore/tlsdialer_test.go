@@ -11,6 +11,8 @@ import (
 	"errors"
 	"log/slog"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -149,6 +151,51 @@ func TestNetwork_DialTLSContext(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Same(t, mockTLSConn, conn)
 	})
+
+	t.Run("handshake timeout", func(t *testing.T) {
+		var closed bool
+		mockConn := &mocks.Conn{
+			MockClose: func() error {
+				closed = true
+				return nil
+			},
+			MockLocalAddr: func() net.Addr {
+				return &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 1234}
+			},
+			MockRemoteAddr: func() net.Addr {
+				return &net.TCPAddr{IP: net.ParseIP("1.2.3.4"), Port: 443}
+			},
+		}
+
+		mockTLSConn := &mocks.TLSConn{
+			Conn: mockConn,
+			MockHandshakeContext: func(ctx context.Context) error {
+				<-ctx.Done() // simulate a blackholed handshake
+				return ctx.Err()
+			},
+			MockConnectionState: func() tls.ConnectionState {
+				return tls.ConnectionState{}
+			},
+		}
+
+		var buf bytes.Buffer
+		nx := &Network{
+			DialContextFunc: func(ctx context.Context, network, address string) (net.Conn, error) {
+				return mockConn, nil
+			},
+			Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+			NewTLSClientConn: func(conn net.Conn, config *tls.Config) TLSConn {
+				return mockTLSConn
+			},
+			TLSHandshakeTimeout: 10 * time.Millisecond,
+		}
+
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", "1.2.3.4:443")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Nil(t, conn)
+		assert.True(t, closed)
+		assert.Contains(t, buf.String(), `"errClass":"ETIMEDOUT"`)
+	})
 }
 
 func Test_tlsDialer_dial(t *testing.T) {
@@ -377,3 +424,74 @@ func Test_tlsPeerCerts(t *testing.T) {
 		assert.Empty(t, certs)
 	})
 }
+
+func TestNetwork_DialTLSContextWithAddrs(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+	nx := &Network{
+		LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+			panic("should not be called")
+		},
+		RootCAs: pool,
+	}
+
+	t.Run("we use the domain name for the SNI", func(t *testing.T) {
+		conn, err := nx.DialTLSContextWithAddrs(context.Background(),
+			"tcp", "example.com", port, []string{"127.0.0.1"})
+		require.NoError(t, err)
+		defer conn.Close()
+		assert.Equal(t, "example.com", conn.(TLSConn).ConnectionState().ServerName)
+	})
+
+	t.Run("we reject the empty list of addresses", func(t *testing.T) {
+		conn, err := nx.DialTLSContextWithAddrs(context.Background(), "tcp", "example.com", port, nil)
+		assert.Error(t, err)
+		assert.Nil(t, conn)
+	})
+}
+
+func TestNetwork_DialTLSConnContext(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	t.Run("we expose the negotiated TLS state", func(t *testing.T) {
+		nx := &Network{
+			TLSConfig: &tls.Config{NextProtos: []string{"h2", "http/1.1"}, RootCAs: pool, ServerName: "example.com"},
+		}
+		tconn, err := nx.DialTLSConnContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		defer tconn.Close()
+
+		state := tconn.ConnectionState()
+		assert.True(t, state.HandshakeComplete)
+		assert.Equal(t, "http/1.1", state.NegotiatedProtocol)
+		assert.Equal(t, uint16(tls.VersionTLS13), state.Version)
+		require.NotEmpty(t, state.PeerCertificates)
+		assert.Equal(t, srv.Certificate().Raw, state.PeerCertificates[0].Raw)
+
+		other, ok := TLSConnectionState(tconn)
+		assert.True(t, ok)
+		assert.Equal(t, state.NegotiatedProtocol, other.NegotiatedProtocol)
+	})
+
+	t.Run("we return the dial error", func(t *testing.T) {
+		nx := &Network{RootCAs: x509.NewCertPool()}
+		tconn, err := nx.DialTLSConnContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		assert.Error(t, err)
+		assert.Nil(t, tconn)
+	})
+
+	t.Run("TLSConnectionState with a non-TLS conn", func(t *testing.T) {
+		state, ok := TLSConnectionState(&mocks.Conn{})
+		assert.False(t, ok)
+		assert.Equal(t, tls.ConnectionState{}, state)
+	})
+}
@@ -0,0 +1,156 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// uTLS engine.
+//
+
+// Package tlsengineutls implements a [netcore.TLSEngine] using
+// [github.com/refraction-networking/utls] to parrot the TLS
+// ClientHello of popular browsers.
+//
+// Use it by setting the TLSEngine field of [*netcore.Network]:
+//
+//	nx := &netcore.Network{TLSEngine: tlsengineutls.MustNew(tlsengineutls.ParrotChrome)}
+//
+// The structured logs emitted by [*netcore.Network] will then contain
+// "utls" as the "tlsEngineName" and the parrot name as the "tlsParrot".
+package tlsengineutls
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/rbmk-project/common/runtimex"
+	"github.com/rbmk-project/x/netcore"
+	utls "github.com/refraction-networking/utls"
+)
+
+// These are the supported parrot names.
+const (
+	// ParrotChrome parrots the most recent supported Chrome version.
+	ParrotChrome = "chrome"
+
+	// ParrotFirefox parrots the most recent supported Firefox version.
+	ParrotFirefox = "firefox"
+
+	// ParrotIOS parrots the most recent supported iOS version.
+	ParrotIOS = "ios"
+)
+
+// parrots maps the parrot names to the corresponding [utls.ClientHelloID].
+var parrots = map[string]utls.ClientHelloID{
+	ParrotChrome:  utls.HelloChrome_Auto,
+	ParrotFirefox: utls.HelloFirefox_Auto,
+	ParrotIOS:     utls.HelloIOS_Auto,
+}
+
+// errUnknownParrot indicates that we do not support the given parrot.
+var errUnknownParrot = errors.New("tlsengineutls: unknown parrot")
+
+// Engine is a [netcore.TLSEngine] using uTLS.
+//
+// Construct using [New].
+type Engine struct {
+	id     utls.ClientHelloID
+	parrot string
+}
+
+// Ensure that [*Engine] implements [netcore.TLSEngine].
+var _ netcore.TLSEngine = &Engine{}
+
+// MustNew is like [New] but panics on failure.
+func MustNew(parrot string) *Engine {
+	return runtimex.Try1(New(parrot))
+}
+
+// New creates a new [*Engine] parroting the given browser, which
+// must be one of [ParrotChrome], [ParrotFirefox], and [ParrotIOS].
+func New(parrot string) (*Engine, error) {
+	id, found := parrots[parrot]
+	if !found {
+		return nil, fmt.Errorf("%w: %q", errUnknownParrot, parrot)
+	}
+	return &Engine{id: id, parrot: parrot}, nil
+}
+
+// Name implements [netcore.TLSEngine] and returns "utls".
+func (*Engine) Name() string {
+	return "utls"
+}
+
+// NewClientConn implements [netcore.TLSEngine].
+//
+// We honour the NextProtos field of the config, when not empty, by
+// overriding the ALPN protocols advertised by the parrot.
+func (e *Engine) NewClientConn(conn net.Conn, config *tls.Config) netcore.TLSConn {
+	uconfig := &utls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		KeyLogWriter:       config.KeyLogWriter,
+		MaxVersion:         config.MaxVersion,
+		MinVersion:         config.MinVersion,
+		NextProtos:         config.NextProtos,
+		RootCAs:            config.RootCAs,
+		ServerName:         config.ServerName,
+		Time:               config.Time,
+	}
+	return &clientConn{
+		UConn:      utls.UClient(conn, uconfig, e.id),
+		nextProtos: config.NextProtos,
+	}
+}
+
+// Parrot implements [netcore.TLSEngine] and returns the parrot name.
+func (e *Engine) Parrot() string {
+	return e.parrot
+}
+
+// clientConn adapts [*utls.UConn] to [netcore.TLSConn].
+type clientConn struct {
+	*utls.UConn
+	nextProtos []string
+}
+
+// ConnectionState implements [netcore.TLSConn].
+func (c *clientConn) ConnectionState() tls.ConnectionState {
+	state := c.UConn.ConnectionState()
+	return tls.ConnectionState{
+		Version:                     state.Version,
+		HandshakeComplete:           state.HandshakeComplete,
+		DidResume:                   state.DidResume,
+		CipherSuite:                 state.CipherSuite,
+		NegotiatedProtocol:          state.NegotiatedProtocol,
+		ServerName:                  state.ServerName,
+		PeerCertificates:            state.PeerCertificates,
+		VerifiedChains:              state.VerifiedChains,
+		SignedCertificateTimestamps: state.SignedCertificateTimestamps,
+		OCSPResponse:                state.OCSPResponse,
+		TLSUnique:                   state.TLSUnique,
+	}
+}
+
+// HandshakeContext implements [netcore.TLSConn].
+func (c *clientConn) HandshakeContext(ctx context.Context) error {
+	if err := c.overrideALPN(); err != nil {
+		return err
+	}
+	return c.UConn.HandshakeContext(ctx)
+}
+
+// overrideALPN replaces the parrot's ALPN protocols with nextProtos.
+func (c *clientConn) overrideALPN() error {
+	if len(c.nextProtos) <= 0 {
+		return nil // keep the parrot's ALPN protocols
+	}
+	if err := c.BuildHandshakeStateWithoutSession(); err != nil {
+		return err
+	}
+	for _, ext := range c.Extensions {
+		if alpn, ok := ext.(*utls.ALPNExtension); ok {
+			alpn.AlpnProtocols = c.nextProtos
+		}
+	}
+	return nil
+}
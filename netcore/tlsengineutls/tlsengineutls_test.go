@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package tlsengineutls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/x/netcore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("we accept the supported parrots", func(t *testing.T) {
+		for _, parrot := range []string{ParrotChrome, ParrotFirefox, ParrotIOS} {
+			engine, err := New(parrot)
+			require.NoError(t, err)
+			assert.Equal(t, "utls", engine.Name())
+			assert.Equal(t, parrot, engine.Parrot())
+		}
+	})
+
+	t.Run("we reject unknown parrots", func(t *testing.T) {
+		engine, err := New("netscape")
+		assert.ErrorIs(t, err, errUnknownParrot)
+		assert.Nil(t, engine)
+		assert.Panics(t, func() { MustNew("netscape") })
+	})
+}
+
+func TestEngine(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1", "doh"}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	for _, parrot := range []string{ParrotChrome, ParrotFirefox, ParrotIOS} {
+		t.Run(parrot, func(t *testing.T) {
+			var buf bytes.Buffer
+			nx := &netcore.Network{
+				Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+				TLSConfig: &tls.Config{
+					NextProtos: []string{"doh"},
+					RootCAs:    pool,
+					ServerName: "example.com",
+				},
+				TLSEngine: MustNew(parrot),
+			}
+			conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			state := conn.(netcore.TLSConn).ConnectionState()
+			assert.True(t, state.HandshakeComplete)
+			assert.Equal(t, "doh", state.NegotiatedProtocol)
+			assert.NotEmpty(t, state.PeerCertificates)
+
+			var found bool
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var ev map[string]any
+				require.NoError(t, json.Unmarshal([]byte(line), &ev))
+				if ev["msg"] == "tlsHandshakeDone" {
+					found = true
+					assert.Equal(t, "utls", ev["tlsEngineName"])
+					assert.Equal(t, parrot, ev["tlsParrot"])
+					assert.Regexp(t, "^t13d[0-9]{4}dh_", ev["tlsJA4"])
+					assert.Equal(t, "", ev["errClass"])
+				}
+			}
+			assert.True(t, found)
+		})
+	}
+}
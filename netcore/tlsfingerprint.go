@@ -0,0 +1,269 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// TLS ClientHello fingerprinting.
+//
+
+package netcore
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// fingerprints returns the JA3 and JA4 fingerprints of the recorded
+// ClientHello or empty strings if we did not record it.
+func (c *handshakeRecorder) fingerprints() (ja3, ja4 string) {
+	msg := c.clientHello()
+	if msg == nil {
+		return
+	}
+	hello, err := parseClientHello(msg)
+	if err != nil {
+		return
+	}
+	return hello.ja3(), hello.ja4("t")
+}
+
+// clientHelloInfo contains the ClientHello fields we use for fingerprinting.
+type clientHelloInfo struct {
+	alpn              []string
+	cipherSuites      []uint16
+	extensions        []uint16
+	pointFormats      []uint8
+	signatureAlgs     []uint16
+	supportedGroups   []uint16
+	supportedVersions []uint16
+	version           uint16
+}
+
+// errInvalidClientHello indicates that we cannot parse a ClientHello.
+var errInvalidClientHello = errors.New("netcore: invalid ClientHello")
+
+// parseClientHello parses a ClientHello handshake message.
+func parseClientHello(msg []byte) (*clientHelloInfo, error) {
+	var (
+		hello      = &clientHelloInfo{}
+		msgType    uint8
+		body       cryptobyte.String
+		ciphers    cryptobyte.String
+		extensions cryptobyte.String
+		ignored    cryptobyte.String
+		input      = cryptobyte.String(msg)
+	)
+	if !input.ReadUint8(&msgType) || msgType != 1 ||
+		!input.ReadUint24LengthPrefixed(&body) ||
+		!body.ReadUint16(&hello.version) ||
+		!body.Skip(32) || // random
+		!body.ReadUint8LengthPrefixed(&ignored) || // session ID
+		!body.ReadUint16LengthPrefixed(&ciphers) ||
+		!body.ReadUint8LengthPrefixed(&ignored) { // compression methods
+		return nil, errInvalidClientHello
+	}
+	for !ciphers.Empty() {
+		var cipher uint16
+		if !ciphers.ReadUint16(&cipher) {
+			return nil, errInvalidClientHello
+		}
+		hello.cipherSuites = append(hello.cipherSuites, cipher)
+	}
+	if body.Empty() {
+		return hello, nil // no extensions
+	}
+	if !body.ReadUint16LengthPrefixed(&extensions) {
+		return nil, errInvalidClientHello
+	}
+	for !extensions.Empty() {
+		var (
+			extType uint16
+			extData cryptobyte.String
+		)
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil, errInvalidClientHello
+		}
+		hello.extensions = append(hello.extensions, extType)
+		if !hello.parseExtension(extType, extData) {
+			return nil, errInvalidClientHello
+		}
+	}
+	return hello, nil
+}
+
+// parseExtension parses the extensions we use for fingerprinting.
+func (hello *clientHelloInfo) parseExtension(extType uint16, data cryptobyte.String) bool {
+	var list cryptobyte.String
+	switch extType {
+	case 10: // supported_groups
+		return data.ReadUint16LengthPrefixed(&list) && readUint16s(list, &hello.supportedGroups)
+	case 11: // ec_point_formats
+		return data.ReadUint8LengthPrefixed(&list) && list.ReadBytes(&hello.pointFormats, len(list))
+	case 13: // signature_algorithms
+		return data.ReadUint16LengthPrefixed(&list) && readUint16s(list, &hello.signatureAlgs)
+	case 16: // application_layer_protocol_negotiation
+		if !data.ReadUint16LengthPrefixed(&list) {
+			return false
+		}
+		for !list.Empty() {
+			var proto cryptobyte.String
+			if !list.ReadUint8LengthPrefixed(&proto) {
+				return false
+			}
+			hello.alpn = append(hello.alpn, string(proto))
+		}
+		return true
+	case 43: // supported_versions
+		return data.ReadUint8LengthPrefixed(&list) && readUint16s(list, &hello.supportedVersions)
+	default:
+		return true
+	}
+}
+
+// readUint16s reads a list of uint16 values.
+func readUint16s(input cryptobyte.String, out *[]uint16) bool {
+	for !input.Empty() {
+		var value uint16
+		if !input.ReadUint16(&value) {
+			return false
+		}
+		*out = append(*out, value)
+	}
+	return true
+}
+
+// isGREASE returns whether the value is a GREASE value (RFC 8701).
+func isGREASE(value uint16) bool {
+	return value&0x0f0f == 0x0a0a && value>>8 == value&0xff
+}
+
+// withoutGREASE returns a copy of the values without GREASE values.
+func withoutGREASE(values []uint16) []uint16 {
+	out := []uint16{}
+	for _, value := range values {
+		if !isGREASE(value) {
+			out = append(out, value)
+		}
+	}
+	return out
+}
+
+// joinUint16s formats the values using the given format and joins them.
+func joinUint16s(values []uint16, format, sep string) string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		out = append(out, fmt.Sprintf(format, value))
+	}
+	return strings.Join(out, sep)
+}
+
+// ja3String returns the JA3 string, i.e., the decimal values of the version,
+// ciphers, extensions, groups, and point formats without GREASE values.
+func (hello *clientHelloInfo) ja3String() string {
+	formats := make([]uint16, 0, len(hello.pointFormats))
+	for _, format := range hello.pointFormats {
+		formats = append(formats, uint16(format))
+	}
+	return strings.Join([]string{
+		strconv.Itoa(int(hello.version)),
+		joinUint16s(withoutGREASE(hello.cipherSuites), "%d", "-"),
+		joinUint16s(withoutGREASE(hello.extensions), "%d", "-"),
+		joinUint16s(withoutGREASE(hello.supportedGroups), "%d", "-"),
+		joinUint16s(formats, "%d", "-"),
+	}, ",")
+}
+
+// ja3 returns the JA3 fingerprint, i.e., the MD5 of the JA3 string.
+func (hello *clientHelloInfo) ja3() string {
+	sum := md5.Sum([]byte(hello.ja3String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// ja4 returns the JA4 fingerprint using the given protocol
+// (i.e., "t" for TLS over TCP and "q" for QUIC).
+func (hello *clientHelloInfo) ja4(protocol string) string {
+	ciphers := withoutGREASE(hello.cipherSuites)
+	extensions := withoutGREASE(hello.extensions)
+
+	// section a: protocol, version, SNI, counts, and ALPN
+	sni := "i"
+	if slices.Contains(extensions, 0) {
+		sni = "d"
+	}
+	a := fmt.Sprintf("%s%s%s%02d%02d%s", protocol, hello.ja4Version(),
+		sni, min(len(ciphers), 99), min(len(extensions), 99), hello.ja4ALPN())
+
+	// section b: sorted ciphers
+	slices.Sort(ciphers)
+	b := ja4Hash(joinUint16s(ciphers, "%04x", ","))
+
+	// section c: sorted extensions without SNI and ALPN plus signature algorithms
+	extensions = slices.DeleteFunc(extensions, func(ext uint16) bool {
+		return ext == 0 || ext == 16
+	})
+	slices.Sort(extensions)
+	c := joinUint16s(extensions, "%04x", ",")
+	if sigalgs := withoutGREASE(hello.signatureAlgs); len(sigalgs) > 0 {
+		c += "_" + joinUint16s(sigalgs, "%04x", ",")
+	}
+	if len(extensions) <= 0 {
+		c = ""
+	}
+	return a + "_" + b + "_" + ja4Hash(c)
+}
+
+// ja4Version returns the JA4 version string.
+func (hello *clientHelloInfo) ja4Version() string {
+	version := hello.version
+	if versions := withoutGREASE(hello.supportedVersions); len(versions) > 0 {
+		version = slices.Max(versions)
+	}
+	switch version {
+	case 0x0304:
+		return "13"
+	case 0x0303:
+		return "12"
+	case 0x0302:
+		return "11"
+	case 0x0301:
+		return "10"
+	case 0x0300:
+		return "s3"
+	default:
+		return "00"
+	}
+}
+
+// ja4ALPN returns the first and last characters of the first ALPN value.
+func (hello *clientHelloInfo) ja4ALPN() string {
+	if len(hello.alpn) <= 0 || hello.alpn[0] == "" {
+		return "00"
+	}
+	value := hello.alpn[0]
+	first, last := value[0], value[len(value)-1]
+	if !isAlnum(first) || !isAlnum(last) {
+		encoded := hex.EncodeToString([]byte(value))
+		return encoded[:1] + encoded[len(encoded)-1:]
+	}
+	return string([]byte{first, last})
+}
+
+// isAlnum returns whether the byte is an ASCII letter or digit.
+func isAlnum(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'A' && ch <= 'Z') || (ch >= 'a' && ch <= 'z')
+}
+
+// ja4Hash returns the truncated SHA256 used by JA4 or zeros if empty.
+func ja4Hash(value string) string {
+	if value == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:12]
+}
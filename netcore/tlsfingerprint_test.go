@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// newTestClientHello returns a Chrome-like ClientHello including GREASE values.
+func newTestClientHello() []byte {
+	uint16s := func(b *cryptobyte.Builder, values ...uint16) {
+		for _, value := range values {
+			b.AddUint16(value)
+		}
+	}
+	extension := func(b *cryptobyte.Builder, extType uint16, fx cryptobyte.BuilderContinuation) {
+		b.AddUint16(extType)
+		b.AddUint16LengthPrefixed(fx)
+	}
+	empty := func(b *cryptobyte.Builder) {}
+
+	b := cryptobyte.NewBuilder(nil)
+	b.AddUint8(1) // ClientHello
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(0x0303)
+		b.AddBytes(make([]byte, 32))
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(make([]byte, 32)) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			uint16s(b, 0x0a0a, 0x1301, 0x1302, 0x1303, 0xc02b, 0xc02f, 0xc02c, 0xc030,
+				0xcca9, 0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035)
+		})
+		b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			extension(b, 0x2a2a, empty)
+			extension(b, 0x0000, func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8(0)
+					b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("example.com")) })
+				})
+			})
+			extension(b, 0x0017, empty)
+			extension(b, 0xff01, func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			extension(b, 0x000a, func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { uint16s(b, 0x3a3a, 29, 23, 24) })
+			})
+			extension(b, 0x000b, func(b *cryptobyte.Builder) {
+				b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+			})
+			extension(b, 0x0023, empty)
+			extension(b, 0x0010, func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("h2")) })
+					b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes([]byte("http/1.1")) })
+				})
+			})
+			extension(b, 0x0005, empty)
+			extension(b, 0x000d, func(b *cryptobyte.Builder) {
+				b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+					uint16s(b, 0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601)
+				})
+			})
+			extension(b, 0x0012, empty)
+			extension(b, 0x0033, empty)
+			extension(b, 0x002d, empty)
+			extension(b, 0x002b, func(b *cryptobyte.Builder) {
+				b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { uint16s(b, 0x4a4a, 0x0304, 0x0303) })
+			})
+			extension(b, 0x001b, empty)
+			extension(b, 0x4469, empty)
+			extension(b, 0x0015, empty)
+		})
+	})
+	return b.BytesOrPanic()
+}
+
+// newTestRecord wraps the given data into a TLS handshake record.
+func newTestRecord(data []byte) []byte {
+	return append([]byte{22, 3, 1, byte(len(data) >> 8), byte(len(data))}, data...)
+}
+
+func TestParseClientHello(t *testing.T) {
+	t.Run("we compute the JA3 and JA4 fingerprints", func(t *testing.T) {
+		hello, err := parseClientHello(newTestClientHello())
+		require.NoError(t, err)
+		assert.Equal(t, "771,4865-4866-4867-49195-49199-49196-49200-52393-52392-49171-49172-"+
+			"156-157-47-53,0-23-65281-10-11-35-16-5-13-18-51-45-43-27-17513-21,29-23-24,0", hello.ja3String())
+		assert.Equal(t, "cd08e31494f9531f560d64c695473da9", hello.ja3())
+		assert.Equal(t, "t13d1516h2_8daaf6152771_e5627efa2ab1", hello.ja4("t"))
+		assert.Equal(t, "q13d1516h2_8daaf6152771_e5627efa2ab1", hello.ja4("q"))
+	})
+
+	t.Run("we handle a ClientHello without extensions", func(t *testing.T) {
+		b := cryptobyte.NewBuilder(nil)
+		b.AddUint8(1)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddUint16(0x0303)
+			b.AddBytes(make([]byte, 32))
+			b.AddUint8(0)
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint16(0x002f) })
+			b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddUint8(0) })
+		})
+		hello, err := parseClientHello(b.BytesOrPanic())
+		require.NoError(t, err)
+		assert.Equal(t, "771,47,,,", hello.ja3String())
+		assert.Equal(t, "t12i010000_"+ja4Hash("002f")+"_000000000000", hello.ja4("t"))
+	})
+
+	t.Run("we reject invalid messages", func(t *testing.T) {
+		msg := newTestClientHello()
+		for _, input := range [][]byte{nil, {2, 0, 0, 0}, msg[:len(msg)-1]} {
+			_, err := parseClientHello(input)
+			assert.ErrorIs(t, err, errInvalidClientHello)
+		}
+	})
+}
+
+func TestJA4ALPN(t *testing.T) {
+	cases := map[string][]string{
+		"00": nil,
+		"h2": {"h2", "http/1.1"},
+		"h1": {"http/1.1"},
+		"dq": {"doq"},
+		"3b": {"0\xab"},
+	}
+	for expect, alpn := range cases {
+		assert.Equal(t, expect, (&clientHelloInfo{alpn: alpn}).ja4ALPN())
+	}
+}
+
+func TestTLSHandshakeFingerprints(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	var buf bytes.Buffer
+	nx := &Network{
+		Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+		TLSConfig: &tls.Config{
+			NextProtos: []string{"h2", "http/1.1"},
+			RootCAs:    pool,
+			ServerName: "example.com",
+		},
+	}
+	conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	conn.Close()
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		if ev["msg"] == "tlsHandshakeDone" {
+			found = true
+			assert.Len(t, ev["tlsJA3"], 32)
+			assert.True(t, strings.HasPrefix(ev["tlsJA4"].(string), "t13d"), ev["tlsJA4"])
+			assert.Contains(t, ev["tlsJA4"], "h2_")
+		}
+	}
+	assert.True(t, found)
+}
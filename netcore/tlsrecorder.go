@@ -0,0 +1,113 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// TLS handshake recorder.
+//
+
+package netcore
+
+import (
+	"net"
+	"sync"
+)
+
+// handshakeRecorder wraps a [net.Conn] to record the first handshake
+// message written to it, i.e., the ClientHello, and optionally the first
+// bytes read from it, i.e., the first records sent by the server.
+type handshakeRecorder struct {
+	net.Conn
+	done       bool
+	mu         sync.Mutex
+	msg        []byte
+	pending    []byte
+	readServer bool
+	server     []byte
+}
+
+// newHandshakeRecorder creates a new [*handshakeRecorder] that also
+// records the bytes read when readServer is true.
+func newHandshakeRecorder(conn net.Conn, readServer bool) *handshakeRecorder {
+	return &handshakeRecorder{Conn: conn, readServer: readServer}
+}
+
+// maxClientHelloSize is the maximum ClientHello size we record.
+const maxClientHelloSize = 1 << 16
+
+// maxServerRecordsSize is the maximum number of server bytes we record.
+const maxServerRecordsSize = 1 << 14
+
+// Read implements [net.Conn].
+func (c *handshakeRecorder) Read(data []byte) (int, error) {
+	count, err := c.Conn.Read(data)
+	c.mu.Lock()
+	if c.readServer && count > 0 {
+		room := max(maxServerRecordsSize-len(c.server), 0)
+		c.server = append(c.server, data[:min(count, room)]...)
+	}
+	c.mu.Unlock()
+	return count, err
+}
+
+// Write implements [net.Conn].
+func (c *handshakeRecorder) Write(data []byte) (int, error) {
+	c.mu.Lock()
+	if !c.done {
+		c.record(data)
+	}
+	c.mu.Unlock()
+	return c.Conn.Write(data)
+}
+
+// record reassembles the handshake message from the TLS records.
+func (c *handshakeRecorder) record(data []byte) {
+	c.pending = append(c.pending, data...)
+	for len(c.pending) >= 5 && !c.done {
+		// parse the record header and wait for the full record
+		contentType, length := c.pending[0], int(c.pending[3])<<8|int(c.pending[4])
+		if len(c.pending) < 5+length {
+			return
+		}
+		record := c.pending[5 : 5+length]
+		c.pending = c.pending[5+length:]
+
+		// give up on non-handshake records or oversized messages
+		if contentType != 22 || len(c.msg)+len(record) > maxClientHelloSize {
+			c.done, c.msg, c.pending = true, nil, nil
+			return
+		}
+		c.msg = append(c.msg, record...)
+
+		// stop once we have a complete handshake message
+		if len(c.msg) >= 4 && len(c.msg) >= 4+(int(c.msg[1])<<16|int(c.msg[2])<<8|int(c.msg[3])) {
+			c.done, c.pending = true, nil
+		}
+	}
+}
+
+// stop stops recording, which we do once the handshake is over.
+func (c *handshakeRecorder) stop() {
+	c.mu.Lock()
+	c.done, c.pending, c.readServer = true, nil, false
+	c.mu.Unlock()
+}
+
+// clientHello returns the recorded ClientHello or nil.
+func (c *handshakeRecorder) clientHello() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.msg) < 4 || c.msg[0] != 1 {
+		return nil
+	}
+	size := 4 + (int(c.msg[1])<<16 | int(c.msg[2])<<8 | int(c.msg[3]))
+	if len(c.msg) < size {
+		return nil
+	}
+	return c.msg[:size]
+}
+
+// serverRecords returns the recorded server bytes or nil.
+func (c *handshakeRecorder) serverRecords() []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.server
+}
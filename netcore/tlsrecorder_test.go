@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/rbmk-project/common/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandshakeRecorder(t *testing.T) {
+	newRecorder := func() *handshakeRecorder {
+		return newHandshakeRecorder(&mocks.Conn{
+			MockRead: func(b []byte) (int, error) {
+				return copy(b, "HTTP/1.1 400 Bad Request\r\n"), nil
+			},
+			MockWrite: func(b []byte) (int, error) { return len(b), nil },
+		}, true)
+	}
+
+	t.Run("we reassemble fragmented writes and records", func(t *testing.T) {
+		msg := newTestClientHello()
+		data := append(newTestRecord(msg[:100]), newTestRecord(msg[100:])...)
+		rec := newRecorder()
+		for len(data) > 0 {
+			n := min(len(data), 7)
+			_, err := rec.Write(data[:n])
+			require.NoError(t, err)
+			data = data[n:]
+		}
+		assert.Equal(t, msg, rec.clientHello())
+		ja3, ja4 := rec.fingerprints()
+		assert.Equal(t, "cd08e31494f9531f560d64c695473da9", ja3)
+		assert.Equal(t, "t13d1516h2_8daaf6152771_e5627efa2ab1", ja4)
+	})
+
+	t.Run("we ignore non-handshake records", func(t *testing.T) {
+		rec := newRecorder()
+		record := newTestRecord(newTestClientHello())
+		record[0] = 23 // application data
+		rec.Write(record)
+		assert.Nil(t, rec.clientHello())
+		ja3, ja4 := rec.fingerprints()
+		assert.Empty(t, ja3)
+		assert.Empty(t, ja4)
+	})
+
+	t.Run("we return nil before the message is complete", func(t *testing.T) {
+		rec := newRecorder()
+		rec.Write(newTestRecord(newTestClientHello())[:50])
+		rec.stop()
+		assert.Nil(t, rec.clientHello())
+	})
+
+	t.Run("we record the server bytes until we stop", func(t *testing.T) {
+		rec := newRecorder()
+		buf := make([]byte, 1024)
+		for range 2 {
+			_, err := rec.Read(buf)
+			require.NoError(t, err)
+		}
+		rec.stop()
+		rec.Read(buf)
+		assert.Equal(t, strings.Repeat("HTTP/1.1 400 Bad Request\r\n", 2), string(rec.serverRecords()))
+	})
+
+	t.Run("we bound the recorded server bytes", func(t *testing.T) {
+		rec := newRecorder()
+		buf := make([]byte, 1024)
+		for range maxServerRecordsSize {
+			rec.Read(buf)
+		}
+		assert.Len(t, rec.serverRecords(), maxServerRecordsSize)
+	})
+
+	t.Run("we do not record the server bytes unless asked", func(t *testing.T) {
+		rec := newHandshakeRecorder(&mocks.Conn{
+			MockRead: func(b []byte) (int, error) { return len(b), nil },
+		}, false)
+		rec.Read(make([]byte, 128))
+		assert.Nil(t, rec.serverRecords())
+	})
+}
+
+func TestTLSLogRawHandshake(t *testing.T) {
+	// a server replying to the ClientHello using cleartext HTTP, like
+	// a middlebox injecting a blockpage would do
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Read(make([]byte, 4096))
+		conn.Write([]byte("HTTP/1.1 403 Forbidden\r\n\r\n"))
+	}()
+
+	var buf bytes.Buffer
+	nx := &Network{
+		Logger:             slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{})),
+		TLSConfig:          &tls.Config{ServerName: "example.com"},
+		TLSLogRawHandshake: true,
+	}
+	conn, err := nx.DialTLSContext(context.Background(), "tcp", listener.Addr().String())
+	require.Error(t, err)
+	require.Nil(t, conn)
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ev map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &ev))
+		if ev["msg"] != "tlsHandshakeDone" {
+			continue
+		}
+		found = true
+		hello, err := base64.StdEncoding.DecodeString(ev["tlsRawClientHello"].(string))
+		require.NoError(t, err)
+		info, err := parseClientHello(hello)
+		require.NoError(t, err)
+		assert.Equal(t, info.ja3(), ev["tlsJA3"])
+		server, err := base64.StdEncoding.DecodeString(ev["tlsRawServerRecords"].(string))
+		require.NoError(t, err)
+		assert.Equal(t, "HTTP/1.1 403 Forbidden\r\n\r\n", string(server))
+	}
+	assert.True(t, found)
+}
@@ -0,0 +1,132 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Stapled OCSP responses and signed certificate timestamps.
+//
+
+package netcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	"golang.org/x/crypto/ocsp"
+)
+
+// tlsOCSPStatus returns the status of the OCSP response stapled by the
+// server, that is, "good", "revoked", "unknown", or "invalid" when we cannot
+// parse the response or it does not match the certificate, or an empty
+// string when the server did not staple a response. When verification
+// succeeds, we also check the signature of the response using the issuer.
+func tlsOCSPStatus(state tls.ConnectionState) string {
+	if len(state.OCSPResponse) <= 0 {
+		return ""
+	}
+	var leaf, issuer *x509.Certificate
+	if len(state.PeerCertificates) > 0 {
+		leaf = state.PeerCertificates[0]
+	}
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 1 {
+		issuer = state.VerifiedChains[0][1]
+	}
+	resp, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+	if err != nil {
+		return "invalid"
+	}
+	switch resp.Status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
+
+// oidEmbeddedSCTs is the OID of the certificate extension containing
+// the signed certificate timestamps (see RFC 6962, Section 3.3).
+var oidEmbeddedSCTs = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// tlsSCTs returns the number of signed certificate timestamps (SCTs)
+// that the server provided, either using the TLS extension or embedded
+// into the leaf certificate, and the number of invalid SCTs, that is,
+// the SCTs we cannot parse or whose timestamp is after the given time.
+//
+// We do not verify the signatures of the SCTs, which would require
+// knowing the public keys of the certificate transparency logs.
+func tlsSCTs(state tls.ConnectionState, now time.Time) (count, invalid int) {
+	scts := slices.Clone(state.SignedCertificateTimestamps)
+	if len(state.PeerCertificates) > 0 {
+		embedded, ok := tlsEmbeddedSCTs(state.PeerCertificates[0])
+		if !ok {
+			count, invalid = 1, 1 // count the malformed extension as an invalid SCT
+		}
+		scts = append(scts, embedded...)
+	}
+	for _, sct := range scts {
+		count++
+		if timestamp, ok := parseSCT(sct); !ok || timestamp.After(now) {
+			invalid++
+		}
+	}
+	return
+}
+
+// tlsEmbeddedSCTs returns the SCTs embedded into the given certificate
+// and whether we could parse the corresponding extension, if present.
+func tlsEmbeddedSCTs(cert *x509.Certificate) ([][]byte, bool) {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidEmbeddedSCTs) {
+			continue
+		}
+		var raw []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &raw); err != nil || len(rest) > 0 {
+			return nil, false
+		}
+		var list cryptobyte.String
+		input := cryptobyte.String(raw)
+		if !input.ReadUint16LengthPrefixed(&list) || !input.Empty() {
+			return nil, false
+		}
+		var out [][]byte
+		for !list.Empty() {
+			var sct cryptobyte.String
+			if !list.ReadUint16LengthPrefixed(&sct) {
+				return nil, false
+			}
+			out = append(out, sct)
+		}
+		return out, true
+	}
+	return nil, true
+}
+
+// parseSCT parses a serialized v1 SCT (see RFC 6962, Section 3.2) and
+// returns its timestamp and whether parsing succeeded.
+func parseSCT(data []byte) (time.Time, bool) {
+	var (
+		version   uint8
+		logID     []byte
+		timestamp uint64
+		exts      cryptobyte.String
+		hashAlg   uint8
+		sigAlg    uint8
+		sig       cryptobyte.String
+	)
+	input := cryptobyte.String(data)
+	if !input.ReadUint8(&version) || version != 0 ||
+		!input.ReadBytes(&logID, 32) ||
+		!input.ReadUint64(&timestamp) ||
+		!input.ReadUint16LengthPrefixed(&exts) ||
+		!input.ReadUint8(&hashAlg) ||
+		!input.ReadUint8(&sigAlg) ||
+		!input.ReadUint16LengthPrefixed(&sig) ||
+		!input.Empty() {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(timestamp)), true
+}
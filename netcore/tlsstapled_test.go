@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rbmk-project/x/netsim/simpki"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// newTestSCT returns a serialized v1 SCT with the given timestamp.
+func newTestSCT(timestamp time.Time) []byte {
+	var builder cryptobyte.Builder
+	builder.AddUint8(0)                // version
+	builder.AddBytes(make([]byte, 32)) // log ID
+	builder.AddUint64(uint64(timestamp.UnixMilli()))
+	builder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {}) // extensions
+	builder.AddUint8(4)                                             // SHA-256
+	builder.AddUint8(3)                                             // ECDSA
+	builder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddBytes([]byte("signature"))
+	})
+	return builder.BytesOrPanic()
+}
+
+// newTestSCTExtension returns the certificate extension embedding the given SCTs.
+func newTestSCTExtension(scts ...[]byte) []byte {
+	var builder cryptobyte.Builder
+	builder.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+				b.AddBytes(sct)
+			})
+		}
+	})
+	value, err := asn1.Marshal(builder.BytesOrPanic())
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func Test_tlsSCTs(t *testing.T) {
+	now := time.Now()
+	past, future := newTestSCT(now.Add(-time.Hour)), newTestSCT(now.Add(time.Hour))
+
+	t.Run("without SCTs", func(t *testing.T) {
+		count, invalid := tlsSCTs(tls.ConnectionState{}, now)
+		assert.Equal(t, 0, count)
+		assert.Equal(t, 0, invalid)
+	})
+
+	t.Run("with SCTs from the TLS extension and the certificate", func(t *testing.T) {
+		cert := &x509.Certificate{Extensions: []pkix.Extension{{
+			Id:    oidEmbeddedSCTs,
+			Value: newTestSCTExtension(past, future),
+		}}}
+		state := tls.ConnectionState{
+			PeerCertificates:            []*x509.Certificate{cert},
+			SignedCertificateTimestamps: [][]byte{past, {0x01}},
+		}
+		count, invalid := tlsSCTs(state, now)
+		assert.Equal(t, 4, count)
+		assert.Equal(t, 2, invalid)
+	})
+
+	t.Run("with a malformed certificate extension", func(t *testing.T) {
+		cert := &x509.Certificate{Extensions: []pkix.Extension{{
+			Id:    oidEmbeddedSCTs,
+			Value: []byte{0x04, 0x01, 0x00},
+		}}}
+		count, invalid := tlsSCTs(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}, now)
+		assert.Equal(t, 1, count)
+		assert.Equal(t, 1, invalid)
+	})
+}
+
+func Test_tlsOCSPStatus(t *testing.T) {
+	t.Run("without a stapled response", func(t *testing.T) {
+		assert.Equal(t, "", tlsOCSPStatus(tls.ConnectionState{}))
+	})
+
+	t.Run("with a malformed response", func(t *testing.T) {
+		assert.Equal(t, "invalid", tlsOCSPStatus(tls.ConnectionState{OCSPResponse: []byte{0x30}}))
+	})
+}
+
+func TestNetwork_DialTLSContext_stapled(t *testing.T) {
+	pki := simpki.MustNew(t.TempDir())
+
+	// dial returns the tlsHandshakeDone event when connecting to
+	// a server using the given certificate
+	dial := func(t *testing.T, cert tls.Certificate) map[string]any {
+		srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		srv.TLS = &tls.Config{Certificates: []tls.Certificate{cert}}
+		srv.StartTLS()
+		defer srv.Close()
+
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger:    slog.New(slog.NewJSONHandler(&buf, nil)),
+			TLSConfig: &tls.Config{RootCAs: pki.CertPool(), ServerName: "example.com"},
+		}
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		require.NoError(t, err)
+		conn.Close()
+
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "tlsHandshakeDone" {
+				return ev
+			}
+		}
+		t.Fatal("no tlsHandshakeDone event")
+		return nil
+	}
+
+	t.Run("without stapling", func(t *testing.T) {
+		cert := pki.MustNewCert(&simpki.Config{CommonName: "example.com", DNSNames: []string{"example.com"}})
+		ev := dial(t, cert)
+		assert.Equal(t, false, ev["tlsOCSPStapled"])
+		assert.Equal(t, "", ev["tlsOCSPStatus"])
+		assert.Equal(t, float64(0), ev["tlsSCTCount"])
+		assert.Equal(t, float64(0), ev["tlsSCTInvalid"])
+	})
+
+	t.Run("with a good response and SCTs", func(t *testing.T) {
+		cert := pki.MustNewCert(&simpki.Config{CommonName: "example.com", DNSNames: []string{"example.com"}})
+		pki.MustStapleOCSP(&cert, simpki.OCSPGood)
+		cert.SignedCertificateTimestamps = [][]byte{
+			newTestSCT(time.Now().Add(-time.Hour)),
+			newTestSCT(time.Now().Add(time.Hour)),
+		}
+		ev := dial(t, cert)
+		assert.Equal(t, true, ev["tlsOCSPStapled"])
+		assert.Equal(t, "good", ev["tlsOCSPStatus"])
+		assert.Equal(t, float64(2), ev["tlsSCTCount"])
+		assert.Equal(t, float64(1), ev["tlsSCTInvalid"])
+	})
+
+	t.Run("with a revoked response", func(t *testing.T) {
+		cert := pki.MustNewCert(&simpki.Config{CommonName: "example.com", DNSNames: []string{"example.com"}})
+		pki.MustStapleOCSP(&cert, simpki.OCSPRevoked)
+		ev := dial(t, cert)
+		assert.Equal(t, "revoked", ev["tlsOCSPStatus"])
+	})
+}
@@ -0,0 +1,101 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Certificate verification details.
+//
+
+package netcore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"strings"
+)
+
+// tlsVerifiedChains describes the verified chains of the given connection
+// state, which are empty when the verification fails or we skip it. We
+// describe each chain using the subjects of its certificates, from the
+// leaf to the root, separated by " -> ".
+func tlsVerifiedChains(state tls.ConnectionState) []string {
+	out := []string{}
+	for _, chain := range state.VerifiedChains {
+		subjects := make([]string, 0, len(chain))
+		for _, cert := range chain {
+			subjects = append(subjects, cert.Subject.String())
+		}
+		out = append(out, strings.Join(subjects, " -> "))
+	}
+	return out
+}
+
+// tlsVerifiedRoot returns the subject of the root of the first verified
+// chain of the given connection state, or an empty string.
+func tlsVerifiedRoot(state tls.ConnectionState) string {
+	if len(state.VerifiedChains) <= 0 || len(state.VerifiedChains[0]) <= 0 {
+		return ""
+	}
+	chain := state.VerifiedChains[0]
+	return chain[len(chain)-1].Subject.String()
+}
+
+// tlsInvalidReasons maps the reasons of [x509.CertificateInvalidError]
+// to the values of the "tlsVerifyFailure" field.
+var tlsInvalidReasons = map[x509.InvalidReason]string{
+	x509.NotAuthorizedToSign:           "not_authorized_to_sign",
+	x509.Expired:                       "expired",
+	x509.CANotAuthorizedForThisName:    "ca_not_authorized_for_this_name",
+	x509.TooManyIntermediates:          "too_many_intermediates",
+	x509.IncompatibleUsage:             "incompatible_usage",
+	x509.NameMismatch:                  "name_mismatch",
+	x509.NameConstraintsWithoutSANs:    "name_constraints_without_sans",
+	x509.UnconstrainedName:             "unconstrained_name",
+	x509.TooManyConstraints:            "too_many_constraints",
+	x509.CANotAuthorizedForExtKeyUsage: "ca_not_authorized_for_ext_key_usage",
+}
+
+// tlsVerifyFailure returns the certificate verification step that failed
+// according to the given handshake error (e.g., "expired" when the
+// certificate is expired or not yet valid, "hostname_mismatch", and
+// "unknown_authority"), or an empty string when the handshake did not
+// fail because of the certificate verification.
+func tlsVerifyFailure(err error) string {
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return "hostname_mismatch"
+	}
+
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthorityErr) {
+		return "unknown_authority"
+	}
+
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &invalidErr) {
+		if reason, found := tlsInvalidReasons[invalidErr.Reason]; found {
+			return reason
+		}
+		return "certificate_invalid"
+	}
+
+	var systemRootsErr x509.SystemRootsError
+	if errors.As(err, &systemRootsErr) {
+		return "system_roots"
+	}
+
+	var constraintErr x509.ConstraintViolationError
+	if errors.As(err, &constraintErr) {
+		return "constraint_violation"
+	}
+
+	var insecureAlgorithmErr x509.InsecureAlgorithmError
+	if errors.As(err, &insecureAlgorithmErr) {
+		return "insecure_algorithm"
+	}
+
+	var verificationErr *tls.CertificateVerificationError
+	if errors.As(err, &verificationErr) {
+		return "unknown"
+	}
+	return ""
+}
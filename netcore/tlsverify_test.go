@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_tlsVerifyFailure(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		expect string
+	}{{
+		name:   "nil error",
+		err:    nil,
+		expect: "",
+	}, {
+		name:   "non-verification error",
+		err:    errors.New("mocked error"),
+		expect: "",
+	}, {
+		name:   "hostname mismatch",
+		err:    &tls.CertificateVerificationError{Err: x509.HostnameError{Certificate: &x509.Certificate{}}},
+		expect: "hostname_mismatch",
+	}, {
+		name:   "unknown authority",
+		err:    x509.UnknownAuthorityError{},
+		expect: "unknown_authority",
+	}, {
+		name:   "expired",
+		err:    fmt.Errorf("wrapped: %w", x509.CertificateInvalidError{Reason: x509.Expired}),
+		expect: "expired",
+	}, {
+		name:   "not authorized to sign",
+		err:    x509.CertificateInvalidError{Reason: x509.NotAuthorizedToSign},
+		expect: "not_authorized_to_sign",
+	}, {
+		name:   "unknown invalid reason",
+		err:    x509.CertificateInvalidError{Reason: x509.InvalidReason(1000)},
+		expect: "certificate_invalid",
+	}, {
+		name:   "system roots",
+		err:    x509.SystemRootsError{},
+		expect: "system_roots",
+	}, {
+		name:   "constraint violation",
+		err:    x509.ConstraintViolationError{},
+		expect: "constraint_violation",
+	}, {
+		name:   "insecure algorithm",
+		err:    x509.InsecureAlgorithmError(x509.MD5WithRSA),
+		expect: "insecure_algorithm",
+	}, {
+		name:   "other verification error",
+		err:    &tls.CertificateVerificationError{Err: errors.New("mocked error")},
+		expect: "unknown",
+	}}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tlsVerifyFailure(tc.err))
+		})
+	}
+}
+
+func TestNetwork_DialTLSContext_verification(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	// dial returns the tlsHandshakeDone event
+	dial := func(t *testing.T, config *tls.Config) map[string]any {
+		var buf bytes.Buffer
+		nx := &Network{
+			Logger:    slog.New(slog.NewJSONHandler(&buf, nil)),
+			TLSConfig: config,
+		}
+		conn, err := nx.DialTLSContext(context.Background(), "tcp", srv.Listener.Addr().String())
+		if err == nil {
+			conn.Close()
+		}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var ev map[string]any
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "tlsHandshakeDone" {
+				return ev
+			}
+		}
+		t.Fatal("no tlsHandshakeDone event")
+		return nil
+	}
+
+	t.Run("we log the verified chains and root", func(t *testing.T) {
+		ev := dial(t, &tls.Config{RootCAs: pool, ServerName: "example.com"})
+		subject := srv.Certificate().Subject.String()
+		assert.Equal(t, []any{subject}, ev["tlsVerifiedChains"])
+		assert.Equal(t, subject, ev["tlsVerifiedRoot"])
+		assert.Equal(t, "", ev["tlsVerifyFailure"])
+	})
+
+	t.Run("we log the unknown authority", func(t *testing.T) {
+		ev := dial(t, &tls.Config{RootCAs: x509.NewCertPool(), ServerName: "example.com"})
+		assert.Equal(t, []any{}, ev["tlsVerifiedChains"])
+		assert.Equal(t, "", ev["tlsVerifiedRoot"])
+		assert.Equal(t, "unknown_authority", ev["tlsVerifyFailure"])
+	})
+
+	t.Run("we log the hostname mismatch", func(t *testing.T) {
+		ev := dial(t, &tls.Config{RootCAs: pool, ServerName: "example.org"})
+		assert.Equal(t, "hostname_mismatch", ev["tlsVerifyFailure"])
+	})
+
+	t.Run("we do not log chains when skipping verification", func(t *testing.T) {
+		ev := dial(t, &tls.Config{InsecureSkipVerify: true})
+		assert.Equal(t, []any{}, ev["tlsVerifiedChains"])
+		assert.Equal(t, "", ev["tlsVerifyFailure"])
+	})
+}
@@ -0,0 +1,198 @@
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Traceroute using UDP, TCP, or ICMP probes.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// These are the probe protocols supported by [*Network.Traceroute].
+const (
+	TracerouteICMP = "icmp"
+	TracerouteTCP  = "tcp"
+	TracerouteUDP  = "udp"
+)
+
+// These are the defaults used by [*Network.Traceroute].
+const (
+	// DefaultTracerouteMaxTTL is the default maximum TTL.
+	DefaultTracerouteMaxTTL = 30
+
+	// DefaultTracerouteProbeTimeout is the default time we wait for each probe.
+	DefaultTracerouteProbeTimeout = time.Second
+
+	// DefaultTracerouteTCPPort is the default destination port of the TCP probes.
+	DefaultTracerouteTCPPort = "80"
+
+	// DefaultTracerouteUDPPort is the default destination port of the UDP probes.
+	DefaultTracerouteUDPPort = "33434"
+)
+
+// TracerouteOptions contains the options for [*Network.Traceroute].
+//
+// The zero value is ready to use.
+type TracerouteOptions struct {
+	// FirstTTL is the optional TTL of the first probe. If this
+	// field is zero or negative, we start from 1.
+	FirstTTL int
+
+	// MaxTTL is the optional maximum TTL. If this field is zero
+	// or negative, we use [DefaultTracerouteMaxTTL].
+	MaxTTL int
+
+	// Port is the optional destination port of the UDP and TCP probes,
+	// which we ignore for ICMP. If this field is empty, we use
+	// [DefaultTracerouteUDPPort] or [DefaultTracerouteTCPPort].
+	Port string
+
+	// ProbeTimeout is the optional time to wait for the response to each
+	// probe. If this field is zero or negative, we use [DefaultTracerouteProbeTimeout].
+	ProbeTimeout time.Duration
+
+	// Protocol is the optional probe protocol, that is, [TracerouteUDP],
+	// [TracerouteTCP], or [TracerouteICMP]. If this field is empty, we
+	// use [TracerouteUDP]. The ICMP probes use unprivileged ICMP sockets,
+	// which require a suitable net.ipv4.ping_group_range sysctl.
+	Protocol string
+}
+
+// TracerouteHop is a hop discovered by [*Network.Traceroute].
+type TracerouteHop struct {
+	// Addr is the IP address that responded to the probe or empty
+	// if we did not receive any response before the ProbeTimeout.
+	Addr string
+
+	// Err is the error that occurred, if any, including the errors
+	// signalled by the routers (e.g., host unreachable) and the timeouts.
+	Err error
+
+	// Reached indicates that the response comes from the target.
+	Reached bool
+
+	// RTT is the round trip time or zero without a response.
+	RTT time.Duration
+
+	// TTL is the TTL of the probe.
+	TTL int
+}
+
+// errTracerouteUnsupportedProtocol indicates that the probe protocol is not supported.
+var errTracerouteUnsupportedProtocol = errors.New("netcore: unsupported traceroute protocol")
+
+// Traceroute discovers the path to the given target, which is a domain
+// name or an IP address, sending probes with increasing TTLs until we
+// reach the target or MaxTTL, and returns the discovered hops. We emit a
+// "tracerouteHop" event for each probe, with the responding IP address
+// and the RTT, which helps to localize where the interference happens.
+//
+// We use the first address of the target matching the AddressFamilyPolicy
+// and create raw kernel sockets for the probes, thus ignoring DialContextFunc,
+// ProxyURL, and SocketOptions. We stop when a router signals that the target
+// is unreachable or when we cannot send the probes, in which case we return
+// the hops discovered so far along with the error. Traceroute is only
+// supported on Linux, where it does not require special privileges.
+//
+// This method is goroutine safe.
+func (nx *Network) Traceroute(ctx context.Context, target string, opts *TracerouteOptions) ([]TracerouteHop, error) {
+	// apply the defaults
+	if opts == nil {
+		opts = &TracerouteOptions{}
+	}
+	protocol, port := opts.Protocol, opts.Port
+	switch protocol {
+	case "", TracerouteUDP:
+		protocol = TracerouteUDP
+		if port == "" {
+			port = DefaultTracerouteUDPPort
+		}
+	case TracerouteTCP:
+		if port == "" {
+			port = DefaultTracerouteTCPPort
+		}
+	case TracerouteICMP:
+		port = "0"
+	default:
+		return nil, fmt.Errorf("%w: %s", errTracerouteUnsupportedProtocol, protocol)
+	}
+	firstTTL := max(opts.FirstTTL, 1)
+	maxTTL := opts.MaxTTL
+	if maxTTL <= 0 {
+		maxTTL = DefaultTracerouteMaxTTL
+	}
+	timeout := opts.ProbeTimeout
+	if timeout <= 0 {
+		timeout = DefaultTracerouteProbeTimeout
+	}
+
+	// resolve the target and pick the first usable endpoint
+	dst, err := nx.lookupFirstAddr(ctx, target, port)
+	if err != nil {
+		return nil, err
+	}
+
+	// send the probes with increasing TTLs
+	var hops []TracerouteHop
+	for ttl := firstTTL; ttl <= maxTTL; ttl++ {
+		if err := ctx.Err(); err != nil {
+			return hops, err
+		}
+		t0 := nx.timeNow()
+		reply, err := tracerouteProbe(ctx, protocol, dst, ttl, timeout)
+		hop := TracerouteHop{Err: err, Reached: reply.reached, RTT: reply.rtt, TTL: ttl}
+		if reply.addr.IsValid() {
+			hop.Addr = reply.addr.String()
+		}
+		nx.emitTracerouteHop(ctx, protocol, dst.String(), t0, hop)
+		hops = append(hops, hop)
+
+		switch {
+		case hop.Reached:
+			return hops, nil
+		case err == nil, errors.Is(err, os.ErrDeadlineExceeded):
+			continue // router responded or timeout: try with the next TTL
+		default:
+			return hops, err
+		}
+	}
+	return hops, nil
+}
+
+// tracerouteReply is the reply to a traceroute probe.
+type tracerouteReply struct {
+	// addr is the responding IP address or invalid without a response.
+	addr netip.Addr
+
+	// reached indicates that the response comes from the target.
+	reached bool
+
+	// rtt is the round trip time or zero without a response.
+	rtt time.Duration
+}
+
+// emitTracerouteHop emits a traceroute hop event.
+func (nx *Network) emitTracerouteHop(ctx context.Context,
+	protocol, address string, t0 time.Time, hop TracerouteHop) {
+	if nx.emitEnabled() {
+		nx.emit(ctx, &TracerouteHopEvent{
+			Err:               errString(hop.Err),
+			ErrClass:          nx.errClass(hop.Err),
+			Protocol:          protocol,
+			RemoteAddr:        address,
+			TracerouteHopAddr: hop.Addr,
+			TracerouteReached: hop.Reached,
+			TracerouteRTTUsec: hop.RTT.Microseconds(),
+			TracerouteTTL:     hop.TTL,
+			T0:                t0,
+			T:                 nx.timeNow(),
+		})
+	}
+}
@@ -0,0 +1,248 @@
+//go:build linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Linux traceroute probes using IP_RECVERR.
+//
+
+package netcore
+
+import (
+	"context"
+	"encoding/binary"
+	"net/netip"
+	"os"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// tracerouteProbe sends a single probe with the given TTL to the destination
+// and waits for the reply until the given timeout. We use IP_RECVERR (or
+// IPV6_RECVERR) to read from the socket error queue the ICMP errors caused
+// by the probe, including their source address, which does not require
+// special privileges. The returned error is [os.ErrDeadlineExceeded] when we
+// do not receive any reply and a [syscall.Errno] when a router signals an
+// error (e.g., EHOSTUNREACH), in which case the reply contains its address.
+func tracerouteProbe(ctx context.Context,
+	protocol string, dst netip.AddrPort, ttl int, timeout time.Duration) (tracerouteReply, error) {
+	// create and configure the socket
+	fd, err := tracerouteSocket(protocol, dst.Addr(), ttl)
+	if err != nil {
+		return tracerouteReply{}, err
+	}
+	defer unix.Close(fd)
+
+	// send the probe
+	t0 := time.Now()
+	if err := tracerouteSend(fd, protocol, dst); err != nil {
+		return tracerouteReply{}, err
+	}
+
+	// wait for the reply, periodically checking whether the context is done
+	events := int16(unix.POLLIN)
+	if protocol == TracerouteTCP {
+		events = unix.POLLOUT
+	}
+	deadline := t0.Add(timeout)
+	for {
+		if err := ctx.Err(); err != nil {
+			return tracerouteReply{}, err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return tracerouteReply{}, os.ErrDeadlineExceeded
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: events}}
+		count, err := unix.Poll(fds, int(min(remaining, 100*time.Millisecond).Milliseconds())+1)
+		if err == unix.EINTR || (err == nil && count == 0) {
+			continue
+		}
+		if err != nil {
+			return tracerouteReply{}, os.NewSyscallError("poll", err)
+		}
+		rtt := time.Since(t0)
+
+		// check the error queue first, since the routers signal
+		// that the TTL expired using ICMP errors
+		if fds[0].Revents&unix.POLLERR != 0 {
+			if reply, found, err := tracerouteReadErrQueue(fd, protocol, rtt); found {
+				return reply, err
+			}
+		}
+
+		// otherwise, the target replied
+		if reply, found, err := tracerouteReadReply(fd, protocol, dst, rtt); found {
+			return reply, err
+		}
+	}
+}
+
+// tracerouteSocket creates a nonblocking socket for the given protocol
+// and destination address and sets the TTL and IP_RECVERR options.
+func tracerouteSocket(protocol string, addr netip.Addr, ttl int) (int, error) {
+	family, level, ttlOpt, recvErrOpt, icmpProto := unix.AF_INET,
+		unix.SOL_IP, unix.IP_TTL, unix.IP_RECVERR, unix.IPPROTO_ICMP
+	if addr.Is6() {
+		family, level, ttlOpt, recvErrOpt, icmpProto = unix.AF_INET6,
+			unix.SOL_IPV6, unix.IPV6_UNICAST_HOPS, unix.IPV6_RECVERR, unix.IPPROTO_ICMPV6
+	}
+	sotype, proto := unix.SOCK_DGRAM, 0
+	switch protocol {
+	case TracerouteTCP:
+		sotype = unix.SOCK_STREAM
+	case TracerouteICMP:
+		proto = icmpProto
+	}
+
+	fd, err := unix.Socket(family, sotype|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return -1, os.NewSyscallError("socket", err)
+	}
+	if err := unix.SetsockoptInt(fd, level, ttlOpt, ttl); err != nil {
+		unix.Close(fd)
+		return -1, os.NewSyscallError("setsockopt", err)
+	}
+	if err := unix.SetsockoptInt(fd, level, recvErrOpt, 1); err != nil {
+		unix.Close(fd)
+		return -1, os.NewSyscallError("setsockopt", err)
+	}
+	return fd, nil
+}
+
+// tracerouteSend sends the probe to the destination.
+func tracerouteSend(fd int, protocol string, dst netip.AddrPort) error {
+	var sa unix.Sockaddr = &unix.SockaddrInet4{Addr: dst.Addr().As4(), Port: int(dst.Port())}
+	if dst.Addr().Is6() {
+		sa = &unix.SockaddrInet6{Addr: dst.Addr().As16(), Port: int(dst.Port())}
+	}
+
+	switch protocol {
+	case TracerouteTCP:
+		// the SYN segment is the probe
+		if err := unix.Connect(fd, sa); err != nil && err != unix.EINPROGRESS {
+			return os.NewSyscallError("connect", err)
+		}
+		return nil
+
+	case TracerouteICMP:
+		// the kernel fills the identifier and the checksum
+		echo := make([]byte, 8+32)
+		echo[0] = 8 // echo request
+		if dst.Addr().Is6() {
+			echo[0] = 128
+		}
+		binary.BigEndian.PutUint16(echo[6:8], 1) // sequence number
+		return os.NewSyscallError("sendto", unix.Sendto(fd, echo, 0, sa))
+
+	default:
+		return os.NewSyscallError("sendto", unix.Sendto(fd, make([]byte, 32), 0, sa))
+	}
+}
+
+// tracerouteReadErrQueue reads the ICMP error from the socket error
+// queue, if any, and returns the corresponding reply and error.
+func tracerouteReadErrQueue(fd int, protocol string, rtt time.Duration) (tracerouteReply, bool, error) {
+	buf, oob := make([]byte, 512), make([]byte, 512)
+	_, oobn, _, _, err := unix.Recvmsg(fd, buf, oob, unix.MSG_ERRQUEUE)
+	if err != nil {
+		return tracerouteReply{}, false, nil
+	}
+	messages, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return tracerouteReply{}, false, nil
+	}
+
+	for _, msg := range messages {
+		if !(msg.Header.Level == unix.SOL_IP && msg.Header.Type == unix.IP_RECVERR) &&
+			!(msg.Header.Level == unix.SOL_IPV6 && msg.Header.Type == unix.IPV6_RECVERR) {
+			continue
+		}
+
+		// parse the struct sock_extended_err followed by the offender address
+		const eeSize = 16
+		data := msg.Data
+		if len(data) < eeSize {
+			continue
+		}
+		errno := syscall.Errno(binary.NativeEndian.Uint32(data[0:4]))
+		origin, icmpType, icmpCode := data[4], data[5], data[6]
+		if origin != unix.SO_EE_ORIGIN_ICMP && origin != unix.SO_EE_ORIGIN_ICMP6 {
+			return tracerouteReply{}, true, errno
+		}
+		reply := tracerouteReply{addr: tracerouteOffender(data[eeSize:]), rtt: rtt}
+
+		switch {
+		case origin == unix.SO_EE_ORIGIN_ICMP && icmpType == 11, // time exceeded
+			origin == unix.SO_EE_ORIGIN_ICMP6 && icmpType == 3:
+			return reply, true, nil
+
+		case origin == unix.SO_EE_ORIGIN_ICMP && icmpType == 3 && icmpCode == 3, // port unreachable
+			origin == unix.SO_EE_ORIGIN_ICMP6 && icmpType == 1 && icmpCode == 4:
+			if protocol == TracerouteUDP {
+				reply.reached = true
+				return reply, true, nil
+			}
+			return reply, true, errno
+
+		default:
+			return reply, true, errno
+		}
+	}
+	return tracerouteReply{}, false, nil
+}
+
+// tracerouteOffender parses the address of the node that sent the ICMP error.
+func tracerouteOffender(data []byte) netip.Addr {
+	if len(data) < 2 {
+		return netip.Addr{}
+	}
+	switch binary.NativeEndian.Uint16(data[0:2]) {
+	case unix.AF_INET:
+		if len(data) >= 8 {
+			return netip.AddrFrom4([4]byte(data[4:8]))
+		}
+	case unix.AF_INET6:
+		if len(data) >= 24 {
+			return netip.AddrFrom16([16]byte(data[8:24]))
+		}
+	}
+	return netip.Addr{}
+}
+
+// tracerouteReadReply reads the reply of the target, if any.
+func tracerouteReadReply(fd int, protocol string,
+	dst netip.AddrPort, rtt time.Duration) (tracerouteReply, bool, error) {
+	reply := tracerouteReply{addr: dst.Addr(), reached: true, rtt: rtt}
+
+	switch protocol {
+	case TracerouteTCP:
+		// both SYN-ACK and RST mean that we reached the target
+		soerr, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ERROR)
+		if err != nil {
+			return tracerouteReply{}, true, os.NewSyscallError("getsockopt", err)
+		}
+		switch errno := syscall.Errno(soerr); errno {
+		case 0, unix.ECONNREFUSED:
+			return reply, true, nil
+		default:
+			return tracerouteReply{}, true, errno
+		}
+
+	default:
+		// any datagram means that we reached the target, since ICMP
+		// sockets only receive the replies to their echo requests
+		buf := make([]byte, 1500)
+		_, _, err := unix.Recvfrom(fd, buf, 0)
+		switch err {
+		case nil:
+			return reply, true, nil
+		case unix.EAGAIN:
+			return tracerouteReply{}, false, nil
+		default:
+			return tracerouteReply{}, true, os.NewSyscallError("recvfrom", err)
+		}
+	}
+}
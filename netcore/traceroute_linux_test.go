@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNetwork_Traceroute_linux(t *testing.T) {
+	// closedPort returns a local UDP or TCP port where nobody listens
+	closedPort := func(t *testing.T, network string) string {
+		var addr net.Addr
+		switch network {
+		case "tcp":
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			addr = listener.Addr()
+			listener.Close()
+		default:
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			require.NoError(t, err)
+			addr = conn.LocalAddr()
+			conn.Close()
+		}
+		_, port, _ := net.SplitHostPort(addr.String())
+		return port
+	}
+
+	t.Run("udp probes reaching a closed port", func(t *testing.T) {
+		var buf bytes.Buffer
+		nx := &Network{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}
+		hops, err := nx.Traceroute(context.Background(), "127.0.0.1", &TracerouteOptions{
+			MaxTTL: 3,
+			Port:   closedPort(t, "udp"),
+		})
+		require.NoError(t, err)
+		require.Len(t, hops, 1)
+		assert.Equal(t, "127.0.0.1", hops[0].Addr)
+		assert.NoError(t, hops[0].Err)
+		assert.True(t, hops[0].Reached)
+		assert.Equal(t, 1, hops[0].TTL)
+
+		var ev map[string]any
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			require.NoError(t, json.Unmarshal([]byte(line), &ev))
+			if ev["msg"] == "tracerouteHop" {
+				break
+			}
+		}
+		assert.Equal(t, "tracerouteHop", ev["msg"])
+		assert.Equal(t, "udp", ev["protocol"])
+		assert.Equal(t, "127.0.0.1", ev["tracerouteHopAddr"])
+		assert.Equal(t, true, ev["tracerouteReached"])
+		assert.Equal(t, float64(1), ev["tracerouteTtl"])
+		assert.Nil(t, ev["err"])
+	})
+
+	t.Run("tcp probes reaching a listening port", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+		_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+		nx := &Network{}
+		hops, err := nx.Traceroute(context.Background(), "127.0.0.1", &TracerouteOptions{
+			Port:     port,
+			Protocol: TracerouteTCP,
+		})
+		require.NoError(t, err)
+		require.Len(t, hops, 1)
+		assert.True(t, hops[0].Reached)
+		assert.Equal(t, "127.0.0.1", hops[0].Addr)
+	})
+
+	t.Run("tcp probes reaching a closed port", func(t *testing.T) {
+		nx := &Network{}
+		hops, err := nx.Traceroute(context.Background(), "127.0.0.1", &TracerouteOptions{
+			Port:     closedPort(t, "tcp"),
+			Protocol: TracerouteTCP,
+		})
+		require.NoError(t, err)
+		require.Len(t, hops, 1)
+		assert.True(t, hops[0].Reached)
+	})
+
+	t.Run("icmp probes", func(t *testing.T) {
+		nx := &Network{}
+		hops, err := nx.Traceroute(context.Background(), "127.0.0.1", &TracerouteOptions{
+			Protocol: TracerouteICMP,
+		})
+		if errors.Is(err, syscall.EACCES) || errors.Is(err, syscall.EPERM) {
+			t.Skip("unprivileged ICMP sockets not allowed on this system")
+		}
+		require.NoError(t, err)
+		require.Len(t, hops, 1)
+		assert.True(t, hops[0].Reached)
+		assert.Equal(t, "127.0.0.1", hops[0].Addr)
+	})
+}
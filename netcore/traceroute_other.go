@@ -0,0 +1,25 @@
+//go:build !linux
+
+//
+// SPDX-License-Identifier: GPL-3.0-or-later
+//
+// Traceroute probes on other platforms.
+//
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"time"
+)
+
+// errTracerouteUnsupported indicates that traceroute is not supported on this platform.
+var errTracerouteUnsupported = errors.New("netcore: traceroute not supported on this platform")
+
+// tracerouteProbe fails with [errTracerouteUnsupported] because we only support traceroute on Linux.
+func tracerouteProbe(ctx context.Context,
+	protocol string, dst netip.AddrPort, ttl int, timeout time.Duration) (tracerouteReply, error) {
+	return tracerouteReply{}, errTracerouteUnsupported
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netcore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetwork_Traceroute(t *testing.T) {
+	t.Run("unsupported protocol", func(t *testing.T) {
+		nx := &Network{}
+		hops, err := nx.Traceroute(context.Background(), "127.0.0.1", &TracerouteOptions{Protocol: "sctp"})
+		assert.ErrorIs(t, err, errTracerouteUnsupportedProtocol)
+		assert.Nil(t, hops)
+	})
+
+	t.Run("lookup failure", func(t *testing.T) {
+		expectedErr := errors.New("mocked lookup error")
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return nil, expectedErr
+			},
+		}
+		hops, err := nx.Traceroute(context.Background(), "example.com", nil)
+		assert.ErrorIs(t, err, expectedErr)
+		assert.Nil(t, hops)
+	})
+
+	t.Run("lookup without addresses", func(t *testing.T) {
+		nx := &Network{
+			LookupHostFunc: func(ctx context.Context, domain string) ([]string, error) {
+				return []string{}, nil
+			},
+		}
+		hops, err := nx.Traceroute(context.Background(), "example.com", nil)
+		assert.ErrorIs(t, err, errNoAddresses)
+		assert.Nil(t, hops)
+	})
+
+	t.Run("no address matching the address family", func(t *testing.T) {
+		nx := &Network{AddressFamilyPolicy: AddressFamilyOnlyIPv6}
+		hops, err := nx.Traceroute(context.Background(), "127.0.0.1", nil)
+		assert.Error(t, err)
+		assert.Nil(t, hops)
+	})
+
+	t.Run("canceled context", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		nx := &Network{}
+		hops, err := nx.Traceroute(ctx, "127.0.0.1", nil)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, hops)
+	})
+}
@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// MeasurementBackend simulates the API of a measurement collector, which
+// allows end-to-end tests of measurement tools, including the submission of
+// results, to run entirely inside the simulation.
+//
+// The backend exposes the following HTTPS JSON endpoints:
+//
+// 1. POST /api/v1/check-in returns the URLs to measure as a
+// [MeasurementCheckInResponse];
+//
+// 2. POST /api/v1/submit stores the measurement in the request
+// body and returns a [MeasurementSubmitResponse].
+//
+// Both endpoints reply with 400 if the request body is not valid JSON.
+//
+// Use [*Scenario.MustNewMeasurementBackendStack] to serve the backend
+// and [*MeasurementBackend.Submissions] to inspect the submitted results.
+type MeasurementBackend struct {
+	// URLs contains the URLs to return on check-in.
+	URLs []string
+
+	// mu protects submissions.
+	mu sync.Mutex
+
+	// submissions contains the submitted measurements.
+	submissions []json.RawMessage
+}
+
+// MeasurementCheckInResponse is the response to a check-in.
+type MeasurementCheckInResponse struct {
+	// URLs contains the URLs to measure.
+	URLs []string `json:"urls"`
+}
+
+// MeasurementSubmitResponse is the response to a submission.
+type MeasurementSubmitResponse struct {
+	// MeasurementUID is the unique ID of the submitted measurement.
+	MeasurementUID string `json:"measurement_uid"`
+}
+
+// Submissions returns a copy of the submitted measurements.
+//
+// This method is goroutine safe.
+func (mb *MeasurementBackend) Submissions() []json.RawMessage {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	out := make([]json.RawMessage, len(mb.submissions))
+	copy(out, mb.submissions)
+	return out
+}
+
+// newHandler returns the [http.Handler] implementing the API.
+func (mb *MeasurementBackend) newHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v1/check-in", mb.handleCheckIn)
+	mux.HandleFunc("POST /api/v1/submit", mb.handleSubmit)
+	return mux
+}
+
+// handleCheckIn handles the check-in endpoint.
+func (mb *MeasurementBackend) handleCheckIn(w http.ResponseWriter, r *http.Request) {
+	if _, ok := readJSONBody(w, r); !ok {
+		return
+	}
+	urls := mb.URLs
+	if urls == nil {
+		urls = []string{}
+	}
+	writeJSON(w, &MeasurementCheckInResponse{URLs: urls})
+}
+
+// handleSubmit handles the submit endpoint.
+func (mb *MeasurementBackend) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	body, ok := readJSONBody(w, r)
+	if !ok {
+		return
+	}
+	mb.mu.Lock()
+	mb.submissions = append(mb.submissions, body)
+	uid := strconv.Itoa(len(mb.submissions))
+	mb.mu.Unlock()
+	writeJSON(w, &MeasurementSubmitResponse{MeasurementUID: uid})
+}
+
+// readJSONBody reads the request body and checks whether it contains
+// valid JSON, otherwise it replies with 400 and returns false.
+func readJSONBody(w http.ResponseWriter, r *http.Request) (json.RawMessage, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil || !json.Valid(body) {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// writeJSON writes the given value as a JSON response.
+func writeJSON(w http.ResponseWriter, value any) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -3,9 +3,13 @@
 package censor
 
 import (
+	"math/rand/v2"
 	"net/netip"
+	"sync"
+	"time"
 
 	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim/clock"
 	netsimdns "github.com/rbmk-project/x/netsim/dns"
 	"github.com/rbmk-project/x/netsim/packet"
 )
@@ -13,20 +17,114 @@ import (
 // Database is an alias for [netsimdns.Database].
 type Database = netsimdns.Database
 
+// DNSAddrSelection controls which records of the queried type
+// a [*DNSPoisoner] includes in each spoofed response.
+type DNSAddrSelection int
+
+const (
+	// DNSAddrSelectionDefault includes all the records when injecting
+	// a single response and behaves like [DNSAddrSelectionRoundRobin]
+	// when injecting a burst of responses.
+	DNSAddrSelectionDefault DNSAddrSelection = iota
+
+	// DNSAddrSelectionAll includes all the records in each response.
+	DNSAddrSelectionAll
+
+	// DNSAddrSelectionRoundRobin includes a single record in each
+	// response, selected in round-robin order within the burst.
+	DNSAddrSelectionRoundRobin
+
+	// DNSAddrSelectionRandom includes a single, randomly
+	// selected record in each response.
+	DNSAddrSelectionRandom
+)
+
+// DNSPoisonerConfig contains optional settings for a [*DNSPoisoner].
+//
+// The zero value is ready to use and injects a single response.
+type DNSPoisonerConfig struct {
+	// AddrSelection controls how to select the records of the queried
+	// type from the database when building each spoofed response.
+	AddrSelection DNSAddrSelection
+
+	// Authoritative sets the AA bit in the spoofed responses.
+	Authoritative bool
+
+	// Burst is the number of spoofed responses to inject for each
+	// matching query. If zero or negative, we inject one response.
+	//
+	// When the database contains several records of the queried type,
+	// by default each response in the burst contains a different record,
+	// thus modeling injectors that race several answers carrying different
+	// forged addresses. Use AddrSelection to change this behavior.
+	Burst int
+
+	// Clock is the optional clock used to implement Spacing. If nil,
+	// we use [clock.Real]. Pass the scenario clock when using a fake
+	// clock to drive the simulation.
+	Clock clock.Clock
+
+	// EmptyAnswer causes the spoofed responses to have an empty answer
+	// section. We still only inject for names found in the database.
+	EmptyAnswer bool
+
+	// IPTTL is the IP TTL of the spoofed packets. If zero, we use 64.
+	IPTTL uint8
+
+	// Injector is the optional [packet.Injector] (e.g., the router) used
+	// to deliver the responses following the first one. If nil, all the
+	// responses in the burst are injected immediately. Otherwise, call
+	// [*DNSPoisoner.Close] to stop injecting the pending responses.
+	Injector packet.Injector
+
+	// RecursionAvailable sets the RA bit in the spoofed responses.
+	RecursionAvailable bool
+
+	// Spacing is the delay between consecutive responses in a burst. This
+	// field is only meaningful when Injector is not nil.
+	Spacing time.Duration
+
+	// ZeroTTL causes the records in the spoofed responses to have a zero TTL.
+	ZeroTTL bool
+}
+
 // DNSPoisoner implements GFW-style DNS poisoning
 type DNSPoisoner struct {
-	addrs map[netip.Addr]struct{}
-	db    *Database
+	addrs     map[netip.Addr]struct{}
+	closeOnce sync.Once
+	config    DNSPoisonerConfig
+	db        *Database
+	done      chan struct{}
 }
 
 // NewDNSPoisoner creates a new DNS poisoner that injects
 // responses as configured in the given database.
 func NewDNSPoisoner(db *Database, addrs ...netip.Addr) *DNSPoisoner {
+	return NewDNSPoisonerWithConfig(db, &DNSPoisonerConfig{}, addrs...)
+}
+
+// NewDNSPoisonerWithConfig is like [NewDNSPoisoner] but allows
+// to customize the poisoner using a [*DNSPoisonerConfig].
+func NewDNSPoisonerWithConfig(db *Database, config *DNSPoisonerConfig, addrs ...netip.Addr) *DNSPoisoner {
 	am := make(map[netip.Addr]struct{}, len(addrs))
 	for _, addr := range addrs {
 		am[addr] = struct{}{}
 	}
-	return &DNSPoisoner{addrs: am, db: db}
+	return &DNSPoisoner{
+		addrs:     am,
+		closeOnce: sync.Once{},
+		config:    *config,
+		db:        db,
+		done:      make(chan struct{}),
+	}
+}
+
+// Close stops injecting the responses scheduled using the Injector
+// and Spacing, which would otherwise be pending until the clock fires
+// the corresponding timers. This method is idempotent.
+func (p *DNSPoisoner) Close() error {
+	p.closeOnce.Do(func() { close(p.done) })
+	return nil
 }
 
 // Filter implements [packet.Filter].
@@ -55,41 +153,147 @@ func (p *DNSPoisoner) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packe
 		return packet.CONTINUE, nil
 	}
 
-	// Create poisoned response
+	// Create poisoned responses
 	spoofed := p.spoof(pkt, query)
 
+	// Possibly schedule delayed injection for the rest of the burst
+	if p.config.Injector != nil && len(spoofed) > 1 {
+		p.injectLater(spoofed[1:])
+		spoofed = spoofed[:1]
+	}
+
 	// Let original query continue
 	return packet.CONTINUE, spoofed
 }
 
+// injectLater injects the given packets spacing them in time until
+// we inject all of them or [*DNSPoisoner.Close] is called.
+func (p *DNSPoisoner) injectLater(pkts []*packet.Packet) {
+	clk := p.config.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	timer := clk.NewTimer(p.config.Spacing)
+	go func() {
+		defer timer.Stop()
+		for idx, pkt := range pkts {
+			select {
+			case <-timer.C():
+			case <-p.done:
+				return
+			}
+			select {
+			case <-p.done: // select picks a random ready case, so check again
+				return
+			default:
+			}
+			if idx+1 < len(pkts) {
+				timer.Reset(p.config.Spacing) // before injecting, to schedule deterministically
+			}
+			_ = p.config.Injector.Inject(pkt)
+		}
+	}()
+}
+
 func (p *DNSPoisoner) spoof(
 	pkt *packet.Packet, query *dns.Msg) []*packet.Packet {
-	// Prepare the response
-	resp := &dns.Msg{}
-	resp.SetReply(query)
-
 	// Get records from database
 	q0 := query.Question[0]
 	rrs, found := p.db.Lookup(q0.Qtype, q0.Name)
 	if !found {
 		return []*packet.Packet{}
 	}
-	resp.Answer = rrs
 
-	// Pack the response
-	payload, err := resp.Pack()
-	if err != nil {
-		return []*packet.Packet{}
+	// Create a spoofed packet for each response in the burst
+	burst := max(1, p.config.Burst)
+	out := make([]*packet.Packet, 0, burst)
+	for idx := 0; idx < burst; idx++ {
+		// Prepare the response
+		resp := &dns.Msg{}
+		resp.SetReply(query)
+		resp.Authoritative = p.config.Authoritative
+		resp.RecursionAvailable = p.config.RecursionAvailable
+		resp.Answer = p.answer(rrs, q0.Qtype, idx, burst)
+
+		// Pack the response
+		payload, err := resp.Pack()
+		if err != nil {
+			return []*packet.Packet{}
+		}
+
+		// Create the spoofed packet
+		out = append(out, &packet.Packet{
+			TTL:        p.ipTTL(),
+			SrcAddr:    pkt.DstAddr,
+			DstAddr:    pkt.SrcAddr,
+			IPProtocol: packet.IPProtocolUDP,
+			SrcPort:    pkt.DstPort,
+			DstPort:    pkt.SrcPort,
+			Payload:    payload,
+		})
+	}
+	return out
+}
+
+// ipTTL returns the IP TTL to use for spoofed packets.
+func (p *DNSPoisoner) ipTTL() uint8 {
+	if p.config.IPTTL > 0 {
+		return p.config.IPTTL
+	}
+	return 64
+}
+
+// answer builds the answer section for the idx-th response in the burst.
+func (p *DNSPoisoner) answer(rrs []dns.RR, qtype uint16, idx, burst int) []dns.RR {
+	if p.config.EmptyAnswer {
+		return nil
 	}
 
-	// Create the spoofed packet
-	return []*packet.Packet{{
-		TTL:        64,
-		SrcAddr:    pkt.DstAddr,
-		DstAddr:    pkt.SrcAddr,
-		IPProtocol: packet.IPProtocolUDP,
-		SrcPort:    pkt.DstPort,
-		DstPort:    pkt.SrcPort,
-		Payload:    payload,
-	}}
+	selection := p.config.AddrSelection
+	if selection == DNSAddrSelectionDefault {
+		selection = DNSAddrSelectionAll
+		if burst > 1 {
+			selection = DNSAddrSelectionRoundRobin
+		}
+	}
+
+	switch selection {
+	case DNSAddrSelectionRoundRobin:
+		rrs = selectOne(rrs, qtype, func(n int) int { return idx % n })
+	case DNSAddrSelectionRandom:
+		rrs = selectOne(rrs, qtype, rand.IntN)
+	}
+
+	if p.config.ZeroTTL {
+		// Make sure we do not modify the records owned by the database.
+		out := make([]dns.RR, 0, len(rrs))
+		for _, rr := range rrs {
+			rr = dns.Copy(rr)
+			rr.Header().Ttl = 0
+			out = append(out, rr)
+		}
+		rrs = out
+	}
+	return rrs
+}
+
+// selectOne returns the records that do not match the qtype (e.g., the
+// CNAME chain) followed by one of the records matching the qtype, whose
+// index is chosen by calling pick with the number of matching records.
+func selectOne(rrs []dns.RR, qtype uint16, pick func(n int) int) []dns.RR {
+	var (
+		matching []dns.RR
+		others   []dns.RR
+	)
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == qtype {
+			matching = append(matching, rr)
+			continue
+		}
+		others = append(others, rr)
+	}
+	if len(matching) <= 0 {
+		return rrs
+	}
+	return append(others, matching[pick(len(matching))])
 }
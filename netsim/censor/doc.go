@@ -15,6 +15,11 @@ The [*DNSPoisoner] type implements GFW-style DNS poisoning by injecting spoofed
 responses. It can target specific resolvers and is based on a database of poisoned
 responses to inject. Legitimate responses are allowed to pass through, thus the
 client is expected to receive multiple responses for each censored query.
+Using [NewDNSPoisonerWithConfig], it is also possible to inject a burst of
+spoofed responses carrying different forged addresses, optionally spaced in
+time, thus modeling injectors that race several answers. The [*DNSPoisonerConfig]
+also controls the fingerprint of the forged responses (e.g., IP TTL, AA/RA bits,
+zero-TTL records, and empty answer sections).
 
 # TCP Reset Injection
 
@@ -38,5 +43,11 @@ The [*DNatter] type implements transparent proxying through destination NAT
 (DNAT): it allows redirecting traffic from specific sources to alternative destinations
 while maintaining proper connection tracking. This models censors that redirect
 traffic to warning pages or surveillance systems.
+
+# Debug Logging
+
+The [*Logger] type emits a structured [log/slog] event for each packet
+matching an optional [PacketMatcher] without altering the packet. This is
+useful to instrument complex filter chains while developing tests.
 */
 package censor
@@ -8,6 +8,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rbmk-project/x/netsim/clock"
 	"github.com/rbmk-project/x/netsim/packet"
 )
 
@@ -23,6 +24,9 @@ type Blackholer struct {
 	// if nil, only considers the target (if set).
 	pattern []byte
 
+	// clock is the clock used to expire the blackholing state.
+	clock clock.Clock
+
 	// duration specifies how long to maintain blackholing state, if set.
 	duration time.Duration
 
@@ -51,6 +55,7 @@ type fiveTuple struct {
 // If pattern is nil, it doesn't perform payload matching.
 func NewBlackholer(duration time.Duration, target netip.AddrPort, pattern []byte) *Blackholer {
 	return &Blackholer{
+		clock:    clock.Real(),
 		target:   target,
 		pattern:  pattern,
 		duration: duration,
@@ -59,6 +64,15 @@ func NewBlackholer(duration time.Duration, target netip.AddrPort, pattern []byte
 	}
 }
 
+// SetClock sets the clock used to expire the blackholing state, which by
+// default is [clock.Real]. Pass the scenario clock when using a fake clock
+// to drive the simulation.
+//
+// Note that this method IS NOT goroutine safe.
+func (t *Blackholer) SetClock(clock clock.Clock) {
+	t.clock = clock
+}
+
 // Filter implements [packet.Filter].
 func (t *Blackholer) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet) {
 	// Check if this connection is already blocked
@@ -69,7 +83,7 @@ func (t *Blackholer) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet
 		dstAddr: pkt.DstAddr,
 		dstPort: pkt.DstPort,
 	}
-	now := time.Now()
+	now := t.clock.Now()
 	t.mu.Lock()
 	deadline, ok := t.blocked[tuple]
 	blocked := ok && now.Before(deadline)
@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package censor
+
+import (
+	"log/slog"
+
+	"github.com/rbmk-project/x/netsim/clock"
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// PacketMatcher returns whether a [*packet.Packet] matches.
+type PacketMatcher func(pkt *packet.Packet) bool
+
+// Logger is a [packet.Filter] emitting a structured event for each
+// matching packet, without altering or dropping the packet.
+//
+// This filter is useful to instrument complex filter chains while
+// developing tests, since you can place it before or after other filters.
+type Logger struct {
+	// clock is the clock used to timestamp the events.
+	clock clock.Clock
+
+	// logger is the structured logger to use.
+	logger *slog.Logger
+
+	// matcher is the optional packet matcher.
+	matcher PacketMatcher
+}
+
+// NewLogger creates a new [*Logger] instance.
+//
+// If matcher is nil, we log all the packets.
+func NewLogger(logger *slog.Logger, matcher PacketMatcher) *Logger {
+	return &Logger{clock: clock.Real(), logger: logger, matcher: matcher}
+}
+
+// SetClock sets the clock used to timestamp the events, which by default
+// is [clock.Real]. Pass the scenario clock when using a fake clock to
+// drive the simulation.
+//
+// Note that this method IS NOT goroutine safe.
+func (l *Logger) SetClock(clock clock.Clock) {
+	l.clock = clock
+}
+
+// Filter implements [packet.Filter].
+func (l *Logger) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet) {
+	if l.matcher == nil || l.matcher(pkt) {
+		l.logger.Info(
+			"packetMatched",
+			slog.String("dstAddr", pkt.DstAddr.String()),
+			slog.Int("dstPort", int(pkt.DstPort)),
+			slog.String("ipProtocol", pkt.IPProtocol.String()),
+			slog.Int("ipTTL", int(pkt.TTL)),
+			slog.Int("payloadSize", len(pkt.Payload)),
+			slog.String("srcAddr", pkt.SrcAddr.String()),
+			slog.Int("srcPort", int(pkt.SrcPort)),
+			slog.Time("t", l.clock.Now()),
+			slog.String("tcpFlags", pkt.Flags.String()),
+		)
+	}
+	return packet.CONTINUE, nil
+}
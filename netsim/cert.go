@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"crypto/tls"
+	"errors"
+	"sync"
+
+	"github.com/rbmk-project/common/runtimex"
+	"github.com/rbmk-project/x/netsim/simpki"
+)
+
+// stackCert is the certificate used by the TLS and QUIC servers
+// of a stack, which we may replace at runtime.
+type stackCert struct {
+	// cert is the current certificate.
+	cert tls.Certificate
+
+	// config is the config used to issue the certificate, or nil
+	// when the stack uses the [StackConfig] TLSCertificate.
+	config *simpki.Config
+
+	// mu protects cert.
+	mu sync.Mutex
+}
+
+// get returns the current certificate and is suitable
+// for the GetCertificate field of [*tls.Config].
+func (sc *stackCert) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	sc.mu.Lock()
+	cert := sc.cert
+	sc.mu.Unlock()
+	return &cert, nil
+}
+
+// set replaces the current certificate.
+func (sc *stackCert) set(cert tls.Certificate) {
+	sc.mu.Lock()
+	sc.cert = cert
+	sc.mu.Unlock()
+}
+
+// errCannotRotate indicates that we cannot rotate the certificate of a stack.
+var errCannotRotate = errors.New("stack certificate not issued by the scenario PKI")
+
+// MustRotateCert is like [*Scenario.RotateCert] but panics on error.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) MustRotateCert(stack *Stack) tls.Certificate {
+	return runtimex.Try1(s.RotateCert(stack))
+}
+
+// RotateCert reissues the certificate of the given stack using a new
+// serial number and key (see [*simpki.PKI.ReissueCert]), which revokes
+// the previous certificate. The TLS and QUIC servers of the stack use the
+// new certificate for new handshakes, while existing connections keep
+// working, which allows to test clients across certificate rotation.
+//
+// This method fails if the stack has no certificate or uses
+// the certificate configured using [StackConfig] TLSCertificate.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) RotateCert(stack *Stack) (tls.Certificate, error) {
+	sc := s.certs[stack]
+	if sc == nil || sc.config == nil {
+		return tls.Certificate{}, errCannotRotate
+	}
+	cert, err := s.pki.ReissueCert(sc.config)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	sc.set(cert)
+	return cert, nil
+}
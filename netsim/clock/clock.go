@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package clock allows to inject the passing of time into the simulation.
+//
+// The [Real] clock uses the [time] package. The [*Fake] clock only
+// moves forward when calling [*Fake.Advance], which allows to drive a
+// whole simulation deterministically (see netsim's ScenarioConfig).
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the passing of time.
+type Clock interface {
+	// AfterFunc is like [time.AfterFunc].
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTimer is like [time.NewTimer].
+	NewTimer(d time.Duration) Timer
+
+	// Now is like [time.Now].
+	Now() time.Time
+}
+
+// Timer abstracts a [*time.Timer].
+type Timer interface {
+	// C returns the channel on which we deliver the time when the
+	// timer fires. It returns nil for timers created by AfterFunc.
+	C() <-chan time.Time
+
+	// Reset is like [*time.Timer.Reset].
+	Reset(d time.Duration) bool
+
+	// Stop is like [*time.Timer.Stop].
+	Stop() bool
+}
+
+// Real returns the [Clock] using the [time] package.
+func Real() Clock {
+	return realClock{}
+}
+
+// realClock implements [Clock] using the [time] package.
+type realClock struct{}
+
+// AfterFunc implements [Clock].
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{time.AfterFunc(d, f)}
+}
+
+// NewTimer implements [Clock].
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{time.NewTimer(d)}
+}
+
+// Now implements [Clock].
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// realTimer implements [Timer] using a [*time.Timer].
+type realTimer struct {
+	t *time.Timer
+}
+
+// C implements [Timer].
+func (t *realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Reset implements [Timer].
+func (t *realTimer) Reset(d time.Duration) bool {
+	return t.t.Reset(d)
+}
+
+// Stop implements [Timer].
+func (t *realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// Fake is a [Clock] that only moves forward when calling [*Fake.Advance].
+//
+// The zero value is not ready to use; construct using [NewFake].
+type Fake struct {
+	// mu protects now and timers.
+	mu sync.Mutex
+
+	// now is the current time.
+	now time.Time
+
+	// timers contains the active timers.
+	timers map[*fakeTimer]bool
+}
+
+// NewFake creates a new [*Fake] clock starting at the given time.
+func NewFake(now time.Time) *Fake {
+	return &Fake{
+		mu:     sync.Mutex{},
+		now:    now,
+		timers: make(map[*fakeTimer]bool),
+	}
+}
+
+var _ Clock = &Fake{}
+
+// Advance moves the clock forward by the given duration and fires, in
+// chronological order, all the timers expiring in the meanwhile. Functions
+// registered using AfterFunc run synchronously after releasing the lock.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	var expired []*fakeTimer
+	for t := range f.timers {
+		if !t.when.After(f.now) {
+			expired = append(expired, t)
+			delete(f.timers, t)
+		}
+	}
+	slices.SortStableFunc(expired, func(a, b *fakeTimer) int {
+		return a.when.Compare(b.when)
+	})
+	f.mu.Unlock()
+
+	for _, t := range expired {
+		if t.fn != nil {
+			t.fn()
+			continue
+		}
+		select {
+		case t.c <- t.when:
+		default:
+		}
+	}
+}
+
+// AfterFunc implements [Clock].
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	t.Reset(d)
+	return t
+}
+
+// NewTimer implements [Clock].
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+// Now implements [Clock].
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// fakeTimer implements [Timer] for [*Fake].
+type fakeTimer struct {
+	// c is the channel, which is nil for AfterFunc timers.
+	c chan time.Time
+
+	// clock is the clock owning the timer.
+	clock *Fake
+
+	// fn is the function to call, which is nil for channel timers.
+	fn func()
+
+	// when is when the timer fires, protected by the clock mutex.
+	when time.Time
+}
+
+// C implements [Timer].
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+// Reset implements [Timer].
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	active := t.Stop()
+	t.clock.mu.Lock()
+	t.when = t.clock.now.Add(d)
+	t.clock.timers[t] = true
+	t.clock.mu.Unlock()
+	if d <= 0 {
+		t.clock.Advance(0)
+	}
+	return active
+}
+
+// Stop implements [Timer].
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	t.clock.mu.Unlock()
+	if t.c != nil {
+		select {
+		case <-t.c:
+		default:
+		}
+	}
+	return active
+}
@@ -3,6 +3,7 @@
 package netsim
 
 import (
+	"encoding/base64"
 	"io"
 	"net/http"
 
@@ -20,12 +21,31 @@ var newDNSDatabase = dns.NewDatabase
 
 // NewDNSHTTPHandler returns an [http.Handler] handling DNS-over-HTTPS.
 func NewDNSHTTPHandler(dd dns.Database) http.Handler {
+	return newDNSOverHTTPSHandler(&dd)
+}
+
+// newDNSOverHTTPSHandler adapts a [DNSHandler] to handle DNS-over-HTTPS
+// using either the POST or the GET method as documented by RFC 8484.
+func newDNSOverHTTPSHandler(handler DNSHandler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		rawQuery, err := io.ReadAll(r.Body)
+		var (
+			rawQuery []byte
+			err      error
+		)
+		switch r.Method {
+		case http.MethodGet:
+			rawQuery, err = base64.RawURLEncoding.DecodeString(r.URL.Query().Get("dns"))
+		case http.MethodPost:
+			rawQuery, err = io.ReadAll(r.Body)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
 		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
 		w.Header().Add("Content-Type", "application/dns-message")
-		dd.Handle(w, rawQuery)
+		handler.Handle(dns.WithClientAddr(w, clientAddr(r.RemoteAddr)), rawQuery)
 	})
 }
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"net/netip"
+
+	"github.com/rbmk-project/dnscore/dnscoretest"
+)
+
+// ClientAddrResponseWriter is a [dnscoretest.ResponseWriter] that
+// also knows the address of the client that sent the query.
+//
+// The netsim package uses [WithClientAddr] to create this kind of
+// writers when serving DNS, thus allowing handlers to compute answers
+// depending on the client address.
+type ClientAddrResponseWriter interface {
+	dnscoretest.ResponseWriter
+
+	// ClientAddr returns the address of the client.
+	ClientAddr() netip.Addr
+}
+
+// WithClientAddr returns a [ClientAddrResponseWriter] that writes
+// using the given writer and returns the given client address.
+func WithClientAddr(rw dnscoretest.ResponseWriter, addr netip.Addr) ClientAddrResponseWriter {
+	return &clientAddrResponseWriter{addr: addr.Unmap(), rw: rw}
+}
+
+// clientAddrResponseWriter is the writer returned by [WithClientAddr].
+type clientAddrResponseWriter struct {
+	addr netip.Addr
+	rw   dnscoretest.ResponseWriter
+}
+
+// ClientAddr implements [ClientAddrResponseWriter].
+func (w *clientAddrResponseWriter) ClientAddr() netip.Addr {
+	return w.addr
+}
+
+// Write implements [dnscoretest.ResponseWriter].
+func (w *clientAddrResponseWriter) Write(rawResp []byte) (int, error) {
+	return w.rw.Write(rawResp)
+}
+
+// ClientAddr returns the address of the client that sent the query
+// being answered using the given writer, or the zero value when the
+// writer is not a [ClientAddrResponseWriter].
+func ClientAddr(rw dnscoretest.ResponseWriter) netip.Addr {
+	if crw, ok := rw.(ClientAddrResponseWriter); ok {
+		return crw.ClientAddr()
+	}
+	return netip.Addr{}
+}
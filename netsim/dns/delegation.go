@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import "github.com/miekg/dns"
+
+// delegationFor returns the NS records of the zone cut at or above the given
+// name, if any, which occurs when the database contains NS records for a name
+// without an SOA, meaning that another server is authoritative for it. We stop
+// searching at the closest enclosing SOA, for which we are authoritative.
+//
+// We answer queries for the DS records of a zone cut rather than referring
+// them, since the DS records belong to the parent zone (RFC 4035).
+//
+// The caller must hold the lock.
+func (dd *Database) delegationFor(qtype uint16, name string) []dns.RR {
+	for current := name; ; {
+		if dd.hasSOA(current) {
+			return nil
+		}
+		if qtype != dns.TypeDS || current != name {
+			var ns []dns.RR
+			for _, rr := range dd.names[current] {
+				if rr.Header().Rrtype == dns.TypeNS {
+					ns = append(ns, rr)
+				}
+			}
+			if len(ns) > 0 {
+				return ns
+			}
+		}
+		parent, ok := parentName(current)
+		if !ok {
+			return nil
+		}
+		current = parent
+	}
+}
+
+// glueFor returns the A and AAAA records of the given name servers that
+// the database contains, which resolvers need to follow a referral when
+// the name servers are below the zone cut.
+//
+// The caller must hold the lock.
+func (dd *Database) glueFor(ns []dns.RR) []dns.RR {
+	var glue []dns.RR
+	for _, rr := range ns {
+		for _, addr := range dd.names[rr.(*dns.NS).Ns] {
+			if rrtype := addr.Header().Rrtype; rrtype == dns.TypeA || rrtype == dns.TypeAAAA {
+				glue = append(glue, addr)
+			}
+		}
+	}
+	return glue
+}
@@ -4,7 +4,10 @@
 package dns
 
 import (
+	"log/slog"
 	"net"
+	"strings"
+	"sync"
 
 	"github.com/miekg/dns"
 	"github.com/rbmk-project/common/runtimex"
@@ -15,42 +18,207 @@ import (
 type Handler = dnscoretest.Handler
 
 // Database models the global DNS database.
+//
+// The database is goroutine safe, so it is possible to modify it while
+// servers are answering queries (e.g., to simulate a domain migrating to
+// new addresses). Copies of a database share the same records and lock.
 type Database struct {
-	names map[string][]dns.RR
+	defaultFault *FaultConfig
+	fallback     HookFunc
+	faults       map[string]*FaultConfig
+	hooks        map[string]HookFunc
+	logger       *slog.Logger
+	mu           *sync.RWMutex
+	names        map[string][]dns.RR
+	views        []view
+	zones        map[string]*signedZone
 }
 
 // NewDatabase creates a new DNS database.
 func NewDatabase() *Database {
 	return &Database{
-		names: make(map[string][]dns.RR),
+		faults: make(map[string]*FaultConfig),
+		hooks:  make(map[string]HookFunc),
+		mu:     &sync.RWMutex{},
+		names:  make(map[string][]dns.RR),
+		zones:  make(map[string]*signedZone),
 	}
 }
 
-// AddCNAME adds a CNAME alias.
-//
-// This method IS NOT goroutine safe.
-func (dd *Database) AddCNAME(name, alias string) {
-	header := dns.RR_Header{
+// defaultTTL is the TTL of the records added using the convenience adders.
+const defaultTTL = 3600
+
+// newHeader returns the [dns.RR_Header] for a record added using
+// the convenience adders with the given name and type.
+func newHeader(name string, rrtype uint16) dns.RR_Header {
+	return dns.RR_Header{
 		Name:     dns.CanonicalName(name),
-		Rrtype:   dns.TypeCNAME,
+		Rrtype:   rrtype,
 		Class:    dns.ClassINET,
-		Ttl:      3600,
+		Ttl:      defaultTTL,
 		Rdlength: 0,
 	}
+}
+
+// AddRR adds a generic record for the given domain name, thus allowing
+// to serve any record type. We override the name in the record header
+// with the canonical version of the given name.
+//
+// This method is goroutine safe.
+func (dd *Database) AddRR(name string, rr dns.RR) {
+	dd.mu.Lock()
+	dd.addRR(name, rr)
+	dd.mu.Unlock()
+}
+
+// addRR is like AddRR but assumes the caller holds the lock.
+func (dd *Database) addRR(name string, rr dns.RR) {
+	name = dns.CanonicalName(name)
+	rr.Header().Name = name
+	dd.names[name] = append(dd.names[name], rr)
+}
+
+// RemoveName removes all the records of the given domain name.
+//
+// This method is goroutine safe.
+func (dd *Database) RemoveName(name string) {
+	dd.mu.Lock()
+	delete(dd.names, dns.CanonicalName(name))
+	dd.mu.Unlock()
+}
 
-	rr := &dns.CNAME{
-		Hdr:    header,
+// ReplaceAddresses atomically replaces the A/AAAA records of the given
+// domainNames with records mapping them to the given IPv4/IPv6 addresses,
+// while preserving records of other types (e.g., MX or TXT).
+//
+// This method is goroutine safe.
+func (dd *Database) ReplaceAddresses(domainNames, addresses []string) {
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	for _, name := range domainNames {
+		name = dns.CanonicalName(name)
+		var kept []dns.RR
+		for _, rr := range dd.names[name] {
+			if rrtype := rr.Header().Rrtype; rrtype != dns.TypeA && rrtype != dns.TypeAAAA {
+				kept = append(kept, rr)
+			}
+		}
+		dd.names[name] = kept
+	}
+	dd.addAddresses(domainNames, addresses, defaultTTL)
+}
+
+// AddCNAME adds a CNAME alias.
+//
+// This method is goroutine safe.
+func (dd *Database) AddCNAME(name, alias string) {
+	dd.AddRR(name, &dns.CNAME{
+		Hdr:    newHeader(name, dns.TypeCNAME),
 		Target: dns.CanonicalName(alias),
+	})
+}
+
+// AddNS adds NS records delegating the given domain name to the given name servers.
+//
+// Unless the domain name also has an SOA record, meaning that we are authoritative
+// for it, the NS records create a zone cut and [*Database.Handle] answers queries
+// for names at or below it using a referral to the name servers, which includes
+// their A/AAAA records as glue when the database contains them.
+//
+// This method is goroutine safe.
+func (dd *Database) AddNS(name string, servers ...string) {
+	for _, server := range servers {
+		dd.AddRR(name, &dns.NS{
+			Hdr: newHeader(name, dns.TypeNS),
+			Ns:  dns.CanonicalName(server),
+		})
 	}
+}
 
-	dd.names[name] = append(dd.names[name], rr)
+// AddMX adds an MX record for the given domain name.
+//
+// This method is goroutine safe.
+func (dd *Database) AddMX(name string, preference uint16, exchange string) {
+	dd.AddRR(name, &dns.MX{
+		Hdr:        newHeader(name, dns.TypeMX),
+		Preference: preference,
+		Mx:         dns.CanonicalName(exchange),
+	})
+}
+
+// AddTXT adds a TXT record containing the given strings.
+//
+// This method is goroutine safe.
+func (dd *Database) AddTXT(name string, txt ...string) {
+	dd.AddRR(name, &dns.TXT{
+		Hdr: newHeader(name, dns.TypeTXT),
+		Txt: txt,
+	})
+}
+
+// AddSOA adds an SOA record for the zone with the given name using the
+// given primary name server and mailbox and reasonable default timers.
+//
+// This method is goroutine safe.
+func (dd *Database) AddSOA(zone, ns, mbox string) {
+	dd.AddRR(zone, newSOA(zone, ns, mbox))
+}
+
+// newSOA creates an SOA record with reasonable default timers.
+func newSOA(zone, ns, mbox string) *dns.SOA {
+	return &dns.SOA{
+		Hdr:     newHeader(zone, dns.TypeSOA),
+		Ns:      dns.CanonicalName(ns),
+		Mbox:    dns.CanonicalName(mbox),
+		Serial:  1,
+		Refresh: 7200,
+		Retry:   3600,
+		Expire:  1209600,
+		Minttl:  defaultTTL,
+	}
+}
+
+// rootSOA is the SOA we use for negative responses when the
+// database does not contain an SOA for an enclosing zone.
+var rootSOA = newSOA(".", "a.root-servers.net", "nstld.verisign-grs.com")
+
+// AddPTR adds a PTR record mapping the given IPv4/IPv6
+// address to the given domain name for reverse lookups.
+//
+// This method is goroutine safe.
+func (dd *Database) AddPTR(address, name string) {
+	reverse, err := dns.ReverseAddr(address)
+	runtimex.Assert(err == nil, "invalid IP address")
+	dd.AddRR(reverse, &dns.PTR{
+		Hdr: newHeader(reverse, dns.TypePTR),
+		Ptr: dns.CanonicalName(name),
+	})
 }
 
 // AddAddresses adds A/AAAA records mapping the given
 // domainNames to the given IPv4/IPv6 addresses.
 //
-// This method IS NOT goroutine safe.
+// The records have a 3600 seconds TTL. Use [*Database.AddAddressesWithTTL]
+// to choose a different TTL and [*Database.AddRR] to add records
+// with per-record TTLs for any record type.
+//
+// This method is goroutine safe.
 func (dd *Database) AddAddresses(domainNames, addresses []string) {
+	dd.AddAddressesWithTTL(domainNames, addresses, defaultTTL)
+}
+
+// AddAddressesWithTTL is like [*Database.AddAddresses] but the records
+// have the given TTL, which may be zero (e.g., to disable caching).
+//
+// This method is goroutine safe.
+func (dd *Database) AddAddressesWithTTL(domainNames, addresses []string, ttl uint32) {
+	dd.mu.Lock()
+	dd.addAddresses(domainNames, addresses, ttl)
+	dd.mu.Unlock()
+}
+
+// addAddresses is like AddAddressesWithTTL but assumes the caller holds the lock.
+func (dd *Database) addAddresses(domainNames, addresses []string, ttl uint32) {
 	for _, name := range domainNames {
 		name = dns.CanonicalName(name)
 		for _, addr := range addresses {
@@ -59,13 +227,8 @@ func (dd *Database) AddAddresses(domainNames, addresses []string) {
 			runtimex.Assert(ipAddr != nil, "invalid IP address")
 
 			// Create the common DNS header
-			header := dns.RR_Header{
-				Name:     dns.CanonicalName(name),
-				Rrtype:   0,
-				Class:    dns.ClassINET,
-				Ttl:      3600,
-				Rdlength: 0,
-			}
+			header := newHeader(name, 0)
+			header.Ttl = ttl
 
 			// Create the DNS record to add
 			var rr dns.RR
@@ -88,46 +251,92 @@ var _ Handler = (*Database)(nil)
 
 // Handler implements [dnsHandler] using [*dnsDatabase].
 //
-// This method is goroutine safe as long as one does not
-// modify the database while handling queries.
+// This method is goroutine safe.
 func (dd *Database) Handle(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+	// Log the response, if needed.
+	rw = dd.maybeWrapLogger(rw)
+
+	// Defer to the view matching the client address, if any.
+	dd.mu.RLock()
+	viewdb := dd.viewFor(ClientAddr(rw))
+	dd.mu.RUnlock()
+	if viewdb != dd {
+		viewdb.Handle(rw, rawQuery)
+		return
+	}
+
 	// Parse the incoming query and make sure it's a
 	// query containing just one question.
 	var (
-		response = &dns.Msg{}
+		response *dns.Msg
 		query    = &dns.Msg{}
 	)
 	if err := query.Unpack(rawQuery); err != nil {
+		logDropped(rw, rawQuery, DropReasonMalformed)
 		return
 	}
 	if query.Response || query.Opcode != dns.OpcodeQuery || len(query.Question) != 1 {
+		logDropped(rw, rawQuery, DropReasonUnsupported)
 		return
 	}
-	response.SetReply(query)
+	response = newResponse(query)
 
-	// Get the RRs if possible
+	// Inject the configured faults, if any.
 	var (
 		q0   = query.Question[0]
 		name = dns.CanonicalName(q0.Name)
 	)
+	fault := dd.faultFor(name)
+	if fault.shouldDrop() {
+		logDropped(rw, rawQuery, DropReasonFault)
+		return
+	}
+	fault.delay()
+
+	// Get the RRs if possible
+	var referral bool
 	switch {
+	case fault.rcode() != dns.RcodeSuccess:
+		response.Rcode = fault.rcode()
 	case q0.Qclass != dns.ClassINET:
 		response.Rcode = dns.RcodeRefused
-	case q0.Qtype == dns.TypeA ||
-		q0.Qtype == dns.TypeAAAA ||
-		q0.Qtype == dns.TypeCNAME:
+	default:
 		var found bool
-		response.Answer, found = dd.Lookup(q0.Qtype, name)
-		if !found {
-			response.Rcode = dns.RcodeNameError
+		hq := &Query{ClientAddr: ClientAddr(rw), Name: name, Qtype: q0.Qtype}
+		answer, handled := dd.runHooks(hq)
+		dd.mu.RLock()
+		if handled {
+			response.Answer, found = answer, len(answer) > 0
+		} else if ns := dd.delegationFor(q0.Qtype, name); len(ns) > 0 {
+			// Refer the client to the servers authoritative for the
+			// name, including the glue records, if any.
+			response.Ns = ns
+			response.Extra = append(response.Extra, dd.glueFor(ns)...)
+			referral = true
+		} else {
+			response.Answer, response.Rcode, found = dd.lookup(q0.Qtype, name)
 		}
-	default:
-		response.Rcode = dns.RcodeNameError
+		if !found && !referral && response.Rcode != dns.RcodeServerFailure {
+			// Include the SOA in NXDOMAIN and NODATA responses such
+			// that clients can perform negative caching (RFC 2308).
+			response.Ns = []dns.RR{dd.soaFor(name)}
+		}
+		dd.mu.RUnlock()
+	}
+
+	// Include the signatures if the client wants them. We do not sign
+	// referrals, since we're not authoritative for their records.
+	if opt := query.IsEdns0(); opt != nil && opt.Do() && !referral {
+		dd.mu.RLock()
+		response.Answer = dd.signSection(response.Answer)
+		response.Ns = dd.signSection(response.Ns)
+		dd.mu.RUnlock()
 	}
 
 	// Write the response
 	rawResp, err := response.Pack()
 	if err != nil {
+		logDropped(rw, rawQuery, DropReasonInternalError)
 		return
 	}
 	rw.Write(rawResp)
@@ -135,45 +344,94 @@ func (dd *Database) Handle(rw dnscoretest.ResponseWriter, rawQuery []byte) {
 
 // Lookup returns the DNS records for a domain name.
 //
-// This method is goroutine safe as long as one does not
-// modify the database while handling queries.
+// This method is goroutine safe.
 func (dd *Database) Lookup(qtype uint16, name string) ([]dns.RR, bool) {
+	dd.mu.RLock()
+	rrs, _, found := dd.lookup(qtype, name)
+	dd.mu.RUnlock()
+	if !found {
+		return nil, false
+	}
+	return rrs, true
+}
+
+// lookup returns the DNS records for a domain name, following CNAME
+// redirects, along with the response code and whether we found records
+// of the desired type. When the name exists but there are no records
+// of the desired type, we return [dns.RcodeSuccess] (i.e., NODATA).
+func (dd *Database) lookup(qtype uint16, name string) ([]dns.RR, int, bool) {
 	const maxloops = 10
 	var rrs []dns.RR
 	for idx := 0; idx < maxloops; idx++ {
 
 		// Search whether the current name is in the database.
-		var interim []dns.RR
 		interim, found := dd.names[name]
 		if !found {
-			return nil, false
+			if dd.isEmptyNonTerminal(name) {
+				return rrs, dns.RcodeSuccess, false
+			}
+			return rrs, dns.RcodeNameError, false
 		}
 
-		// We have definitely found something related.
-		rrs = append(rrs, interim...)
-
-		// Check whether we have found the desired record.
+		// Check whether we have found the desired records.
+		var matching []dns.RR
 		for _, rr := range interim {
 			if qtype == rr.Header().Rrtype {
-				return rrs, true
+				matching = append(matching, rr)
 			}
 		}
+		if len(matching) > 0 {
+			return append(rrs, matching...), dns.RcodeSuccess, true
+		}
 
 		// Otherwise, follow CNAME redirects.
 		var cname string
 		for _, rr := range interim {
 			if rr, ok := rr.(*dns.CNAME); ok {
+				rrs = append(rrs, rr)
 				cname = rr.Target
 				break
 			}
 		}
 		if cname == "" {
-			return nil, false
+			return rrs, dns.RcodeSuccess, false
 		}
 
 		// Continue searching from the CNAME target.
 		name = cname
 	}
 
-	return nil, false
+	return nil, dns.RcodeServerFailure, false
+}
+
+// isEmptyNonTerminal returns whether the given name has no records
+// but exists because the database contains names below it.
+func (dd *Database) isEmptyNonTerminal(name string) bool {
+	suffix := "." + name
+	if name == "." {
+		suffix = name
+	}
+	for other := range dd.names {
+		if strings.HasSuffix(other, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// soaFor returns the SOA of the closest enclosing zone of
+// the given name or a root SOA when there is no such zone.
+func (dd *Database) soaFor(name string) dns.RR {
+	for {
+		for _, rr := range dd.names[name] {
+			if rr.Header().Rrtype == dns.TypeSOA {
+				return rr
+			}
+		}
+		parent, ok := parentName(name)
+		if !ok {
+			return rootSOA
+		}
+		name = parent
+	}
 }
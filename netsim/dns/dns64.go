@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"net/netip"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore/dnscoretest"
+	"github.com/rbmk-project/x/netsim/nat"
+)
+
+// DNS64Handler is a [Handler] implementing DNS64 as documented by
+// RFC 6147: when a name has no AAAA records, it synthesizes them by
+// embedding the name's IPv4 addresses inside the configured prefix.
+type DNS64Handler struct {
+	// dd is the underlying database.
+	dd *Database
+
+	// prefix is the /96 prefix used to synthesize addresses.
+	prefix netip.Prefix
+}
+
+// NewDNS64Handler creates a new [*DNS64Handler] answering queries
+// using the given database and synthesizing AAAA records using the
+// given /96 prefix (e.g., [nat.WellKnownPrefix]).
+func NewDNS64Handler(dd *Database, prefix netip.Prefix) *DNS64Handler {
+	return &DNS64Handler{dd: dd, prefix: prefix}
+}
+
+// Ensure [*DNS64Handler] implements [Handler].
+var _ Handler = (*DNS64Handler)(nil)
+
+// Handle implements [Handler].
+//
+// This method is goroutine safe.
+func (h *DNS64Handler) Handle(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+	rw = h.dd.maybeWrapLogger(rw)
+
+	// Only handle queries containing one AAAA question and
+	// otherwise defer to the underlying database.
+	query := &dns.Msg{}
+	if err := query.Unpack(rawQuery); err != nil {
+		logDropped(rw, rawQuery, DropReasonMalformed)
+		return
+	}
+	if query.Response || query.Opcode != dns.OpcodeQuery || len(query.Question) != 1 ||
+		query.Question[0].Qclass != dns.ClassINET || query.Question[0].Qtype != dns.TypeAAAA {
+		h.dd.Handle(rw, rawQuery)
+		return
+	}
+	name := dns.CanonicalName(query.Question[0].Name)
+	if _, found := h.dd.Lookup(dns.TypeAAAA, name); found {
+		h.dd.Handle(rw, rawQuery)
+		return
+	}
+
+	// Synthesize the AAAA records from the A records, if any, and
+	// otherwise let the database produce the negative response.
+	rrs, found := h.dd.Lookup(dns.TypeA, name)
+	if !found {
+		h.dd.Handle(rw, rawQuery)
+		return
+	}
+	response := newResponse(query)
+	for _, rr := range rrs {
+		a, ok := rr.(*dns.A)
+		if !ok {
+			response.Answer = append(response.Answer, rr)
+			continue
+		}
+		addr, _ := netip.AddrFromSlice(a.A)
+		header := a.Hdr
+		header.Rrtype = dns.TypeAAAA
+		response.Answer = append(response.Answer, &dns.AAAA{
+			Hdr:  header,
+			AAAA: nat.Synthesize(h.prefix, addr).AsSlice(),
+		})
+	}
+
+	// Write the response
+	rawResp, err := response.Pack()
+	if err != nil {
+		logDropped(rw, rawQuery, DropReasonInternalError)
+		return
+	}
+	rw.Write(rawResp)
+}
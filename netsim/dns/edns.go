@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"net/netip"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore/dnscoretest"
+)
+
+const (
+	// minUDPSize is the maximum size of a response sent over
+	// UDP when the query does not advertise a size using EDNS0.
+	minUDPSize = 512
+
+	// maxUDPSize is the UDP size we advertise using EDNS0.
+	maxUDPSize = 1232
+)
+
+// newResponse creates a response for the given query, which
+// includes an OPT record if the query also included one.
+func newResponse(query *dns.Msg) *dns.Msg {
+	response := &dns.Msg{}
+	response.SetReply(query)
+	if opt := query.IsEdns0(); opt != nil {
+		response.SetEdns0(maxUDPSize, opt.Do())
+	}
+	return response
+}
+
+// NewUDPHandler wraps a [Handler] such that it limits the size of
+// responses to the size advertised by the query using EDNS0 or to
+// 512 bytes otherwise. When the response exceeds the limit, we remove
+// records and set the TC bit, prompting clients to retry over TCP.
+//
+// The netsim package uses this function to wrap the DNS-over-UDP handlers.
+func NewUDPHandler(handler Handler) Handler {
+	return &udpHandler{handler}
+}
+
+// udpHandler is the [Handler] returned by [NewUDPHandler].
+type udpHandler struct {
+	handler Handler
+}
+
+// Handle implements [Handler].
+func (h *udpHandler) Handle(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+	h.handler.Handle(&udpResponseWriter{rawQuery: rawQuery, rw: rw}, rawQuery)
+}
+
+// udpResponseWriter truncates the responses written using it.
+type udpResponseWriter struct {
+	rawQuery []byte
+	rw       dnscoretest.ResponseWriter
+}
+
+// ClientAddr implements [ClientAddrResponseWriter].
+func (w *udpResponseWriter) ClientAddr() netip.Addr {
+	return ClientAddr(w.rw)
+}
+
+// Write implements [dnscoretest.ResponseWriter].
+func (w *udpResponseWriter) Write(rawResp []byte) (int, error) {
+	// Determine the size limit based on the query.
+	query := &dns.Msg{}
+	if err := query.Unpack(w.rawQuery); err != nil {
+		return w.rw.Write(rawResp)
+	}
+	limit := minUDPSize
+	if opt := query.IsEdns0(); opt != nil {
+		limit = max(limit, int(opt.UDPSize()))
+	}
+	if len(rawResp) <= limit {
+		return w.rw.Write(rawResp)
+	}
+
+	// Truncate the response to fit the limit.
+	response := &dns.Msg{}
+	if err := response.Unpack(rawResp); err != nil {
+		return w.rw.Write(rawResp)
+	}
+	response.Truncate(limit)
+	response.Truncated = true
+	truncated, err := response.Pack()
+	if err != nil {
+		return 0, err
+	}
+	return w.rw.Write(truncated)
+}
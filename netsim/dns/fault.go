@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim/clock"
+)
+
+// FaultConfig describes server-side misbehavior that [*Database.Handle]
+// injects when answering queries, which differs from on-path censorship
+// because it happens at the server rather than within the network.
+type FaultConfig struct {
+	// Clock is the optional clock used to implement the delay. If
+	// nil, we use [clock.Real].
+	Clock clock.Clock
+
+	// Delay is the optional delay before answering.
+	Delay time.Duration
+
+	// DropRate is the optional rate, between zero and one, with
+	// which we randomly drop queries without answering.
+	DropRate float64
+
+	// Rcode optionally causes us to answer with the given rcode (e.g.,
+	// [dns.RcodeServerFailure] or [dns.RcodeRefused]) and no records.
+	Rcode int
+}
+
+// SetFault configures the faults to inject when answering queries for the
+// given domain name, overriding the default faults configured using
+// [*Database.SetDefaultFault]. A nil config removes the faults.
+//
+// This method is goroutine safe.
+func (dd *Database) SetFault(name string, config *FaultConfig) {
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	name = dns.CanonicalName(name)
+	if config == nil {
+		delete(dd.faults, name)
+		return
+	}
+	dd.faults[name] = config
+}
+
+// SetDefaultFault configures the faults to inject when answering queries for
+// domain names without faults configured using [*Database.SetFault]. A nil
+// config removes the faults.
+//
+// This method is goroutine safe.
+func (dd *Database) SetDefaultFault(config *FaultConfig) {
+	dd.mu.Lock()
+	dd.defaultFault = config
+	dd.mu.Unlock()
+}
+
+// faultFor returns the faults to inject for the given name or nil.
+func (dd *Database) faultFor(name string) *FaultConfig {
+	dd.mu.RLock()
+	defer dd.mu.RUnlock()
+	if config := dd.faults[name]; config != nil {
+		return config
+	}
+	return dd.defaultFault
+}
+
+// shouldDrop returns whether we should drop the current query.
+func (c *FaultConfig) shouldDrop() bool {
+	return c != nil && c.DropRate > 0 && rand.Float64() < c.DropRate
+}
+
+// delay blocks for the configured delay.
+func (c *FaultConfig) delay() {
+	if c == nil || c.Delay <= 0 {
+		return
+	}
+	clk := c.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	timer := clk.NewTimer(c.Delay)
+	defer timer.Stop()
+	<-timer.C()
+}
+
+// rcode returns the rcode to use or [dns.RcodeSuccess].
+func (c *FaultConfig) rcode() int {
+	if c == nil {
+		return dns.RcodeSuccess
+	}
+	return c.Rcode
+}
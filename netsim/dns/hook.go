@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"net/netip"
+
+	"github.com/miekg/dns"
+)
+
+// Query describes a query answered by a [HookFunc].
+type Query struct {
+	// ClientAddr is the address of the client that sent the query, which is
+	// the zero value when unknown (see [ClientAddrResponseWriter]).
+	ClientAddr netip.Addr
+
+	// Name is the canonical queried name.
+	Name string
+
+	// Qtype is the queried type.
+	Qtype uint16
+}
+
+// HookFunc computes the answer to a [*Query] at query time, thus allowing to
+// implement round-robin or client-dependent responses. It returns the answer
+// records and whether it handled the query. When it returns true and no records,
+// the response is NODATA. When it returns false, the database answers using
+// its static records. The function must be goroutine safe.
+type HookFunc func(query *Query) ([]dns.RR, bool)
+
+// AddHook registers the [HookFunc] computing the answers to queries
+// for the given domain name, replacing any previously registered hook.
+//
+// Hooks only apply to queries handled by [*Database.Handle] and
+// [*Database.Lookup] does not invoke them.
+//
+// This method is goroutine safe.
+func (dd *Database) AddHook(name string, fx HookFunc) {
+	dd.mu.Lock()
+	dd.hooks[dns.CanonicalName(name)] = fx
+	dd.mu.Unlock()
+}
+
+// SetFallbackHook registers the [HookFunc] computing the answers to queries
+// for domain names having neither static records nor a hook registered using
+// [*Database.AddHook]. When the fallback returns false, we return NXDOMAIN.
+//
+// This method is goroutine safe.
+func (dd *Database) SetFallbackHook(fx HookFunc) {
+	dd.mu.Lock()
+	dd.fallback = fx
+	dd.mu.Unlock()
+}
+
+// NewA is a convenience function for creating A records inside a [HookFunc].
+func NewA(name string, ttl uint32, addr netip.Addr) dns.RR {
+	header := newHeader(name, dns.TypeA)
+	header.Ttl = ttl
+	return &dns.A{Hdr: header, A: addr.AsSlice()}
+}
+
+// NewAAAA is a convenience function for creating AAAA records inside a [HookFunc].
+func NewAAAA(name string, ttl uint32, addr netip.Addr) dns.RR {
+	header := newHeader(name, dns.TypeAAAA)
+	header.Ttl = ttl
+	return &dns.AAAA{Hdr: header, AAAA: addr.AsSlice()}
+}
+
+// runHooks invokes the hook for the given query, if any, and returns
+// the answer records and whether the hook handled the query.
+//
+// We invoke the hook without holding the lock, such that
+// the hook itself may use the database.
+func (dd *Database) runHooks(query *Query) ([]dns.RR, bool) {
+	fx := dd.hookFor(query.Name)
+	if fx == nil {
+		return nil, false
+	}
+	return fx(query)
+}
+
+// hookFor returns the hook for the given name or nil.
+func (dd *Database) hookFor(name string) HookFunc {
+	dd.mu.RLock()
+	defer dd.mu.RUnlock()
+	if fx := dd.hooks[name]; fx != nil {
+		return fx
+	}
+	if _, found := dd.names[name]; found || dd.isEmptyNonTerminal(name) {
+		return nil
+	}
+	return dd.fallback
+}
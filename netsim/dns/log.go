@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore/dnscoretest"
+)
+
+// SetLogger sets the optional structured logger that [*Database.Handle] uses
+// to emit a "dnsQuery" event for each response, containing the client
+// address, the queried name and type, the rcode, and the answer records.
+//
+// When we do not answer a query, we emit a "dnsQueryDropped" event instead,
+// containing the client address, the queried name and type, if we could
+// parse the query, and the reason (see [DropReasonFault] and friends),
+// which allows to distinguish dropped queries from queries we never saw.
+//
+// When a view (see [*Database.AddView]) or a [*DNS64Handler] answers
+// a query, we log using the logger of the outermost database.
+//
+// This method is goroutine safe.
+func (dd *Database) SetLogger(logger *slog.Logger) {
+	dd.mu.Lock()
+	dd.logger = logger
+	dd.mu.Unlock()
+}
+
+// These are the reasons logged by the "dnsQueryDropped" event.
+const (
+	// DropReasonFault indicates that a [*FaultConfig] caused the drop.
+	DropReasonFault = "fault"
+
+	// DropReasonInternalError indicates that we could not build the response.
+	DropReasonInternalError = "internalError"
+
+	// DropReasonMalformed indicates that we could not parse the query.
+	DropReasonMalformed = "malformed"
+
+	// DropReasonUnsupported indicates that the message is not a query
+	// with a single question, which is what we support.
+	DropReasonUnsupported = "unsupported"
+)
+
+// maybeWrapLogger wraps the given writer to log responses, unless
+// we have no logger or the writer is already logging responses.
+func (dd *Database) maybeWrapLogger(rw dnscoretest.ResponseWriter) dnscoretest.ResponseWriter {
+	dd.mu.RLock()
+	logger := dd.logger
+	dd.mu.RUnlock()
+	if _, ok := rw.(*loggingResponseWriter); ok || logger == nil {
+		return rw
+	}
+	return &loggingResponseWriter{logger: logger, rw: rw}
+}
+
+// loggingResponseWriter logs the responses written using it.
+type loggingResponseWriter struct {
+	logger *slog.Logger
+	rw     dnscoretest.ResponseWriter
+}
+
+// ClientAddr implements [ClientAddrResponseWriter].
+func (w *loggingResponseWriter) ClientAddr() netip.Addr {
+	return ClientAddr(w.rw)
+}
+
+// Write implements [dnscoretest.ResponseWriter].
+func (w *loggingResponseWriter) Write(rawResp []byte) (int, error) {
+	response := &dns.Msg{}
+	if err := response.Unpack(rawResp); err == nil && len(response.Question) == 1 {
+		var clientAddr string
+		if addr := w.ClientAddr(); addr.IsValid() {
+			clientAddr = addr.String()
+		}
+		answer := []string{}
+		for _, rr := range response.Answer {
+			answer = append(answer, summarizeRR(rr))
+		}
+		w.logger.Info(
+			"dnsQuery",
+			slog.String("clientAddr", clientAddr),
+			slog.String("qname", response.Question[0].Name),
+			slog.String("qtype", dns.TypeToString[response.Question[0].Qtype]),
+			slog.String("rcode", dns.RcodeToString[response.Rcode]),
+			slog.Any("answer", answer),
+		)
+	}
+	return w.rw.Write(rawResp)
+}
+
+// logDropped emits a "dnsQueryDropped" event for the given query with
+// the given reason, provided that the writer is logging responses.
+func logDropped(rw dnscoretest.ResponseWriter, rawQuery []byte, reason string) {
+	w, ok := rw.(*loggingResponseWriter)
+	if !ok {
+		return
+	}
+	var clientAddr, qname, qtype string
+	if addr := w.ClientAddr(); addr.IsValid() {
+		clientAddr = addr.String()
+	}
+	query := &dns.Msg{}
+	if err := query.Unpack(rawQuery); err == nil && len(query.Question) == 1 {
+		qname = query.Question[0].Name
+		qtype = dns.TypeToString[query.Question[0].Qtype]
+	}
+	w.logger.Info(
+		"dnsQueryDropped",
+		slog.String("clientAddr", clientAddr),
+		slog.String("qname", qname),
+		slog.String("qtype", qtype),
+		slog.String("reason", reason),
+	)
+}
+
+// summarizeRR returns the type and the data of the given record (e.g., "A 10.0.0.1").
+func summarizeRR(rr dns.RR) string {
+	data := strings.TrimPrefix(rr.String(), rr.Header().String())
+	return dns.TypeToString[rr.Header().Rrtype] + " " + data
+}
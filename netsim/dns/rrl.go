@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import (
+	"math"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/dnscore/dnscoretest"
+	"github.com/rbmk-project/x/netsim/clock"
+)
+
+// RRLConfig contains the configuration for [NewRRLHandler].
+type RRLConfig struct {
+	// Burst is the optional maximum number of responses that each client
+	// may receive for the same name without waiting. If zero, we use
+	// ResponsesPerSecond rounded up.
+	Burst int
+
+	// Clock is the optional clock used to refill the buckets. If
+	// nil, we use [clock.Real].
+	Clock clock.Clock
+
+	// ResponsesPerSecond is the rate with which each client
+	// may receive responses for the same name.
+	ResponsesPerSecond float64
+
+	// Slip optionally causes us to answer every Slip-th rate limited
+	// query using an empty, truncated response, rather than dropping
+	// it, prompting legitimate clients to retry over TCP. If zero,
+	// we drop all the rate limited queries.
+	Slip int
+}
+
+// NewRRLHandler wraps a [Handler] implementing response rate limiting using
+// a token bucket for each client address and queried name, such that clients
+// hammering a server observe drops and truncated responses. Since rate
+// limiting is only meaningful for DNS-over-UDP, use this function to
+// wrap the handler set as the DNSOverUDPHandler of a stack.
+func NewRRLHandler(handler Handler, config *RRLConfig) Handler {
+	burst := float64(config.Burst)
+	if burst <= 0 {
+		burst = max(1, math.Ceil(config.ResponsesPerSecond))
+	}
+	clk := config.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	return &rrlHandler{
+		buckets: make(map[rrlKey]*rrlBucket),
+		burst:   burst,
+		clock:   clk,
+		handler: handler,
+		rate:    config.ResponsesPerSecond,
+		slip:    config.Slip,
+	}
+}
+
+// rrlKey is the key identifying a token bucket.
+type rrlKey struct {
+	addr netip.Addr
+	name string
+}
+
+// rrlBucket is a token bucket.
+type rrlBucket struct {
+	dropped int
+	last    time.Time
+	tokens  float64
+}
+
+// rrlHandler is the [Handler] returned by [NewRRLHandler].
+type rrlHandler struct {
+	buckets map[rrlKey]*rrlBucket
+	burst   float64
+	clock   clock.Clock
+	handler Handler
+	mu      sync.Mutex
+	rate    float64
+	slip    int
+}
+
+// rrlAction is the action decided by the [*rrlHandler].
+type rrlAction int
+
+const (
+	rrlAnswer = rrlAction(iota)
+	rrlDrop
+	rrlSlip
+)
+
+// Handle implements [Handler].
+func (h *rrlHandler) Handle(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+	query := &dns.Msg{}
+	if err := query.Unpack(rawQuery); err != nil || len(query.Question) != 1 {
+		h.handler.Handle(rw, rawQuery)
+		return
+	}
+	key := rrlKey{addr: ClientAddr(rw), name: dns.CanonicalName(query.Question[0].Name)}
+	switch h.decide(key) {
+	case rrlDrop:
+		return
+	case rrlSlip:
+		response := newResponse(query)
+		response.Truncated = true
+		if rawResp, err := response.Pack(); err == nil {
+			rw.Write(rawResp)
+		}
+	default:
+		h.handler.Handle(rw, rawQuery)
+	}
+}
+
+// decide refills the bucket for the given key and decides what to do.
+func (h *rrlHandler) decide(key rrlKey) rrlAction {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.clock.Now()
+	bucket := h.buckets[key]
+	if bucket == nil {
+		bucket = &rrlBucket{last: now, tokens: h.burst}
+		h.buckets[key] = bucket
+	}
+	elapsed := now.Sub(bucket.last).Seconds()
+	bucket.tokens = min(h.burst, bucket.tokens+elapsed*h.rate)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		bucket.dropped = 0
+		return rrlAnswer
+	}
+	bucket.dropped++
+	if h.slip > 0 && bucket.dropped%h.slip == 0 {
+		return rrlSlip
+	}
+	return rrlDrop
+}
@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package dns
+
+import "net/netip"
+
+// view is a view added using [*Database.AddView].
+type view struct {
+	// dd is the database answering queries.
+	dd *Database
+
+	// prefix is the client prefix.
+	prefix netip.Prefix
+}
+
+// AddView adds a split-horizon view, such that [*Database.Handle] answers
+// queries sent by clients within the given prefix using the given database
+// rather than using this database, thus allowing to model geo-DNS and
+// resolver-dependent censorship. When several views match the client
+// address, we use the one with the longest prefix.
+//
+// Like for real split-horizon servers, each view is self-contained and
+// we do not fall back to this database for names missing from the view.
+// Queries from unknown client addresses (see [ClientAddr]) and calls to
+// [*Database.Lookup] do not use views.
+//
+// This method is goroutine safe.
+func (dd *Database) AddView(prefix netip.Prefix, viewdb *Database) {
+	dd.mu.Lock()
+	dd.views = append(dd.views, view{dd: viewdb, prefix: prefix.Masked()})
+	dd.mu.Unlock()
+}
+
+// viewFor returns the database to use for the given client address.
+//
+// The caller must hold the lock.
+func (dd *Database) viewFor(addr netip.Addr) *Database {
+	var best *view
+	for idx := range dd.views {
+		vw := &dd.views[idx]
+		if !addr.IsValid() || !vw.prefix.Contains(addr) {
+			continue
+		}
+		if best == nil || vw.prefix.Bits() > best.prefix.Bits() {
+			best = vw
+		}
+	}
+	if best == nil {
+		return dd
+	}
+	return best.dd
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+
+	"github.com/rbmk-project/x/netsim/dns"
+)
+
+// clientAddr returns the [netip.Addr] of the given client
+// address or the zero value when it cannot be parsed.
+func clientAddr(addr string) netip.Addr {
+	epnt, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return epnt.Addr().Unmap()
+}
+
+// dnsOverUDPServer serves DNS-over-UDP.
+//
+// We use this type rather than the dnscoretest server to
+// tell the handler the address of each client.
+type dnsOverUDPServer struct {
+	// handler is the [DNSHandler] to use.
+	handler DNSHandler
+
+	// pconn is the [net.PacketConn] to use.
+	pconn net.PacketConn
+}
+
+// Close implements [io.Closer].
+func (srv *dnsOverUDPServer) Close() error {
+	return srv.pconn.Close()
+}
+
+// serve handles incoming queries until the conn is closed.
+func (srv *dnsOverUDPServer) serve() {
+	for {
+		buf := make([]byte, 65535)
+		count, addr, err := srv.pconn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		rw := dns.WithClientAddr(&dnsOverUDPResponseWriter{addr, srv.pconn}, clientAddr(addr.String()))
+		go srv.handler.Handle(rw, buf[:count])
+	}
+}
+
+// dnsOverUDPResponseWriter writes responses to a given client.
+type dnsOverUDPResponseWriter struct {
+	addr  net.Addr
+	pconn net.PacketConn
+}
+
+// Write sends the response to the client.
+func (rw *dnsOverUDPResponseWriter) Write(rawResp []byte) (int, error) {
+	return rw.pconn.WriteTo(rawResp, rw.addr)
+}
+
+// dnsOverStreamServer serves DNS-over-TCP and DNS-over-TLS.
+//
+// We use this type rather than the dnscoretest server to
+// tell the handler the address of each client.
+type dnsOverStreamServer struct {
+	// handler is the [DNSHandler] to use.
+	handler DNSHandler
+
+	// listener is the [net.Listener] to use.
+	listener net.Listener
+}
+
+// Close implements [io.Closer].
+func (srv *dnsOverStreamServer) Close() error {
+	return srv.listener.Close()
+}
+
+// serve accepts connections until the listener is closed.
+func (srv *dnsOverStreamServer) serve() {
+	for {
+		conn, err := srv.listener.Accept()
+		if err != nil {
+			return
+		}
+		go srv.serveConn(conn)
+	}
+}
+
+// serveConn handles the queries sent using the given conn until the
+// client closes it. Both the queries and the responses are prefixed by
+// their length as documented by RFC 1035.
+func (srv *dnsOverStreamServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	rw := dns.WithClientAddr(&dnsOverStreamResponseWriter{conn}, clientAddr(conn.RemoteAddr().String()))
+	for {
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		rawQuery := make([]byte, length)
+		if _, err := io.ReadFull(conn, rawQuery); err != nil {
+			return
+		}
+		srv.handler.Handle(rw, rawQuery)
+	}
+}
+
+// dnsOverStreamResponseWriter writes length-prefixed responses.
+type dnsOverStreamResponseWriter struct {
+	conn net.Conn
+}
+
+// Write writes the response prefixed by its length.
+func (rw *dnsOverStreamResponseWriter) Write(rawResp []byte) (int, error) {
+	buf := binary.BigEndian.AppendUint16(nil, uint16(len(rawResp)))
+	if _, err := rw.conn.Write(append(buf, rawResp...)); err != nil {
+		return 0, err
+	}
+	return len(rawResp), nil
+}
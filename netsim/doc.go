@@ -40,6 +40,19 @@ The errors returned by these types are the same [syscall.Errno] the
 standard library and the kernel would generate in similar cases (we use
 the [x/sys] repository to pull system-dependent error values).
 
+The [LoadScenario] function creates a scenario from a declarative YAML
+or JSON document, which allows to check large test matrices into testdata
+rather than hand-writing Go code for each test case.
+
+A [*Scenario] connects stacks through a central router by default,
+but it is also possible to create additional named routers, connect
+them to each other, and attach stacks to specific routers, such that
+censorship applies at a specific hop (see [*Scenario.MustNewRouter]).
+To simulate outages, [*Scenario.Detach] disconnects a device from the
+topology and [*Scenario.Partition] splits the topology at runtime.
+For large scenarios, [*Scenario.AllocateAddresses] hands out stack
+addresses from configurable pools, thus avoiding hardcoding them.
+
 This package contains comprehensive examples showing how to use it.
 
 # Design Documents
@@ -0,0 +1,84 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/quic-go/quic-go"
+	"github.com/rbmk-project/x/netsim/dns"
+)
+
+// dnsOverQUICServer serves DNS-over-QUIC as documented by RFC 9250.
+type dnsOverQUICServer struct {
+	// handler is the [DNSHandler] to use.
+	handler DNSHandler
+
+	// listener is the QUIC listener.
+	listener *quic.Listener
+
+	// pconn is the underlying [net.PacketConn].
+	pconn net.PacketConn
+}
+
+// Close implements [io.Closer].
+func (srv *dnsOverQUICServer) Close() error {
+	err := srv.listener.Close()
+	srv.pconn.Close()
+	return err
+}
+
+// serve accepts QUIC connections until the listener is closed.
+func (srv *dnsOverQUICServer) serve() {
+	for {
+		conn, err := srv.listener.Accept(context.Background())
+		if err != nil {
+			return
+		}
+		go srv.serveConn(conn)
+	}
+}
+
+// serveConn accepts streams until the connection is closed.
+func (srv *dnsOverQUICServer) serveConn(conn *quic.Conn) {
+	for {
+		stream, err := conn.AcceptStream(context.Background())
+		if err != nil {
+			return
+		}
+		go srv.serveStream(conn, stream)
+	}
+}
+
+// serveStream handles a single query. Each query uses its own stream
+// and both the query and the response are prefixed by their length.
+func (srv *dnsOverQUICServer) serveStream(conn *quic.Conn, stream *quic.Stream) {
+	defer stream.Close()
+	var length uint16
+	if err := binary.Read(stream, binary.BigEndian, &length); err != nil {
+		return
+	}
+	rawQuery := make([]byte, length)
+	if _, err := io.ReadFull(stream, rawQuery); err != nil {
+		return
+	}
+	rw := dns.WithClientAddr(&dnsOverQUICResponseWriter{stream}, clientAddr(conn.RemoteAddr().String()))
+	srv.handler.Handle(rw, rawQuery)
+}
+
+// dnsOverQUICResponseWriter writes length-prefixed responses.
+type dnsOverQUICResponseWriter struct {
+	stream *quic.Stream
+}
+
+// Write writes the response prefixed by its length.
+func (rw *dnsOverQUICResponseWriter) Write(rawResp []byte) (int, error) {
+	buf := binary.BigEndian.AppendUint16(nil, uint16(len(rawResp)))
+	if _, err := rw.stream.Write(append(buf, rawResp...)); err != nil {
+		return 0, err
+	}
+	return len(rawResp), nil
+}
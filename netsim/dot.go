@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rbmk-project/x/netsim/geolink"
+)
+
+// WriteDOT writes the scenario topology to w using the Graphviz DOT
+// language. The output includes routers along with their filters, attached
+// devices along with their addresses, and the links between them.
+//
+// Use, e.g., `dot -Tsvg` to render the output.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("graph scenario {\n")
+
+	// Emit the routers sorted by name for stable output.
+	names := make([]string, 0, len(s.routers))
+	for name := range s.routers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		label := []string{"router " + name}
+		for _, pf := range s.routers[name].Filters() {
+			if pf == s.partitions || pf == s.observers {
+				continue // implementation detail
+			}
+			label = append(label, fmt.Sprintf("%T", pf))
+		}
+		fmt.Fprintf(&sb, "\t%s [shape=box, label=%s];\n",
+			dotRouterID(name), strconv.Quote(strings.Join(label, "\n")))
+	}
+
+	// Emit the attached devices and their links.
+	for idx, att := range s.attachments {
+		var label []string
+		for _, addr := range att.dev.Addresses() {
+			label = append(label, addr.String())
+		}
+		devID := strconv.Quote("device" + strconv.Itoa(idx))
+		fmt.Fprintf(&sb, "\t%s [shape=ellipse, label=%s];\n",
+			devID, strconv.Quote(strings.Join(label, "\n")))
+		fmt.Fprintf(&sb, "\t%s -- %s%s;\n",
+			devID, dotRouterID(att.router), dotLinkAttrs(att.link))
+	}
+
+	// Emit the links between routers.
+	for _, p := range s.peerings {
+		fmt.Fprintf(&sb, "\t%s -- %s%s;\n",
+			dotRouterID(p.left), dotRouterID(p.right), dotLinkAttrs(p.link))
+	}
+
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// dotRouterID returns the DOT node ID of the given router.
+func dotRouterID(name string) string {
+	return strconv.Quote("router:" + name)
+}
+
+// dotLinkAttrs returns the DOT edge attributes describing a link.
+func dotLinkAttrs(config *geolink.Config) string {
+	if config == nil {
+		return ""
+	}
+	label := []string{"delay=" + config.Delay.String()}
+	if config.Bandwidth > 0 {
+		label = append(label, "bandwidth="+strconv.FormatInt(config.Bandwidth, 10))
+	}
+	if config.PLR > 0 {
+		label = append(label, "plr="+strconv.FormatFloat(config.PLR, 'g', -1, 64))
+	}
+	return fmt.Sprintf(" [label=%s]", strconv.Quote(strings.Join(label, "\n")))
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to simulate a measurement
+// backend and run an end-to-end check-in and submission flow.
+func Example_measurementBackend() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server and the backend.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	backend := &netsim.MeasurementBackend{
+		URLs: []string{"https://www.example.com/"},
+	}
+	scenario.Attach(scenario.MustNewMeasurementBackendStack(backend))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP client
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Check in to get the URLs to measure.
+	resp, err := clientHTTP.Post("https://api.backend.example/api/v1/check-in",
+		"application/json", bytes.NewReader([]byte(`{}`)))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var checkIn netsim.MeasurementCheckInResponse
+	err = json.NewDecoder(resp.Body).Decode(&checkIn)
+	resp.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%v\n", checkIn.URLs)
+
+	// Submit a measurement for each URL.
+	for _, URL := range checkIn.URLs {
+		measurement, err := json.Marshal(map[string]string{"input": URL})
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp, err := clientHTTP.Post("https://api.backend.example/api/v1/submit",
+			"application/json", bytes.NewReader(measurement))
+		if err != nil {
+			log.Fatal(err)
+		}
+		var submit netsim.MeasurementSubmitResponse
+		err = json.NewDecoder(resp.Body).Decode(&submit)
+		resp.Body.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s\n", submit.MeasurementUID)
+	}
+
+	// Inspect the measurements received by the backend.
+	for _, measurement := range backend.Submissions() {
+		fmt.Printf("%s\n", string(measurement))
+	}
+
+	// Output:
+	// [https://www.example.com/]
+	// 1
+	// {"input":"https://www.example.com/"}
+}
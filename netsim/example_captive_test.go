@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to simulate a captive
+// portal intercepting the connectivity-check requests.
+func Example_captivePortal() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server and the captive portal.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewCaptivePortalStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP client without following redirects.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{
+		Transport: clientTxp,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// Perform the connectivity check and another request.
+	for _, URL := range []string{
+		"http://connectivitycheck.gstatic.com/generate_204",
+		"http://captive.apple.com/index.html",
+	} {
+		resp, err := clientHTTP.Get(URL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp.Body.Close()
+		fmt.Printf("%d %q\n", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	// Output:
+	// 200 ""
+	// 302 "http://login.captive-portal.example/login"
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to simulate a CDN where
+// several replicas share the same domain name.
+func Example_cdn() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Create and attach three replicas, each of them
+	// including its own address in the responses.
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		laddr := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+		host, _, _ := net.SplitHostPort(laddr.String())
+		fmt.Fprintf(w, "served by %s\n", host)
+	})
+	replicas := scenario.MustNewCDNStacks(&netsim.StackConfig{
+		DomainNames: []string{"cdn.example.com"},
+		HTTPHandler: handler,
+	}, []string{"10.20.0.1"}, []string{"10.20.0.2"}, []string{"10.20.0.3"})
+	for _, replica := range replicas {
+		scenario.Attach(replica)
+	}
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Resolve the CDN domain name.
+	conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	query := new(dns.Msg)
+	query.SetQuestion("cdn.example.com.", dns.TypeA)
+	resp, _, err := (&dns.Client{}).ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, ans := range resp.Answer {
+		if a, ok := ans.(*dns.A); ok {
+			fmt.Printf("%s\n", a.A.String())
+		}
+	}
+
+	// Fetch the content from each replica.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+	for _, ans := range resp.Answer {
+		a, ok := ans.(*dns.A)
+		if !ok {
+			continue
+		}
+		req, err := http.NewRequest("GET", "http://"+a.A.String()+"/", nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		req.Host = "cdn.example.com"
+		httpResp, err := clientHTTP.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		body, err := io.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s", string(body))
+	}
+
+	// Output:
+	// 10.20.0.1
+	// 10.20.0.2
+	// 10.20.0.3
+	// served by 10.20.0.1
+	// served by 10.20.0.2
+	// served by 10.20.0.3
+}
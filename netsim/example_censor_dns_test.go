@@ -6,13 +6,16 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/netip"
 	"time"
 
 	"github.com/miekg/dns"
 	"github.com/rbmk-project/dnscore"
 	"github.com/rbmk-project/x/netsim"
 	"github.com/rbmk-project/x/netsim/censor"
+	"github.com/rbmk-project/x/netsim/clock"
 	netsimdns "github.com/rbmk-project/x/netsim/dns"
+	"github.com/rbmk-project/x/netsim/packet"
 )
 
 // This example shows how to use [netsim] to simulate GFW-style DNS
@@ -111,3 +114,213 @@ func Example_censorDNS() {
 	// 10.0.0.1
 	// 8.8.8.8
 }
+
+// This example shows how to use [netsim] to simulate a DNS injector
+// that races several spoofed responses, each carrying a different forged
+// address, before the legitimate response arrives.
+func Example_censorDNSBurst() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create server stack emulating dns.google (8.8.8.8).
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Configure DNS poisoning injecting two responses per query,
+	// each of them containing a different forged address.
+	censorDB := netsimdns.NewDatabase()
+	censorDB.AddAddresses([]string{"dns.google"}, []string{"10.0.0.1", "10.0.0.2"})
+	scenario.Router().AddFilter(censor.NewDNSPoisonerWithConfig(
+		censorDB, &censor.DNSPoisonerConfig{Burst: 2}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Create DNS query for dns.google A record
+	query, err := dnscore.NewQuery("dns.google.", dns.TypeA)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Configure transport to use our simulated network
+	txp := &dnscore.Transport{}
+	txp.DialContext = clientStack.DialContext
+
+	// Query 8.8.8.8 over UDP and collect responses
+	serverAddr := &dnscore.ServerAddr{
+		Protocol: dnscore.ProtocolUDP,
+		Address:  "8.8.8.8:53",
+	}
+	results := txp.QueryWithDuplicates(ctx, serverAddr, query)
+
+	// Print responses as they arrive, stopping after three responses.
+	var count int
+	for result := range results {
+		if err := result.Err; err != nil {
+			break
+		}
+		for _, ans := range result.Msg.Answer {
+			if a, ok := ans.(*dns.A); ok {
+				fmt.Printf("%s\n", a.A.String())
+			}
+		}
+		count++
+		if count >= 3 {
+			cancel()
+		}
+	}
+
+	// Output:
+	// 10.0.0.1
+	// 10.0.0.2
+	// 8.8.8.8
+}
+
+// This example shows how each [censor.DNSPoisonerConfig] knob changes
+// the spoofed responses, which allows to reproduce the variants of DNS
+// injection observed in the wild. We invoke the poisoner directly with
+// a query packet and inspect the packets it would inject.
+func Example_censorDNSVariants() {
+	// Create the database containing the forged addresses.
+	censorDB := netsimdns.NewDatabase()
+	censorDB.AddAddresses([]string{"dns.google"}, []string{"10.0.0.1", "10.0.0.2"})
+
+	// Create the query packet directed to 8.8.8.8.
+	query, err := dnscore.NewQuery("dns.google.", dns.TypeA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawQuery, err := query.Pack()
+	if err != nil {
+		log.Fatal(err)
+	}
+	queryPkt := &packet.Packet{
+		TTL:        64,
+		SrcAddr:    netip.MustParseAddr("130.192.91.211"),
+		DstAddr:    netip.MustParseAddr("8.8.8.8"),
+		IPProtocol: packet.IPProtocolUDP,
+		SrcPort:    54321,
+		DstPort:    53,
+		Payload:    rawQuery,
+	}
+
+	// Print the spoofed responses created using each configuration.
+	variants := []struct {
+		name   string
+		config *censor.DNSPoisonerConfig
+	}{
+		{"default", &censor.DNSPoisonerConfig{}},
+		{"round-robin", &censor.DNSPoisonerConfig{AddrSelection: censor.DNSAddrSelectionRoundRobin}},
+		{"ip-ttl", &censor.DNSPoisonerConfig{IPTTL: 37}},
+		{"flags", &censor.DNSPoisonerConfig{Authoritative: true, RecursionAvailable: true}},
+		{"zero-ttl", &censor.DNSPoisonerConfig{ZeroTTL: true}},
+		{"empty-answer", &censor.DNSPoisonerConfig{EmptyAnswer: true}},
+	}
+	for _, variant := range variants {
+		poisoner := censor.NewDNSPoisonerWithConfig(censorDB, variant.config)
+		_, spoofed := poisoner.Filter(queryPkt)
+		for _, pkt := range spoofed {
+			resp := &dns.Msg{}
+			if err := resp.Unpack(pkt.Payload); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s: ipTTL=%d aa=%v ra=%v answers=%d",
+				variant.name, pkt.TTL, resp.Authoritative,
+				resp.RecursionAvailable, len(resp.Answer))
+			for _, ans := range resp.Answer {
+				if a, ok := ans.(*dns.A); ok {
+					fmt.Printf(" %s/%d", a.A.String(), a.Hdr.Ttl)
+				}
+			}
+			fmt.Printf("\n")
+		}
+	}
+
+	// Output:
+	// default: ipTTL=64 aa=false ra=false answers=2 10.0.0.1/3600 10.0.0.2/3600
+	// round-robin: ipTTL=64 aa=false ra=false answers=1 10.0.0.1/3600
+	// ip-ttl: ipTTL=37 aa=false ra=false answers=2 10.0.0.1/3600 10.0.0.2/3600
+	// flags: ipTTL=64 aa=true ra=true answers=2 10.0.0.1/3600 10.0.0.2/3600
+	// zero-ttl: ipTTL=64 aa=false ra=false answers=2 10.0.0.1/0 10.0.0.2/0
+	// empty-answer: ipTTL=64 aa=false ra=false answers=0
+}
+
+// channelInjector is a [packet.Injector] sending packets to a channel.
+type channelInjector chan *packet.Packet
+
+// Inject implements [packet.Injector].
+func (ci channelInjector) Inject(pkt *packet.Packet) error {
+	ci <- pkt
+	return nil
+}
+
+// This example shows how to close a [*censor.DNSPoisoner] injecting
+// a burst of responses spaced in time to stop the pending injections.
+func Example_censorDNSBurstClose() {
+	// Create the database containing the forged addresses.
+	censorDB := netsimdns.NewDatabase()
+	censorDB.AddAddresses([]string{"dns.google"}, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+
+	// Create the query packet directed to 8.8.8.8.
+	query, err := dnscore.NewQuery("dns.google.", dns.TypeA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawQuery, err := query.Pack()
+	if err != nil {
+		log.Fatal(err)
+	}
+	queryPkt := &packet.Packet{
+		TTL:        64,
+		SrcAddr:    netip.MustParseAddr("130.192.91.211"),
+		DstAddr:    netip.MustParseAddr("8.8.8.8"),
+		IPProtocol: packet.IPProtocolUDP,
+		SrcPort:    54321,
+		DstPort:    53,
+		Payload:    rawQuery,
+	}
+
+	// Create a poisoner injecting a burst of three responses spaced
+	// by one second according to a fake clock.
+	clk := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	injected := make(channelInjector, 3)
+	poisoner := censor.NewDNSPoisonerWithConfig(censorDB, &censor.DNSPoisonerConfig{
+		Burst:    3,
+		Clock:    clk,
+		Injector: injected,
+		Spacing:  time.Second,
+	})
+
+	// The filter returns the first response and schedules the others.
+	_, spoofed := poisoner.Filter(queryPkt)
+	fmt.Printf("immediate: %d\n", len(spoofed))
+
+	// wait returns whether we inject a response within a short time.
+	wait := func() bool {
+		select {
+		case <-injected:
+			return true
+		case <-time.After(250 * time.Millisecond):
+			return false
+		}
+	}
+
+	// Advancing the clock injects the second response, while
+	// closing the poisoner cancels the third one.
+	clk.Advance(time.Second)
+	fmt.Printf("second: %v\n", wait())
+	poisoner.Close()
+	clk.Advance(time.Second)
+	fmt.Printf("third: %v\n", wait())
+
+	// Output:
+	// immediate: 1
+	// second: true
+	// third: false
+}
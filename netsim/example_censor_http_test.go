@@ -63,3 +63,48 @@ func Example_blockpageTransparentProxy() {
 	// Output:
 	// Access to this website has been blocked by network policy.
 }
+
+// This example shows how to use [netsim] to simulate transparent
+// proxying of HTTP requests to serve legal blockpages.
+func Example_legalBlockpageTransparentProxy() {
+	// Create scenario
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create legal blockpage server
+	scenario.Attach(scenario.MustNewLegalBlockpageStack())
+
+	// Create target website
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Configure DNAT to send blocked traffic to blockpage server
+	scenario.Router().AddFilter(censor.NewDNatter(
+		netip.MustParseAddr("193.206.158.22"),       // source addr
+		netip.MustParseAddrPort("93.184.216.34:80"), // target dest epnt
+		netip.MustParseAddrPort("10.10.34.36:80"),   // repl dest epnt
+	))
+
+	// Create client stack
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP client
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Get the response.
+	resp, err := clientHTTP.Get("http://93.184.216.34/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Print the status code and the Link header
+	fmt.Printf("%d\n", resp.StatusCode)
+	fmt.Printf("%s\n", resp.Header.Get("Link"))
+
+	// Output:
+	// 451
+	// <http://blocked-by.legal-authority.example/>; rel="blocked-by"
+}
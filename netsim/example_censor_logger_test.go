@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"log/slog"
+	"net/netip"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/rbmk-project/x/netsim/censor"
+	"github.com/rbmk-project/x/netsim/clock"
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// This example shows how to use a [*censor.Logger] to observe the packets
+// flowing through a filter chain. The logger only emits events for the
+// matching packets and passes all the packets on unchanged.
+func Example_censorLogger() {
+	// Create a logger writing to stdout, omitting the wall-clock time
+	// since the events already contain the time of the simulation.
+	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey && len(groups) <= 0 {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+
+	// Create the filter only logging the packets directed to port 53
+	// and timestamping the events using a fake clock.
+	filter := censor.NewLogger(slog.New(handler), func(pkt *packet.Packet) bool {
+		return pkt.DstPort == 53
+	})
+	filter.SetClock(clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	// Pass a matching and a non-matching packet through the filter.
+	for _, dstPort := range []uint16{53, 443} {
+		pkt := &packet.Packet{
+			TTL:        64,
+			SrcAddr:    netip.MustParseAddr("130.192.91.211"),
+			DstAddr:    netip.MustParseAddr("8.8.8.8"),
+			IPProtocol: packet.IPProtocolUDP,
+			SrcPort:    54321,
+			DstPort:    dstPort,
+			Payload:    []byte("abc"),
+		}
+		orig := pkt.Clone()
+		target, injected := filter.Filter(pkt)
+		slog.New(handler).Info("filterDone",
+			slog.Bool("continue", target == packet.CONTINUE),
+			slog.Int("injected", len(injected)),
+			slog.Bool("unchanged", reflect.DeepEqual(pkt, orig)),
+		)
+	}
+
+	// Output:
+	// level=INFO msg=packetMatched dstAddr=8.8.8.8 dstPort=53 ipProtocol=udp ipTTL=64 payloadSize=3 srcAddr=130.192.91.211 srcPort=54321 t=2025-01-01T00:00:00.000Z tcpFlags=.....
+	// level=INFO msg=filterDone continue=true injected=0 unchanged=true
+	// level=INFO msg=filterDone continue=true injected=0 unchanged=true
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/nat"
+)
+
+// This example shows how to use [netsim] to simulate a carrier-grade
+// NAT running out of ports, where a client cannot communicate until
+// the mapping used by another client expires.
+func Example_cgnat() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach a UDP echo server.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses: []string{"203.0.113.10"},
+		UDPHandlers: map[uint16]func(pconn net.PacketConn){
+			7: func(pconn net.PacketConn) {
+				buffer := make([]byte, 1024)
+				for {
+					count, addr, err := pconn.ReadFrom(buffer)
+					if err != nil {
+						return
+					}
+					pconn.WriteTo(buffer[:count], addr)
+				}
+			},
+		},
+	}))
+
+	// Create and attach two clients behind the CGNAT.
+	clients := []*netsim.Stack{
+		scenario.MustNewStack(&netsim.StackConfig{Addresses: []string{"100.64.0.1"}}),
+		scenario.MustNewStack(&netsim.StackConfig{Addresses: []string{"100.64.0.2"}}),
+	}
+	for _, client := range clients {
+		scenario.Attach(client)
+	}
+
+	// Install a CGNAT with a single port on the central router.
+	cgnat := nat.NewCGNAT(&nat.CGNATConfig{
+		Clock:          scenario.Clock(),
+		Inside:         netip.MustParsePrefix("100.64.0.0/10"),
+		MappingTimeout: 500 * time.Millisecond,
+		MaxPort:        40000,
+		MinPort:        40000,
+		Public:         netip.MustParseAddr("198.51.100.1"),
+	})
+	scenario.Router().AddFilter(cgnat)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// echo sends a message using the given client and reads the echo.
+	echo := func(idx int) {
+		conn, err := clients[idx].DialContext(ctx, "udp", "203.0.113.10:7")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(250 * time.Millisecond))
+		if _, err := conn.Write([]byte("hello")); err != nil {
+			log.Fatal(err)
+		}
+		buffer := make([]byte, 1024)
+		count, err := conn.Read(buffer)
+		if err != nil {
+			fmt.Printf("client %d: no response\n", idx)
+			return
+		}
+		fmt.Printf("client %d: %s\n", idx, string(buffer[:count]))
+	}
+
+	// The first client gets the only port and the second client
+	// must wait for the mapping of the first client to expire.
+	echo(0)
+	echo(1)
+	time.Sleep(time.Second)
+	echo(1)
+	fmt.Printf("mappings: %d\n", cgnat.Mappings())
+
+	// Output:
+	// client 0: hello
+	// client 1: no response
+	// client 1: hello
+	// mappings: 1
+}
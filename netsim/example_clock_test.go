@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/censor"
+	"github.com/rbmk-project/x/netsim/clock"
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// This example shows how to use [netsim] with a fake clock to
+// deterministically expire the cache of an ISP resolver.
+func Example_fakeClock() {
+	// Create a new scenario driven by a fake clock using the given
+	// directory to cache the certificates used by the simulated PKI
+	clk := clock.NewFake(time.Now())
+	scenario := netsim.NewScenarioWithConfig("testdata", &netsim.ScenarioConfig{Clock: clk})
+	defer scenario.Close()
+
+	// Create and attach the upstream DNS server and the ISP resolver.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewISPResolverStack(&netsim.ISPResolverConfig{
+		Addresses: []string{"10.0.0.53"},
+		Upstreams: []string{"8.8.8.8"},
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Count the queries sent to the upstream DNS server.
+	var upstreamQueries atomic.Int64
+	scenario.Observe(func(pkt *packet.Packet) {
+		if pkt.DstAddr.String() == "8.8.8.8" {
+			upstreamQueries.Add(1)
+		}
+	})
+
+	// query queries the ISP resolver using deadlines computed
+	// using the fake clock and prints the results.
+	query := func() {
+		conn, err := clientStack.DialContext(context.Background(), "udp", "10.0.0.53:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(clk.Now().Add(5 * time.Second))
+		dnsConn := &dns.Conn{Conn: conn}
+		msg := new(dns.Msg)
+		msg.SetQuestion("dns.google.", dns.TypeA)
+		if err := dnsConn.WriteMsg(msg); err != nil {
+			log.Fatal(err)
+		}
+		resp, err := dnsConn.ReadMsg()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, ans := range resp.Answer {
+			if a, ok := ans.(*dns.A); ok {
+				fmt.Printf("%s (upstream queries: %d)\n", a.A.String(), upstreamQueries.Load())
+			}
+		}
+	}
+
+	// The second query hits the cache, while the third query
+	// happens after the cached records have expired.
+	query()
+	query()
+	clk.Advance(2 * time.Hour)
+	query()
+
+	// Output:
+	// 8.8.8.8 (upstream queries: 1)
+	// 8.8.8.8 (upstream queries: 1)
+	// 8.8.8.8 (upstream queries: 2)
+}
+
+// This example shows how to pass the scenario clock to the censorship
+// filters, such that a fake clock also drives their state. Here, a
+// blackholed connection recovers once the fake clock moves forward.
+func Example_fakeClockBlackholer() {
+	// Create a new scenario driven by a fake clock using the given
+	// directory to cache the certificates used by the simulated PKI
+	clk := clock.NewFake(time.Now())
+	scenario := netsim.NewScenarioWithConfig("testdata", &netsim.ScenarioConfig{Clock: clk})
+	defer scenario.Close()
+
+	// Create the blackholer using the scenario clock.
+	blackholer := censor.NewBlackholer(time.Minute, netip.AddrPort{}, []byte("example.com"))
+	blackholer.SetClock(scenario.Clock())
+
+	// filter passes a packet of the same connection through the
+	// blackholer and prints whether the packet was dropped.
+	filter := func(payload string) {
+		target, _ := blackholer.Filter(&packet.Packet{
+			TTL:        64,
+			SrcAddr:    netip.MustParseAddr("130.192.91.211"),
+			DstAddr:    netip.MustParseAddr("93.184.216.34"),
+			IPProtocol: packet.IPProtocolTCP,
+			SrcPort:    54321,
+			DstPort:    443,
+			Payload:    []byte(payload),
+		})
+		fmt.Printf("%q dropped: %v\n", payload, target == packet.DROP)
+	}
+
+	// The pattern triggers blackholing, which lasts until
+	// the fake clock moves past the configured duration.
+	filter("example.com")
+	filter("hello")
+	clk.Advance(time.Minute)
+	filter("hello")
+
+	// Output:
+	// "example.com" dropped: true
+	// "hello" dropped: true
+	// "hello" dropped: false
+}
@@ -5,14 +5,25 @@ package netsim_test
 import (
 	"context"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/miekg/dns"
+	"github.com/quic-go/quic-go"
 	"github.com/rbmk-project/dnscore"
 	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/clock"
+	netsimdns "github.com/rbmk-project/x/netsim/dns"
 )
 
 // This example shows how to use [netsim] to simulate a DNS
@@ -274,3 +285,915 @@ func Example_dnsOverHTTPS() {
 	// Output:
 	// 8.8.8.8
 }
+
+// This example shows how to use [netsim] to simulate a DNS
+// server that listens for incoming requests over QUIC.
+func Example_dnsOverQUIC() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create server stack emulating dns.google.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Create the client QUIC connection with the DNS server.
+	conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:853")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	qconn, err := quic.Dial(ctx, conn.(net.PacketConn), conn.RemoteAddr(), &tls.Config{
+		RootCAs:    scenario.RootCAs(),
+		NextProtos: []string{"doq"},
+		ServerName: "dns.google",
+	}, &quic.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer qconn.CloseWithError(0, "")
+
+	// Create the query to send, noting that DoQ requires a zero ID
+	query := new(dns.Msg)
+	query.RecursionDesired = true
+	query.Question = []dns.Question{{
+		Name:   "dns.google.",
+		Qtype:  dns.TypeA,
+		Qclass: dns.ClassINET,
+	}}
+	rawQuery, err := query.Pack()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Perform the DNS round trip using a dedicated stream
+	stream, err := qconn.OpenStreamSync(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawQuery = append(binary.BigEndian.AppendUint16(nil, uint16(len(rawQuery))), rawQuery...)
+	if _, err := stream.Write(rawQuery); err != nil {
+		log.Fatal(err)
+	}
+	stream.Close() // we're done writing
+	rawResp, err := io.ReadAll(stream)
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp := new(dns.Msg)
+	if err := resp.Unpack(rawResp[2:]); err != nil {
+		log.Fatal(err)
+	}
+
+	// Print the responses
+	for _, ans := range resp.Answer {
+		if a, ok := ans.(*dns.A); ok {
+			fmt.Printf("%s\n", a.A.String())
+		}
+	}
+
+	// Output:
+	// 8.8.8.8
+}
+
+// This example shows how to use [netsim] to serve record types
+// other than A, AAAA, and CNAME, as well as custom TTLs.
+func Example_dnsRecordTypes() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Add additional records to the scenario DNS database.
+	dnsd := scenario.DNSDatabase()
+	dnsd.AddTXT("example.com", "v=spf1 -all")
+	dnsd.AddMX("example.com", 10, "mail.example.com")
+	dnsd.AddPTR("93.184.216.34", "www.example.com")
+	dnsd.AddAddressesWithTTL([]string{"nocache.example.com"}, []string{"93.184.216.34"}, 0)
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Query for each record type and print the answers.
+	questions := []struct {
+		name  string
+		qtype uint16
+	}{
+		{"example.com.", dns.TypeTXT},
+		{"example.com.", dns.TypeMX},
+		{"34.216.184.93.in-addr.arpa.", dns.TypePTR},
+		{"nocache.example.com.", dns.TypeA},
+	}
+	for _, q := range questions {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		query := new(dns.Msg)
+		query.SetQuestion(q.name, q.qtype)
+		resp, _, err := (&dns.Client{}).ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		conn.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, ans := range resp.Answer {
+			fmt.Printf("%s\n", ans.String())
+		}
+	}
+
+	// Output:
+	// example.com.	3600	IN	TXT	"v=spf1 -all"
+	// example.com.	3600	IN	MX	10 mail.example.com.
+	// 34.216.184.93.in-addr.arpa.	3600	IN	PTR	www.example.com.
+	// nocache.example.com.	0	IN	A	93.184.216.34
+}
+
+// This example shows how [netsim] answers queries for
+// names that do not exist or lack the queried records.
+func Example_dnsNegativeResponses() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the servers and the client stack.
+	scenario.DNSDatabase().AddSOA("example.com", "ns.example.com", "hostmaster.example.com")
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		DomainNames: []string{"v4only.example.com"},
+		Addresses:   []string{"203.0.113.10"},
+	}))
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Query for a missing record type and for a missing name.
+	for _, name := range []string{"v4only.example.com.", "nonexistent.example.com."} {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeAAAA)
+		resp, _, err := (&dns.Client{}).ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		conn.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %s answers=%d\n", name, dns.RcodeToString[resp.Rcode], len(resp.Answer))
+		for _, rr := range resp.Ns {
+			fmt.Printf("%s\n", rr.String())
+		}
+	}
+
+	// Output:
+	// v4only.example.com. NOERROR answers=0
+	// example.com.	3600	IN	SOA	ns.example.com. hostmaster.example.com. 1 7200 3600 1209600 3600
+	// nonexistent.example.com. NXDOMAIN answers=0
+	// example.com.	3600	IN	SOA	ns.example.com. hostmaster.example.com. 1 7200 3600 1209600 3600
+}
+
+// This example shows how [netsim] truncates large DNS-over-UDP
+// responses, prompting clients to retry using DNS-over-TCP.
+func Example_dnsTruncation() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Register a name with many addresses.
+	var addrs []string
+	for idx := 1; idx <= 60; idx++ {
+		addrs = append(addrs, fmt.Sprintf("10.0.0.%d", idx))
+	}
+	scenario.DNSDatabase().AddAddresses([]string{"many.example.com"}, addrs)
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange performs a DNS round trip and prints the results.
+	exchange := func(network string, edns0 bool) {
+		conn, err := clientStack.DialContext(ctx, network, "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion("many.example.com.", dns.TypeA)
+		if edns0 {
+			query.SetEdns0(4096, false)
+		}
+		clientDNS := &dns.Client{Net: network, UDPSize: 4096}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s edns0=%v truncated=%v answers=%d\n",
+			network, edns0, resp.Truncated, len(resp.Answer))
+	}
+
+	exchange("udp", false)
+	exchange("udp", true)
+	exchange("tcp", false)
+
+	// Output:
+	// udp edns0=false truncated=true answers=29
+	// udp edns0=true truncated=false answers=60
+	// tcp edns0=false truncated=false answers=60
+}
+
+// This example shows how to use [netsim] to simulate DNSSEC signed
+// zones, including zones serving signatures that do not verify.
+func Example_dnsSEC() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Sign a zone correctly and another one using bogus signatures.
+	dd := scenario.DNSDatabase()
+	dd.AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+	dd.AddAddresses([]string{"www.example.org"}, []string{"93.184.216.34"})
+	if err := dd.SignZone("example.com"); err != nil {
+		log.Fatal(err)
+	}
+	if err := dd.SignZoneWithConfig("example.org", &netsimdns.DNSSECConfig{
+		BogusSignatures: true,
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for the A records of the given name setting
+	// the DO bit and verifies the signature using the zone key.
+	exchange := func(name, zone string) {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeA)
+		query.SetEdns0(4096, true)
+		clientDNS := &dns.Client{UDPSize: 4096}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		var (
+			rrset []dns.RR
+			sig   *dns.RRSIG
+		)
+		for _, rr := range resp.Answer {
+			switch rr := rr.(type) {
+			case *dns.RRSIG:
+				sig = rr
+			default:
+				rrset = append(rrset, rr)
+			}
+		}
+		if sig == nil {
+			log.Fatal("missing RRSIG")
+		}
+		key, _ := dd.ZoneKey(zone)
+		fmt.Printf("%s signer=%s valid=%v\n", name, sig.SignerName, sig.Verify(key, rrset) == nil)
+	}
+
+	exchange("www.example.com.", "example.com")
+	exchange("www.example.org.", "example.org")
+
+	// Output:
+	// www.example.com. signer=example.com. valid=true
+	// www.example.org. signer=example.org. valid=false
+}
+
+// This example shows how to use [netsim] to sign a zone using the
+// scenario clock, such that the validity period of the signatures
+// follows the simulated time rather than the wall clock.
+func Example_dnsSECFakeClock() {
+	// Create a new scenario driven by a fake clock set in the past
+	// using the given directory to cache the certificates used by
+	// the simulated PKI
+	clk := clock.NewFake(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC))
+	scenario := netsim.NewScenarioWithConfig("testdata", &netsim.ScenarioConfig{Clock: clk})
+	defer scenario.Close()
+
+	// Sign the zone using the scenario clock.
+	dd := scenario.DNSDatabase()
+	dd.AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+	if err := dd.SignZoneWithConfig("example.com", &netsimdns.DNSSECConfig{
+		Clock: scenario.Clock(),
+	}); err != nil {
+		log.Fatal(err)
+	}
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Query for the A records setting the DO bit using deadlines
+	// computed using the fake clock.
+	conn, err := clientStack.DialContext(context.Background(), "udp", "8.8.8.8:53")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(clk.Now().Add(5 * time.Second))
+	dnsConn := &dns.Conn{Conn: conn, UDPSize: 4096}
+	query := new(dns.Msg)
+	query.SetQuestion("www.example.com.", dns.TypeA)
+	query.SetEdns0(4096, true)
+	if err := dnsConn.WriteMsg(query); err != nil {
+		log.Fatal(err)
+	}
+	resp, err := dnsConn.ReadMsg()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Print whether the signature is valid now in the simulation
+	// and whether it is valid according to the wall clock.
+	for _, rr := range resp.Answer {
+		if sig, ok := rr.(*dns.RRSIG); ok {
+			fmt.Printf("simulated=%v wall=%v\n",
+				sig.ValidityPeriod(clk.Now()), sig.ValidityPeriod(time.Now()))
+		}
+	}
+
+	// Output:
+	// simulated=true wall=false
+}
+
+// This example shows how to use [netsim] to compute DNS
+// answers at query time using per-name hooks.
+func Example_dnsHooks() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Register a hook returning the client address.
+	dd := scenario.DNSDatabase()
+	dd.AddHook("whoami.example.com", func(query *netsimdns.Query) ([]dns.RR, bool) {
+		if query.Qtype != dns.TypeA || !query.ClientAddr.Is4() {
+			return nil, true
+		}
+		return []dns.RR{netsimdns.NewA(query.Name, 0, query.ClientAddr)}, true
+	})
+
+	// Register a hook returning addresses in round-robin order.
+	var counter atomic.Int64
+	backends := []netip.Addr{
+		netip.MustParseAddr("10.0.0.1"),
+		netip.MustParseAddr("10.0.0.2"),
+	}
+	dd.AddHook("rr.example.com", func(query *netsimdns.Query) ([]dns.RR, bool) {
+		if query.Qtype != dns.TypeA {
+			return nil, true
+		}
+		addr := backends[counter.Add(1)%int64(len(backends))]
+		return []dns.RR{netsimdns.NewA(query.Name, 0, addr)}, true
+	})
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for the A records of the given name.
+	exchange := func(network, name string) {
+		conn, err := clientStack.DialContext(ctx, network, "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeA)
+		clientDNS := &dns.Client{Net: network}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, rr := range resp.Answer {
+			if rr, ok := rr.(*dns.A); ok {
+				fmt.Printf("%s %s %s\n", network, name, rr.A.String())
+			}
+		}
+	}
+
+	exchange("udp", "whoami.example.com.")
+	exchange("tcp", "whoami.example.com.")
+	exchange("udp", "rr.example.com.")
+	exchange("udp", "rr.example.com.")
+
+	// Output:
+	// udp whoami.example.com. 193.206.158.22
+	// tcp whoami.example.com. 193.206.158.22
+	// udp rr.example.com. 10.0.0.2
+	// udp rr.example.com. 10.0.0.1
+}
+
+// This example shows how to use [netsim] to simulate split-horizon
+// DNS, where the answer depends on the client address.
+func Example_dnsViews() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Make clients within 193.206.158.0/24 resolve www.example.com
+	// to the blockpage address, while others see the real address.
+	dd := scenario.DNSDatabase()
+	dd.AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+	view := netsimdns.NewDatabase()
+	view.AddAddresses([]string{"www.example.com"}, []string{"10.10.34.35"})
+	dd.AddView(netip.MustParsePrefix("193.206.158.0/24"), view)
+
+	// Create and attach the DNS server and the client stacks.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	censoredClient := scenario.MustNewClientStack()
+	scenario.Attach(censoredClient)
+	otherClient := scenario.MustNewStack(&netsim.StackConfig{
+		Addresses: []string{"130.192.91.211"},
+	})
+	scenario.Attach(otherClient)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for www.example.com using the given stack.
+	exchange := func(stack *netsim.Stack) {
+		conn, err := stack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion("www.example.com.", dns.TypeA)
+		clientDNS := &dns.Client{}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, rr := range resp.Answer {
+			if rr, ok := rr.(*dns.A); ok {
+				fmt.Printf("%s\n", rr.A.String())
+			}
+		}
+	}
+
+	exchange(censoredClient)
+	exchange(otherClient)
+
+	// Output:
+	// 10.10.34.35
+	// 93.184.216.34
+}
+
+// This example shows how to use [netsim] to change DNS records while
+// the server is running, e.g., to simulate a domain migration.
+func Example_dnsMigration() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Register the original address of the domain.
+	dd := scenario.DNSDatabase()
+	dd.AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+	dd.AddTXT("www.example.com", "v=spf1 -all")
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for the given type and prints the results.
+	exchange := func(qtype uint16) {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion("www.example.com.", qtype)
+		clientDNS := &dns.Client{}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s", dns.RcodeToString[resp.Rcode])
+		for _, rr := range resp.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				fmt.Printf(" %s", rr.A.String())
+			case *dns.TXT:
+				fmt.Printf(" %q", rr.Txt)
+			}
+		}
+		fmt.Printf("\n")
+	}
+
+	// Migrate the domain to a new address while the server is running.
+	exchange(dns.TypeA)
+	dd.ReplaceAddresses([]string{"www.example.com"}, []string{"104.18.26.120"})
+	exchange(dns.TypeA)
+	exchange(dns.TypeTXT)
+
+	// Remove the domain entirely.
+	dd.RemoveName("www.example.com")
+	exchange(dns.TypeA)
+
+	// Output:
+	// NOERROR 93.184.216.34
+	// NOERROR 104.18.26.120
+	// NOERROR ["v=spf1 -all"]
+	// NXDOMAIN
+}
+
+// This example shows how to use [netsim] to log the
+// queries received by the simulated DNS servers.
+func Example_dnsQueryLogging() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Log the queries omitting the time for reproducibility.
+	dd := scenario.DNSDatabase()
+	dd.AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+	dd.SetLogger(slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})))
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for the A records of the given name.
+	exchange := func(name string) {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeA)
+		clientDNS := &dns.Client{}
+		if _, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// send sends the given raw message and waits briefly for
+	// a response, which we do not expect to receive.
+	send := func(rawQuery []byte) {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write(rawQuery); err != nil {
+			log.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+		if _, err := conn.Read(make([]byte, 1500)); err == nil {
+			log.Fatal("unexpected response")
+		}
+	}
+
+	exchange("www.example.com.")
+	exchange("nonexistent.example.com.")
+
+	// Drop all the queries for a name, which we log along with
+	// the queries we cannot parse, to distinguish them from the
+	// queries that never reach the server.
+	dd.SetFault("dropped.example.com", &netsimdns.FaultConfig{DropRate: 1})
+	query := new(dns.Msg)
+	query.SetQuestion("dropped.example.com.", dns.TypeA)
+	rawQuery, err := query.Pack()
+	if err != nil {
+		log.Fatal(err)
+	}
+	send(rawQuery)
+	send([]byte("not a DNS message"))
+
+	// Output:
+	// level=INFO msg=dnsQuery clientAddr=193.206.158.22 qname=www.example.com. qtype=A rcode=NOERROR answer="[A 93.184.216.34]"
+	// level=INFO msg=dnsQuery clientAddr=193.206.158.22 qname=nonexistent.example.com. qtype=A rcode=NXDOMAIN answer=[]
+	// level=INFO msg=dnsQueryDropped clientAddr=193.206.158.22 qname=dropped.example.com. qtype=A reason=fault
+	// level=INFO msg=dnsQueryDropped clientAddr=193.206.158.22 qname="" qtype="" reason=malformed
+}
+
+// This example shows how to use [netsim] to simulate DNS
+// servers that misbehave when answering specific queries.
+func Example_dnsFaultInjection() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Configure the faults for specific names.
+	dd := scenario.DNSDatabase()
+	dd.AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+	dd.AddAddresses([]string{"slow.example.com"}, []string{"93.184.216.34"})
+	dd.SetFault("servfail.example.com", &netsimdns.FaultConfig{
+		Rcode: dns.RcodeServerFailure,
+	})
+	dd.SetFault("refused.example.com", &netsimdns.FaultConfig{
+		Rcode: dns.RcodeRefused,
+	})
+	dd.SetFault("slow.example.com", &netsimdns.FaultConfig{
+		Delay: 500 * time.Millisecond,
+	})
+	dd.SetFault("dropped.example.com", &netsimdns.FaultConfig{
+		DropRate: 1,
+	})
+
+	// Create and attach the DNS server and the client stack.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for the A records of the given name.
+	exchange := func(name string) {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeA)
+		clientDNS := &dns.Client{Timeout: time.Second}
+		t0 := time.Now()
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			fmt.Printf("%s timeout\n", name)
+			return
+		}
+		slow := time.Since(t0) >= 500*time.Millisecond
+		fmt.Printf("%s %s slow=%v\n", name, dns.RcodeToString[resp.Rcode], slow)
+	}
+
+	exchange("www.example.com.")
+	exchange("servfail.example.com.")
+	exchange("refused.example.com.")
+	exchange("slow.example.com.")
+	exchange("dropped.example.com.")
+
+	// Output:
+	// www.example.com. NOERROR slow=false
+	// servfail.example.com. SERVFAIL slow=false
+	// refused.example.com. REFUSED slow=false
+	// slow.example.com. NOERROR slow=true
+	// dropped.example.com. timeout
+}
+
+// This example shows how to use [netsim] to simulate a DNS
+// server implementing response rate limiting (RRL).
+func Example_dnsRRL() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Register the name to query.
+	scenario.DNSDatabase().AddAddresses([]string{"www.example.com"}, []string{"93.184.216.34"})
+
+	// Create and attach a DNS server rate limiting responses and
+	// using a fake clock to control the refilling of the buckets.
+	rrlClock := clock.NewFake(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses: []string{"8.8.8.8"},
+		DNSOverUDPHandler: netsimdns.NewRRLHandler(scenario.DNSHandler(), &netsimdns.RRLConfig{
+			Burst:              3,
+			Clock:              rrlClock,
+			ResponsesPerSecond: 1,
+			Slip:               2,
+		}),
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries for www.example.com and prints the outcome.
+	exchange := func() {
+		conn, err := clientStack.DialContext(ctx, "udp", "8.8.8.8:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion("www.example.com.", dns.TypeA)
+		clientDNS := &dns.Client{Timeout: 250 * time.Millisecond}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		switch {
+		case err != nil:
+			fmt.Printf("dropped\n")
+		case resp.Truncated:
+			fmt.Printf("truncated\n")
+		default:
+			fmt.Printf("answers=%d\n", len(resp.Answer))
+		}
+	}
+
+	// Exhaust the bucket, then wait for it to refill.
+	for idx := 0; idx < 6; idx++ {
+		exchange()
+	}
+	rrlClock.Advance(time.Second)
+	exchange()
+
+	// Output:
+	// answers=1
+	// answers=1
+	// answers=1
+	// dropped
+	// truncated
+	// dropped
+	// answers=1
+}
+
+// This example shows how to use [netsim] to resolve using each of the
+// DNS transports served by a stack, including DNS-over-HTTPS using both
+// the POST and the GET methods defined by RFC 8484, and how the HTTPS
+// handler keeps serving the paths other than /dns-query.
+func Example_dnsOverAllTransports() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create server stack emulating dns.google, which serves
+	// DNS over UDP, TCP, TLS, and HTTPS on 8.8.8.8.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Create the dnscore transport using the client stack.
+	tlsConfig := &tls.Config{
+		RootCAs:    scenario.RootCAs(),
+		ServerName: "dns.google",
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext:     clientStack.DialContext,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	defer httpClient.CloseIdleConnections()
+	txp := &dnscore.Transport{
+		DialContext: clientStack.DialContext,
+		DialTLSContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			conn, err := clientStack.DialContext(ctx, network, address)
+			if err != nil {
+				return nil, err
+			}
+			tconn := tls.Client(conn, tlsConfig)
+			if err := tconn.HandshakeContext(ctx); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return tconn, nil
+		},
+		HTTPClient: httpClient,
+	}
+
+	// Create the query to send
+	query, err := dnscore.NewQuery("dns.google", dns.TypeA)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// printAnswer prints the A records in the response.
+	printAnswer := func(transport string, resp *dns.Msg) {
+		for _, ans := range resp.Answer {
+			if a, ok := ans.(*dns.A); ok {
+				fmt.Printf("%s: %s\n", transport, a.A.String())
+			}
+		}
+	}
+
+	// Perform the DNS round trip using each dnscore transport, where
+	// DNS-over-HTTPS uses the POST method.
+	serverAddrs := []*dnscore.ServerAddr{
+		dnscore.NewServerAddr(dnscore.ProtocolUDP, "8.8.8.8:53"),
+		dnscore.NewServerAddr(dnscore.ProtocolTCP, "8.8.8.8:53"),
+		dnscore.NewServerAddr(dnscore.ProtocolDoT, "8.8.8.8:853"),
+		dnscore.NewServerAddr(dnscore.ProtocolDoH, "https://8.8.8.8/dns-query"),
+	}
+	for _, serverAddr := range serverAddrs {
+		resp, err := txp.Query(ctx, serverAddr, query)
+		if err != nil {
+			log.Fatal(err)
+		}
+		printAnswer(string(serverAddr.Protocol), resp)
+	}
+
+	// Perform the DNS-over-HTTPS round trip using the GET method.
+	rawQuery, err := query.Pack()
+	if err != nil {
+		log.Fatal(err)
+	}
+	URL := "https://8.8.8.8/dns-query?dns=" + base64.RawURLEncoding.EncodeToString(rawQuery)
+	httpResp, err := httpClient.Get(URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rawResp, err := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp := &dns.Msg{}
+	if err := resp.Unpack(rawResp); err != nil {
+		log.Fatal(err)
+	}
+	printAnswer("doh-get", resp)
+
+	// Make sure the HTTPS handler still serves the other paths.
+	httpResp, err = httpClient.Get("https://8.8.8.8/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	body, err := io.ReadAll(httpResp.Body)
+	httpResp.Body.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s", body)
+
+	// Output:
+	// udp: 8.8.8.8
+	// tcp: 8.8.8.8
+	// dot: 8.8.8.8
+	// doh: 8.8.8.8
+	// doh-get: 8.8.8.8
+	// Google Public DNS server.
+}
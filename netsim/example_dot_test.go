@@ -0,0 +1,52 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/censor"
+	"github.com/rbmk-project/x/netsim/geolink"
+)
+
+// This example shows how to export the scenario topology
+// using the Graphviz DOT language for visual inspection.
+func Example_writeDOT() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Attach the server to the central router.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Create a censoring ISP router connected to the central router.
+	ispRouter := scenario.MustNewRouter("isp")
+	ispRouter.AddFilter(censor.NewTCPResetter(netip.AddrPort{}, []byte("example.org")))
+	scenario.MustConnectRouters("isp", netsim.CentralRouterName, &geolink.Config{
+		Delay: 10 * time.Millisecond,
+	})
+
+	// Attach the client stack to the ISP router.
+	scenario.MustAttachTo("isp", scenario.MustNewClientStack())
+
+	// Write the topology to the standard output.
+	if err := scenario.WriteDOT(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+
+	// Output:
+	// graph scenario {
+	// 	"router:central" [shape=box, label="router central"];
+	// 	"router:isp" [shape=box, label="router isp\n*censor.TCPResetter"];
+	// 	"device0" [shape=ellipse, label="2001:4860:4860::8888\n8.8.8.8"];
+	// 	"device0" -- "router:central";
+	// 	"device1" [shape=ellipse, label="193.206.158.22\n2001:760:0:158::22"];
+	// 	"device1" -- "router:isp";
+	// 	"router:isp" -- "router:central" [label="delay=10ms"];
+	// }
+}
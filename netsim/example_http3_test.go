@@ -0,0 +1,58 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to simulate an HTTP/3
+// server that listens for incoming requests over QUIC.
+func Example_http3() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the server stacks.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP/3 client dialing QUIC connections
+	// using the simulated client stack.
+	clientTxp, err := scenario.NewHTTPTransportWithConfig(clientStack, &netsim.HTTPTransportConfig{
+		Protocol: netsim.HTTPProtocolHTTP3,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Get the response body.
+	resp, err := clientHTTP.Get("https://www.example.com/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Print the protocol and the response body
+	fmt.Printf("%s\n%s", resp.Proto, string(body))
+
+	// Output:
+	// HTTP/3.0
+	// Example Web Server.
+}
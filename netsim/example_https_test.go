@@ -59,3 +59,44 @@ func Example_https() {
 	// Output:
 	// Google Public DNS server.
 }
+
+// This example shows how to force using a specific HTTP
+// protocol version when creating the HTTP transport.
+func Example_httpsHTTP2() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the server stack emulating dns.google.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Fetch the same resource forcing HTTP/1.1 and HTTP/2.
+	for _, protocol := range []netsim.HTTPProtocol{
+		netsim.HTTPProtocolHTTP1,
+		netsim.HTTPProtocolHTTP2,
+	} {
+		clientTxp, err := scenario.NewHTTPTransportWithConfig(clientStack, &netsim.HTTPTransportConfig{
+			Protocol: protocol,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		clientHTTP := &http.Client{Transport: clientTxp}
+		resp, err := clientHTTP.Get("https://8.8.8.8/")
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp.Body.Close()
+		clientTxp.CloseIdleConnections()
+		fmt.Printf("%s\n", resp.Proto)
+	}
+
+	// Output:
+	// HTTP/1.1
+	// HTTP/2.0
+}
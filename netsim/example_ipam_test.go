@@ -0,0 +1,65 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to create
+// client stacks without hardcoding their addresses.
+func Example_allocateAddresses() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server and the web server.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Create and attach several clients using allocated
+	// addresses and make sure each of them works.
+	for idx := 0; idx < 3; idx++ {
+		addrs := scenario.MustAllocateAddresses()
+		clientStack := scenario.MustNewStack(&netsim.StackConfig{
+			Addresses:       addrs,
+			ClientResolvers: []string{"8.8.8.8"},
+		})
+		scenario.Attach(clientStack)
+
+		clientTxp := scenario.NewHTTPTransport(clientStack)
+		clientHTTP := &http.Client{Transport: clientTxp}
+		req, err := http.NewRequestWithContext(ctx, "GET", "http://www.example.com/", nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp, err := clientHTTP.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		clientTxp.CloseIdleConnections()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%v: %s", addrs, string(body))
+	}
+
+	// Output:
+	// [198.18.0.1 2001:db8::1]: Example Web Server.
+	// [198.18.0.2 2001:db8::2]: Example Web Server.
+	// [198.18.0.3 2001:db8::3]: Example Web Server.
+}
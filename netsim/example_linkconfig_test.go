@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to declare
+// the link impairments of a stack in its config.
+func Example_stackLinkDelay() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach a UDP echo server with a 50 ms link
+	// delay, which is interposed automatically on attach.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses: []string{"203.0.113.10"},
+		LinkDelay: 50 * time.Millisecond,
+		UDPHandlers: map[uint16]func(pconn net.PacketConn){
+			7: func(pconn net.PacketConn) {
+				buffer := make([]byte, 1024)
+				for {
+					count, addr, err := pconn.ReadFrom(buffer)
+					if err != nil {
+						return
+					}
+					pconn.WriteTo(buffer[:count], addr)
+				}
+			},
+		},
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Measure the round trip time.
+	conn, err := clientStack.DialContext(ctx, "udp", "203.0.113.10:7")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	t0 := time.Now()
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		log.Fatal(err)
+	}
+	buffer := make([]byte, 1024)
+	count, err := conn.Read(buffer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rtt := time.Since(t0)
+
+	// Print the results.
+	fmt.Printf("%s\n", string(buffer[:count]))
+	fmt.Printf("rtt >= 100ms: %v\n", rtt >= 100*time.Millisecond)
+
+	// Output:
+	// hello
+	// rtt >= 100ms: true
+}
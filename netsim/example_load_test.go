@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to load a scenario
+// from a declarative document stored inside testdata.
+func Example_loadScenario() {
+	// Register the HTTP handlers referenced by the document
+	netsim.RegisterHTTPHandler("hello", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello, world!\n"))
+	}))
+
+	// Load the scenario, which also caches the certificates
+	// used by the simulated PKI inside the document's directory
+	scenario, err := netsim.LoadScenario("testdata/scenario.yaml")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer scenario.Close()
+
+	// Create the HTTP client using the client stack
+	clientTxp := scenario.NewHTTPTransport(scenario.Stacks["client"])
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Get the response, which the document's DNAT rule
+	// causes to be served by the blockpage stack.
+	resp, err := clientHTTP.Get("http://www.example.com/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Print the status code and the response body
+	fmt.Printf("%d\n", resp.StatusCode)
+	fmt.Printf("%s", string(body))
+
+	// Output:
+	// 403
+	// Access to this website has been blocked by network policy.
+}
+
+// This example shows that [netsim.LoadScenario] returns an error
+// when the document declares stacks with conflicting addresses.
+func Example_loadScenarioConflict() {
+	// Load the scenario, which declares the same preset twice
+	scenario, err := netsim.LoadScenario("testdata/conflict.yaml")
+	if err == nil {
+		scenario.Close()
+		log.Fatal("expected an error")
+	}
+
+	// Print the error
+	fmt.Printf("%s\n", err)
+
+	// Output:
+	// stack "other-dns": address already used by another stack: 2001:4860:4860::8888
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/censor"
+	"github.com/rbmk-project/x/netsim/geolink"
+)
+
+// This example shows how to use multiple routers to simulate a
+// client-ISP-transit-server path where censorship happens at the
+// ISP router rather than at the central router.
+func Example_multiRouter() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Attach the servers to the central router.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create the ISP and transit routers and connect them such
+	// that the path is ISP <=> transit <=> central.
+	ispRouter := scenario.MustNewRouter("isp")
+	scenario.MustNewRouter("transit")
+	scenario.MustConnectRouters("isp", "transit", &geolink.Config{
+		Delay: 5 * time.Millisecond,
+	})
+	scenario.MustConnectRouters("transit", netsim.CentralRouterName, &geolink.Config{
+		Delay: 10 * time.Millisecond,
+	})
+
+	// Configure the ISP router to reset HTTP requests for www.example.org.
+	ispRouter.AddFilter(censor.NewTCPResetter(
+		netip.AddrPort{}, []byte("Host: www.example.org")))
+
+	// Create the client stack and attach it to the ISP router.
+	clientStack := scenario.MustNewClientStack()
+	scenario.MustAttachTo("isp", clientStack)
+
+	// Create the HTTP client
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	clientTxp.DisableKeepAlives = true
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Fetch www.example.com, which is not censored.
+	resp, err := clientHTTP.Get("http://www.example.com/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s", string(body))
+
+	// Fetch www.example.org, which is censored by the ISP.
+	_, err = clientHTTP.Get("http://www.example.org/")
+	fmt.Printf("censored: %v\n", err != nil)
+
+	// Output:
+	// Example Web Server.
+	// censored: true
+}
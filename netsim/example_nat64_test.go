@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/nat"
+)
+
+// This example shows how to use [netsim] to simulate a DNS64/NAT64
+// network where an IPv6-only client reaches an IPv4-only server.
+func Example_nat64() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS64 server.
+	scenario.Attach(scenario.MustNewGoogleDNS64Stack())
+
+	// Create and attach an IPv4-only server that tells
+	// the client which address it is connecting from.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		DomainNames: []string{"v4only.example.org"},
+		Addresses:   []string{"203.0.113.10"},
+		HTTPHandler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, _ := net.SplitHostPort(r.RemoteAddr)
+			fmt.Fprintf(w, "hello, %s\n", host)
+		}),
+	}))
+
+	// Create and attach an IPv6-only client stack.
+	clientStack := scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:       []string{"2001:760:0:158::22"},
+		ClientResolvers: []string{"2001:4860:4860::6464"},
+	})
+	scenario.Attach(clientStack)
+
+	// Install the NAT64 translator on the central router.
+	scenario.Router().AddFilter(nat.NewNAT64(
+		nat.WellKnownPrefix, netip.MustParseAddr("192.0.2.1")))
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Obtain the synthesized AAAA record.
+	conn, err := clientStack.DialContext(ctx, "udp", "[2001:4860:4860::6464]:53")
+	if err != nil {
+		log.Fatal(err)
+	}
+	query := new(dns.Msg)
+	query.SetQuestion("v4only.example.org.", dns.TypeAAAA)
+	resp, _, err := (&dns.Client{}).ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+	conn.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var addr string
+	for _, ans := range resp.Answer {
+		if aaaa, ok := ans.(*dns.AAAA); ok {
+			addr = aaaa.AAAA.String()
+			fmt.Printf("%s\n", addr)
+		}
+	}
+
+	// Fetch the content through the NAT64 translator.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+	httpResp, err := clientHTTP.Get("http://" + net.JoinHostPort(addr, "80") + "/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s", string(body))
+
+	// Output:
+	// 64:ff9b::cb00:710a
+	// hello, 192.0.2.1
+}
@@ -0,0 +1,88 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rbmk-project/x/netcore"
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] along with [netcore].
+func Example_netcoreNetwork() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server and the web server.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Create the network bound to the client stack.
+	netx := scenario.NewNetcoreNetwork(clientStack)
+
+	// Establish a TLS connection with the server, which involves
+	// resolving the domain name and verifying the certificate.
+	conn, err := netx.DialTLSContext(ctx, "tcp", "www.example.com:443")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Printf("%s\n", conn.RemoteAddr())
+
+	// Output:
+	// 93.184.216.34:443
+}
+
+// This example shows how to use [netsim] along with [netcore]
+// to establish QUIC connections.
+func Example_netcoreQUIC() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server and the web server.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Create the network bound to the client stack and only
+	// use IPv4 to get a predictable remote address.
+	netx := scenario.NewNetcoreNetwork(clientStack)
+	netx.AddressFamilyPolicy = netcore.AddressFamilyOnlyIPv4
+
+	// Establish a QUIC connection with the server using the
+	// default TLS config, which uses the "h3" ALPN for port 443.
+	qconn, err := netx.DialQUICContext(ctx, "www.example.com:443", nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer qconn.CloseWithError(0, "")
+	fmt.Printf("%s\n", qconn.RemoteAddr())
+	fmt.Printf("%s\n", qconn.ConnectionState().TLS.NegotiatedProtocol)
+
+	// Output:
+	// 93.184.216.34:443
+	// h3
+}
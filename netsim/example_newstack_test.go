@@ -0,0 +1,48 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [*netsim.Scenario.NewStack] to
+// handle configuration errors rather than panicking.
+func Example_newStackError() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Attempt to create a stack with an invalid address.
+	_, err := scenario.NewStack(&netsim.StackConfig{
+		Addresses: []string{"10.0.0.256"},
+	})
+	fmt.Printf("%v\n", err != nil)
+
+	// Attempt to create a stack serving DNS-over-TLS without
+	// domain names, hence without a TLS certificate.
+	_, err = scenario.NewStack(&netsim.StackConfig{
+		Addresses:         []string{"10.0.0.1"},
+		DNSOverTLSHandler: scenario.DNSHandler(),
+	})
+	fmt.Printf("%v\n", err)
+
+	// Output:
+	// true
+	// no TLS certificate available
+}
+
+// This example shows how to use [netsim.OpenScenario] to handle
+// errors creating the simulated PKI rather than panicking.
+func Example_openScenarioError() {
+	// Attempt to use a regular file as the directory
+	// caching the certificates used by the simulated PKI.
+	scenario, err := netsim.OpenScenario("example_newstack_test.go", &netsim.ScenarioConfig{})
+	fmt.Printf("%v %v\n", scenario == nil, err != nil)
+
+	// Output:
+	// true true
+}
@@ -0,0 +1,61 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"sync"
+
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// This example shows how to use [*netsim.Scenario.Observe] to
+// assert wire-level facts without modifying the filters chain.
+func Example_observe() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the stacks.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Count the packets sent to each destination address.
+	var (
+		mu    sync.Mutex
+		count = make(map[netip.Addr]int)
+	)
+	scenario.Observe(func(pkt *packet.Packet) {
+		mu.Lock()
+		count[pkt.DstAddr]++
+		mu.Unlock()
+	})
+
+	// Fetch www.example.com.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+	resp, err := clientHTTP.Get("http://www.example.com/")
+	if err != nil {
+		log.Fatal(err)
+	}
+	resp.Body.Close()
+
+	// Check which servers have received packets.
+	mu.Lock()
+	defer mu.Unlock()
+	for _, addr := range []string{"93.184.216.34", "1.1.1.1"} {
+		fmt.Printf("%s: %v\n", addr, count[netip.MustParseAddr(addr)] > 0)
+	}
+
+	// Output:
+	// 93.184.216.34: true
+	// 1.1.1.1: false
+}
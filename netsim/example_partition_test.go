@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// This example shows how to use [*netsim.Scenario.Partition] and
+// [*netsim.Scenario.Detach] to simulate connectivity outages.
+func Example_partition() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the server stacks.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	serverStack := scenario.MustNewExampleComStack()
+	scenario.Attach(serverStack)
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP client using a short timeout, such that
+	// requests fail quickly during the outages.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	clientTxp.DisableKeepAlives = true
+	clientHTTP := &http.Client{Transport: clientTxp, Timeout: time.Second}
+
+	fetch := func(label string) {
+		resp, err := clientHTTP.Get("http://www.example.com/")
+		if err != nil {
+			fmt.Printf("%s: failure\n", label)
+			return
+		}
+		resp.Body.Close()
+		fmt.Printf("%s: %d\n", label, resp.StatusCode)
+	}
+
+	// Fetch before, during, and after a partition between
+	// the client and the server. Note that the client can
+	// still resolve domain names during the partition.
+	fetch("before")
+	scenario.Partition(
+		[]packet.NetworkDevice{clientStack},
+		[]packet.NetworkDevice{serverStack},
+	)
+	fetch("partition")
+	scenario.Heal()
+	fetch("healed")
+
+	// Fetch while the client is detached from the
+	// topology and once it is attached again.
+	scenario.Detach(clientStack)
+	fetch("detached")
+	scenario.Attach(clientStack)
+	fetch("reattached")
+
+	// Output:
+	// before: 200
+	// partition: failure
+	// healed: 200
+	// detached: failure
+	// reattached: 200
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/geolink"
+)
+
+// This example shows how to use [*netsim.Scenario.AttachWithLink] and
+// [*netsim.Scenario.Detach] to attach a stack again through a new link.
+func Example_attachWithLinkAgain() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach a UDP echo server.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses: []string{"203.0.113.10"},
+		UDPHandlers: map[uint16]func(pconn net.PacketConn){
+			7: func(pconn net.PacketConn) {
+				buffer := make([]byte, 1024)
+				for {
+					count, addr, err := pconn.ReadFrom(buffer)
+					if err != nil {
+						return
+					}
+					pconn.WriteTo(buffer[:count], addr)
+				}
+			},
+		},
+	}))
+
+	// Create the client stack and attach it through a link twice,
+	// which replaces the first link, then detach the client and
+	// attach it again through another link.
+	clientStack := scenario.MustNewClientStack()
+	config := &geolink.Config{Delay: 5 * time.Millisecond}
+	scenario.AttachWithLink(clientStack, config)
+	scenario.AttachWithLink(clientStack, config)
+	scenario.Detach(clientStack)
+	scenario.AttachWithLink(clientStack, config)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Send the datagrams and count the echoed ones, which should
+	// all arrive since no stale link reads from the client stack.
+	conn, err := clientStack.DialContext(ctx, "udp", "203.0.113.10:7")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	const total = 20
+	var received int
+	buffer := make([]byte, 1024)
+	for idx := 0; idx < total; idx++ {
+		if _, err := conn.Write([]byte("hello")); err != nil {
+			log.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Read(buffer); err == nil {
+			received++
+		}
+	}
+
+	// Print the results.
+	fmt.Printf("received %d/%d\n", received, total)
+
+	// Output:
+	// received 20/20
+}
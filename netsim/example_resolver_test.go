@@ -0,0 +1,169 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/censor"
+	netsimdns "github.com/rbmk-project/x/netsim/dns"
+)
+
+// This example shows how to use [netsim] to simulate ISP resolvers
+// that forward the queries to an upstream resolver, including a
+// resolver that censors a domain name by returning a blockpage address.
+func Example_ispResolver() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the upstream DNS server and the web server.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create and attach an honest ISP resolver.
+	scenario.Attach(scenario.MustNewISPResolverStack(&netsim.ISPResolverConfig{
+		Addresses: []string{"10.0.0.53"},
+		Upstreams: []string{"8.8.8.8"},
+	}))
+
+	// Create and attach a censoring ISP resolver.
+	overrides := netsimdns.NewDatabase()
+	overrides.AddAddresses([]string{"www.example.com"}, []string{"10.10.34.35"})
+	scenario.Attach(scenario.MustNewISPResolverStack(&netsim.ISPResolverConfig{
+		Addresses: []string{"10.0.0.54"},
+		Overrides: overrides,
+		Upstreams: []string{"8.8.8.8"},
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Query each resolver and print the results.
+	for _, resolver := range []string{"10.0.0.53", "10.0.0.54"} {
+		conn, err := clientStack.DialContext(ctx, "udp", net.JoinHostPort(resolver, "53"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		query := new(dns.Msg)
+		query.SetQuestion("www.example.com.", dns.TypeA)
+		resp, _, err := (&dns.Client{}).ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		conn.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, ans := range resp.Answer {
+			if a, ok := ans.(*dns.A); ok {
+				fmt.Printf("%s: %s\n", resolver, a.A.String())
+			}
+		}
+	}
+
+	// Output:
+	// 10.0.0.53: 93.184.216.34
+	// 10.0.0.54: 10.10.34.35
+}
+
+// This example shows how to use [netsim] to simulate an ISP resolver
+// walking the DNS hierarchy across the root, TLD, and authoritative
+// servers, including NS-level censorship of the authoritative server.
+func Example_iterativeResolver() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// newAuthoritativeStack creates and attaches an authoritative
+	// server with the given address answering using the given database.
+	newAuthoritativeStack := func(addr string, db *netsimdns.Database) {
+		scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+			Addresses:         []string{addr},
+			DNSOverUDPHandler: db,
+			DNSOverTCPHandler: db,
+		}))
+	}
+
+	// Create the root server delegating com to the TLD server.
+	rootDB := netsimdns.NewDatabase()
+	rootDB.AddSOA(".", "a.root-servers.net", "nstld.verisign-grs.com")
+	rootDB.AddNS("com", "a.gtld-servers.net")
+	rootDB.AddAddresses([]string{"a.gtld-servers.net"}, []string{"192.5.6.30"})
+	newAuthoritativeStack("198.41.0.4", rootDB)
+
+	// Create the TLD server delegating example.com to its server.
+	comDB := netsimdns.NewDatabase()
+	comDB.AddSOA("com", "a.gtld-servers.net", "nstld.verisign-grs.com")
+	comDB.AddNS("example.com", "ns.example.com")
+	comDB.AddAddresses([]string{"ns.example.com"}, []string{"199.43.135.53"})
+	newAuthoritativeStack("192.5.6.30", comDB)
+
+	// Create the authoritative server for example.com.
+	exampleDB := netsimdns.NewDatabase()
+	exampleDB.AddSOA("example.com", "ns.example.com", "hostmaster.example.com")
+	exampleDB.AddAddresses([]string{"www.example.com", "api.example.com"}, []string{"93.184.216.34"})
+	newAuthoritativeStack("199.43.135.53", exampleDB)
+
+	// Create and attach the ISP resolver starting from the root server.
+	scenario.Attach(scenario.MustNewISPResolverStack(&netsim.ISPResolverConfig{
+		Addresses:   []string{"10.0.0.53"},
+		RootServers: []string{"198.41.0.4"},
+		Timeout:     250 * time.Millisecond,
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// exchange queries the ISP resolver and prints the results.
+	exchange := func(name string) {
+		conn, err := clientStack.DialContext(ctx, "udp", "10.0.0.53:53")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		query := new(dns.Msg)
+		query.SetQuestion(name, dns.TypeA)
+		resp, _, err := (&dns.Client{}).ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s %s", name, dns.RcodeToString[resp.Rcode])
+		for _, ans := range resp.Answer {
+			if a, ok := ans.(*dns.A); ok {
+				fmt.Printf(" %s", a.A.String())
+			}
+		}
+		fmt.Printf("\n")
+	}
+
+	// Resolve a name, then censor the authoritative server and
+	// resolve another name within the same zone.
+	exchange("www.example.com.")
+	scenario.Router().AddFilter(censor.NewBlackholer(
+		time.Minute,
+		netip.MustParseAddrPort("199.43.135.53:53"),
+		nil,
+	))
+	exchange("api.example.com.")
+
+	// Output:
+	// www.example.com. NOERROR 93.184.216.34
+	// api.example.com. SERVFAIL
+}
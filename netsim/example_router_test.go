@@ -38,11 +38,10 @@ func Example_router() {
 	// Create the client stack, build a geographic point-to-point link
 	// and attach the scenario router to the other end of the link.
 	clientStack := scenario.MustNewClientStack()
-	linkDev := geolink.Extend(clientStack, &geolink.Config{
+	scenario.AttachWithLink(clientStack, &geolink.Config{
 		Delay: 10 * time.Millisecond,
 		Log:   true,
 	})
-	scenario.Attach(linkDev)
 
 	// Create the HTTP client
 	clientTxp := scenario.NewHTTPTransport(clientStack)
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use [netsim] to host arbitrary
+// TCP and UDP services using the generic handlers.
+func Example_services() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach a server stack hosting an SMTP
+	// banner on 25/tcp and an echo service on 7/udp.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses: []string{"10.0.0.1"},
+		TCPHandlers: map[uint16]func(net.Conn){
+			25: func(conn net.Conn) {
+				defer conn.Close()
+				fmt.Fprintf(conn, "220 mail.example.com ESMTP\r\n")
+			},
+		},
+		UDPHandlers: map[uint16]func(net.PacketConn){
+			7: func(pconn net.PacketConn) {
+				buffer := make([]byte, 1024)
+				for {
+					count, addr, err := pconn.ReadFrom(buffer)
+					if err != nil {
+						return
+					}
+					pconn.WriteTo(buffer[:count], addr)
+				}
+			},
+		},
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Read the SMTP banner.
+	conn, err := clientStack.DialContext(ctx, "tcp", "10.0.0.1:25")
+	if err != nil {
+		log.Fatal(err)
+	}
+	banner, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn.Close()
+	fmt.Printf("%q\n", banner)
+
+	// Use the echo service.
+	uconn, err := clientStack.DialContext(ctx, "udp", "10.0.0.1:7")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer uconn.Close()
+	if _, err := uconn.Write([]byte("ping")); err != nil {
+		log.Fatal(err)
+	}
+	buffer := make([]byte, 1024)
+	count, err := uconn.Read(buffer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s\n", string(buffer[:count]))
+
+	// Output:
+	// "220 mail.example.com ESMTP\r\n"
+	// ping
+}
@@ -3,13 +3,24 @@
 package netsim_test
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/rbmk-project/common/errclass"
 	"github.com/rbmk-project/x/netsim"
+	"github.com/rbmk-project/x/netsim/simpki"
+	"golang.org/x/crypto/ocsp"
 )
 
 // This example shows how to use [netsim] to simulate a TLS
@@ -62,3 +73,605 @@ func Example_tls() {
 	// Output:
 	// <nil>
 }
+
+// This example shows how to use [netsim] to simulate a TLS
+// server presenting an expired certificate.
+func Example_tlsExpiredCertificate() {
+	// Create a new scenario caching the certificates used by the
+	// simulated PKI inside a temporary directory, since the validity
+	// period of the certificate depends on the current date
+	cacheDir, err := os.MkdirTemp("", "netsim")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	scenario := netsim.NewScenario(cacheDir)
+	defer scenario.Close()
+
+	// Create and attach a server stack whose certificate expired
+	// one day ago, along with the client stack.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:    []string{"10.10.0.2"},
+		CertNotAfter: time.Now().Add(-24 * time.Hour).Truncate(24 * time.Hour),
+		DomainNames:  []string{"expired.example.com"},
+		HTTPSHandler: http.NotFoundHandler(),
+	}))
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Connect to the server
+	conn, err := clientStack.DialContext(ctx, "tcp", "10.10.0.2:443")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Perform the TLS handshake
+	tconn := tls.Client(conn, &tls.Config{
+		RootCAs:    scenario.RootCAs(),
+		ServerName: "expired.example.com",
+	})
+	defer tconn.Close()
+	err = tconn.HandshakeContext(ctx)
+
+	// Print the error classification and whether the certificate expired
+	var invalidErr x509.CertificateInvalidError
+	fmt.Printf("%s\n", errclass.New(err))
+	fmt.Printf("%v\n", errors.As(err, &invalidErr) && invalidErr.Reason == x509.Expired)
+
+	// Output:
+	// ETLS_CERT_INVALID
+	// true
+}
+
+// This example shows how to use [netsim] to simulate a TLS server
+// presenting a certificate that is not valid yet, as seen by clients
+// with a correct clock and by clients whose clock is ahead.
+func Example_tlsNotYetValidCertificate() {
+	// Create a new scenario caching the certificates used by the
+	// simulated PKI inside a temporary directory, since the validity
+	// period of the certificate depends on the current date
+	cacheDir, err := os.MkdirTemp("", "netsim")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(cacheDir)
+	scenario := netsim.NewScenario(cacheDir)
+	defer scenario.Close()
+
+	// Create and attach a server stack whose certificate becomes
+	// valid within two days, along with the client stack.
+	notBefore := time.Now().Add(48 * time.Hour).Truncate(24 * time.Hour)
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:     []string{"10.10.0.3"},
+		CertNotBefore: notBefore,
+		DomainNames:   []string{"future.example.com"},
+		HTTPSHandler:  http.NotFoundHandler(),
+	}))
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// handshake performs a TLS handshake using the given clock.
+	handshake := func(now func() time.Time) error {
+		conn, err := clientStack.DialContext(ctx, "tcp", "10.10.0.3:443")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		tconn := tls.Client(conn, &tls.Config{
+			RootCAs:    scenario.RootCAs(),
+			ServerName: "future.example.com",
+			Time:       now,
+		})
+		defer tconn.Close()
+		return tconn.HandshakeContext(ctx)
+	}
+
+	// Print the outcome with a correct clock and with a clock ahead.
+	fmt.Printf("%s\n", errclass.New(handshake(time.Now)))
+	fmt.Printf("%v\n", handshake(func() time.Time {
+		return notBefore.Add(time.Hour)
+	}))
+
+	// Output:
+	// ETLS_CERT_INVALID
+	// <nil>
+}
+
+// This example shows how to use [netsim] to simulate TLS servers
+// presenting certificates for the wrong host or self-signed.
+func Example_tlsCertificateErrors() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach a server presenting a certificate for the wrong host.
+	wrongHostCert := scenario.PKI().MustNewWrongHostCert("mismatch.example.com")
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:      []string{"10.10.0.4"},
+		DomainNames:    []string{"mismatch.example.com"},
+		HTTPSHandler:   http.NotFoundHandler(),
+		TLSCertificate: &wrongHostCert,
+	}))
+
+	// Create and attach a server presenting an untrusted self-signed certificate.
+	selfSignedCert := scenario.PKI().MustNewSelfSignedCert(&simpki.Config{
+		CommonName: "untrusted.example.com",
+		DNSNames:   []string{"untrusted.example.com"},
+	})
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:      []string{"10.10.0.5"},
+		DomainNames:    []string{"untrusted.example.com"},
+		HTTPSHandler:   http.NotFoundHandler(),
+		TLSCertificate: &selfSignedCert,
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// handshake performs a TLS handshake and prints the error class.
+	handshake := func(address, serverName string) {
+		conn, err := clientStack.DialContext(ctx, "tcp", address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		tconn := tls.Client(conn, &tls.Config{
+			RootCAs:    scenario.RootCAs(),
+			ServerName: serverName,
+		})
+		defer tconn.Close()
+		fmt.Printf("%s: %s\n", serverName, errclass.New(tconn.HandshakeContext(ctx)))
+	}
+
+	handshake("10.10.0.4:443", "mismatch.example.com")
+	handshake("10.10.0.5:443", "untrusted.example.com")
+
+	// Output:
+	// mismatch.example.com: ETLS_HOSTNAME_MISMATCH
+	// untrusted.example.com: ETLS_CA_UNKNOWN
+}
+
+// This example shows how to use [netsim] to simulate TLS servers
+// presenting certificates using different key algorithms.
+func Example_tlsKeyAlgorithms() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach servers using Ed25519 and RSA keys.
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:        []string{"10.10.0.6"},
+		CertKeyAlgorithm: simpki.KeyAlgorithmEd25519,
+		DomainNames:      []string{"ed25519.example.com"},
+		HTTPSHandler:     http.NotFoundHandler(),
+	}))
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:        []string{"10.10.0.7"},
+		CertKeyAlgorithm: simpki.KeyAlgorithmRSA2048,
+		DomainNames:      []string{"rsa.example.com"},
+		HTTPSHandler:     http.NotFoundHandler(),
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// handshake performs a TLS handshake and prints the key algorithm.
+	handshake := func(address, serverName string) {
+		conn, err := clientStack.DialContext(ctx, "tcp", address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		tconn := tls.Client(conn, &tls.Config{
+			RootCAs:    scenario.RootCAs(),
+			ServerName: serverName,
+		})
+		defer tconn.Close()
+		if err := tconn.HandshakeContext(ctx); err != nil {
+			log.Fatal(err)
+		}
+		cert := tconn.ConnectionState().PeerCertificates[0]
+		fmt.Printf("%s: %s\n", serverName, cert.PublicKeyAlgorithm)
+	}
+
+	handshake("10.10.0.6:443", "ed25519.example.com")
+	handshake("10.10.0.7:443", "rsa.example.com")
+
+	// Output:
+	// ed25519.example.com: Ed25519
+	// rsa.example.com: RSA
+}
+
+// This example shows how to use [netsim] to simulate the OCSP responder
+// of the PKI and TLS servers stapling OCSP responses.
+func Example_tlsOCSP() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server, the OCSP responder, and a server
+	// whose certificate advertises the OCSP responder URL.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewOCSPResponderStack())
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:      []string{"10.10.0.8"},
+		CertOCSPServer: netsim.OCSPResponderURL,
+		DomainNames:    []string{"ocsp.example.com"},
+		HTTPSHandler:   http.NotFoundHandler(),
+	}))
+
+	// Create and attach a server stapling a revoked OCSP response.
+	stapledCert := scenario.PKI().MustNewCert(&simpki.Config{
+		CommonName: "stapled.example.com",
+		DNSNames:   []string{"stapled.example.com"},
+	})
+	scenario.PKI().MustStapleOCSP(&stapledCert, simpki.OCSPRevoked)
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:      []string{"10.10.0.9"},
+		DomainNames:    []string{"stapled.example.com"},
+		HTTPSHandler:   http.NotFoundHandler(),
+		TLSCertificate: &stapledCert,
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP client for querying the OCSP responder.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// handshake performs a TLS handshake and returns the connection state.
+	handshake := func(address, serverName string) tls.ConnectionState {
+		conn, err := clientStack.DialContext(ctx, "tcp", address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		tconn := tls.Client(conn, &tls.Config{
+			RootCAs:    scenario.RootCAs(),
+			ServerName: serverName,
+		})
+		defer tconn.Close()
+		if err := tconn.HandshakeContext(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return tconn.ConnectionState()
+	}
+
+	// query asks the OCSP responder for the status of the server certificate.
+	query := func(state tls.ConnectionState) int {
+		leaf, issuer := state.VerifiedChains[0][0], state.VerifiedChains[0][1]
+		rawReq, err := ocsp.CreateRequest(leaf, issuer, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		resp, err := clientHTTP.Post(leaf.OCSPServer[0], "application/ocsp-request", bytes.NewReader(rawReq))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		rawResp, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		parsed, err := ocsp.ParseResponseForCert(rawResp, leaf, issuer)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return parsed.Status
+	}
+
+	// Query the responder before and after revoking the certificate.
+	state := handshake("10.10.0.8:443", "ocsp.example.com")
+	fmt.Printf("responder: %v\n", query(state) == ocsp.Good)
+	scenario.PKI().SetOCSPStatus(state.PeerCertificates[0], simpki.OCSPRevoked)
+	fmt.Printf("responder: %v\n", query(state) == ocsp.Revoked)
+
+	// Check the stapled OCSP response.
+	state = handshake("10.10.0.9:443", "stapled.example.com")
+	leaf, issuer := state.VerifiedChains[0][0], state.VerifiedChains[0][1]
+	stapled, err := ocsp.ParseResponseForCert(state.OCSPResponse, leaf, issuer)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("stapled: %v\n", stapled.Status == ocsp.Revoked)
+
+	// Output:
+	// responder: true
+	// responder: true
+	// stapled: true
+}
+
+// This example shows how to use [netsim] to simulate the server
+// distributing the CRL of the PKI and to revoke certificates.
+func Example_tlsCRL() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the DNS server, the CRL server, and a server
+	// whose certificate advertises the CRL distribution point.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewCRLStack())
+	scenario.Attach(scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:                []string{"10.10.0.10"},
+		CertCRLDistributionPoint: netsim.CRLDistributionPointURL,
+		DomainNames:              []string{"crl.example.com"},
+		HTTPSHandler:             http.NotFoundHandler(),
+	}))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create the HTTP client for fetching the CRL.
+	clientTxp := scenario.NewHTTPTransport(clientStack)
+	defer clientTxp.CloseIdleConnections()
+	clientHTTP := &http.Client{Transport: clientTxp}
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Perform the TLS handshake.
+	conn, err := clientStack.DialContext(ctx, "tcp", "10.10.0.10:443")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+	tconn := tls.Client(conn, &tls.Config{
+		RootCAs:    scenario.RootCAs(),
+		ServerName: "crl.example.com",
+	})
+	defer tconn.Close()
+	if err := tconn.HandshakeContext(ctx); err != nil {
+		log.Fatal(err)
+	}
+	chain := tconn.ConnectionState().VerifiedChains[0]
+	leaf, issuer := chain[0], chain[1]
+
+	// isRevoked fetches the CRL and checks whether it lists the certificate.
+	isRevoked := func() bool {
+		resp, err := clientHTTP.Get(leaf.CRLDistributionPoints[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer resp.Body.Close()
+		rawCRL, err := io.ReadAll(resp.Body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		crl, err := x509.ParseRevocationList(rawCRL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			log.Fatal(err)
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Check the CRL before and after revoking the certificate.
+	fmt.Printf("revoked: %v\n", isRevoked())
+	scenario.PKI().Revoke(leaf)
+	fmt.Printf("revoked: %v\n", isRevoked())
+
+	// Output:
+	// revoked: false
+	// revoked: true
+}
+
+// This example shows how to use [netsim] to export the simulated
+// PKI as PEM files that external tools could trust.
+func Example_tlsExportPEM() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create server stack emulating dns.google, which
+	// also issues the certificate for dns.google.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+
+	// Export the PKI inside a temporary directory.
+	dir, err := os.MkdirTemp("", "netsim")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	scenario.PKI().MustExportPEM(dir)
+
+	// Load the exported root CA.
+	caPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		log.Fatal("cannot load ca.pem")
+	}
+
+	// Verify the exported certificates using the exported root CA.
+	certPEMs, err := filepath.Glob(filepath.Join(dir, "*", "cert.pem"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, certPEM := range certPEMs {
+		cert, err := tls.LoadX509KeyPair(certPEM, filepath.Join(filepath.Dir(certPEM), "key.pem"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = leaf.Verify(x509.VerifyOptions{Roots: roots})
+		fmt.Printf("%s: %v\n", leaf.Subject.CommonName, err)
+	}
+
+	// Output:
+	// dns.google: <nil>
+}
+
+// This example shows how to use [netsim] to test SPKI pinning
+// against the certificates issued by the simulated PKI.
+func Example_tlsSPKIPinning() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach servers emulating dns.google and www.example.com.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewExampleComStack())
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Pin the certificate of dns.google only.
+	pinnedCert := scenario.PKI().MustNewCert(&simpki.Config{
+		CommonName: "dns.google",
+		DNSNames:   []string{"dns.google", "dns.google.com"},
+		IPAddrs:    []net.IP{net.ParseIP("2001:4860:4860::8888"), net.ParseIP("8.8.8.8")},
+	})
+	pin := simpki.MustSPKIPinTLS(&pinnedCert)
+
+	// handshake performs a TLS handshake that fails unless the
+	// peer certificate matches the pin and prints the result.
+	handshake := func(address, serverName string) {
+		conn, err := clientStack.DialContext(ctx, "tcp", address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		tconn := tls.Client(conn, &tls.Config{
+			RootCAs:    scenario.RootCAs(),
+			ServerName: serverName,
+			VerifyConnection: func(state tls.ConnectionState) error {
+				if simpki.SPKIPin(state.PeerCertificates[0]) != pin {
+					return errors.New("pin mismatch")
+				}
+				return nil
+			},
+		})
+		defer tconn.Close()
+		err = tconn.HandshakeContext(ctx)
+		fmt.Printf("%s: %v\n", serverName, err)
+	}
+
+	handshake("8.8.8.8:443", "dns.google")
+	handshake("93.184.216.34:443", "www.example.com")
+
+	// Output:
+	// dns.google: <nil>
+	// www.example.com: pin mismatch
+}
+
+// This example shows how to use [netsim] to simulate a TLS
+// server rotating its certificate at runtime.
+func Example_tlsCertificateRotation() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the server and the client stacks.
+	serverStack := scenario.MustNewStack(&netsim.StackConfig{
+		Addresses:    []string{"10.10.0.11"},
+		DomainNames:  []string{"rotate.example.com"},
+		HTTPSHandler: http.NotFoundHandler(),
+	})
+	scenario.Attach(serverStack)
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// handshake performs a TLS handshake and returns the peer certificate.
+	handshake := func() *x509.Certificate {
+		conn, err := clientStack.DialContext(ctx, "tcp", "10.10.0.11:443")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		tconn := tls.Client(conn, &tls.Config{
+			RootCAs:    scenario.RootCAs(),
+			ServerName: "rotate.example.com",
+		})
+		defer tconn.Close()
+		if err := tconn.HandshakeContext(ctx); err != nil {
+			log.Fatal(err)
+		}
+		return tconn.ConnectionState().PeerCertificates[0]
+	}
+
+	// isRevoked returns whether the PKI CRL lists the given certificate.
+	isRevoked := func(cert *x509.Certificate) bool {
+		crl, err := x509.ParseRevocationList(scenario.PKI().MustNewCRL())
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Rotate the certificate between two handshakes.
+	before := handshake()
+	scenario.MustRotateCert(serverStack)
+	after := handshake()
+
+	// Print whether the certificate changed and which one is revoked.
+	fmt.Printf("rotated: %v\n", before.SerialNumber.Cmp(after.SerialNumber) != 0)
+	fmt.Printf("before revoked: %v\n", isRevoked(before))
+	fmt.Printf("after revoked: %v\n", isRevoked(after))
+
+	// Output:
+	// rotated: true
+	// before revoked: true
+	// after revoked: false
+}
@@ -0,0 +1,69 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/x/netsim"
+)
+
+// This example shows how to use several well-known
+// resolver presets within the same scenario.
+func Example_wellKnownResolvers() {
+	// Create a new scenario using the given directory to cache
+	// the certificates used by the simulated PKI
+	scenario := netsim.NewScenario("testdata")
+	defer scenario.Close()
+
+	// Create and attach the resolver stacks.
+	scenario.Attach(scenario.MustNewGoogleDNSStack())
+	scenario.Attach(scenario.MustNewCloudflareDNSStack())
+	scenario.Attach(scenario.MustNewQuad9Stack())
+	scenario.Attach(scenario.MustNewResolverStack("dns.example.net", "10.53.0.1"))
+
+	// Create and attach the client stack.
+	clientStack := scenario.MustNewClientStack()
+	scenario.Attach(clientStack)
+
+	// Create a context with a watchdog timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Query each resolver over UDP.
+	for _, endpoint := range []string{
+		"8.8.8.8:53", "1.1.1.1:53", "9.9.9.9:53", "10.53.0.1:53",
+	} {
+		conn, err := clientStack.DialContext(ctx, "udp", endpoint)
+		if err != nil {
+			log.Fatal(err)
+		}
+		query := new(dns.Msg)
+		query.SetQuestion("one.one.one.one.", dns.TypeA)
+		clientDNS := &dns.Client{}
+		resp, _, err := clientDNS.ExchangeWithConnContext(ctx, query, &dns.Conn{Conn: conn})
+		conn.Close()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, ans := range resp.Answer {
+			if a, ok := ans.(*dns.A); ok {
+				fmt.Printf("%s: %s\n", endpoint, a.A.String())
+			}
+		}
+	}
+
+	// Output:
+	// 8.8.8.8:53: 1.1.1.1
+	// 8.8.8.8:53: 1.0.0.1
+	// 1.1.1.1:53: 1.1.1.1
+	// 1.1.1.1:53: 1.0.0.1
+	// 9.9.9.9:53: 1.1.1.1
+	// 9.9.9.9:53: 1.0.0.1
+	// 10.53.0.1:53: 1.1.1.1
+	// 10.53.0.1:53: 1.0.0.1
+}
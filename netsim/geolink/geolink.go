@@ -9,25 +9,69 @@ package geolink
 
 import (
 	"log"
+	"math/rand/v2"
 	"net/netip"
+	"sync"
 	"time"
 
+	"github.com/rbmk-project/x/netsim/clock"
 	"github.com/rbmk-project/x/netsim/packet"
 )
 
 // Config configures a geographic point-to-point link.
 type Config struct {
+	// Bandwidth is the optional link bandwidth in bits per second. When
+	// set, each packet additionally experiences a transmission delay
+	// proportional to its size. If zero, the bandwidth is unlimited.
+	Bandwidth int64
+
+	// Clock is the optional clock used to implement the delay. If
+	// nil, we use [clock.Real].
+	Clock clock.Clock
+
 	// Delay is the propagation delay.
 	Delay time.Duration
 
 	// Log enables logging of delivered packets.
 	Log bool
+
+	// PLR is the optional packet loss rate, between zero and one, with
+	// which the link randomly drops packets in each direction.
+	PLR float64
+}
+
+// packetOverhead approximates the IP and transport headers size.
+const packetOverhead = 40
+
+// delayFor returns the time required to deliver the given packet.
+func (c *Config) delayFor(pkt *packet.Packet) time.Duration {
+	delay := c.Delay
+	if c.Bandwidth > 0 {
+		bits := int64(len(pkt.Payload)+packetOverhead) * 8
+		delay += time.Duration(bits * int64(time.Second) / c.Bandwidth)
+	}
+	return max(time.Millisecond, delay)
+}
+
+// clock returns the clock to use.
+func (c *Config) clock() clock.Clock {
+	if c.Clock != nil {
+		return c.Clock
+	}
+	return clock.Real()
+}
+
+// shouldDrop returns whether the link should lose the current packet.
+func (c *Config) shouldDrop() bool {
+	return c.PLR > 0 && rand.Float64() < c.PLR
 }
 
 // baseDevice is the common implementation for the
 // devices type returned by this package.
 type baseDevice struct {
 	addresses []netip.Addr
+	closeOnce sync.Once
+	eof       chan struct{}
 	input     chan *packet.Packet
 	output    chan *packet.Packet
 }
@@ -37,6 +81,13 @@ func (dev *baseDevice) Addresses() []netip.Addr {
 }
 
 func (dev *baseDevice) EOF() <-chan struct{} {
+	return dev.eof
+}
+
+// Close stops the forwarding goroutines of the link, thus dropping the
+// packets in flight, and does not close the device we extended.
+func (dev *baseDevice) Close() error {
+	dev.closeOnce.Do(func() { close(dev.eof) })
 	return nil
 }
 
@@ -88,12 +139,17 @@ func (ed *externalDevice) Output() <-chan *packet.Packet {
 // Packets flowing through this chain experience
 // the configured delay in both directions.
 //
-// We create two goroutines for forwarding packets,
-// which are closed when dev is closed.
+// We create two goroutines for forwarding packets, which
+// terminate when dev is closed or when closing the returned
+// device, which implements [io.Closer]. After Close, the
+// returned device reports EOF and no one reads from dev
+// anymore, so you can extend dev again.
 func Extend(dev packet.NetworkDevice, config *Config) packet.NetworkDevice {
 	input, output := packet.NewNetworkDeviceIOChannels()
 	local := &baseDevice{
 		addresses: dev.Addresses(),
+		closeOnce: sync.Once{},
+		eof:       make(chan struct{}),
 		input:     input,
 		output:    output,
 	}
@@ -122,24 +178,45 @@ type destDevice interface {
 // Packets are forwarded in order and the delay is applied to each
 // packet individually. This models how packets travel through a
 // physical link where the propagation delay applies to each packet.
+// When the bandwidth is limited, the delay also includes the time
+// required to transmit the packet. Lost packets are dropped as soon
+// as we read them from the source device.
 func forward(src sourceDevice, dst destDevice, config *Config) {
-	delay := max(time.Millisecond, config.Delay)
-	ticker := time.NewTicker(time.Minute)
-	defer ticker.Stop()
+	timer := config.clock().NewTimer(time.Minute)
+	defer timer.Stop()
 	var packets []*packet.Packet
 	for {
+		// give priority to EOF, since select picks a random ready case and
+		// we must not steal packets from the device after Close
+		select {
+		case <-src.EOF():
+			return
+		case <-dst.EOF():
+			return
+		default:
+		}
+
 		select {
 		case pkt := <-src.Output():
+			if config.shouldDrop() {
+				continue
+			}
 			packets = append(packets, pkt)
 			if len(packets) == 1 {
-				ticker.Reset(delay)
+				timer.Reset(config.delayFor(pkt))
 			}
 
-		case <-ticker.C:
+		case <-timer.C():
+			if len(packets) <= 0 {
+				timer.Reset(time.Minute)
+				continue
+			}
 			pkt := packets[0]
 			packets = packets[1:]
 			if len(packets) <= 0 {
-				ticker.Reset(time.Minute)
+				timer.Reset(time.Minute)
+			} else {
+				timer.Reset(config.delayFor(packets[0]))
 			}
 
 			if config.Log {
@@ -5,8 +5,14 @@ package netsim
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"net"
 	"net/http"
+	"net/url"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
 )
 
 // NewHTTPTransport creates an [*http.Transport] configured to use the
@@ -21,3 +27,127 @@ func (s *Scenario) NewHTTPTransport(stack *Stack) *http.Transport {
 		},
 	}
 }
+
+// HTTPProtocol selects the HTTP protocol used by an [HTTPTransport].
+type HTTPProtocol int
+
+const (
+	// HTTPProtocolDefault uses the same protocol selection
+	// as the transport returned by [*Scenario.NewHTTPTransport].
+	HTTPProtocolDefault HTTPProtocol = iota
+
+	// HTTPProtocolHTTP1 forces using HTTP/1.1.
+	HTTPProtocolHTTP1
+
+	// HTTPProtocolHTTP2 forces using HTTP/2 for https URLs, thus
+	// failing when the server does not negotiate "h2" using ALPN.
+	HTTPProtocolHTTP2
+
+	// HTTPProtocolHTTP3 forces using HTTP/3 over QUIC.
+	HTTPProtocolHTTP3
+)
+
+// HTTPTransportConfig contains optional settings for
+// [*Scenario.NewHTTPTransportWithConfig].
+//
+// The zero value is ready to use.
+type HTTPTransportConfig struct {
+	// Protocol selects the HTTP protocol to use.
+	Protocol HTTPProtocol
+
+	// Proxy is the optional proxy function. HTTP/3 does not support proxies.
+	Proxy func(req *http.Request) (*url.URL, error)
+
+	// RootCAs optionally overrides the scenario's root CAs.
+	RootCAs *x509.CertPool
+}
+
+// HTTPTransport is the [http.RoundTripper] returned by
+// [*Scenario.NewHTTPTransportWithConfig].
+type HTTPTransport interface {
+	http.RoundTripper
+	CloseIdleConnections()
+}
+
+var (
+	// errHTTP3Proxy indicates that HTTP/3 does not support proxies.
+	errHTTP3Proxy = errors.New("netsim: HTTP/3 does not support proxies")
+
+	// errNotPacketConn indicates that the stack returned a UDP
+	// connection not implementing [net.PacketConn].
+	errNotPacketConn = errors.New("netsim: UDP connection is not a net.PacketConn")
+
+	// errUnknownHTTPProtocol indicates an invalid [HTTPProtocol] value.
+	errUnknownHTTPProtocol = errors.New("netsim: unknown HTTP protocol")
+)
+
+// NewHTTPTransportWithConfig is like [*Scenario.NewHTTPTransport] but allows
+// to force a specific HTTP protocol and to override the default settings.
+//
+// When using [HTTPProtocolHTTP3], the returned value is an [*http3.Transport],
+// otherwise it is an [*http.Transport].
+func (s *Scenario) NewHTTPTransportWithConfig(stack *Stack, config *HTTPTransportConfig) (HTTPTransport, error) {
+	rootCAs := config.RootCAs
+	if rootCAs == nil {
+		rootCAs = s.RootCAs()
+	}
+
+	switch config.Protocol {
+	case HTTPProtocolDefault, HTTPProtocolHTTP1, HTTPProtocolHTTP2:
+		txp := s.NewHTTPTransport(stack)
+		txp.Proxy = config.Proxy
+		txp.TLSClientConfig.RootCAs = rootCAs
+		switch config.Protocol {
+		case HTTPProtocolHTTP1:
+			txp.TLSClientConfig.NextProtos = []string{"http/1.1"}
+			txp.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		case HTTPProtocolHTTP2:
+			txp.TLSClientConfig.NextProtos = []string{"h2"}
+			txp.ForceAttemptHTTP2 = true
+		}
+		return txp, nil
+
+	case HTTPProtocolHTTP3:
+		if config.Proxy != nil {
+			return nil, errHTTP3Proxy
+		}
+		return &http3.Transport{
+			Dial:            newQUICDialer(stack),
+			TLSClientConfig: &tls.Config{RootCAs: rootCAs},
+		}, nil
+
+	default:
+		return nil, errUnknownHTTPProtocol
+	}
+}
+
+// quicDialFunc is the type of [http3.Transport] Dial field.
+type quicDialFunc = func(ctx context.Context,
+	addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error)
+
+// newQUICDialer returns a function dialing QUIC connections using the
+// given stack. The function closes the underlying [net.PacketConn] once
+// the QUIC connection is closed.
+func newQUICDialer(stack *Stack) quicDialFunc {
+	return func(ctx context.Context, addr string, tlsConfig *tls.Config, config *quic.Config) (*quic.Conn, error) {
+		conn, err := stack.DialContext(ctx, "udp", addr)
+		if err != nil {
+			return nil, err
+		}
+		pconn, ok := conn.(net.PacketConn)
+		if !ok {
+			conn.Close()
+			return nil, errNotPacketConn
+		}
+		qconn, err := quic.DialEarly(ctx, pconn, conn.RemoteAddr(), tlsConfig, config)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		go func() {
+			<-qconn.Context().Done()
+			conn.Close()
+		}()
+		return qconn, nil
+	}
+}
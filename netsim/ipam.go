@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"errors"
+	"net/netip"
+
+	"github.com/rbmk-project/common/runtimex"
+)
+
+// DefaultAddressPools contains the default pools used by
+// [*Scenario.AllocateAddresses]: the 198.18.0.0/15 benchmarking
+// prefix (see RFC 2544) and the 2001:db8::/32 documentation prefix.
+var DefaultAddressPools = []netip.Prefix{
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// errAddressPoolExhausted indicates that an address pool has no free addresses.
+var errAddressPoolExhausted = errors.New("address pool exhausted")
+
+// addressPool is a pool of addresses.
+type addressPool struct {
+	// next is the next address to try.
+	next netip.Addr
+
+	// prefix is the pool prefix.
+	prefix netip.Prefix
+}
+
+// newAddressPools creates the address pools for the given prefixes. We
+// skip the first address of each prefix, which identifies the network.
+func newAddressPools(prefixes ...netip.Prefix) []*addressPool {
+	var pools []*addressPool
+	for _, prefix := range prefixes {
+		prefix = prefix.Masked()
+		pools = append(pools, &addressPool{next: prefix.Addr().Next(), prefix: prefix})
+	}
+	return pools
+}
+
+// SetAddressPools replaces the pools used by [*Scenario.AllocateAddresses]
+// with the given prefixes. Addresses already allocated or used by existing
+// stacks will not be allocated again.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) SetAddressPools(prefixes ...netip.Prefix) {
+	s.pools = newAddressPools(prefixes...)
+}
+
+// MustAllocateAddresses is like [*Scenario.AllocateAddresses] but panics on error.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) MustAllocateAddresses() []string {
+	return runtimex.Try1(s.AllocateAddresses())
+}
+
+// AllocateAddresses returns a new address from each pool (by default, an
+// IPv4 and an IPv6 address from [DefaultAddressPools]), suitable to be used
+// as the Addresses of a [*StackConfig]. The returned addresses are distinct
+// from the ones returned by previous calls and from the addresses of the
+// stacks created by the scenario, thus allowing to create many stacks
+// without hardcoding their addresses.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) AllocateAddresses() ([]string, error) {
+	// Find a free address in each pool without committing.
+	found := make([]netip.Addr, len(s.pools))
+	for idx, pool := range s.pools {
+		addr := pool.next
+		for addr.IsValid() && pool.prefix.Contains(addr) && s.usedAddrs[addr] {
+			addr = addr.Next()
+		}
+		if !addr.IsValid() || !pool.prefix.Contains(addr) {
+			return nil, errAddressPoolExhausted
+		}
+		found[idx] = addr
+	}
+
+	// Commit the allocation.
+	var addrs []string
+	for idx, addr := range found {
+		s.usedAddrs[addr] = true
+		s.pools[idx].next = addr.Next()
+		addrs = append(addrs, addr.String())
+	}
+	return addrs, nil
+}
+
+// markAddressesUsed records the given addresses as used such
+// that [*Scenario.AllocateAddresses] does not return them.
+func (s *Scenario) markAddressesUsed(addrs []string) {
+	for _, addr := range addrs {
+		if pa, err := netip.ParseAddr(addr); err == nil {
+			s.usedAddrs[pa] = true
+		}
+	}
+}
@@ -0,0 +1,496 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rbmk-project/x/netsim/censor"
+	"github.com/rbmk-project/x/netsim/clock"
+	netsimdns "github.com/rbmk-project/x/netsim/dns"
+	"github.com/rbmk-project/x/netsim/geolink"
+	"github.com/rbmk-project/x/netsim/packet"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// httpHandlersMu protects httpHandlers.
+	httpHandlersMu sync.RWMutex
+
+	// httpHandlers contains the registered HTTP handlers.
+	httpHandlers = map[string]http.Handler{}
+)
+
+// RegisterHTTPHandler registers an [http.Handler] with the given name, such
+// that scenario documents loaded using [LoadScenario] can reference it.
+//
+// This function is goroutine safe.
+func RegisterHTTPHandler(name string, handler http.Handler) {
+	httpHandlersMu.Lock()
+	httpHandlers[name] = handler
+	httpHandlersMu.Unlock()
+}
+
+// lookupHTTPHandler returns the [http.Handler] registered with the given name.
+func lookupHTTPHandler(name string) (http.Handler, error) {
+	httpHandlersMu.RLock()
+	handler, found := httpHandlers[name]
+	httpHandlersMu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("no registered HTTP handler named %q", name)
+	}
+	return handler, nil
+}
+
+// ScenarioDocument is the declarative description of a [*Scenario].
+//
+// See [LoadScenario] for more information.
+type ScenarioDocument struct {
+	// CacheDir is the optional directory where to cache the simulated-PKI-related
+	// data. When empty, we use the directory containing the document. Relative
+	// paths are relative to the directory containing the document.
+	CacheDir string `json:"cacheDir"`
+
+	// Censors contains the optional censorship rules to add to the router.
+	Censors []CensorDocument `json:"censors"`
+
+	// Stacks contains the stacks to create and attach to the router.
+	Stacks []StackDocument `json:"stacks"`
+}
+
+// StackDocument is the declarative description of a [*Stack].
+type StackDocument struct {
+	// Name is the unique name of the stack within the document.
+	Name string `json:"name"`
+
+	// Preset optionally creates a well-known stack. When this field is
+	// set, all the other fields but Name and Link are ignored. The
+	// valid presets are "blockpage", "captivePortal", "client", "cloudflareDNS",
+	// "exampleCom", "googleDNS", "googleDNS64", "legalBlockpage", and "quad9".
+	Preset string `json:"preset"`
+
+	// Addresses is like [StackConfig] Addresses.
+	Addresses []string `json:"addresses"`
+
+	// ClientResolvers is like [StackConfig] ClientResolvers.
+	ClientResolvers []string `json:"clientResolvers"`
+
+	// DNSOverUDP enables serving the scenario DNS database over UDP.
+	DNSOverUDP bool `json:"dnsOverUDP"`
+
+	// DNSOverTCP enables serving the scenario DNS database over TCP.
+	DNSOverTCP bool `json:"dnsOverTCP"`
+
+	// DNSOverTLS enables serving the scenario DNS database over TLS.
+	DNSOverTLS bool `json:"dnsOverTLS"`
+
+	// DNSOverQUIC enables serving the scenario DNS database over QUIC.
+	DNSOverQUIC bool `json:"dnsOverQUIC"`
+
+	// DNSOverHTTPS enables serving the scenario DNS database over HTTPS.
+	DNSOverHTTPS bool `json:"dnsOverHTTPS"`
+
+	// DomainNames is like [StackConfig] DomainNames.
+	DomainNames []string `json:"domainNames"`
+
+	// HTTPHandler is the optional name of a handler registered
+	// using [RegisterHTTPHandler] to serve on port 80/tcp.
+	HTTPHandler string `json:"httpHandler"`
+
+	// HTTPSHandler is the optional name of a handler registered
+	// using [RegisterHTTPHandler] to serve on port 443/tcp.
+	HTTPSHandler string `json:"httpsHandler"`
+
+	// HTTP3Handler is the optional name of a handler registered
+	// using [RegisterHTTPHandler] to serve on port 443/udp.
+	HTTP3Handler string `json:"http3Handler"`
+
+	// Link optionally interposes a [geolink] between the
+	// stack and the router of the scenario.
+	Link *LinkDocument `json:"link"`
+}
+
+// LinkDocument is the declarative description of a [geolink] link.
+type LinkDocument struct {
+	// Bandwidth is the optional bandwidth in bits per second.
+	Bandwidth int64 `json:"bandwidth"`
+
+	// Delay is the propagation delay (e.g., "10ms").
+	Delay string `json:"delay"`
+
+	// Log enables logging of delivered packets.
+	Log bool `json:"log"`
+
+	// PLR is the optional packet loss rate between zero and one.
+	PLR float64 `json:"plr"`
+}
+
+// CensorDocument is the declarative description of a censorship rule.
+type CensorDocument struct {
+	// Type is the rule type. The valid types are "blackholer",
+	// "dnat", "dnsPoisoner", and "tcpResetter".
+	Type string `json:"type"`
+
+	// Addresses maps domain names to the forged addresses
+	// that a "dnsPoisoner" should inject.
+	Addresses map[string][]string `json:"addresses"`
+
+	// Duration is the "blackholer" duration (e.g., "300s").
+	Duration string `json:"duration"`
+
+	// Pattern is the optional payload pattern used by
+	// the "blackholer" and "tcpResetter" rules.
+	Pattern string `json:"pattern"`
+
+	// Replacement is the "dnat" replacement destination endpoint.
+	Replacement string `json:"replacement"`
+
+	// Resolvers optionally restricts a "dnsPoisoner" to the
+	// queries directed to the given resolver addresses.
+	Resolvers []string `json:"resolvers"`
+
+	// Source is the "dnat" source address.
+	Source string `json:"source"`
+
+	// Target is the optional destination endpoint used by the "blackholer"
+	// and "tcpResetter" rules and the mandatory one used by "dnat".
+	Target string `json:"target"`
+}
+
+// LoadedScenario is the [*Scenario] returned by [LoadScenario].
+type LoadedScenario struct {
+	*Scenario
+
+	// Stacks maps the name of each stack in the document to the stack.
+	Stacks map[string]*Stack
+}
+
+// LoadScenario loads a [*ScenarioDocument] from the given file and creates
+// the corresponding [*LoadedScenario]. We parse the file as YAML when the
+// extension is ".yaml" or ".yml" and as JSON otherwise.
+//
+// The returned scenario contains all the stacks attached to its router
+// and the router contains all the configured censorship rules.
+//
+// Remember to Close the returned scenario when done.
+func LoadScenario(path string) (*LoadedScenario, error) {
+	return LoadScenarioWithConfig(path, &ScenarioConfig{})
+}
+
+// LoadScenarioWithConfig is like [LoadScenario] but creates the scenario
+// using the given config, whose Clock also drives the censorship rules.
+func LoadScenarioWithConfig(path string, config *ScenarioConfig) (*LoadedScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc ScenarioDocument
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = unmarshalYAMLDocument(data, &doc)
+	default:
+		err = unmarshalJSONDocument(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	cacheDir := doc.CacheDir
+	if !filepath.IsAbs(cacheDir) {
+		cacheDir = filepath.Join(filepath.Dir(path), cacheDir)
+	}
+	return doc.newScenario(cacheDir, config)
+}
+
+// unmarshalJSONDocument parses a JSON document rejecting unknown fields.
+func unmarshalJSONDocument(data []byte, doc *ScenarioDocument) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(doc)
+}
+
+// unmarshalYAMLDocument parses a YAML document by converting it to JSON,
+// which allows us to use the same field names and validation rules.
+func unmarshalYAMLDocument(data []byte, doc *ScenarioDocument) error {
+	var value any
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return unmarshalJSONDocument(jsonData, doc)
+}
+
+// newScenario creates a [*LoadedScenario] from the document.
+func (doc *ScenarioDocument) newScenario(cacheDir string, config *ScenarioConfig) (*LoadedScenario, error) {
+	if err := doc.validate(); err != nil {
+		return nil, err
+	}
+	scenario, err := OpenScenario(cacheDir, config)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := doc.newFilters(scenario.Clock())
+	if err != nil {
+		scenario.Close()
+		return nil, err
+	}
+	ls := &LoadedScenario{
+		Scenario: scenario,
+		Stacks:   map[string]*Stack{},
+	}
+	for _, pf := range filters {
+		if closer, ok := pf.(io.Closer); ok {
+			scenario.pool.Add(closer) // e.g., stop the pending injections
+		}
+		ls.Router().AddFilter(pf)
+	}
+	for _, sd := range doc.Stacks {
+		stack, dev, err := sd.newStack(ls.Scenario)
+		if err != nil {
+			ls.Close()
+			return nil, fmt.Errorf("stack %q: %w", sd.Name, err)
+		}
+		ls.Stacks[sd.Name] = stack
+		ls.Attach(dev)
+	}
+	return ls, nil
+}
+
+// validate returns an error if the document is not valid.
+func (doc *ScenarioDocument) validate() error {
+	names := map[string]bool{}
+	for _, sd := range doc.Stacks {
+		if sd.Name == "" {
+			return errors.New("stack without a name")
+		}
+		if names[sd.Name] {
+			return fmt.Errorf("duplicate stack name %q", sd.Name)
+		}
+		names[sd.Name] = true
+		if sd.Preset != "" {
+			continue
+		}
+		if len(sd.Addresses) < 1 {
+			return fmt.Errorf("stack %q: at least one address is required", sd.Name)
+		}
+		for _, addr := range append(append([]string{}, sd.Addresses...), sd.ClientResolvers...) {
+			if _, err := netip.ParseAddr(addr); err != nil {
+				return fmt.Errorf("stack %q: %w", sd.Name, err)
+			}
+		}
+		needsCert := sd.DNSOverTLS || sd.DNSOverQUIC || sd.DNSOverHTTPS ||
+			sd.HTTPSHandler != "" || sd.HTTP3Handler != ""
+		if needsCert && len(sd.DomainNames) <= 0 {
+			return fmt.Errorf("stack %q: TLS requires at least one domain name", sd.Name)
+		}
+	}
+	return nil
+}
+
+// newStack creates the [*Stack] and returns it along with the
+// [packet.NetworkDevice] that we should attach to the router.
+func (sd *StackDocument) newStack(s *Scenario) (*Stack, packet.NetworkDevice, error) {
+	var (
+		stack *Stack
+		err   error
+	)
+	switch sd.Preset {
+	case "blockpage":
+		stack, err = s.NewBlockpageStack()
+	case "captivePortal":
+		stack, err = s.NewCaptivePortalStack()
+	case "client":
+		stack, err = s.NewClientStack()
+	case "cloudflareDNS":
+		stack, err = s.NewCloudflareDNSStack()
+	case "exampleCom":
+		stack, err = s.NewExampleComStack()
+	case "googleDNS":
+		stack, err = s.NewGoogleDNSStack()
+	case "googleDNS64":
+		stack, err = s.NewGoogleDNS64Stack()
+	case "legalBlockpage":
+		stack, err = s.NewLegalBlockpageStack()
+	case "quad9":
+		stack, err = s.NewQuad9Stack()
+	case "":
+		var config *StackConfig
+		if config, err = sd.stackConfig(s); err == nil {
+			stack, err = s.NewStack(config)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown preset %q", sd.Preset)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if sd.Link == nil {
+		return stack, stack, nil
+	}
+	var delay time.Duration
+	if sd.Link.Delay != "" {
+		if delay, err = time.ParseDuration(sd.Link.Delay); err != nil {
+			return nil, nil, err
+		}
+	}
+	dev := geolink.Extend(stack, &geolink.Config{
+		Bandwidth: sd.Link.Bandwidth,
+		Clock:     s.Clock(),
+		Delay:     delay,
+		Log:       sd.Link.Log,
+		PLR:       sd.Link.PLR,
+	})
+	return stack, dev, nil
+}
+
+// stackConfig creates the [*StackConfig] for a non-preset stack.
+func (sd *StackDocument) stackConfig(s *Scenario) (*StackConfig, error) {
+	config := &StackConfig{
+		Addresses:       sd.Addresses,
+		ClientResolvers: sd.ClientResolvers,
+		DomainNames:     sd.DomainNames,
+	}
+	if sd.DNSOverUDP {
+		config.DNSOverUDPHandler = s.DNSHandler()
+	}
+	if sd.DNSOverTCP {
+		config.DNSOverTCPHandler = s.DNSHandler()
+	}
+	if sd.DNSOverTLS {
+		config.DNSOverTLSHandler = s.DNSHandler()
+	}
+	if sd.DNSOverQUIC {
+		config.DNSOverQUICHandler = s.DNSHandler()
+	}
+	if sd.DNSOverHTTPS {
+		config.DNSOverHTTPSHandler = s.DNSHandler()
+	}
+	if sd.HTTPHandler != "" {
+		handler, err := lookupHTTPHandler(sd.HTTPHandler)
+		if err != nil {
+			return nil, err
+		}
+		config.HTTPHandler = handler
+	}
+	if sd.HTTPSHandler != "" {
+		handler, err := lookupHTTPHandler(sd.HTTPSHandler)
+		if err != nil {
+			return nil, err
+		}
+		config.HTTPSHandler = handler
+	}
+	if sd.HTTP3Handler != "" {
+		handler, err := lookupHTTPHandler(sd.HTTP3Handler)
+		if err != nil {
+			return nil, err
+		}
+		config.HTTP3Handler = handler
+	}
+	return config, nil
+}
+
+// newFilters creates the [packet.Filter] for all the censorship
+// rules using the given clock to implement time-dependent behavior.
+func (doc *ScenarioDocument) newFilters(clk clock.Clock) ([]packet.Filter, error) {
+	var filters []packet.Filter
+	for idx, cd := range doc.Censors {
+		pf, err := cd.newFilter(clk)
+		if err != nil {
+			return nil, fmt.Errorf("censor #%d: %w", idx, err)
+		}
+		filters = append(filters, pf)
+	}
+	return filters, nil
+}
+
+// newFilter creates the [packet.Filter] for the censorship rule.
+func (cd *CensorDocument) newFilter(clk clock.Clock) (packet.Filter, error) {
+	var pattern []byte
+	if cd.Pattern != "" {
+		pattern = []byte(cd.Pattern)
+	}
+
+	switch cd.Type {
+	case "blackholer":
+		duration, err := time.ParseDuration(cd.Duration)
+		if err != nil {
+			return nil, err
+		}
+		target, err := parseOptionalAddrPort(cd.Target)
+		if err != nil {
+			return nil, err
+		}
+		blackholer := censor.NewBlackholer(duration, target, pattern)
+		blackholer.SetClock(clk)
+		return blackholer, nil
+
+	case "dnat":
+		source, err := netip.ParseAddr(cd.Source)
+		if err != nil {
+			return nil, err
+		}
+		target, err := netip.ParseAddrPort(cd.Target)
+		if err != nil {
+			return nil, err
+		}
+		repl, err := netip.ParseAddrPort(cd.Replacement)
+		if err != nil {
+			return nil, err
+		}
+		return censor.NewDNatter(source, target, repl), nil
+
+	case "dnsPoisoner":
+		db := netsimdns.NewDatabase()
+		for name, addrs := range cd.Addresses {
+			for _, addr := range addrs {
+				if _, err := netip.ParseAddr(addr); err != nil {
+					return nil, err
+				}
+			}
+			db.AddAddresses([]string{name}, addrs)
+		}
+		var resolvers []netip.Addr
+		for _, addr := range cd.Resolvers {
+			paddr, err := netip.ParseAddr(addr)
+			if err != nil {
+				return nil, err
+			}
+			resolvers = append(resolvers, paddr)
+		}
+		config := &censor.DNSPoisonerConfig{Clock: clk}
+		return censor.NewDNSPoisonerWithConfig(db, config, resolvers...), nil
+
+	case "tcpResetter":
+		target, err := parseOptionalAddrPort(cd.Target)
+		if err != nil {
+			return nil, err
+		}
+		return censor.NewTCPResetter(target, pattern), nil
+
+	default:
+		return nil, fmt.Errorf("unknown censor type %q", cd.Type)
+	}
+}
+
+// parseOptionalAddrPort parses an endpoint returning the zero value
+// when the endpoint is empty, which means "match any endpoint".
+func parseOptionalAddrPort(value string) (netip.AddrPort, error) {
+	if value == "" {
+		return netip.AddrPort{}, nil
+	}
+	return netip.ParseAddrPort(value)
+}
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package nat
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/rbmk-project/x/netsim/clock"
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// CGNATExhaustionPolicy controls how a [*CGNAT] behaves
+// when there are no free ports for creating a new mapping.
+type CGNATExhaustionPolicy int
+
+const (
+	// CGNATExhaustionDrop drops the packets requiring a new mapping
+	// until an existing mapping expires.
+	CGNATExhaustionDrop CGNATExhaustionPolicy = iota
+
+	// CGNATExhaustionRecycle recycles the least recently used mapping,
+	// thus breaking the connection that was using it.
+	CGNATExhaustionRecycle
+)
+
+// CGNATConfig contains the configuration for a [*CGNAT].
+type CGNATConfig struct {
+	// Clock is the optional clock used to expire the mappings. If nil,
+	// we use [clock.Real]. Pass the scenario clock when using a fake
+	// clock to drive the simulation.
+	Clock clock.Clock
+
+	// Exhaustion controls the behavior when all ports are in use.
+	Exhaustion CGNATExhaustionPolicy
+
+	// Inside contains the addresses behind the NAT (e.g., 100.64.0.0/10).
+	//
+	// The config is invalid if this prefix is not valid.
+	Inside netip.Prefix
+
+	// MappingTimeout is the idle timeout after which a mapping
+	// expires. If zero, we use a two minutes timeout.
+	MappingTimeout time.Duration
+
+	// MaxPort is the last port of the pool. If zero, we use 65535.
+	MaxPort uint16
+
+	// MinPort is the first port of the pool. If zero, we use 1024.
+	MinPort uint16
+
+	// Public is the public address shared by the hosts behind the NAT.
+	Public netip.Addr
+}
+
+// cgnatEndpoint is the inside endpoint of a CGNAT mapping.
+type cgnatEndpoint struct {
+	proto packet.IPProtocol
+	addr  netip.Addr
+	port  uint16
+}
+
+// cgnatPort is the public port of a CGNAT mapping.
+type cgnatPort struct {
+	proto packet.IPProtocol
+	port  uint16
+}
+
+// cgnatMapping is a CGNAT mapping.
+type cgnatMapping struct {
+	endpoint cgnatEndpoint
+	port     cgnatPort
+	lastUsed time.Time
+}
+
+// CGNAT implements a carrier-grade NAT translating the source endpoint
+// of packets sent from the inside addresses using a shared public address
+// and a limited pool of ports, which allows to test how clients behave
+// when the NAT runs out of ports or recycles mappings mid-connection.
+//
+// Mappings are endpoint-independent: all the packets from a given inside
+// endpoint use the same public port regardless of the destination.
+type CGNAT struct {
+	// config is the configuration.
+	config CGNATConfig
+
+	// mu protects the mappings.
+	mu sync.Mutex
+
+	// outbound maps inside endpoints to mappings.
+	outbound map[cgnatEndpoint]*cgnatMapping
+
+	// inbound maps public ports to mappings.
+	inbound map[cgnatPort]*cgnatMapping
+
+	// next is the next port to try when allocating.
+	next uint16
+}
+
+// NewCGNAT creates a new [*CGNAT] instance using the given config.
+//
+// For example, with Inside = "100.64.0.0/10" and Public = "198.51.100.1",
+// traffic from "100.64.0.1:1234" to "93.184.216.34:80" will be sent from
+// "198.51.100.1:1024" and return traffic to "198.51.100.1:1024" will be
+// sent to "100.64.0.1:1234". Traffic to the public address not matching
+// any active mapping is dropped.
+func NewCGNAT(config *CGNATConfig) *CGNAT {
+	cfg := *config
+	if cfg.Clock == nil {
+		cfg.Clock = clock.Real()
+	}
+	if cfg.MappingTimeout <= 0 {
+		cfg.MappingTimeout = 2 * time.Minute
+	}
+	if cfg.MinPort == 0 {
+		cfg.MinPort = 1024
+	}
+	if cfg.MaxPort == 0 {
+		cfg.MaxPort = 65535
+	}
+	return &CGNAT{
+		config:   cfg,
+		mu:       sync.Mutex{},
+		outbound: make(map[cgnatEndpoint]*cgnatMapping),
+		inbound:  make(map[cgnatPort]*cgnatMapping),
+		next:     cfg.MinPort,
+	}
+}
+
+// Mappings returns the number of active mappings.
+func (n *CGNAT) Mappings() int {
+	now := n.config.Clock.Now()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var count int
+	for _, mapping := range n.inbound {
+		if !n.expiredLocked(mapping, now) {
+			count++
+		}
+	}
+	return count
+}
+
+// Filter implements [packet.Filter].
+func (n *CGNAT) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet) {
+	now := n.config.Clock.Now()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	// forward path: translate traffic leaving the inside network
+	if n.config.Inside.Contains(pkt.SrcAddr) && !n.config.Inside.Contains(pkt.DstAddr) {
+		mapping := n.mapOutboundLocked(cgnatEndpoint{
+			proto: pkt.IPProtocol,
+			addr:  pkt.SrcAddr,
+			port:  pkt.SrcPort,
+		}, now)
+		if mapping == nil {
+			return packet.DROP, nil
+		}
+		mapping.lastUsed = now
+		pkt.SrcAddr = n.config.Public
+		pkt.SrcPort = mapping.port.port
+		return packet.CONTINUE, nil
+	}
+
+	// return path: translate traffic to the public address
+	if pkt.DstAddr == n.config.Public {
+		mapping := n.inbound[cgnatPort{proto: pkt.IPProtocol, port: pkt.DstPort}]
+		if mapping == nil || n.expiredLocked(mapping, now) {
+			return packet.DROP, nil
+		}
+		mapping.lastUsed = now
+		pkt.DstAddr = mapping.endpoint.addr
+		pkt.DstPort = mapping.endpoint.port
+		return packet.CONTINUE, nil
+	}
+
+	// otherwise just accept the packet
+	return packet.CONTINUE, nil
+}
+
+// expiredLocked returns whether the mapping is expired. This
+// method assumes the caller holds the mutex.
+func (n *CGNAT) expiredLocked(mapping *cgnatMapping, now time.Time) bool {
+	return now.Sub(mapping.lastUsed) >= n.config.MappingTimeout
+}
+
+// removeLocked removes a mapping. This method assumes the caller holds the mutex.
+func (n *CGNAT) removeLocked(mapping *cgnatMapping) {
+	delete(n.outbound, mapping.endpoint)
+	delete(n.inbound, mapping.port)
+}
+
+// mapOutboundLocked returns the mapping for the given inside endpoint,
+// creating a new mapping if needed. It returns nil when the pool is
+// exhausted and the policy is [CGNATExhaustionDrop]. This method
+// assumes the caller holds the mutex.
+func (n *CGNAT) mapOutboundLocked(endpoint cgnatEndpoint, now time.Time) *cgnatMapping {
+	// Reuse the existing mapping, if it's still active.
+	if mapping := n.outbound[endpoint]; mapping != nil {
+		if !n.expiredLocked(mapping, now) {
+			return mapping
+		}
+		n.removeLocked(mapping)
+	}
+
+	// Search for a free port, starting from where we left off.
+	var (
+		lru  *cgnatMapping
+		size = int(n.config.MaxPort) - int(n.config.MinPort) + 1
+	)
+	for attempt := 0; attempt < size; attempt++ {
+		port := cgnatPort{proto: endpoint.proto, port: n.next}
+		if n.next >= n.config.MaxPort {
+			n.next = n.config.MinPort
+		} else {
+			n.next++
+		}
+		existing := n.inbound[port]
+		if existing != nil && n.expiredLocked(existing, now) {
+			n.removeLocked(existing)
+			existing = nil
+		}
+		if existing == nil {
+			return n.addLocked(endpoint, port, now)
+		}
+		if lru == nil || existing.lastUsed.Before(lru.lastUsed) {
+			lru = existing
+		}
+	}
+
+	// Handle the exhaustion of the pool.
+	if n.config.Exhaustion != CGNATExhaustionRecycle || lru == nil {
+		return nil
+	}
+	n.removeLocked(lru)
+	return n.addLocked(endpoint, lru.port, now)
+}
+
+// addLocked adds a new mapping. This method assumes the caller holds the mutex.
+func (n *CGNAT) addLocked(endpoint cgnatEndpoint, port cgnatPort, now time.Time) *cgnatMapping {
+	mapping := &cgnatMapping{endpoint: endpoint, port: port, lastUsed: now}
+	n.outbound[endpoint] = mapping
+	n.inbound[port] = mapping
+	return mapping
+}
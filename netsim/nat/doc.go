@@ -0,0 +1,27 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+/*
+Package nat implements network address translation for testing.
+
+All the translators implement the [packet.Filter] interface and are
+meant to be installed on a router (e.g., the central router of a
+scenario) that sees both directions of the translated traffic.
+
+# NAT64
+
+The [*NAT64] type implements stateful NAT64 as documented by RFC 6146:
+it translates IPv6 packets sent to addresses embedding an IPv4 address
+inside a /96 prefix (e.g., [WellKnownPrefix]) into IPv4 packets using a
+single public IPv4 address, and translates the return traffic back. Combined
+with a DNS64 resolver, this allows IPv6-only client stacks to reach
+IPv4-only server stacks.
+
+# Carrier-Grade NAT
+
+The [*CGNAT] type implements a carrier-grade NAT sharing a public address
+among the hosts of an inside prefix (e.g., 100.64.0.0/10) using a limited pool
+of ports. Mappings expire after a configurable idle timeout and, when the pool
+is exhausted, the [CGNATExhaustionPolicy] controls whether to drop the packets
+requiring new mappings or to recycle the least recently used mapping.
+*/
+package nat
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package nat
+
+import (
+	"net/netip"
+	"sync"
+
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// WellKnownPrefix is the NAT64 well-known prefix (see RFC 6052).
+var WellKnownPrefix = netip.MustParsePrefix("64:ff9b::/96")
+
+// Synthesize returns the IPv6 address embedding the given IPv4
+// address inside the given /96 prefix, as documented by RFC 6052.
+func Synthesize(prefix netip.Prefix, addr netip.Addr) netip.Addr {
+	raw := prefix.Addr().As16()
+	v4 := addr.Unmap().As4()
+	copy(raw[12:], v4[:])
+	return netip.AddrFrom16(raw)
+}
+
+// extract returns the IPv4 address embedded in the given IPv6 address.
+func extract(addr netip.Addr) netip.Addr {
+	raw := addr.As16()
+	return netip.AddrFrom4([4]byte(raw[12:]))
+}
+
+// nat64Endpoint is the IPv6 endpoint of a NAT64 mapping.
+type nat64Endpoint struct {
+	proto packet.IPProtocol
+	addr  netip.Addr
+	port  uint16
+}
+
+// nat64Port is the IPv4 port of a NAT64 mapping.
+type nat64Port struct {
+	proto packet.IPProtocol
+	port  uint16
+}
+
+// NAT64 implements stateful NAT64 translation.
+//
+// Mappings never expire, therefore this type is not suitable
+// for testing how clients behave when mappings are recycled.
+type NAT64 struct {
+	// prefix is the /96 prefix embedding IPv4 addresses.
+	prefix netip.Prefix
+
+	// public is the public IPv4 address.
+	public netip.Addr
+
+	// mu protects the mappings.
+	mu sync.Mutex
+
+	// outbound maps IPv6 endpoints to IPv4 ports.
+	outbound map[nat64Endpoint]nat64Port
+
+	// inbound maps IPv4 ports to IPv6 endpoints.
+	inbound map[nat64Port]nat64Endpoint
+}
+
+// NewNAT64 creates a new [*NAT64] instance.
+//
+// Arguments:
+//
+// - prefix is the /96 prefix embedding IPv4 addresses (e.g., [WellKnownPrefix]).
+//
+// - public is the IPv4 address used as the source of translated packets.
+//
+// For example, with prefix = "64:ff9b::/96" and public = "192.0.2.1",
+// traffic from "2001:db8::1" to "[64:ff9b::5db8:d822]:80" will be sent
+// from "192.0.2.1" to "93.184.216.34:80" and return traffic will seem to
+// come from "[64:ff9b::5db8:d822]:80".
+func NewNAT64(prefix netip.Prefix, public netip.Addr) *NAT64 {
+	return &NAT64{
+		prefix:   prefix,
+		public:   public.Unmap(),
+		mu:       sync.Mutex{},
+		outbound: make(map[nat64Endpoint]nat64Port),
+		inbound:  make(map[nat64Port]nat64Endpoint),
+	}
+}
+
+// Filter implements [packet.Filter].
+func (n *NAT64) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet) {
+	// forward path: translate IPv6 traffic to the prefix
+	if pkt.DstAddr.Is6() && n.prefix.Contains(pkt.DstAddr) {
+		mapped, ok := n.mapOutbound(nat64Endpoint{
+			proto: pkt.IPProtocol,
+			addr:  pkt.SrcAddr,
+			port:  pkt.SrcPort,
+		})
+		if !ok {
+			return packet.DROP, nil
+		}
+		pkt.SrcAddr = n.public
+		pkt.SrcPort = mapped.port
+		pkt.DstAddr = extract(pkt.DstAddr)
+		return packet.CONTINUE, nil
+	}
+
+	// return path: translate IPv4 traffic to the public address
+	if pkt.DstAddr == n.public {
+		n.mu.Lock()
+		endpoint, found := n.inbound[nat64Port{proto: pkt.IPProtocol, port: pkt.DstPort}]
+		n.mu.Unlock()
+		if !found {
+			return packet.DROP, nil
+		}
+		pkt.SrcAddr = Synthesize(n.prefix, pkt.SrcAddr)
+		pkt.DstAddr = endpoint.addr
+		pkt.DstPort = endpoint.port
+		return packet.CONTINUE, nil
+	}
+
+	// otherwise just accept the packet
+	return packet.CONTINUE, nil
+}
+
+// mapOutbound returns the IPv4 port for the given IPv6 endpoint, creating
+// a new mapping if needed. We try to preserve the original port and otherwise
+// use the next free port. The boolean is false when all ports are in use.
+func (n *NAT64) mapOutbound(endpoint nat64Endpoint) (nat64Port, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if mapped, found := n.outbound[endpoint]; found {
+		return mapped, true
+	}
+	mapped := nat64Port{proto: endpoint.proto, port: endpoint.port}
+	for attempt := 0; attempt < 1<<16; attempt++ {
+		if _, found := n.inbound[mapped]; !found && mapped.port != 0 {
+			n.outbound[endpoint] = mapped
+			n.inbound[mapped] = endpoint
+			return mapped, true
+		}
+		mapped.port++
+	}
+	return nat64Port{}, false
+}
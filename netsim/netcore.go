@@ -0,0 +1,17 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import "github.com/rbmk-project/x/netcore"
+
+// NewNetcoreNetwork creates a [*netcore.Network] configured to dial and
+// resolve domain names using the given stack and to use the scenario's root
+// CAs, such that code using [netcore] can run inside the simulation.
+//
+// The caller may further customize the returned [*netcore.Network] (e.g.,
+// by setting the Logger field) before using it.
+func (s *Scenario) NewNetcoreNetwork(stack *Stack) *netcore.Network {
+	nx := &netcore.Network{RootCAs: s.RootCAs()}
+	nx.BindTo(stack)
+	return nx
+}
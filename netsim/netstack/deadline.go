@@ -11,18 +11,21 @@ package netstack
 import (
 	"sync"
 	"time"
+
+	"github.com/rbmk-project/x/netsim/clock"
 )
 
 // deadline is an abstraction for handling timeouts.
 type deadline struct {
+	clock  clock.Clock
 	mu     sync.Mutex // Guards timer and cancel
-	timer  *time.Timer
+	timer  clock.Timer
 	cancel chan struct{} // Must be non-nil
 }
 
-// newDeadline creates a new [*deadline] instance.
-func newDeadline() *deadline {
-	return &deadline{cancel: make(chan struct{})}
+// newDeadline creates a new [*deadline] instance using the given clock.
+func newDeadline(clock clock.Clock) *deadline {
+	return &deadline{clock: clock, cancel: make(chan struct{})}
 }
 
 // Set sets the point in time when the deadline will time out.
@@ -50,11 +53,11 @@ func (d *deadline) Set(t time.Time) {
 	}
 
 	// Time in the future, setup a timer to cancel in the future.
-	if dur := time.Until(t); dur > 0 {
+	if dur := t.Sub(d.clock.Now()); dur > 0 {
 		if closed {
 			d.cancel = make(chan struct{})
 		}
-		d.timer = time.AfterFunc(dur, func() {
+		d.timer = d.clock.AfterFunc(dur, func() {
 			close(d.cancel)
 		})
 		return
@@ -29,14 +29,22 @@ func (ns *Stack) DialContext(ctx context.Context, network, address string) (net.
 		return ns.dialContext(ctx, network, address)
 	}
 
-	// Otherwise, bail if there are no configured resolvers.
+	// Otherwise, configure netcore to perform the actual dial.
+	netx := &netcore.Network{}
+	netx.DialContextFunc = ns.dialContext
+	netx.LookupHostFunc = ns.LookupHost
+	return netx.DialContext(ctx, network, address)
+}
+
+// LookupHost resolves a domain name to IP addresses using the
+// resolvers configured using [*Stack.SetResolvers].
+func (ns *Stack) LookupHost(ctx context.Context, domain string) ([]string, error) {
+	// Bail if there are no configured resolvers.
 	if len(ns.resolvers) <= 0 {
 		return nil, ErrNoConfiguredResolvers
 	}
 
-	// Configure dnscore and netcore to perform the actual dial.
-	netx := &netcore.Network{}
-	netx.DialContextFunc = ns.dialContext
+	// Configure dnscore to perform the actual lookup.
 	reso := &dnscore.Resolver{}
 	reso.Config = dnscore.NewConfig()
 	for _, server := range ns.resolvers {
@@ -45,6 +53,5 @@ func (ns *Stack) DialContext(ctx context.Context, network, address string) (net.
 	reso.Transport = &dnscore.Transport{
 		DialContext: ns.dialContext,
 	}
-	netx.LookupHostFunc = reso.LookupHost
-	return netx.DialContext(ctx, network, address)
+	return reso.LookupHost(ctx, domain)
 }
@@ -13,6 +13,8 @@ import (
 	"os"
 	"sync"
 	"time"
+
+	"github.com/rbmk-project/x/netsim/clock"
 )
 
 // PortAddr is the [*Port] address.
@@ -40,6 +42,9 @@ func (pa *PortAddr) String() string {
 
 // PortStack is the stack to which a [*Port] is attached.
 type PortStack interface {
+	// Clock returns the clock used to implement deadlines.
+	Clock() clock.Clock
+
 	// ClosePort closes the given port.
 	ClosePort(addr *PortAddr)
 
@@ -86,9 +91,9 @@ func NewPort(stack PortStack, addr *PortAddr) *Port {
 		eofOnce: sync.Once{},
 		input:   make(chan *Packet),
 		output:  make(chan *Packet),
-		rd:      newDeadline(),
+		rd:      newDeadline(stack.Clock()),
 		stack:   stack,
-		wd:      newDeadline(),
+		wd:      newDeadline(stack.Clock()),
 	}
 }
 
@@ -16,6 +16,7 @@ import (
 
 	"github.com/rbmk-project/common/runtimex"
 	"github.com/rbmk-project/dnscore"
+	"github.com/rbmk-project/x/netsim/clock"
 	"github.com/rbmk-project/x/netsim/packet"
 )
 
@@ -24,6 +25,9 @@ type Stack struct {
 	// addr is the stack network address.
 	addrs []netip.Addr
 
+	// clock is the clock used to implement deadlines.
+	clock clock.Clock
+
 	// eof unblocks any blocking operation when the stack is closed.
 	eof chan struct{}
 
@@ -67,6 +71,7 @@ func New(addrs ...netip.Addr) *Stack {
 	input, output := packet.NewNetworkDeviceIOChannels()
 	ns := &Stack{
 		addrs:   addrs,
+		clock:   clock.Real(),
 		eof:     make(chan struct{}),
 		eofOnce: sync.Once{},
 		input:   input,
@@ -82,6 +87,21 @@ func New(addrs ...netip.Addr) *Stack {
 	return ns
 }
 
+// SetClock sets the clock used to implement deadlines, which by
+// default is [clock.Real]. When using a [*clock.Fake], the deadlines
+// passed to the connections must be computed using the same clock.
+//
+// Note that this method IS NOT goroutine safe and only
+// affects the connections created afterwards.
+func (ns *Stack) SetClock(clock clock.Clock) {
+	ns.clock = clock
+}
+
+// Clock implements [PortStack].
+func (ns *Stack) Clock() clock.Clock {
+	return ns.clock
+}
+
 // SetResolvers sets the resolvers endpoints to use.
 //
 // Note that this method IS NOT goroutine safe.
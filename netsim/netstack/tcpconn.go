@@ -39,7 +39,7 @@ func NewTCPConn(p *Port) *TCPConn {
 // Accept responds to the incoming SYN with SYN|ACK.
 func (c *TCPConn) Accept() (err error) {
 	c.initonce.Do(func() {
-		c.SetDeadline(time.Now().Add(time.Second))
+		c.SetDeadline(c.p.stack.Clock().Now().Add(time.Second))
 		defer c.SetDeadline(time.Time{})
 		err = c.p.WritePacket(nil, TCPFlagSYN|TCPFlagACK, netip.AddrPort{})
 	})
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"sync"
+
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// Observe registers a function receiving a copy of every packet that
+// the central router handles, before applying any filter. This allows
+// to assert wire-level facts (e.g., that no packet ever reached a given
+// address) without modifying the filters chain.
+//
+// The router invokes the function synchronously from its goroutines,
+// so the function MUST NOT block and MUST be goroutine safe. Packets
+// injected by filters do not traverse the filters and are not observed.
+//
+// This method is goroutine safe.
+func (s *Scenario) Observe(fx func(pkt *packet.Packet)) {
+	s.observers.add(fx)
+}
+
+// observerBus is a [packet.Filter] dispatching copies of
+// the packets it sees to the registered observers.
+type observerBus struct {
+	// mu protects observers.
+	mu sync.RWMutex
+
+	// observers contains the registered observers.
+	observers []func(pkt *packet.Packet)
+}
+
+// add registers a new observer.
+func (ob *observerBus) add(fx func(pkt *packet.Packet)) {
+	ob.mu.Lock()
+	ob.observers = append(ob.observers, fx)
+	ob.mu.Unlock()
+}
+
+// Filter implements [packet.Filter].
+func (ob *observerBus) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet) {
+	ob.mu.RLock()
+	observers := ob.observers
+	ob.mu.RUnlock()
+	for _, fx := range observers {
+		fx(pkt.Clone())
+	}
+	return packet.CONTINUE, nil
+}
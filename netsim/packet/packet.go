@@ -4,6 +4,7 @@
 package packet
 
 import (
+	"bytes"
 	"fmt"
 	"net"
 	"net/netip"
@@ -119,6 +120,13 @@ type Packet struct {
 	Payload []byte
 }
 
+// Clone returns a deep copy of the packet.
+func (p *Packet) Clone() *Packet {
+	out := *p
+	out.Payload = bytes.Clone(p.Payload)
+	return &out
+}
+
 // String returns the string representation of the packet.
 func (p *Packet) String() string {
 	switch p.IPProtocol {
@@ -203,6 +211,12 @@ type Filter interface {
 	Filter(pkt *Packet) (Target, []*Packet)
 }
 
+// Injector injects [*Packet] into the network outside of the
+// synchronous [Filter] return path (e.g., after a delay).
+type Injector interface {
+	Inject(pkt *Packet) error
+}
+
 // FilterFunc allows using a function as a [Filter].
 type FilterFunc func(pkt *Packet) (Target, []*Packet)
 
@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"net/netip"
+	"sync"
+
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// addrPair is an unordered pair of addresses.
+type addrPair struct {
+	a, b netip.Addr
+}
+
+// newAddrPair creates a new normalized [addrPair].
+func newAddrPair(a, b netip.Addr) addrPair {
+	if b.Less(a) {
+		a, b = b, a
+	}
+	return addrPair{a, b}
+}
+
+// partitionFilter is a [packet.Filter] dropping packets
+// exchanged between partitioned addresses.
+type partitionFilter struct {
+	// mu protects pairs.
+	mu sync.RWMutex
+
+	// pairs contains the partitioned address pairs.
+	pairs map[addrPair]struct{}
+}
+
+// newPartitionFilter creates a new [*partitionFilter].
+func newPartitionFilter() *partitionFilter {
+	return &partitionFilter{pairs: make(map[addrPair]struct{})}
+}
+
+// add partitions the addresses of groupA from the addresses of groupB.
+func (pf *partitionFilter) add(groupA, groupB []packet.NetworkDevice) {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	for _, devA := range groupA {
+		for _, devB := range groupB {
+			for _, addrA := range devA.Addresses() {
+				for _, addrB := range devB.Addresses() {
+					pf.pairs[newAddrPair(addrA, addrB)] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+// clear removes all the partitions.
+func (pf *partitionFilter) clear() {
+	pf.mu.Lock()
+	clear(pf.pairs)
+	pf.mu.Unlock()
+}
+
+// Filter implements [packet.Filter].
+func (pf *partitionFilter) Filter(pkt *packet.Packet) (packet.Target, []*packet.Packet) {
+	pf.mu.RLock()
+	_, found := pf.pairs[newAddrPair(pkt.SrcAddr, pkt.DstAddr)]
+	pf.mu.RUnlock()
+	if found {
+		return packet.DROP, nil
+	}
+	return packet.CONTINUE, nil
+}
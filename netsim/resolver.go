@@ -0,0 +1,397 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package netsim
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/rbmk-project/common/runtimex"
+	"github.com/rbmk-project/dnscore/dnscoretest"
+	"github.com/rbmk-project/x/netsim/clock"
+	netsimdns "github.com/rbmk-project/x/netsim/dns"
+)
+
+// ISPResolverConfig contains the configuration for creating an
+// ISP resolver stack using [*Scenario.NewISPResolverStack].
+type ISPResolverConfig struct {
+	// Addresses contains the IP addresses of the resolver stack.
+	//
+	// The config is invalid if there is not at least one address.
+	Addresses []string
+
+	// Overrides optionally contains records the resolver returns
+	// without querying the upstreams, which allows to model
+	// resolver-level censorship (e.g., returning a blockpage address).
+	Overrides *netsimdns.Database
+
+	// RootServers optionally contains the IP addresses of the stacks
+	// serving the root zone. When set, rather than forwarding queries to
+	// the Upstreams, we resolve them iteratively starting from the root
+	// servers and following the referrals returned by the authoritative
+	// servers (see [netsimdns.Database.AddNS]), thus allowing to model
+	// NS-level censorship and delegation breakage.
+	RootServers []string
+
+	// Timeout is the optional timeout for querying each upstream. If
+	// zero, we use a five seconds timeout.
+	Timeout time.Duration
+
+	// Upstreams contains the IP addresses of the stacks (e.g., the
+	// stack created by [*Scenario.MustNewGoogleDNSStack]) to which we
+	// forward queries using DNS-over-UDP on port 53, retrying using DNS-over-TCP
+	// for truncated responses. We try the upstreams in order until one of
+	// them returns a response.
+	//
+	// The config is invalid if there is not at least one upstream,
+	// unless RootServers is set, in which case we ignore this field.
+	Upstreams []string
+}
+
+var (
+	// errNoUpstreams indicates that an [*ISPResolverConfig] has no upstreams.
+	errNoUpstreams = errors.New("at least one upstream or root server is required")
+
+	// errNoServers indicates that we do not know any server to query.
+	errNoServers = errors.New("no name server addresses")
+
+	// errTooManyIterations indicates that iterative resolution did not converge.
+	errTooManyIterations = errors.New("too many iterations")
+)
+
+// MustNewISPResolverStack is like [*Scenario.NewISPResolverStack] but panics on error.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) MustNewISPResolverStack(config *ISPResolverConfig) *Stack {
+	return runtimex.Try1(s.NewISPResolverStack(config))
+}
+
+// NewISPResolverStack creates a new stack simulating an ISP recursive
+// resolver serving DNS-over-UDP and DNS-over-TCP on port 53.
+//
+// Unlike the stacks using [*Scenario.DNSHandler], this stack does not
+// answer from the scenario DNS database. Rather, it forwards each query to
+// the configured upstreams, or resolves it iteratively starting from the
+// configured root servers, from within the simulation, so the queries
+// traverse the routers and are subject to the configured filters.
+//
+// The resolver caches successful responses according to the minimum TTL
+// of their answers. Therefore, a response spoofed on the path between the
+// resolver and the upstreams poisons the cache and is served to all the
+// clients until it expires, even after removing the censor.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewISPResolverStack(config *ISPResolverConfig) (*Stack, error) {
+	if len(config.Upstreams) < 1 && len(config.RootServers) < 1 {
+		return nil, errNoUpstreams
+	}
+	handler := &ispResolverHandler{
+		cache:       make(map[dns.Question]ispResolverCacheEntry),
+		clock:       s.clock,
+		overrides:   config.Overrides,
+		rootServers: config.RootServers,
+		timeout:     config.Timeout,
+		upstreams:   config.Upstreams,
+	}
+	stack, err := s.NewStack(&StackConfig{
+		Addresses:         config.Addresses,
+		DNSOverUDPHandler: handler,
+		DNSOverTCPHandler: handler,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Note: the stack cannot receive queries before being attached,
+	// hence it's fine to set the stack after starting the servers.
+	handler.stack = stack
+	return stack, nil
+}
+
+// ispResolverCacheEntry is an entry in the [*ispResolverHandler] cache.
+type ispResolverCacheEntry struct {
+	// answer contains the cached answer RRs.
+	answer []dns.RR
+
+	// expires is when the entry expires.
+	expires time.Time
+}
+
+// ispResolverHandler is the [DNSHandler] used by the ISP resolver.
+type ispResolverHandler struct {
+	// cache maps questions to cached answers.
+	cache map[dns.Question]ispResolverCacheEntry
+
+	// clock is the clock used to expire cache entries.
+	clock clock.Clock
+
+	// mu protects cache.
+	mu sync.Mutex
+
+	// overrides contains the optional overrides.
+	overrides *netsimdns.Database
+
+	// rootServers contains the optional root servers addresses.
+	rootServers []string
+
+	// stack is the stack to use for querying the upstreams.
+	stack *Stack
+
+	// timeout is the timeout for querying each upstream.
+	timeout time.Duration
+
+	// upstreams contains the upstreams addresses.
+	upstreams []string
+}
+
+var _ DNSHandler = &ispResolverHandler{}
+
+// Handle implements [DNSHandler].
+func (h *ispResolverHandler) Handle(rw dnscoretest.ResponseWriter, rawQuery []byte) {
+	// Parse the incoming query and make sure it's a
+	// query containing just one question.
+	query := &dns.Msg{}
+	if err := query.Unpack(rawQuery); err != nil {
+		return
+	}
+	if query.Response || query.Opcode != dns.OpcodeQuery || len(query.Question) != 1 {
+		return
+	}
+	q0 := query.Question[0]
+	q0.Name = dns.CanonicalName(q0.Name)
+
+	// Obtain the response from the overrides, the cache, or the upstreams.
+	response := &dns.Msg{}
+	response.SetReply(query)
+	if answer, found := h.lookupLocally(q0); found {
+		response.Answer = answer
+	} else {
+		upstreamResp, err := h.forward(q0)
+		if err != nil {
+			response.Rcode = dns.RcodeServerFailure
+		} else {
+			response.Rcode = upstreamResp.Rcode
+			response.Answer = upstreamResp.Answer
+			response.Ns = upstreamResp.Ns
+		}
+	}
+	response.RecursionAvailable = true
+
+	// Write the response
+	rawResp, err := response.Pack()
+	if err != nil {
+		return
+	}
+	rw.Write(rawResp)
+}
+
+// lookupLocally looks up the question inside the overrides and the cache.
+func (h *ispResolverHandler) lookupLocally(q0 dns.Question) ([]dns.RR, bool) {
+	if h.overrides != nil {
+		if answer, found := h.overrides.Lookup(q0.Qtype, q0.Name); found {
+			return answer, true
+		}
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, found := h.cache[q0]
+	if !found || h.clock.Now().After(entry.expires) {
+		delete(h.cache, q0)
+		return nil, false
+	}
+	return entry.answer, true
+}
+
+// forward resolves the question using the upstreams or, if configured,
+// iteratively starting from the root servers and caches the response.
+func (h *ispResolverHandler) forward(q0 dns.Question) (*dns.Msg, error) {
+	var (
+		resp *dns.Msg
+		err  error
+	)
+	switch {
+	case len(h.rootServers) > 0:
+		resp, err = h.iterate(q0, 0)
+	default:
+		resp, err = h.query(q0, h.upstreams)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.maybeCache(q0, resp)
+	return resp, nil
+}
+
+// query sends the question to the given servers in order until one
+// of them returns a response, retrying over TCP on truncation.
+func (h *ispResolverHandler) query(q0 dns.Question, servers []string) (*dns.Msg, error) {
+	err := errNoServers
+	for _, server := range servers {
+		var resp *dns.Msg
+		address := net.JoinHostPort(server, "53")
+		resp, err = h.exchange(q0, "udp", address)
+		if err == nil && resp.Truncated {
+			resp, err = h.exchange(q0, "tcp", address)
+		}
+		if err != nil {
+			continue
+		}
+		return resp, nil
+	}
+	return nil, err
+}
+
+// maxIterations is the maximum depth of iterative resolution.
+const maxIterations = 16
+
+// iterate resolves the question starting from the root servers, following
+// referrals and CNAMEs pointing outside the zone of the server answering.
+func (h *ispResolverHandler) iterate(q0 dns.Question, depth int) (*dns.Msg, error) {
+	servers := h.rootServers
+	for ; depth < maxIterations; depth++ {
+		resp, err := h.query(q0, servers)
+		if err != nil {
+			return nil, err
+		}
+
+		// Handle the case of a final response.
+		var ns []*dns.NS
+		for _, rr := range resp.Ns {
+			if rr, ok := rr.(*dns.NS); ok {
+				ns = append(ns, rr)
+			}
+		}
+		if len(resp.Answer) > 0 || resp.Rcode != dns.RcodeSuccess || len(ns) <= 0 {
+			return h.followCNAME(q0, resp, depth)
+		}
+
+		// Otherwise, follow the referral.
+		if servers, err = h.serversFor(ns, resp.Extra, depth); err != nil {
+			return nil, err
+		}
+	}
+	return nil, errTooManyIterations
+}
+
+// followCNAME resolves the target of the last CNAME in the answer when
+// the answer does not contain records of the queried type.
+func (h *ispResolverHandler) followCNAME(q0 dns.Question, resp *dns.Msg, depth int) (*dns.Msg, error) {
+	var target string
+	for _, rr := range resp.Answer {
+		if rr.Header().Rrtype == q0.Qtype {
+			return resp, nil
+		}
+		if rr, ok := rr.(*dns.CNAME); ok {
+			target = rr.Target
+		}
+	}
+	if target == "" {
+		return resp, nil
+	}
+	next := q0
+	next.Name = dns.CanonicalName(target)
+	tail, err := h.iterate(next, depth+1)
+	if err != nil {
+		return nil, err
+	}
+	resp.Answer = append(resp.Answer, tail.Answer...)
+	resp.Ns = tail.Ns
+	resp.Rcode = tail.Rcode
+	return resp, nil
+}
+
+// serversFor returns the addresses of the given name servers using the
+// glue records, if any, and otherwise resolving their addresses.
+func (h *ispResolverHandler) serversFor(ns []*dns.NS, extra []dns.RR, depth int) ([]string, error) {
+	var servers []string
+	for _, rr := range ns {
+		for _, glue := range extra {
+			if dns.CanonicalName(glue.Header().Name) != dns.CanonicalName(rr.Ns) {
+				continue
+			}
+			switch glue := glue.(type) {
+			case *dns.A:
+				servers = append(servers, glue.A.String())
+			case *dns.AAAA:
+				servers = append(servers, glue.AAAA.String())
+			}
+		}
+	}
+	for _, rr := range ns {
+		if len(servers) > 0 {
+			break
+		}
+		q0 := dns.Question{Name: dns.CanonicalName(rr.Ns), Qtype: dns.TypeA, Qclass: dns.ClassINET}
+		resp, err := h.iterate(q0, depth+1)
+		if err != nil {
+			continue
+		}
+		for _, addr := range resp.Answer {
+			if addr, ok := addr.(*dns.A); ok {
+				servers = append(servers, addr.A.String())
+			}
+		}
+	}
+	if len(servers) <= 0 {
+		return nil, errNoServers
+	}
+	return servers, nil
+}
+
+// exchange sends the question to the given upstream using the given
+// network (i.e., "udp" or "tcp") and reads the response.
+//
+// We set the deadline using the scenario clock rather than using the
+// [*dns.Client], which would compute the deadline using [time.Now].
+func (h *ispResolverHandler) exchange(q0 dns.Question, network, address string) (*dns.Msg, error) {
+	timeout := h.timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	conn, err := h.stack.DialContext(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(h.clock.Now().Add(timeout))
+	query := &dns.Msg{}
+	query.SetQuestion(q0.Name, q0.Qtype)
+	query.Question[0].Qclass = q0.Qclass
+	dnsConn := &dns.Conn{Conn: conn}
+	if err := dnsConn.WriteMsg(query); err != nil {
+		return nil, err
+	}
+	for {
+		resp, err := dnsConn.ReadMsg()
+		if err != nil {
+			return nil, err
+		}
+		if resp.Id == query.Id {
+			return resp, nil
+		}
+	}
+}
+
+// maybeCache caches the answer of a successful response.
+func (h *ispResolverHandler) maybeCache(q0 dns.Question, resp *dns.Msg) {
+	if resp.Rcode != dns.RcodeSuccess || len(resp.Answer) <= 0 {
+		return
+	}
+	ttl := resp.Answer[0].Header().Ttl
+	for _, rr := range resp.Answer {
+		ttl = min(ttl, rr.Header().Ttl)
+	}
+	if ttl <= 0 {
+		return // zero TTL records must not be cached
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cache[q0] = ispResolverCacheEntry{
+		answer:  resp.Answer,
+		expires: h.clock.Now().Add(time.Duration(ttl) * time.Second),
+	}
+}
@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package router
+
+import (
+	"net/netip"
+	"sync"
+
+	"github.com/rbmk-project/x/netsim/packet"
+)
+
+// PeerDevice is one end of a point-to-point connection between two
+// routers. Packets posted on the Input channel of an end are read
+// from the Output channel of the other end.
+//
+// Construct using [NewPeerDevices].
+type PeerDevice struct {
+	// eof is shared by both ends of the connection.
+	eof chan struct{}
+
+	// eofOnce ensures we close eof just once.
+	eofOnce *sync.Once
+
+	// input is the channel where we receive packets.
+	input chan *packet.Packet
+
+	// output is the channel where we emit packets.
+	output chan *packet.Packet
+}
+
+// NewPeerDevices creates the two connected ends of a point-to-point
+// connection between two routers. Use [*Router.AttachPeer] to attach
+// each end to a router (possibly extending it using a geolink).
+//
+// Closing either end closes the whole connection.
+func NewPeerDevices() (*PeerDevice, *PeerDevice) {
+	var (
+		eof     = make(chan struct{})
+		eofOnce = &sync.Once{}
+	)
+	left2right, right2left := packet.NewNetworkDeviceIOChannels()
+	left := &PeerDevice{
+		eof:     eof,
+		eofOnce: eofOnce,
+		input:   left2right,
+		output:  right2left,
+	}
+	right := &PeerDevice{
+		eof:     eof,
+		eofOnce: eofOnce,
+		input:   right2left,
+		output:  left2right,
+	}
+	return left, right
+}
+
+// Ensure [*PeerDevice] implements [packet.NetworkDevice].
+var _ packet.NetworkDevice = &PeerDevice{}
+
+// Addresses implements [packet.NetworkDevice].
+//
+// A peer device does not have any address.
+func (pd *PeerDevice) Addresses() []netip.Addr {
+	return nil
+}
+
+// EOF implements [packet.NetworkDevice].
+func (pd *PeerDevice) EOF() <-chan struct{} {
+	return pd.eof
+}
+
+// Input implements [packet.NetworkDevice].
+func (pd *PeerDevice) Input() chan<- *packet.Packet {
+	return pd.input
+}
+
+// Output implements [packet.NetworkDevice].
+func (pd *PeerDevice) Output() <-chan *packet.Packet {
+	return pd.output
+}
+
+// Close closes both ends of the connection.
+func (pd *PeerDevice) Close() error {
+	pd.eofOnce.Do(func() { close(pd.eof) })
+	return nil
+}
@@ -13,12 +13,19 @@ import (
 
 // Router provides routing capabilities.
 type Router struct {
+	// devices maps attached devices to the channel that
+	// stops the corresponding read loop when closed.
+	devices map[packet.NetworkDevice]chan struct{}
+
 	// filtermu protects access to filters.
 	filtermu sync.RWMutex
 
 	// filters contains pre-routing packet filters.
 	filters []packet.Filter
 
+	// srtmu protects access to srt and devices.
+	srtmu sync.RWMutex
+
 	// srt is the static routing table.
 	srt map[netip.Addr]packet.NetworkDevice
 }
@@ -26,8 +33,10 @@ type Router struct {
 // New creates a new [*Router].
 func New() *Router {
 	return &Router{
+		devices:  make(map[packet.NetworkDevice]chan struct{}),
 		filtermu: sync.RWMutex{},
 		filters:  make([]packet.Filter, 0),
+		srtmu:    sync.RWMutex{},
 		srt:      make(map[netip.Addr]packet.NetworkDevice),
 	}
 }
@@ -39,22 +48,99 @@ func (r *Router) AddFilter(pf packet.Filter) {
 	r.filtermu.Unlock()
 }
 
+// Filters returns a copy of the router's packet filters.
+func (r *Router) Filters() []packet.Filter {
+	r.filtermu.RLock()
+	defer r.filtermu.RUnlock()
+	filters := make([]packet.Filter, len(r.filters))
+	copy(filters, r.filters)
+	return filters
+}
+
 // Attach attaches a [packet.NetworkDevice] to the [*Router] reading
 // packets from the router and setting up routes for all the device
 // addresses to correctly forward packets back to the device.
 func (r *Router) Attach(dev packet.NetworkDevice) {
+	r.srtmu.Lock()
 	for _, addr := range dev.Addresses() {
 		r.srt[addr] = dev
 	}
-	go r.readLoop(dev)
+	stop := r.startLocked(dev)
+	r.srtmu.Unlock()
+	go r.readLoop(dev, stop)
+}
+
+// AttachPeer attaches a [packet.NetworkDevice] connecting this [*Router]
+// to another router (see [NewPeerDevices]). We read packets from the device
+// but we do not set up any route, since the addresses reachable through
+// the device depend on the topology. Use AddRoute to add routes.
+func (r *Router) AttachPeer(dev packet.NetworkDevice) {
+	r.srtmu.Lock()
+	stop := r.startLocked(dev)
+	r.srtmu.Unlock()
+	go r.readLoop(dev, stop)
+}
+
+// startLocked registers the device and returns the channel used to stop
+// reading from it. This method assumes the caller holds srtmu.
+func (r *Router) startLocked(dev packet.NetworkDevice) chan struct{} {
+	if stop, found := r.devices[dev]; found {
+		close(stop) // make sure we only have one read loop per device
+	}
+	stop := make(chan struct{})
+	r.devices[dev] = stop
+	return stop
+}
+
+// Detach undoes [*Router.Attach] or [*Router.AttachPeer]: it stops reading
+// packets from the device and removes all the routes using the device. It
+// does not close the device. Detaching a device that is not attached is a no-op.
+func (r *Router) Detach(dev packet.NetworkDevice) {
+	r.srtmu.Lock()
+	defer r.srtmu.Unlock()
+	for addr, nextHop := range r.srt {
+		if nextHop == dev {
+			delete(r.srt, addr)
+		}
+	}
+	if stop, found := r.devices[dev]; found {
+		close(stop)
+		delete(r.devices, dev)
+	}
 }
 
-// readLoop reads packets from a [packet.NetworkDevice] until EOF.
-func (r *Router) readLoop(dev packet.NetworkDevice) {
+// AddRoute adds or replaces the static route for the given
+// address, such that packets are forwarded to the given device.
+func (r *Router) AddRoute(addr netip.Addr, dev packet.NetworkDevice) {
+	r.srtmu.Lock()
+	r.srt[addr] = dev
+	r.srtmu.Unlock()
+}
+
+// RemoveRoute removes the static route for the given address, if any.
+func (r *Router) RemoveRoute(addr netip.Addr) {
+	r.srtmu.Lock()
+	delete(r.srt, addr)
+	r.srtmu.Unlock()
+}
+
+// readLoop reads packets from a [packet.NetworkDevice] until
+// EOF or until the stop channel is closed.
+func (r *Router) readLoop(dev packet.NetworkDevice, stop <-chan struct{}) {
 	for {
+		// give priority to stop, since select picks a random ready case
+		// and we must not steal packets from the device after Detach
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
 		select {
 		case <-dev.EOF():
 			return
+		case <-stop:
+			return
 		case pkt := <-dev.Output():
 			r.handle(pkt)
 		}
@@ -64,10 +150,7 @@ func (r *Router) readLoop(dev packet.NetworkDevice) {
 // handle handles a packet by applying filters and routing it.
 func (r *Router) handle(pkt *packet.Packet) error {
 	// Get a consistent view of filters
-	r.filtermu.RLock()
-	filters := make([]packet.Filter, len(r.filters))
-	copy(filters, r.filters)
-	r.filtermu.RUnlock()
+	filters := r.Filters()
 
 	// Apply filters
 	for _, pf := range filters {
@@ -91,6 +174,16 @@ func (r *Router) handle(pkt *packet.Packet) error {
 	return r.route(pkt)
 }
 
+// Ensure [*Router] implements [packet.Injector].
+var _ packet.Injector = &Router{}
+
+// Inject routes a packet without applying the filters, exactly like
+// the router does for packets returned by a [packet.Filter]. This allows
+// filters to inject packets asynchronously (e.g., after a delay).
+func (r *Router) Inject(pkt *packet.Packet) error {
+	return r.route(pkt)
+}
+
 var (
 	// errTTLExceeded is returned when a packet's TTL is exceeded.
 	errTTLExceeded = errors.New("TTL exceeded in transit")
@@ -111,7 +204,9 @@ func (r *Router) route(pkt *packet.Packet) error {
 	pkt.TTL--
 
 	// Find next hop.
+	r.srtmu.RLock()
 	nextHop := r.srt[pkt.DstAddr]
+	r.srtmu.RUnlock()
 	if nextHop == nil {
 		return errNoRouteToHost
 	}
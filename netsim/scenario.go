@@ -4,9 +4,16 @@ package netsim
 
 import (
 	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
 
 	"github.com/rbmk-project/common/closepool"
 	"github.com/rbmk-project/common/runtimex"
+	"github.com/rbmk-project/x/netsim/clock"
+	"github.com/rbmk-project/x/netsim/dns"
+	"github.com/rbmk-project/x/netsim/geolink"
 	"github.com/rbmk-project/x/netsim/packet"
 	"github.com/rbmk-project/x/netsim/router"
 	"github.com/rbmk-project/x/netsim/simpki"
@@ -20,10 +27,40 @@ import (
 // 1. Each stack is connected only to the central router;
 //
 // 2. The router forwards packets between stacks.
+//
+// It is also possible to create additional named routers, to connect
+// them with point-to-point links, and to attach stacks to specific routers,
+// thus modeling, e.g., client-ISP-transit-server paths. In such a case,
+// the scenario computes the routes using the shortest path between routers.
 type Scenario struct {
+	// attachments contains the devices attached to routers.
+	attachments []*attachment
+
+	// certs contains the certificates of the stacks.
+	certs map[*Stack]*stackCert
+
+	// clock is the clock driving the simulation.
+	clock clock.Clock
+
 	// dnsd is the [*DNSDatabase].
 	dnsd *dnsDatabase
 
+	// links contains the default links of the stacks
+	// created using a [*StackConfig] with link impairments.
+	links map[packet.NetworkDevice]*stackLink
+
+	// peerings contains the links between routers.
+	peerings []*peering
+
+	// observers contains the packet observers.
+	observers *observerBus
+
+	// partitions contains the active network partitions.
+	partitions *partitionFilter
+
+	// pools contains the pools used to allocate addresses.
+	pools []*addressPool
+
 	// pki is the [*PKI] database.
 	pki *simpki.PKI
 
@@ -32,18 +69,149 @@ type Scenario struct {
 
 	// router is the star-topology router.
 	router *router.Router
+
+	// routers contains all the routers indexed by name.
+	routers map[string]*router.Router
+
+	// stackAddrs contains the addresses of the stacks.
+	stackAddrs map[netip.Addr]bool
+
+	// usedAddrs contains the addresses allocated or used by stacks.
+	usedAddrs map[netip.Addr]bool
+}
+
+// attachment is a device attached to a router.
+type attachment struct {
+	// closer is the optional [io.Closer] stopping the [geolink]
+	// created by [*Scenario.AttachWithLink] on detach.
+	closer io.Closer
+
+	// dev is the device attached to the router.
+	dev packet.NetworkDevice
+
+	// link is the optional [geolink] configuration.
+	link *geolink.Config
+
+	// owner is the device passed by the user, which differs
+	// from dev when we interpose a [geolink].
+	owner packet.NetworkDevice
+
+	// router is the name of the router.
+	router string
+}
+
+// stackLink is the default link of a stack.
+type stackLink struct {
+	// config is the [geolink] configuration.
+	config *geolink.Config
+
+	// dev is the device wrapping the stack.
+	dev packet.NetworkDevice
+}
+
+// peering is a link between two routers.
+type peering struct {
+	// left is the name of the left router.
+	left string
+
+	// leftDev is the device attached to the left router.
+	leftDev packet.NetworkDevice
+
+	// link is the optional [geolink] configuration.
+	link *geolink.Config
+
+	// right is the name of the right router.
+	right string
+
+	// rightDev is the device attached to the right router.
+	rightDev packet.NetworkDevice
+}
+
+// CentralRouterName is the name of the central router created by [NewScenario].
+const CentralRouterName = "central"
+
+// ScenarioConfig contains optional settings for [NewScenarioWithConfig].
+//
+// The zero value is ready to use.
+type ScenarioConfig struct {
+	// Clock is the optional clock driving the simulation. If nil, we use
+	// [clock.Real]. The scenario propagates this clock to the stacks and the
+	// geolinks it creates, as well as to the stacks it creates internally
+	// (e.g., the ISP resolver cache), such that a single [*clock.Fake]
+	// drives the whole simulation deterministically. Use [*Scenario.Clock]
+	// to pass the same clock to filters (e.g., [censor.DNSPoisonerConfig],
+	// [*censor.Blackholer.SetClock], and [nat.CGNATConfig]). The scenarios
+	// created by [LoadScenarioWithConfig] do this automatically.
+	//
+	// When using a [*clock.Fake], the deadlines passed to connections
+	// must be computed using the same clock. Code computing deadlines
+	// using [time.Now] (e.g., [net/http]) is thus not suitable.
+	Clock clock.Clock
 }
 
 // NewScenario creates a new network simulation scenario.
 //
 // The cacheDir caches simulated-PKI-related data.
 func NewScenario(cacheDir string) *Scenario {
-	return &Scenario{
-		dnsd:   newDNSDatabase(),
-		pki:    simpki.MustNew(cacheDir),
-		pool:   &closepool.Pool{},
-		router: router.New(),
+	return NewScenarioWithConfig(cacheDir, &ScenarioConfig{})
+}
+
+// NewScenarioWithConfig is like [NewScenario] but uses the given config.
+//
+// This function panics if it cannot load or create the PKI data in
+// the cacheDir. Use [OpenScenario] to handle this error.
+func NewScenarioWithConfig(cacheDir string, config *ScenarioConfig) *Scenario {
+	return runtimex.Try1(OpenScenario(cacheDir, config))
+}
+
+// OpenScenario is like [NewScenarioWithConfig] but returns an error, rather
+// than panicking, when it cannot load or create the PKI data in the cacheDir.
+func OpenScenario(cacheDir string, config *ScenarioConfig) (*Scenario, error) {
+	pki, err := simpki.New(cacheDir)
+	if err != nil {
+		return nil, err
+	}
+	clk := config.Clock
+	if clk == nil {
+		clk = clock.Real()
+	}
+	observers := &observerBus{}
+	partitions := newPartitionFilter()
+	central := router.New()
+	central.AddFilter(observers)
+	central.AddFilter(partitions)
+	scenario := &Scenario{
+		certs:      make(map[*Stack]*stackCert),
+		clock:      clk,
+		dnsd:       newDNSDatabase(),
+		links:      make(map[packet.NetworkDevice]*stackLink),
+		observers:  observers,
+		partitions: partitions,
+		pools:      newAddressPools(DefaultAddressPools...),
+		pki:        pki,
+		pool:       &closepool.Pool{},
+		router:     central,
+		routers:    map[string]*router.Router{CentralRouterName: central},
+		stackAddrs: make(map[netip.Addr]bool),
+		usedAddrs:  make(map[netip.Addr]bool),
 	}
+	return scenario, nil
+}
+
+// Clock returns the clock driving the simulation.
+func (s *Scenario) Clock() clock.Clock {
+	return s.clock
+}
+
+// withClock returns a copy of the given [geolink] config using the
+// scenario clock, unless the config already specifies a clock.
+func (s *Scenario) withClock(config *geolink.Config) *geolink.Config {
+	if config == nil || config.Clock != nil {
+		return config
+	}
+	out := *config
+	out.Clock = s.clock
+	return &out
 }
 
 // Router returns the [*router.Router] for the scenario.
@@ -51,53 +219,246 @@ func (s *Scenario) Router() *router.Router {
 	return s.router
 }
 
+// RouterByName returns the [*router.Router] with the given
+// name or nil if there is no router with such a name.
+func (s *Scenario) RouterByName(name string) *router.Router {
+	return s.routers[name]
+}
+
+// MustNewRouter is like [*Scenario.NewRouter] but panics on error.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) MustNewRouter(name string) *router.Router {
+	return runtimex.Try1(s.NewRouter(name))
+}
+
+var (
+	// errRouterExists indicates that a router with the same name already exists.
+	errRouterExists = errors.New("router already exists")
+
+	// errNoSuchRouter indicates that there is no router with the given name.
+	errNoSuchRouter = errors.New("no such router")
+)
+
+// NewRouter creates a new [*router.Router] with the given name.
+//
+// This method fails if a router with the same name already exists.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewRouter(name string) (*router.Router, error) {
+	if _, found := s.routers[name]; found {
+		return nil, errRouterExists
+	}
+	r := router.New()
+	r.AddFilter(s.partitions)
+	s.routers[name] = r
+	return r, nil
+}
+
+// MustConnectRouters is like [*Scenario.ConnectRouters] but panics on error.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) MustConnectRouters(left, right string, config *geolink.Config) {
+	runtimex.Try0(s.ConnectRouters(left, right, config))
+}
+
+// ConnectRouters connects the two routers with the given names using
+// a point-to-point link. If config is not nil, we interpose a [geolink]
+// configured accordingly between the two routers.
+//
+// This method fails if either router does not exist.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) ConnectRouters(left, right string, config *geolink.Config) error {
+	leftRouter, rightRouter := s.routers[left], s.routers[right]
+	if leftRouter == nil || rightRouter == nil {
+		return errNoSuchRouter
+	}
+	leftDev, rightPeer := router.NewPeerDevices()
+	s.pool.Add(leftDev)
+	var rightDev packet.NetworkDevice = rightPeer
+	if config != nil {
+		rightDev = geolink.Extend(rightPeer, s.withClock(config))
+	}
+	leftRouter.AttachPeer(leftDev)
+	rightRouter.AttachPeer(rightDev)
+	s.peerings = append(s.peerings, &peering{
+		left:     left,
+		leftDev:  leftDev,
+		link:     config,
+		right:    right,
+		rightDev: rightDev,
+	})
+	s.updateRoutes()
+	return nil
+}
+
 // DNSHandler returns the [DNSHandler] for the scenario. The returned
 // handler will serve queries based on the scenario's DNS database.
 func (s *Scenario) DNSHandler() DNSHandler {
 	return s.dnsd
 }
 
+// DNSDatabase returns the scenario's DNS database, which allows
+// to add records other than the A/AAAA records registered when
+// creating stacks (e.g., TXT and PTR records).
+//
+// The database is goroutine safe, so it is possible to modify
+// records while stacks are answering queries.
+func (s *Scenario) DNSDatabase() *dns.Database {
+	return s.dnsd
+}
+
+// PKI returns the scenario's simulated PKI, which allows to create
+// certificates to use with [StackConfig] TLSCertificate.
+func (s *Scenario) PKI() *simpki.PKI {
+	return s.pki
+}
+
 // RootCAs returns the [*x509.CertPool] that clients should use.
 func (s *Scenario) RootCAs() *x509.CertPool {
 	return s.pki.CertPool()
 }
 
-// MustNewStack creates a new network stack using the given configuration.
-//
-// This method panics on error.
+// MustNewStack is like [*Scenario.NewStack] but panics on error.
 //
 // This method IS NOT goroutine safe.
 func (s *Scenario) MustNewStack(config *StackConfig) *Stack {
+	return runtimex.Try1(s.NewStack(config))
+}
+
+// NewStack creates a new network stack using the given configuration.
+//
+// This method fails if another stack of the scenario already uses
+// any of the addresses, since the routers could not tell them apart.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewStack(config *StackConfig) (*Stack, error) {
 	// Initialize and configure the stack.
-	runtimex.Try0(config.validate())
-	stack := runtimex.Try1(s.newBaseStack(config))
-	runtimex.Try0(config.setupClientResolvers(stack))
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	stack, err := s.newBaseStack(config)
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range stack.Addresses() {
+		if s.stackAddrs[addr] {
+			stack.Close()
+			return nil, fmt.Errorf("%w: %s", errAddressInUse, addr)
+		}
+	}
+	servers := &closepool.Pool{}
+	cert, err := s.setupStack(stack, config, servers)
+	if err != nil {
+		servers.Close()
+		stack.Close()
+		return nil, err
+	}
+
+	// Only register the stack and its servers on success.
+	if cert != nil {
+		s.certs[stack] = cert
+	}
 	s.dnsd.AddAddresses(config.DomainNames, config.Addresses)
-	cert, hasCert := s.mustSetupPKI(config)
+	s.markAddressesUsed(config.Addresses)
+	for _, addr := range stack.Addresses() {
+		s.stackAddrs[addr] = true
+	}
+	s.pool.Add(servers)
+	s.pool.Add(stack)
+
+	// Create the default link once, such that there is a single pair
+	// of goroutines forwarding packets even if we attach several times.
+	if link := config.linkConfig(); link != nil {
+		s.links[stack] = &stackLink{
+			config: link,
+			dev:    geolink.Extend(stack, s.withClock(link)),
+		}
+	}
+	return stack, nil
+}
+
+// errAddressInUse indicates that another stack already uses the address.
+var errAddressInUse = errors.New("address already used by another stack")
+
+// errNoCertificate indicates that a TLS handler has been
+// configured but the stack has no certificate.
+var errNoCertificate = errors.New("no TLS certificate available")
+
+// setupStack configures the client resolvers and the handlers of a freshly
+// created stack, adding the servers it starts to the given pool, and returns
+// the stack certificate, or nil if the stack has no certificate.
+func (s *Scenario) setupStack(stack *Stack, config *StackConfig, servers *closepool.Pool) (*stackCert, error) {
+	if err := config.setupClientResolvers(stack); err != nil {
+		return nil, err
+	}
+	cert, err := s.setupPKI(config)
+	if err != nil {
+		return nil, err
+	}
+	hasCert := cert != nil
 
 	// Start DNS handlers.
 	if config.DNSOverUDPHandler != nil {
-		s.mustSetupDNSOverUDP(stack, config)
+		if err := s.setupDNSOverUDP(stack, config, servers); err != nil {
+			return nil, err
+		}
 	}
 	if config.DNSOverTCPHandler != nil {
-		s.mustSetupDNSOverTCP(stack, config)
+		if err := s.setupDNSOverTCP(stack, config, servers); err != nil {
+			return nil, err
+		}
 	}
 	if config.DNSOverTLSHandler != nil {
-		runtimex.Assert(hasCert, "no TLS certificate available")
-		s.mustSetupDNSOverTLS(stack, config, cert)
+		if !hasCert {
+			return nil, errNoCertificate
+		}
+		if err := s.setupDNSOverTLS(stack, config, cert, servers); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.DNSOverQUICHandler != nil {
+		if !hasCert {
+			return nil, errNoCertificate
+		}
+		if err := s.setupDNSOverQUIC(stack, config, cert, servers); err != nil {
+			return nil, err
+		}
 	}
 
 	// Start HTTP handlers.
 	if config.HTTPHandler != nil {
-		s.mustSetupHTTPOverTCP(stack, config)
+		if err := s.setupHTTPOverTCP(stack, config); err != nil {
+			return nil, err
+		}
 	}
-	if config.HTTPSHandler != nil {
-		runtimex.Assert(hasCert, "no TLS certificate available")
-		s.mustSetupHTTPOverTLS(stack, config, cert)
+	if config.HTTPSHandler != nil || config.DNSOverHTTPSHandler != nil {
+		if !hasCert {
+			return nil, errNoCertificate
+		}
+		if err := s.setupHTTPOverTLS(stack, config, cert); err != nil {
+			return nil, err
+		}
+	}
+	if config.HTTP3Handler != nil {
+		if !hasCert {
+			return nil, errNoCertificate
+		}
+		if err := s.setupHTTPOverQUIC(stack, config, cert, servers); err != nil {
+			return nil, err
+		}
 	}
 
-	s.pool.Add(stack)
-	return stack
+	// Start generic handlers.
+	if err := s.setupTCPHandlers(stack, config, servers); err != nil {
+		return nil, err
+	}
+	if err := s.setupUDPHandlers(stack, config, servers); err != nil {
+		return nil, err
+	}
+	return cert, nil
 }
 
 // Close releases all resources associated with the scenario.
@@ -115,5 +476,175 @@ func (s *Scenario) Close() error {
 //
 // All network traffic to/from this device will flow through the router.
 func (s *Scenario) Attach(dev packet.NetworkDevice) {
-	s.router.Attach(dev)
+	runtimex.Try0(s.AttachTo(CentralRouterName, dev))
+}
+
+// AttachWithLink is like [*Scenario.Attach] but interposes a [geolink]
+// configured according to config (e.g., delay, packet loss rate, and
+// bandwidth) between the device and the central router. For stacks
+// created using a [*StackConfig] with link impairments (e.g., LinkDelay),
+// this link is chained with the stack default link.
+//
+// If the device is already attached, we detach it first, such that a
+// single link reads the packets sent by the device. The link goroutines
+// terminate when calling [*Scenario.Detach] or when the device is closed,
+// which, for stacks created using the scenario, happens when calling
+// [*Scenario.Close].
+func (s *Scenario) AttachWithLink(dev packet.NetworkDevice, config *geolink.Config) {
+	s.Detach(dev)
+	inner := dev
+	if link := s.links[dev]; link != nil {
+		inner = link.dev // chain with the stack default link
+	}
+	linkDev := geolink.Extend(inner, s.withClock(config))
+	runtimex.Try0(s.attachTo(CentralRouterName, dev, linkDev, config))
+	s.attachments[len(s.attachments)-1].closer = linkDev.(io.Closer)
+}
+
+// MustAttachTo is like [*Scenario.AttachTo] but panics on error.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) MustAttachTo(routerName string, dev packet.NetworkDevice) {
+	runtimex.Try0(s.AttachTo(routerName, dev))
+}
+
+// AttachTo is like [*Scenario.Attach] but attaches the device to
+// the router with the given name and updates the routes of all the other
+// routers such that they can forward packets to the device.
+//
+// For stacks created using a [*StackConfig] with link impairments (e.g.,
+// LinkDelay), we interpose the stack default link.
+//
+// This method fails if the router does not exist.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) AttachTo(routerName string, dev packet.NetworkDevice) error {
+	if link := s.links[dev]; link != nil {
+		return s.attachTo(routerName, dev, link.dev, link.config)
+	}
+	return s.attachTo(routerName, dev, dev, nil)
+}
+
+// attachTo attaches dev, which is either owner or a device wrapping owner
+// using the given link config, to the router with the given name.
+func (s *Scenario) attachTo(routerName string, owner, dev packet.NetworkDevice, link *geolink.Config) error {
+	r := s.routers[routerName]
+	if r == nil {
+		return errNoSuchRouter
+	}
+	r.Attach(dev)
+	s.attachments = append(s.attachments, &attachment{
+		dev:    dev,
+		link:   link,
+		owner:  owner,
+		router: routerName,
+	})
+	s.updateRoutes()
+	return nil
+}
+
+// Detach disconnects a device previously attached using [*Scenario.Attach],
+// [*Scenario.AttachWithLink] or [*Scenario.MustAttachTo] from the topology,
+// thus simulating an outage. Packets sent by the device are not routed
+// anymore and packets sent to the device are dropped for lack of routes.
+//
+// Detach does not close the device, which can be attached again later,
+// but closes the [geolink] created by [*Scenario.AttachWithLink], dropping
+// the packets in flight, such that no one reads the packets sent by the
+// device until we attach it again. The stack default links, instead,
+// survive and are reused when attaching again. Detaching a device that
+// is not attached is a no-op.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) Detach(dev packet.NetworkDevice) {
+	var attachments []*attachment
+	for _, att := range s.attachments {
+		if att.owner != dev && att.dev != dev {
+			attachments = append(attachments, att)
+			continue
+		}
+		s.routers[att.router].Detach(att.dev)
+		if att.closer != nil {
+			att.closer.Close()
+		}
+		for _, r := range s.routers {
+			for _, addr := range att.dev.Addresses() {
+				r.RemoveRoute(addr)
+			}
+		}
+	}
+	s.attachments = attachments
+	s.updateRoutes()
+}
+
+// Partition splits the topology such that the devices in groupA cannot
+// exchange packets with the devices in groupB, while all the other traffic
+// continues to flow. Use [*Scenario.Heal] to remove all the partitions.
+//
+// This method is goroutine safe.
+func (s *Scenario) Partition(groupA, groupB []packet.NetworkDevice) {
+	s.partitions.add(groupA, groupB)
+}
+
+// Heal removes all the partitions created using [*Scenario.Partition].
+//
+// This method is goroutine safe.
+func (s *Scenario) Heal() {
+	s.partitions.clear()
+}
+
+// updateRoutes updates the routes of each router such that packets
+// for devices attached to other routers are forwarded to the first
+// hop of the shortest path towards the router owning the device.
+func (s *Scenario) updateRoutes() {
+	for name, r := range s.routers {
+		nextHops := s.nextHops(name)
+		for _, att := range s.attachments {
+			dev, found := nextHops[att.router]
+			if !found {
+				continue // either the router itself or unreachable
+			}
+			for _, addr := range att.dev.Addresses() {
+				r.AddRoute(addr, dev)
+			}
+		}
+	}
+}
+
+// nextHops performs a breadth-first visit of the routers graph starting
+// from the given router and returns, for each reachable router, the
+// device the source router should use to forward packets to it.
+func (s *Scenario) nextHops(source string) map[string]packet.NetworkDevice {
+	nextHops := map[string]packet.NetworkDevice{}
+	visited := map[string]bool{source: true}
+	queue := []string{source}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, p := range s.peerings {
+			var (
+				neighbor string
+				dev      packet.NetworkDevice
+			)
+			switch current {
+			case p.left:
+				neighbor, dev = p.right, p.leftDev
+			case p.right:
+				neighbor, dev = p.left, p.rightDev
+			default:
+				continue
+			}
+			if visited[neighbor] {
+				continue
+			}
+			visited[neighbor] = true
+			if current == source {
+				nextHops[neighbor] = dev
+			} else {
+				nextHops[neighbor] = nextHops[current]
+			}
+			queue = append(queue, neighbor)
+		}
+	}
+	return nextHops
 }
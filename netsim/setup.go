@@ -6,12 +6,19 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"maps"
 	"net"
 	"net/http"
 	"net/netip"
+	"slices"
+	"strconv"
+	"time"
 
-	"github.com/rbmk-project/common/runtimex"
-	"github.com/rbmk-project/dnscore/dnscoretest"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/rbmk-project/common/closepool"
+	"github.com/rbmk-project/x/netsim/dns"
+	"github.com/rbmk-project/x/netsim/geolink"
 	"github.com/rbmk-project/x/netsim/simpki"
 )
 
@@ -22,6 +29,32 @@ type StackConfig struct {
 	// The config is invalid if there is not at least one address.
 	Addresses []string
 
+	// CertCRLDistributionPoint optionally sets the URL of the CRL that
+	// the stack certificate advertises (see [simpki.Config]). Use, e.g.,
+	// [CRLDistributionPointURL] along with [*Scenario.MustNewCRLStack].
+	CertCRLDistributionPoint string
+
+	// CertKeyAlgorithm optionally sets the algorithm of the key of the
+	// stack certificate (see [simpki.Config]). If empty, we use ECDSA
+	// with the P-256 curve.
+	CertKeyAlgorithm simpki.KeyAlgorithm
+
+	// CertNotAfter optionally sets the end of the validity period of the
+	// stack certificate. Setting it in the past creates an expired certificate
+	// (see [simpki.Config]). If zero, the certificate is valid for one year.
+	CertNotAfter time.Time
+
+	// CertNotBefore optionally sets the start of the validity period of the
+	// stack certificate. Setting it in the future creates a not-yet-valid
+	// certificate (see [simpki.Config]). If zero, the certificate is
+	// valid starting from its creation.
+	CertNotBefore time.Time
+
+	// CertOCSPServer optionally sets the URL of the OCSP responder that
+	// the stack certificate advertises (see [simpki.Config]). Use, e.g.,
+	// [OCSPResponderURL] along with [*Scenario.MustNewOCSPResponderStack].
+	CertOCSPServer string
+
 	// ClientResolvers optionally specifies resolvers for client stacks.
 	ClientResolvers []string
 
@@ -34,6 +67,15 @@ type StackConfig struct {
 	// DNSOverTLSHandler optionally specifies a handler for DNS-over-TLS.
 	DNSOverTLSHandler DNSHandler
 
+	// DNSOverQUICHandler optionally specifies a handler for DNS-over-QUIC,
+	// which we serve on port 853/udp using the stack certificate.
+	DNSOverQUICHandler DNSHandler
+
+	// DNSOverHTTPSHandler optionally specifies a handler for DNS-over-HTTPS,
+	// which we serve at /dns-query on port 443/tcp. The HTTPSHandler, if
+	// any, continues to handle all the other paths.
+	DNSOverHTTPSHandler DNSHandler
+
 	// DomainNames contains the optional domain names associated with this stack.
 	//
 	// If there are associated domain names, we will configure the DNS and
@@ -45,6 +87,43 @@ type StackConfig struct {
 
 	// HTTPSHandler optionally specifies a handle to use on port 443/tcp.
 	HTTPSHandler http.Handler
+
+	// HTTP3Handler optionally specifies a handle to use on port 443/udp
+	// using HTTP/3. Using the same handler as HTTPSHandler allows to compare
+	// HTTP/2-over-TCP and HTTP/3-over-QUIC reachability.
+	HTTP3Handler http.Handler
+
+	// LinkBandwidth optionally specifies the bandwidth in bits per second
+	// of the link connecting the stack to the router. See LinkDelay.
+	LinkBandwidth int64
+
+	// LinkDelay optionally specifies the propagation delay of the link
+	// connecting the stack to the router. When this field, LinkBandwidth,
+	// or LinkLoss are set, we interpose a [geolink] configured accordingly
+	// whenever the stack is attached (e.g., using [*Scenario.Attach]).
+	LinkDelay time.Duration
+
+	// LinkLoss optionally specifies the packet loss rate, between zero
+	// and one, of the link connecting the stack to the router. See LinkDelay.
+	LinkLoss float64
+
+	// TCPHandlers optionally maps TCP ports to functions handling each
+	// accepted connection in a background goroutine, which allows to host
+	// arbitrary services (e.g., an SMTP banner) on any port. The function
+	// owns the connection and is responsible for closing it.
+	TCPHandlers map[uint16]func(conn net.Conn)
+
+	// TLSCertificate optionally specifies the certificate that the stack
+	// presents when serving TLS, overriding the certificate the scenario
+	// PKI would otherwise generate for the DomainNames. Use it along with
+	// the helpers of [*simpki.PKI] (see [*Scenario.PKI]) to simulate
+	// certificate errors (e.g., [*simpki.PKI.MustNewSelfSignedCert]).
+	TLSCertificate *tls.Certificate
+
+	// UDPHandlers optionally maps UDP ports to functions handling the
+	// listening [net.PacketConn] in a background goroutine. The scenario
+	// closes the [net.PacketConn] when it is closed.
+	UDPHandlers map[uint16]func(pconn net.PacketConn)
 }
 
 // validate returns an error if the configuration is not valid.
@@ -55,6 +134,19 @@ func (cfg *StackConfig) validate() error {
 	return nil
 }
 
+// linkConfig returns the [*geolink.Config] for the link connecting
+// the stack to the router or nil if there are no link impairments.
+func (cfg *StackConfig) linkConfig() *geolink.Config {
+	if cfg.LinkBandwidth == 0 && cfg.LinkDelay == 0 && cfg.LinkLoss == 0 {
+		return nil
+	}
+	return &geolink.Config{
+		Bandwidth: cfg.LinkBandwidth,
+		Delay:     cfg.LinkDelay,
+		PLR:       cfg.LinkLoss,
+	}
+}
+
 // newBaseStack returns the base stack given a [*StackConfig].
 func (s *Scenario) newBaseStack(cfg *StackConfig) (*Stack, error) {
 	addrs := make([]netip.Addr, len(cfg.Addresses))
@@ -66,6 +158,7 @@ func (s *Scenario) newBaseStack(cfg *StackConfig) (*Stack, error) {
 		addrs[idx] = pa
 	}
 	stack := NewStack(addrs...)
+	stack.SetClock(s.clock)
 	return stack, nil
 }
 
@@ -83,80 +176,202 @@ func (cfg *StackConfig) setupClientResolvers(stack *Stack) error {
 	return nil
 }
 
-// mustSetupPKI sets up the PKI database for the stack, if possible.
-//
-// This method panics on error.
-func (s *Scenario) mustSetupPKI(cfg *StackConfig) (tls.Certificate, bool) {
+// setupPKI sets up the PKI database for the stack, if possible, and
+// returns the stack certificate, or nil if the stack has no certificate.
+func (s *Scenario) setupPKI(cfg *StackConfig) (*stackCert, error) {
+	if cfg.TLSCertificate != nil {
+		return &stackCert{cert: *cfg.TLSCertificate}, nil
+	}
 	if len(cfg.DomainNames) <= 0 {
-		return tls.Certificate{}, false
+		return nil, nil
 	}
 	var ipAddr []net.IP
 	for _, addr := range cfg.Addresses {
-		ipAddr = append(ipAddr, netip.MustParseAddr(addr).AsSlice())
+		pa, err := netip.ParseAddr(addr)
+		if err != nil {
+			return nil, err
+		}
+		ipAddr = append(ipAddr, pa.AsSlice())
+	}
+	config := &simpki.Config{
+		CRLDistributionPoint: cfg.CertCRLDistributionPoint,
+		CommonName:           cfg.DomainNames[0],
+		DNSNames:             cfg.DomainNames,
+		IPAddrs:              ipAddr,
+		KeyAlgorithm:         cfg.CertKeyAlgorithm,
+		NotAfter:             cfg.CertNotAfter,
+		NotBefore:            cfg.CertNotBefore,
+		OCSPServer:           cfg.CertOCSPServer,
 	}
-	cert := s.pki.MustNewCert(&simpki.Config{
-		CommonName: cfg.DomainNames[0],
-		DNSNames:   cfg.DomainNames,
-		IPAddrs:    ipAddr,
-	})
-	return cert, true
+	cert, err := s.pki.NewCert(config)
+	if err != nil {
+		return nil, err
+	}
+	return &stackCert{cert: cert, config: config}, nil
 }
 
-// mustSetupDNSOverUDP configures the DNS-over-UDP handler for the stack.
-func (s *Scenario) mustSetupDNSOverUDP(stack *Stack, cfg *StackConfig) {
-	server := &dnscoretest.Server{
-		ListenPacket: func(network, address string) (net.PacketConn, error) {
-			return stack.ListenPacket(context.Background(), network, "[::]:53")
-		},
+// setupDNSOverUDP configures the DNS-over-UDP handler for the stack.
+func (s *Scenario) setupDNSOverUDP(stack *Stack, cfg *StackConfig, servers *closepool.Pool) error {
+	pconn, err := stack.ListenPacket(context.Background(), "udp", "[::]:53")
+	if err != nil {
+		return err
+	}
+	server := &dnsOverUDPServer{
+		handler: dns.NewUDPHandler(cfg.DNSOverUDPHandler),
+		pconn:   pconn,
 	}
-	<-server.StartUDP(cfg.DNSOverUDPHandler)
-	s.pool.Add(server)
+	go server.serve()
+	servers.Add(server)
+	return nil
 }
 
-// mustSetupDNSOverTCP configures the DNS-over-TCP handler for the stack.
-func (s *Scenario) mustSetupDNSOverTCP(stack *Stack, cfg *StackConfig) {
-	server := &dnscoretest.Server{
-		Listen: func(network, address string) (net.Listener, error) {
-			return stack.Listen(context.Background(), network, "[::]:53")
-		},
+// setupDNSOverTCP configures the DNS-over-TCP handler for the stack.
+func (s *Scenario) setupDNSOverTCP(stack *Stack, cfg *StackConfig, servers *closepool.Pool) error {
+	listener, err := stack.Listen(context.Background(), "tcp", "[::]:53")
+	if err != nil {
+		return err
 	}
-	<-server.StartTCP(cfg.DNSOverTCPHandler)
-	s.pool.Add(server)
-}
-
-// mustSetupDNSOverTLS configures the DNS-over-TLS handler for the stack.
-func (s *Scenario) mustSetupDNSOverTLS(stack *Stack, cfg *StackConfig, cert tls.Certificate) {
-	server := &dnscoretest.Server{
-		ListenTLS: func(network, address string, config *tls.Config) (net.Listener, error) {
-			listener, err := stack.Listen(context.Background(), network, "[::]:853")
-			if err != nil {
-				return nil, err
-			}
-			config = config.Clone()
-			config.Certificates = []tls.Certificate{cert}
-			listener = tls.NewListener(listener, config)
-			return listener, nil
-		},
+	server := &dnsOverStreamServer{
+		handler:  cfg.DNSOverTCPHandler,
+		listener: listener,
 	}
-	<-server.StartTLS(cfg.DNSOverTCPHandler)
-	s.pool.Add(server)
+	go server.serve()
+	servers.Add(server)
+	return nil
 }
 
-// mustSetupHTTPOverTCP configures the HTTP-over-TCP handler for the stack.
-func (s *Scenario) mustSetupHTTPOverTCP(stack *Stack, cfg *StackConfig) {
-	listener := runtimex.Try1(stack.Listen(context.Background(), "tcp", "[::]:80"))
+// setupDNSOverTLS configures the DNS-over-TLS handler for the stack.
+func (s *Scenario) setupDNSOverTLS(stack *Stack, cfg *StackConfig, cert *stackCert, servers *closepool.Pool) error {
+	listener, err := stack.Listen(context.Background(), "tcp", "[::]:853")
+	if err != nil {
+		return err
+	}
+	server := &dnsOverStreamServer{
+		handler: cfg.DNSOverTLSHandler,
+		listener: tls.NewListener(listener, &tls.Config{
+			GetCertificate: cert.get,
+		}),
+	}
+	go server.serve()
+	servers.Add(server)
+	return nil
+}
+
+// setupDNSOverQUIC configures the DNS-over-QUIC handler for the stack.
+func (s *Scenario) setupDNSOverQUIC(stack *Stack, cfg *StackConfig, cert *stackCert, servers *closepool.Pool) error {
+	pconn, err := stack.ListenPacket(context.Background(), "udp", "[::]:853")
+	if err != nil {
+		return err
+	}
+	tlsConfig := &tls.Config{
+		GetCertificate: cert.get,
+		NextProtos:     []string{"doq"},
+	}
+	listener, err := quic.Listen(pconn, tlsConfig, &quic.Config{})
+	if err != nil {
+		pconn.Close()
+		return err
+	}
+	server := &dnsOverQUICServer{
+		handler:  cfg.DNSOverQUICHandler,
+		listener: listener,
+		pconn:    pconn,
+	}
+	go server.serve()
+	servers.Add(server)
+	return nil
+}
+
+// setupHTTPOverTCP configures the HTTP-over-TCP handler for the stack.
+func (s *Scenario) setupHTTPOverTCP(stack *Stack, cfg *StackConfig) error {
+	listener, err := stack.Listen(context.Background(), "tcp", "[::]:80")
+	if err != nil {
+		return err
+	}
 	srv := &http.Server{Handler: cfg.HTTPHandler}
 	go srv.Serve(listener)
+	return nil
 }
 
-// mustSetupHTTPOverTLS configures the HTTP-over-TLS handler for the stack.
-func (s *Scenario) mustSetupHTTPOverTLS(stack *Stack, cfg *StackConfig, cert tls.Certificate) {
-	listener := runtimex.Try1(stack.Listen(context.Background(), "tcp", "[::]:443"))
+// setupHTTPOverTLS configures the HTTP-over-TLS handler for the stack,
+// including the DNS-over-HTTPS handler, if configured.
+func (s *Scenario) setupHTTPOverTLS(stack *Stack, cfg *StackConfig, cert *stackCert) error {
+	listener, err := stack.Listen(context.Background(), "tcp", "[::]:443")
+	if err != nil {
+		return err
+	}
+	handler := cfg.HTTPSHandler
+	if cfg.DNSOverHTTPSHandler != nil {
+		mux := http.NewServeMux()
+		if handler != nil {
+			mux.Handle("/", handler)
+		}
+		mux.Handle("/dns-query", newDNSOverHTTPSHandler(cfg.DNSOverHTTPSHandler))
+		handler = mux
+	}
 	srv := &http.Server{
-		Handler: cfg.HTTPSHandler,
+		Handler: handler,
 		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{cert},
+			GetCertificate: cert.get,
 		},
 	}
 	go srv.ServeTLS(listener, "", "")
+	return nil
+}
+
+// setupHTTPOverQUIC configures the HTTP/3 handler for the stack.
+func (s *Scenario) setupHTTPOverQUIC(stack *Stack, cfg *StackConfig, cert *stackCert, servers *closepool.Pool) error {
+	pconn, err := stack.ListenPacket(context.Background(), "udp", "[::]:443")
+	if err != nil {
+		return err
+	}
+	srv := &http3.Server{
+		Handler: cfg.HTTP3Handler,
+		TLSConfig: &tls.Config{
+			GetCertificate: cert.get,
+		},
+	}
+	go srv.Serve(pconn)
+	servers.Add(srv)
+	servers.Add(pconn)
+	return nil
+}
+
+// setupTCPHandlers configures the generic TCP handlers for the stack.
+func (s *Scenario) setupTCPHandlers(stack *Stack, cfg *StackConfig, servers *closepool.Pool) error {
+	for _, port := range slices.Sorted(maps.Keys(cfg.TCPHandlers)) {
+		address := net.JoinHostPort("::", strconv.Itoa(int(port)))
+		listener, err := stack.Listen(context.Background(), "tcp", address)
+		if err != nil {
+			return err
+		}
+		servers.Add(listener)
+		go serveTCP(listener, cfg.TCPHandlers[port])
+	}
+	return nil
+}
+
+// serveTCP accepts connections until the listener is closed.
+func serveTCP(listener net.Listener, handler func(conn net.Conn)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go handler(conn)
+	}
+}
+
+// setupUDPHandlers configures the generic UDP handlers for the stack.
+func (s *Scenario) setupUDPHandlers(stack *Stack, cfg *StackConfig, servers *closepool.Pool) error {
+	for _, port := range slices.Sorted(maps.Keys(cfg.UDPHandlers)) {
+		address := net.JoinHostPort("::", strconv.Itoa(int(port)))
+		pconn, err := stack.ListenPacket(context.Background(), "udp", address)
+		if err != nil {
+			return err
+		}
+		servers.Add(pconn)
+		go cfg.UDPHandlers[port](pconn)
+	}
+	return nil
 }
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"crypto/tls"
+	"errors"
+
+	"github.com/rbmk-project/common/runtimex"
+)
+
+// errNoDomains indicates that [*PKI.NewWrongHostCert] got no domains.
+var errNoDomains = errors.New("at least one domain is required")
+
+// MustNewWrongHostCert is like [*PKI.NewWrongHostCert] but panics on failure.
+func (pki *PKI) MustNewWrongHostCert(domains ...string) tls.Certificate {
+	return runtimex.Try1(pki.NewWrongHostCert(domains...))
+}
+
+// NewWrongHostCert creates a certificate that the PKI trusts but that is
+// valid for names not matching any of the given domains (i.e., the domains
+// prefixed by "wrong-host."), such that a server presenting it for any of
+// the given domains causes hostname mismatch errors.
+//
+// As a side effect, this method also updates the
+// certificate pool you can get with [*PKI.CertPool].
+func (pki *PKI) NewWrongHostCert(domains ...string) (tls.Certificate, error) {
+	if len(domains) <= 0 {
+		return tls.Certificate{}, errNoDomains
+	}
+	var names []string
+	for _, domain := range domains {
+		names = append(names, "wrong-host."+domain)
+	}
+	return pki.NewCert(&Config{CommonName: names[0], DNSNames: names})
+}
+
+// MustNewSelfSignedCert is like [*PKI.NewSelfSignedCert] but panics on failure.
+func (pki *PKI) MustNewSelfSignedCert(config *Config) tls.Certificate {
+	return runtimex.Try1(pki.NewSelfSignedCert(config))
+}
+
+// NewSelfSignedCert creates a self-signed certificate using the given
+// [*Config] that the PKI does not trust, i.e., we do not add it to the
+// certificate pool you can get with [*PKI.CertPool], such that a server
+// presenting it causes unknown authority errors.
+//
+// We do not cache these certificates, since they are cheap to generate.
+func (pki *PKI) NewSelfSignedCert(config *Config) (tls.Certificate, error) {
+	certPEM, keyPEM, err := generate(config, nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
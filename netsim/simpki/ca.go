@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// authority is the certification authority issuing the certificates.
+type authority struct {
+	// cert is the CA certificate.
+	cert *x509.Certificate
+
+	// certPEM is the PEM encoding of cert.
+	certPEM []byte
+
+	// key is the CA private key.
+	key crypto.Signer
+}
+
+// caValidity is the validity of the CA certificate.
+const caValidity = 10 * 365 * 24 * time.Hour
+
+// loadAuthority loads the CA cached inside baseDir or creates it when it is
+// missing or expired. The caller must hold the lock protecting baseDir.
+func loadAuthority(baseDir string) (*authority, error) {
+	dirpath := filepath.Join(baseDir, "ca")
+	if err := os.MkdirAll(dirpath, 0700); err != nil {
+		return nil, err
+	}
+	certPEM := filepath.Join(dirpath, "cert.pem")
+	keyPEM := filepath.Join(dirpath, "key.pem")
+	if ca, err := readAuthority(certPEM, keyPEM); err == nil && time.Now().Before(ca.cert.NotAfter) {
+		return ca, nil
+	}
+	certPEMData, keyPEMData, err := generateAuthority()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(certPEM, certPEMData, 0600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(keyPEM, keyPEMData, 0600); err != nil {
+		return nil, err
+	}
+	return readAuthority(certPEM, keyPEM)
+}
+
+// readAuthority reads the CA from the given files.
+func readAuthority(certPEM, keyPEM string) (*authority, error) {
+	certPEMData, err := os.ReadFile(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	keyPEMData, err := os.ReadFile(keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := tls.X509KeyPair(certPEMData, keyPEMData)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	return &authority{cert: cert, certPEM: certPEMData, key: pair.PrivateKey.(crypto.Signer)}, nil
+}
+
+// generateAuthority generates the CA certificate and key
+// and returns them encoded using PEM.
+func generateAuthority() (certPEM, keyPEM []byte, err error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	notBefore := time.Now().Add(-time.Hour)
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"RBMK Project"},
+			CommonName:   "RBMK Project Simulated Root CA",
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notBefore.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// KeyAlgorithm is the algorithm of the certificate key.
+type KeyAlgorithm string
+
+// These are the supported [KeyAlgorithm] values.
+const (
+	// KeyAlgorithmECDSAP256 is ECDSA using the P-256 curve, which is the default.
+	KeyAlgorithmECDSAP256 = KeyAlgorithm("ecdsa-p256")
+
+	// KeyAlgorithmECDSAP384 is ECDSA using the P-384 curve.
+	KeyAlgorithmECDSAP384 = KeyAlgorithm("ecdsa-p384")
+
+	// KeyAlgorithmEd25519 is Ed25519.
+	KeyAlgorithmEd25519 = KeyAlgorithm("ed25519")
+
+	// KeyAlgorithmRSA2048 is RSA using 2048 bit keys.
+	KeyAlgorithmRSA2048 = KeyAlgorithm("rsa-2048")
+)
+
+// Config contains the configuration for [*PKI.NewCert].
+type Config struct {
+	// CRLDistributionPoint is the optional URL of the CRL (e.g., the
+	// one served using [*PKI.CRLHandler]) to include in the certificate.
+	CRLDistributionPoint string
+
+	// CommonName is the certificate common name.
+	CommonName string
+
+	// DNSNames contains the alternative DNS names to include in the certificate.
+	DNSNames []string
+
+	// IPAddrs contains the IP addrs for which the certificate is valid.
+	IPAddrs []net.IP
+
+	// KeyAlgorithm is the optional algorithm of the certificate key, which
+	// allows to exercise the signature algorithm negotiation of clients. If
+	// empty, we use [KeyAlgorithmECDSAP256].
+	KeyAlgorithm KeyAlgorithm
+
+	// NotAfter optionally sets the end of the validity period. Setting it
+	// in the past creates an expired certificate, which allows to reproduce
+	// certificate verification failures deterministically. If zero, the
+	// certificate is valid for one year starting from NotBefore.
+	NotAfter time.Time
+
+	// NotBefore optionally sets the start of the validity period. Setting it
+	// in the future creates a not-yet-valid certificate, which allows to test
+	// how clients report clock-skew-induced failures. If zero, the certificate
+	// is valid starting from its creation.
+	NotBefore time.Time
+
+	// OCSPServer is the optional URL of the OCSP responder (e.g., the
+	// one served using [*PKI.OCSPHandler]) to include in the certificate.
+	OCSPServer string
+}
+
+// certValidity is the default validity of the certificates.
+const certValidity = 365 * 24 * time.Hour
+
+// validity returns the validity period of the certificate.
+func (c *Config) validity() (notBefore, notAfter time.Time) {
+	notBefore = time.Now()
+	if !c.NotBefore.IsZero() {
+		notBefore = c.NotBefore
+	}
+	notAfter = notBefore.Add(certValidity)
+	if !c.NotAfter.IsZero() {
+		notAfter = c.NotAfter
+		if c.NotBefore.IsZero() && notAfter.Before(notBefore) {
+			notBefore = notAfter.Add(-certValidity)
+		}
+	}
+	return
+}
+
+// cacheKey returns the name of the directory caching the certificate,
+// which depends on the options that change the certificate contents,
+// such that, e.g., valid and expired certificates for the same common
+// name do not overwrite each other.
+func (c *Config) cacheKey() string {
+	key := c.CommonName
+	if !c.NotAfter.IsZero() {
+		key += "\x00notAfter=" + strconv.FormatInt(c.NotAfter.Unix(), 10)
+	}
+	if !c.NotBefore.IsZero() {
+		key += "\x00notBefore=" + strconv.FormatInt(c.NotBefore.Unix(), 10)
+	}
+	if c.KeyAlgorithm != "" && c.KeyAlgorithm != KeyAlgorithmECDSAP256 {
+		key += "\x00keyAlgorithm=" + string(c.KeyAlgorithm)
+	}
+	if c.CRLDistributionPoint != "" {
+		key += "\x00crlDistributionPoint=" + c.CRLDistributionPoint
+	}
+	if c.OCSPServer != "" {
+		key += "\x00ocspServer=" + c.OCSPServer
+	}
+	return base64.URLEncoding.EncodeToString([]byte(key))
+}
+
+// isStale returns whether we should regenerate the given cached certificate
+// because it was not issued by the given CA, because it does not include
+// the requested OCSP server or CRL distribution point, because the default
+// validity period has ended, or because it does not match the requested
+// validity period.
+func (c *Config) isStale(cert *x509.Certificate, ca *authority) bool {
+	if cert.CheckSignatureFrom(ca.cert) != nil {
+		return true
+	}
+	if !slices.Equal(cert.OCSPServer, optionalURL(c.OCSPServer)) {
+		return true
+	}
+	if !slices.Equal(cert.CRLDistributionPoints, optionalURL(c.CRLDistributionPoint)) {
+		return true
+	}
+	if c.NotAfter.IsZero() && c.NotBefore.IsZero() {
+		return time.Now().After(cert.NotAfter)
+	}
+	notBefore, notAfter := c.validity()
+	return (!c.NotBefore.IsZero() && cert.NotBefore.Unix() != notBefore.Unix()) ||
+		(!c.NotAfter.IsZero() && cert.NotAfter.Unix() != notAfter.Unix())
+}
+
+// optionalURL returns a slice containing the given URL, if not empty.
+func optionalURL(rawURL string) []string {
+	if rawURL == "" {
+		return nil
+	}
+	return []string{rawURL}
+}
+
+// generate generates a certificate and key using the given config and
+// returns them encoded using PEM. The certificate is issued by the given
+// CA or, when the CA is nil, self-signed.
+func generate(config *Config, ca *authority) (certPEM, keyPEM []byte, err error) {
+	// Generate the private key
+	priv, keyPEM, err := generateKey(config.KeyAlgorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Build the certificate template
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	notBefore, notAfter := config.validity()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			Organization: []string{"RBMK Project"},
+			CommonName:   config.CommonName,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              config.DNSNames,
+		IPAddresses:           config.IPAddrs,
+	}
+
+	// Generate the certificate proper and encode it to PEM
+	parent, signer := &template, priv
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+		template.CRLDistributionPoints = optionalURL(config.CRLDistributionPoint)
+		template.OCSPServer = optionalURL(config.OCSPServer)
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, &template, parent, priv.Public(), signer)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER})
+	return certPEM, keyPEM, nil
+}
+
+// errUnknownKeyAlgorithm indicates that the [KeyAlgorithm] is not supported.
+var errUnknownKeyAlgorithm = errors.New("unknown key algorithm")
+
+// generateKey generates a private key using the given algorithm and
+// returns it along with its PEM encoding.
+func generateKey(algo KeyAlgorithm) (crypto.Signer, []byte, error) {
+	switch algo {
+	case "", KeyAlgorithmECDSAP256:
+		priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		keyDER, err := x509.MarshalECPrivateKey(priv)
+		if err != nil {
+			return nil, nil, err
+		}
+		return priv, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+
+	case KeyAlgorithmECDSAP384:
+		priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encodePKCS8(priv)
+
+	case KeyAlgorithmEd25519:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encodePKCS8(priv)
+
+	case KeyAlgorithmRSA2048:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encodePKCS8(priv)
+
+	default:
+		return nil, nil, fmt.Errorf("%w: %q", errUnknownKeyAlgorithm, algo)
+	}
+}
+
+// encodePKCS8 returns the given key along with its PKCS #8 PEM encoding.
+func encodePKCS8(priv crypto.Signer) (crypto.Signer, []byte, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return priv, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
@@ -0,0 +1,85 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/rbmk-project/common/runtimex"
+)
+
+// crlReasonKeyCompromise is the keyCompromise CRL reason code (see RFC 5280).
+const crlReasonKeyCompromise = 1
+
+// Revoke marks the given certificate as revoked, such that the CRL
+// returned by [*PKI.NewCRL] lists it and the OCSP responder returned
+// by [*PKI.OCSPHandler] reports it as [OCSPRevoked].
+//
+// This method is goroutine safe.
+func (pki *PKI) Revoke(cert *x509.Certificate) {
+	pki.mu.Lock()
+	defer pki.mu.Unlock()
+	key := cert.SerialNumber.String()
+	pki.statuses[key] = OCSPRevoked
+	if _, found := pki.revoked[key]; !found {
+		pki.revoked[key] = x509.RevocationListEntry{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: time.Now(),
+			ReasonCode:     crlReasonKeyCompromise,
+		}
+	}
+}
+
+// MustNewCRL is like [*PKI.NewCRL] but panics on failure.
+func (pki *PKI) MustNewCRL() []byte {
+	return runtimex.Try1(pki.NewCRL())
+}
+
+// NewCRL creates a DER-encoded CRL signed by the root CA listing the
+// certificates revoked using [*PKI.Revoke], valid from one hour ago until
+// one day from now. The root CA must already exist, which happens
+// after issuing at least a certificate using [*PKI.NewCert].
+//
+// This method is goroutine safe.
+func (pki *PKI) NewCRL() ([]byte, error) {
+	pki.mu.Lock()
+	ca := pki.ca
+	pki.crlNumber++
+	number := big.NewInt(pki.crlNumber)
+	entries := make([]x509.RevocationListEntry, 0, len(pki.revoked))
+	for _, entry := range pki.revoked {
+		entries = append(entries, entry)
+	}
+	pki.mu.Unlock()
+	if ca == nil {
+		return nil, errNoAuthority
+	}
+	now := time.Now()
+	template := &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		Number:                    number,
+		ThisUpdate:                now.Add(-time.Hour),
+		NextUpdate:                now.Add(24 * time.Hour),
+	}
+	return x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+}
+
+// CRLHandler returns an [http.Handler] serving the CRL created
+// using [*PKI.NewCRL] on any path. Host it on a scenario stack
+// and include its URL in the certificates using the
+// CRLDistributionPoint field of [*Config].
+func (pki *PKI) CRLHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crl, err := pki.NewCRL()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pkix-crl")
+		w.Write(crl)
+	})
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/rbmk-project/common/runtimex"
+)
+
+// MustExportPEM is like [*PKI.ExportPEM] but panics on failure.
+func (pki *PKI) MustExportPEM(dir string) {
+	runtimex.Try0(pki.ExportPEM(dir))
+}
+
+// ExportPEM writes the PKI material encoded using PEM inside the given
+// directory, which we create if needed, such that external tools (e.g.,
+// curl using --cacert) can trust the simulated PKI. We write:
+//
+// 1. the root CA certificate as ca.pem;
+//
+// 2. each certificate issued using [*PKI.NewCert] and its private key
+// as cert.pem and key.pem inside a subdirectory named like the one
+// caching the certificate inside the cache directory.
+//
+// The simulated PKI does not use intermediate CAs, so leaf certificates
+// are directly issued by the root CA. The root CA must already exist,
+// which happens after issuing at least a certificate.
+//
+// This method is goroutine safe.
+func (pki *PKI) ExportPEM(dir string) error {
+	pki.mu.Lock()
+	ca := pki.ca
+	issued := make(map[string]issuedCert, len(pki.issued))
+	for name, entry := range pki.issued {
+		issued[name] = entry
+	}
+	pki.mu.Unlock()
+	if ca == nil {
+		return errNoAuthority
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), ca.certPEM, 0600); err != nil {
+		return err
+	}
+	for name, entry := range issued {
+		dirpath := filepath.Join(dir, name)
+		if err := os.MkdirAll(dirpath, 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dirpath, "cert.pem"), entry.certPEM, 0600); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dirpath, "key.pem"), entry.keyPEM, 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
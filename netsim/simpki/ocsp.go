@@ -0,0 +1,165 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rbmk-project/common/runtimex"
+	"golang.org/x/crypto/ocsp"
+)
+
+// OCSPStatus is the status of a certificate according to OCSP.
+type OCSPStatus int
+
+// These are the supported [OCSPStatus] values.
+const (
+	// OCSPGood indicates that the certificate is not revoked.
+	OCSPGood = OCSPStatus(ocsp.Good)
+
+	// OCSPRevoked indicates that the certificate is revoked.
+	OCSPRevoked = OCSPStatus(ocsp.Revoked)
+
+	// OCSPUnknown indicates that the responder does not know the certificate.
+	OCSPUnknown = OCSPStatus(ocsp.Unknown)
+)
+
+// errNoAuthority indicates that the root CA has not been created yet.
+var errNoAuthority = errors.New("simpki: no root CA: issue a certificate first")
+
+// SetOCSPStatus sets the status that the OCSP responder returned by
+// [*PKI.OCSPHandler] uses for the given certificate. By default, the
+// status is [OCSPGood] for the certificates created using [*PKI.NewCert]
+// and [OCSPUnknown] for all the other certificates.
+//
+// This method is goroutine safe.
+func (pki *PKI) SetOCSPStatus(cert *x509.Certificate, status OCSPStatus) {
+	pki.mu.Lock()
+	pki.statuses[cert.SerialNumber.String()] = status
+	pki.mu.Unlock()
+}
+
+// ocspStatus returns the OCSP status of the certificate with the given serial.
+func (pki *PKI) ocspStatus(serial string) OCSPStatus {
+	pki.mu.Lock()
+	defer pki.mu.Unlock()
+	status, found := pki.statuses[serial]
+	if !found {
+		return OCSPUnknown
+	}
+	return status
+}
+
+// MustNewOCSPResponse is like [*PKI.NewOCSPResponse] but panics on failure.
+func (pki *PKI) MustNewOCSPResponse(cert *x509.Certificate, status OCSPStatus) []byte {
+	return runtimex.Try1(pki.NewOCSPResponse(cert, status))
+}
+
+// NewOCSPResponse creates a DER-encoded OCSP response signed by the root CA
+// stating that the given certificate has the given status, valid from one hour
+// ago until one day from now. The root CA must already exist, which happens
+// after issuing at least a certificate using [*PKI.NewCert].
+//
+// This method is goroutine safe.
+func (pki *PKI) NewOCSPResponse(cert *x509.Certificate, status OCSPStatus) ([]byte, error) {
+	pki.mu.Lock()
+	ca := pki.ca
+	pki.mu.Unlock()
+	if ca == nil {
+		return nil, errNoAuthority
+	}
+	now := time.Now()
+	template := ocsp.Response{
+		Status:       int(status),
+		SerialNumber: cert.SerialNumber,
+		ThisUpdate:   now.Add(-time.Hour),
+		NextUpdate:   now.Add(24 * time.Hour),
+	}
+	if status == OCSPRevoked {
+		template.RevokedAt = now.Add(-time.Hour)
+		template.RevocationReason = ocsp.KeyCompromise
+	}
+	return ocsp.CreateResponse(ca.cert, ca.cert, template, ca.key)
+}
+
+// MustStapleOCSP is like [*PKI.StapleOCSP] but panics on failure.
+func (pki *PKI) MustStapleOCSP(cert *tls.Certificate, status OCSPStatus) {
+	runtimex.Try0(pki.StapleOCSP(cert, status))
+}
+
+// StapleOCSP sets the OCSPStaple field of the given [*tls.Certificate] to
+// an OCSP response with the given status created using [*PKI.NewOCSPResponse],
+// such that servers using it staple the response to the TLS handshake.
+//
+// This method is goroutine safe.
+func (pki *PKI) StapleOCSP(cert *tls.Certificate, status OCSPStatus) error {
+	leaf, err := leafOf(cert)
+	if err != nil {
+		return err
+	}
+	staple, err := pki.NewOCSPResponse(leaf, status)
+	if err != nil {
+		return err
+	}
+	cert.OCSPStaple = staple
+	return nil
+}
+
+// leafOf returns the parsed leaf of the given [*tls.Certificate].
+func leafOf(cert *tls.Certificate) (*x509.Certificate, error) {
+	if cert.Leaf != nil {
+		return cert.Leaf, nil
+	}
+	if len(cert.Certificate) <= 0 {
+		return nil, errors.New("simpki: empty certificate chain")
+	}
+	return x509.ParseCertificate(cert.Certificate[0])
+}
+
+// OCSPHandler returns an [http.Handler] implementing an OCSP responder
+// as documented by RFC 6960 using either POST or GET. Host it on a
+// scenario stack and include its URL in the certificates using
+// the OCSPServer field of [*Config].
+//
+// The responder answers using the status set with [*PKI.SetOCSPStatus].
+func (pki *PKI) OCSPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			rawReq []byte
+			err    error
+		)
+		switch r.Method {
+		case http.MethodGet:
+			rawReq, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(r.URL.Path, "/"))
+		case http.MethodPost:
+			rawReq, err = io.ReadAll(r.Body)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		if err != nil {
+			w.Write(ocsp.MalformedRequestErrorResponse)
+			return
+		}
+		req, err := ocsp.ParseRequest(rawReq)
+		if err != nil {
+			w.Write(ocsp.MalformedRequestErrorResponse)
+			return
+		}
+		status := pki.ocspStatus(req.SerialNumber.String())
+		resp, err := pki.NewOCSPResponse(&x509.Certificate{SerialNumber: req.SerialNumber}, status)
+		if err != nil {
+			w.Write(ocsp.InternalErrorErrorResponse)
+			return
+		}
+		w.Write(resp)
+	})
+}
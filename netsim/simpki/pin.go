@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package simpki
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+
+	"github.com/rbmk-project/common/runtimex"
+)
+
+// SPKIPin returns the base64-encoded SHA-256 hash of the
+// SubjectPublicKeyInfo of the given certificate, which is
+// the pin format used by HPKP (see RFC 7469).
+func SPKIPin(cert *x509.Certificate) string {
+	digest := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// MustSPKIPinTLS is like [SPKIPinTLS] but panics on failure.
+func MustSPKIPinTLS(cert *tls.Certificate) string {
+	return runtimex.Try1(SPKIPinTLS(cert))
+}
+
+// SPKIPinTLS is like [SPKIPin] but computes the pin of the leaf of
+// the given [*tls.Certificate] (e.g., returned by [*PKI.NewCert]).
+func SPKIPinTLS(cert *tls.Certificate) (string, error) {
+	leaf, err := leafOf(cert)
+	if err != nil {
+		return "", err
+	}
+	return SPKIPin(leaf), nil
+}
+
+// MustRootSPKIPin is like [*PKI.RootSPKIPin] but panics on failure.
+func (pki *PKI) MustRootSPKIPin() string {
+	return runtimex.Try1(pki.RootSPKIPin())
+}
+
+// RootSPKIPin is like [SPKIPin] but computes the pin of the root CA,
+// which must already exist, which happens after issuing at least
+// a certificate using [*PKI.NewCert].
+//
+// This method is goroutine safe.
+func (pki *PKI) RootSPKIPin() (string, error) {
+	pki.mu.Lock()
+	ca := pki.ca
+	pki.mu.Unlock()
+	if ca == nil {
+		return "", errNoAuthority
+	}
+	return SPKIPin(ca.cert), nil
+}
@@ -2,71 +2,145 @@
 
 // Package simpki allows to simulate a PKI for testing purposes.
 //
-// It allows to generate self-signed certificates and to cache them in
-// a directory to avoid regenerating them at every run.
+// It allows to generate certificates issued by a simulated root CA and
+// to cache them, along with the CA, in a directory to avoid regenerating
+// them at every run. Clients should trust the root CA using the
+// certificate pool returned by [*PKI.CertPool].
 //
-// Because this package is only meant to run as part of integration
-// tests, all the functions panic on failure.
+// Because this package is mainly meant to run as part of integration
+// tests, most functions panic on failure. Use [*PKI.NewCert] when you
+// need to handle errors (e.g., when embedding the PKI into a tool).
 package simpki
 
 import (
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/base64"
+	"encoding/pem"
+	"errors"
 	"os"
 	"path/filepath"
+	"sync"
 
 	"github.com/rbmk-project/common/runtimex"
-	"github.com/rbmk-project/common/selfsignedcert"
 	"github.com/rogpeppe/go-internal/lockedfile"
 )
 
 // PKI models the public key infrastructure.
 //
-// Construct using [NewPKI].
+// Construct using [New].
 type PKI struct {
-	cacheDir string
-	pool     *x509.CertPool
+	ca        *authority
+	cacheDir  string
+	crlNumber int64
+	issued    map[string]issuedCert
+	mu        sync.Mutex
+	pool      *x509.CertPool
+	reissued  map[string]issuedCert
+	revoked   map[string]x509.RevocationListEntry
+	statuses  map[string]OCSPStatus
 }
 
-// MustNew constructs a new [*PKI] instance using
+// MustNew is like [New] but panics on failure.
+func MustNew(cacheDir string) *PKI {
+	return runtimex.Try1(New(cacheDir))
+}
+
+// New constructs a new [*PKI] instance using
 // the given filesystem directory to store the
 // certificates, to avoid regenerating them every
 // time we run integration tests.
 //
-// This function panics on failure.
-func MustNew(cacheDir string) *PKI {
-	return &PKI{
+// This function loads the root CA from the cache directory, or creates
+// it, thus failing early when the cache directory is not usable.
+func New(cacheDir string) (*PKI, error) {
+	pki := &PKI{
 		cacheDir: cacheDir,
+		issued:   make(map[string]issuedCert),
 		pool:     x509.NewCertPool(),
+		reissued: make(map[string]issuedCert),
+		revoked:  make(map[string]x509.RevocationListEntry),
+		statuses: make(map[string]OCSPStatus),
+	}
+	baseDir, unlock, err := pki.lockStore()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
+	if _, err := pki.authority(baseDir); err != nil {
+		return nil, err
+	}
+	return pki, nil
 }
 
-// Config is an alias for [selfsignedcert.Config].
-type Config = selfsignedcert.Config
+// MustNewCert is like [*PKI.NewCert] but panics on failure.
+func (pki *PKI) MustNewCert(config *Config) tls.Certificate {
+	return runtimex.Try1(pki.NewCert(config))
+}
 
-// MustNewCert creates the certificate using the given
+// NewCert creates the certificate using the given
 // [*Config] and using the cache directory
 // to avoid regenerating the certificate every time.
 //
 // It returns the [tls.Certificate] to use in server code.
 //
-// As a side effect, this method also updates the
-// certificate pool you can get with [*PKI.CertPool].
-//
-// This function panics on failure.
-func (pki *PKI) MustNewCert(config *Config) tls.Certificate {
+// As a side effect, this method also ensures that the root CA
+// issuing the certificate is in the certificate pool you can
+// get with [*PKI.CertPool].
+func (pki *PKI) NewCert(config *Config) (tls.Certificate, error) {
+	return pki.issue(config, false)
+}
+
+// MustReissueCert is like [*PKI.ReissueCert] but panics on failure.
+func (pki *PKI) MustReissueCert(config *Config) tls.Certificate {
+	return runtimex.Try1(pki.ReissueCert(config))
+}
+
+// ReissueCert is like [*PKI.NewCert] but always generates a new
+// certificate, with a new serial number and key, which replaces the
+// previous one for the lifetime of the [*PKI]. We keep the reissued
+// certificate in memory only, such that the cache directory keeps
+// containing the original certificate. Because the certificate pool
+// contains the root CA rather than the leaf certificates, we invalidate
+// the previous certificate by revoking it (see [*PKI.Revoke]). Use this
+// method to simulate a server rotating its certificate.
+func (pki *PKI) ReissueCert(config *Config) (tls.Certificate, error) {
+	return pki.issue(config, true)
+}
+
+// issue implements [*PKI.NewCert] and [*PKI.ReissueCert].
+func (pki *PKI) issue(config *Config, reissue bool) (tls.Certificate, error) {
 	// ensure there are no race conditions with concurrent invocations
-	baseDir := filepath.Join(pki.cacheDir, "pkistore")
-	runtimex.Try0(os.MkdirAll(baseDir, 0700))
-	mu := lockedfile.MutexAt(filepath.Join(baseDir, ".lock"))
-	unlock := runtimex.Try1(mu.Lock())
+	baseDir, unlock, err := pki.lockStore()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
 	defer unlock()
 
+	// load or create the root CA
+	ca, err := pki.authority(baseDir)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	// reissue the certificate in memory, to avoid modifying the cache
+	key := config.cacheKey()
+	if reissue {
+		return pki.reissue(config, ca, filepath.Join(baseDir, key, "cert.pem"))
+	}
+
+	// prefer the certificate we reissued, if any
+	pki.mu.Lock()
+	reissued, found := pki.reissued[key]
+	pki.mu.Unlock()
+	if found {
+		return pki.load(key, reissued.certPEM, reissued.keyPEM)
+	}
+
 	// possibly create the base directory for the certificate
-	dirname64 := base64.URLEncoding.EncodeToString([]byte(config.CommonName))
-	dirpath := filepath.Join(baseDir, dirname64)
-	runtimex.Try0(os.MkdirAll(dirpath, 0700))
+	dirpath := filepath.Join(baseDir, key)
+	if err := os.MkdirAll(dirpath, 0700); err != nil {
+		return tls.Certificate{}, err
+	}
 
 	// check whether cert.pem already exists
 	certPEM := filepath.Join(dirpath, "cert.pem")
@@ -82,20 +156,164 @@ func (pki *PKI) MustNewCert(config *Config) tls.Certificate {
 		hasKeyPEM = true
 	}
 
-	// regenerate the certificate if we miss either cert.pem or key.pem
-	if !hasCertPEM || !hasKeyPEM {
-		selfsignedcert.New(config).WriteFiles(dirpath)
+	// regenerate the certificate if we miss either cert.pem or key.pem,
+	// or the cached certificate does not have the expected validity
+	if !hasCertPEM || !hasKeyPEM || isStale(certPEM, config, ca) {
+		certPEMData, keyPEMData, err := generate(config, ca)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		if err := os.WriteFile(certPEM, certPEMData, 0600); err != nil {
+			return tls.Certificate{}, err
+		}
+		if err := os.WriteFile(keyPEM, keyPEMData, 0600); err != nil {
+			return tls.Certificate{}, err
+		}
 	}
 
 	// load the certificate and ensure we update the cert pool
-	certPEMData := runtimex.Try1(os.ReadFile(certPEM))
-	keyPEMData := runtimex.Try1(os.ReadFile(keyPEM))
-	runtimex.Assert(pki.pool.AppendCertsFromPEM(certPEMData), "could not append certificate to pool")
-	return runtimex.Try1(tls.X509KeyPair(certPEMData, keyPEMData))
+	certPEMData, err := os.ReadFile(certPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	keyPEMData, err := os.ReadFile(keyPEM)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return pki.load(key, certPEMData, keyPEMData)
+}
+
+// reissue implements [*PKI.ReissueCert]. We revoke the previous certificate,
+// that is, the one we reissued last or the one cached at certPEM.
+func (pki *PKI) reissue(config *Config, ca *authority, certPEM string) (tls.Certificate, error) {
+	key := config.cacheKey()
+	pki.mu.Lock()
+	prevData, found := pki.reissued[key]
+	pki.mu.Unlock()
+	var prev *x509.Certificate
+	if found {
+		prev, _ = parseLeaf(prevData.certPEM)
+	} else {
+		prev, _ = readLeaf(certPEM)
+	}
+
+	certPEMData, keyPEMData, err := generate(config, ca)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert, err := pki.load(key, certPEMData, keyPEMData)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	pki.mu.Lock()
+	pki.reissued[key] = issuedCert{certPEM: certPEMData, keyPEM: keyPEMData}
+	pki.mu.Unlock()
+	if prev != nil {
+		pki.Revoke(prev)
+	}
+	return cert, nil
+}
+
+// load parses the given certificate and key and marks the
+// certificate as issued using the given name.
+func (pki *PKI) load(name string, certPEMData, keyPEMData []byte) (tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(certPEMData, keyPEMData)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := leafOf(&cert)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	pki.markIssued(name, leaf, certPEMData, keyPEMData)
+	return cert, nil
+}
+
+// lockStore creates the directory containing the cached certificates, if
+// needed, and locks it to avoid race conditions with concurrent invocations,
+// possibly from other processes. It returns the directory path and the
+// function to unlock it.
+func (pki *PKI) lockStore() (string, func(), error) {
+	baseDir := filepath.Join(pki.cacheDir, "pkistore")
+	if err := os.MkdirAll(baseDir, 0700); err != nil {
+		return "", nil, err
+	}
+	mu := lockedfile.MutexAt(filepath.Join(baseDir, ".lock"))
+	unlock, err := mu.Lock()
+	if err != nil {
+		return "", nil, err
+	}
+	return baseDir, unlock, nil
+}
+
+// authority returns the root CA, loading it on first use.
+func (pki *PKI) authority(baseDir string) (*authority, error) {
+	pki.mu.Lock()
+	defer pki.mu.Unlock()
+	if pki.ca != nil {
+		return pki.ca, nil
+	}
+	ca, err := loadAuthority(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	pki.pool.AddCert(ca.cert)
+	pki.ca = ca
+	return ca, nil
+}
+
+// issuedCert is a certificate issued by the [*PKI].
+type issuedCert struct {
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// markIssued records that we issued the given certificate, such that
+// [*PKI.ExportPEM] exports it using the given name and the OCSP responder
+// knows about it, unless we already know it.
+func (pki *PKI) markIssued(name string, cert *x509.Certificate, certPEM, keyPEM []byte) {
+	pki.mu.Lock()
+	defer pki.mu.Unlock()
+	pki.issued[name] = issuedCert{certPEM: certPEM, keyPEM: keyPEM}
+	key := cert.SerialNumber.String()
+	if _, found := pki.statuses[key]; !found {
+		pki.statuses[key] = OCSPGood
+	}
+}
+
+// isStale returns whether the certificate cached at the given
+// path is stale and therefore we should regenerate it.
+func isStale(certPEM string, config *Config, ca *authority) bool {
+	cert, err := readLeaf(certPEM)
+	if err != nil {
+		return true
+	}
+	return config.isStale(cert, ca)
+}
+
+// errNoPEMBlock indicates that a file does not contain a PEM block.
+var errNoPEMBlock = errors.New("simpki: no PEM block")
+
+// readLeaf reads the certificate cached at the given path.
+func readLeaf(certPEM string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(certPEM)
+	if err != nil {
+		return nil, err
+	}
+	return parseLeaf(data)
+}
+
+// parseLeaf parses the PEM-encoded leaf certificate.
+func parseLeaf(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errNoPEMBlock
+	}
+	return x509.ParseCertificate(block.Bytes)
 }
 
 // CertPool returns the certificate pool that contains
-// all the certificates generated by this PKI.
+// the root CA issuing the certificates of this PKI.
 func (pki *PKI) CertPool() *x509.CertPool {
 	return pki.pool
 }
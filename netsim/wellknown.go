@@ -7,37 +7,150 @@
 
 package netsim
 
-import "net/http"
+import (
+	"net/http"
 
-// MustNewGoogleDNSStack creates a new stack simulating dns.google.
+	"github.com/rbmk-project/common/runtimex"
+	netsimdns "github.com/rbmk-project/x/netsim/dns"
+	"github.com/rbmk-project/x/netsim/nat"
+)
+
+// MustNewGoogleDNSStack is like [*Scenario.NewGoogleDNSStack] but panics on error.
 func (s *Scenario) MustNewGoogleDNSStack() *Stack {
-	mux := http.NewServeMux()
-	mux.Handle("/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Write([]byte("Google Public DNS server.\n"))
-	}))
-	mux.Handle("/dns-query", NewDNSHTTPHandler(*s.dnsd))
-	return s.MustNewStack(&StackConfig{
+	return runtimex.Try1(s.NewGoogleDNSStack())
+}
+
+// NewGoogleDNSStack creates a new stack simulating dns.google.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewGoogleDNSStack() (*Stack, error) {
+	config := s.newResolverStackConfig([]string{
+		"dns.google",
+		"dns.google.com",
+	}, []string{
+		"2001:4860:4860::8888",
+		"8.8.8.8",
+	})
+	config.HTTPSHandler = newTextHandler("Google Public DNS server.\n")
+	return s.NewStack(config)
+}
+
+// MustNewGoogleDNS64Stack is like [*Scenario.NewGoogleDNS64Stack] but panics on error.
+func (s *Scenario) MustNewGoogleDNS64Stack() *Stack {
+	return runtimex.Try1(s.NewGoogleDNS64Stack())
+}
+
+// NewGoogleDNS64Stack creates a new stack simulating Google's DNS64
+// service, which synthesizes AAAA records using [nat.WellKnownPrefix]. Use
+// it along with a [*nat.NAT64] filter to allow IPv6-only client stacks
+// to reach IPv4-only server stacks.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewGoogleDNS64Stack() (*Stack, error) {
+	handler := netsimdns.NewDNS64Handler(s.dnsd, nat.WellKnownPrefix)
+	return s.NewStack(&StackConfig{
 		DomainNames: []string{
-			"dns.google",
-			"dns.google.com",
+			"dns64.dns.google",
 		},
 		Addresses: []string{
-			"2001:4860:4860::8888",
-			"8.8.8.8",
+			"2001:4860:4860::6464",
+			"2001:4860:4860::64",
 		},
-		DNSOverUDPHandler: s.DNSHandler(),
-		DNSOverTCPHandler: s.DNSHandler(),
-		DNSOverTLSHandler: s.DNSHandler(),
-		HTTPSHandler:      mux,
+		DNSOverUDPHandler: handler,
+		DNSOverTCPHandler: handler,
+	})
+}
+
+// MustNewCloudflareDNSStack is like [*Scenario.NewCloudflareDNSStack] but panics on error.
+func (s *Scenario) MustNewCloudflareDNSStack() *Stack {
+	return runtimex.Try1(s.NewCloudflareDNSStack())
+}
+
+// NewCloudflareDNSStack creates a new stack simulating one.one.one.one.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewCloudflareDNSStack() (*Stack, error) {
+	config := s.newResolverStackConfig([]string{
+		"one.one.one.one",
+		"cloudflare-dns.com",
+	}, []string{
+		"2606:4700:4700::1111",
+		"2606:4700:4700::1001",
+		"1.1.1.1",
+		"1.0.0.1",
+	})
+	config.HTTPSHandler = newTextHandler("Cloudflare DNS resolver.\n")
+	return s.NewStack(config)
+}
+
+// MustNewQuad9Stack is like [*Scenario.NewQuad9Stack] but panics on error.
+func (s *Scenario) MustNewQuad9Stack() *Stack {
+	return runtimex.Try1(s.NewQuad9Stack())
+}
+
+// NewQuad9Stack creates a new stack simulating dns.quad9.net.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewQuad9Stack() (*Stack, error) {
+	config := s.newResolverStackConfig([]string{
+		"dns.quad9.net",
+	}, []string{
+		"2620:fe::fe",
+		"2620:fe::9",
+		"9.9.9.9",
+		"149.112.112.112",
 	})
+	config.HTTPSHandler = newTextHandler("Quad9 DNS resolver.\n")
+	return s.NewStack(config)
 }
 
-// MustNewExampleComStack creates a new stack simulating www.example.com.
+// MustNewResolverStack is like [*Scenario.NewResolverStack] but panics on error.
+func (s *Scenario) MustNewResolverStack(name string, addrs ...string) *Stack {
+	return runtimex.Try1(s.NewResolverStack(name, addrs...))
+}
+
+// NewResolverStack creates a new stack simulating a public DNS resolver
+// with the given domain name and addresses. The stack answers queries
+// using the scenario DNS database over UDP, TCP, TLS, QUIC, and HTTPS.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewResolverStack(name string, addrs ...string) (*Stack, error) {
+	return s.NewStack(s.newResolverStackConfig([]string{name}, addrs))
+}
+
+// newResolverStackConfig returns the [*StackConfig] for a resolver stack.
+func (s *Scenario) newResolverStackConfig(domainNames, addrs []string) *StackConfig {
+	return &StackConfig{
+		DomainNames:         domainNames,
+		Addresses:           addrs,
+		DNSOverUDPHandler:   s.DNSHandler(),
+		DNSOverTCPHandler:   s.DNSHandler(),
+		DNSOverTLSHandler:   s.DNSHandler(),
+		DNSOverQUICHandler:  s.DNSHandler(),
+		DNSOverHTTPSHandler: s.DNSHandler(),
+	}
+}
+
+// newTextHandler returns an [http.Handler] serving the given text.
+func newTextHandler(text string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(text))
+	})
+}
+
+// MustNewExampleComStack is like [*Scenario.NewExampleComStack] but panics on error.
 func (s *Scenario) MustNewExampleComStack() *Stack {
+	return runtimex.Try1(s.NewExampleComStack())
+}
+
+// NewExampleComStack creates a new stack simulating www.example.com.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewExampleComStack() (*Stack, error) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Example Web Server.\n"))
 	})
-	return s.MustNewStack(&StackConfig{
+	return s.NewStack(&StackConfig{
 		DomainNames: []string{
 			"www.example.com",
 			"example.com",
@@ -50,10 +163,16 @@ func (s *Scenario) MustNewExampleComStack() *Stack {
 		},
 		HTTPHandler:  handler,
 		HTTPSHandler: handler,
+		HTTP3Handler: handler,
 	})
 }
 
-// MustNewClientStack creates a new client stack with standard testing configuration.
+// MustNewClientStack is like [*Scenario.NewClientStack] but panics on error.
+func (s *Scenario) MustNewClientStack() *Stack {
+	return runtimex.Try1(s.NewClientStack())
+}
+
+// NewClientStack creates a new client stack with standard testing configuration.
 //
 // We use GARR's (Italian Research & Education Network) public addresses
 // (193.206.158.22 and 2001:760:0:158::22) as default client addresses.
@@ -62,8 +181,10 @@ func (s *Scenario) MustNewExampleComStack() *Stack {
 // being associated with a public research institution.
 //
 // The stack uses Google's public DNS addresses as the default resolvers.
-func (s *Scenario) MustNewClientStack() *Stack {
-	return s.MustNewStack(&StackConfig{
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewClientStack() (*Stack, error) {
+	return s.NewStack(&StackConfig{
 		Addresses: []string{
 			"193.206.158.22",
 			"2001:760:0:158::22",
@@ -75,19 +196,242 @@ func (s *Scenario) MustNewClientStack() *Stack {
 	})
 }
 
-// MustNewBlockpageStack creates a new stack simulating a censorship blockpage server.
+// MustNewBlockpageStack is like [*Scenario.NewBlockpageStack] but panics on error.
+func (s *Scenario) MustNewBlockpageStack() *Stack {
+	return runtimex.Try1(s.NewBlockpageStack())
+}
+
+// NewBlockpageStack creates a new stack simulating a censorship blockpage server.
 //
 // It serves a simple warning page on HTTP/HTTPS indicating that the content has been blocked.
-func (s *Scenario) MustNewBlockpageStack() *Stack {
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewBlockpageStack() (*Stack, error) {
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusForbidden)
 		w.Write([]byte("Access to this website has been blocked by network policy.\n"))
 	})
 
-	return s.MustNewStack(&StackConfig{
+	return s.NewStack(&StackConfig{
 		Addresses: []string{
 			"10.10.34.35",
 		},
 		HTTPHandler: handler,
 	})
 }
+
+// LegalBlockpageAuthorityURL is the URL identifying the authority
+// implementing the block, which the stack created by
+// [*Scenario.MustNewLegalBlockpageStack] advertises using the
+// Link header with the "blocked-by" relation (see RFC 7725).
+const LegalBlockpageAuthorityURL = "http://blocked-by.legal-authority.example/"
+
+// MustNewLegalBlockpageStack is like [*Scenario.NewLegalBlockpageStack] but panics on error.
+func (s *Scenario) MustNewLegalBlockpageStack() *Stack {
+	return runtimex.Try1(s.NewLegalBlockpageStack())
+}
+
+// NewLegalBlockpageStack creates a new stack simulating a blockpage
+// server returning 451 Unavailable For Legal Reasons (see RFC 7725).
+//
+// It complements [*Scenario.MustNewBlockpageStack], which returns 403, and
+// allows to test classification logic distinguishing legal blocks.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewLegalBlockpageStack() (*Stack, error) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Link", "<"+LegalBlockpageAuthorityURL+`>; rel="blocked-by"`)
+		w.WriteHeader(http.StatusUnavailableForLegalReasons)
+		w.Write([]byte("<html><body>This content is unavailable for legal reasons.</body></html>\n"))
+	})
+
+	return s.NewStack(&StackConfig{
+		Addresses: []string{
+			"10.10.34.36",
+		},
+		HTTPHandler: handler,
+	})
+}
+
+// CaptivePortalLoginURL is the URL of the login page served by
+// the stack created by [*Scenario.MustNewCaptivePortalStack].
+const CaptivePortalLoginURL = "http://login.captive-portal.example/login"
+
+// captivePortalCheckPaths contains the URL paths used by
+// common operating systems to detect captive portals.
+var captivePortalCheckPaths = map[string]bool{
+	"/connecttest.txt":     true, // Windows
+	"/gen_204":             true, // Android
+	"/generate_204":        true, // Android and Chrome
+	"/hotspot-detect.html": true, // Apple
+	"/ncsi.txt":            true, // Windows
+}
+
+// MustNewCaptivePortalStack is like [*Scenario.NewCaptivePortalStack] but panics on error.
+func (s *Scenario) MustNewCaptivePortalStack() *Stack {
+	return runtimex.Try1(s.NewCaptivePortalStack())
+}
+
+// NewCaptivePortalStack creates a new stack simulating a captive portal.
+//
+// The stack registers the well-known connectivity-check domains (e.g.,
+// connectivitycheck.gstatic.com and captive.apple.com) in the scenario
+// DNS database, thus intercepting the corresponding HTTP requests.
+//
+// The HTTP handler behaves as follows:
+//
+// 1. connectivity-check URLs (e.g., /generate_204) get a 200 response
+// containing the portal page rather than the expected response;
+//
+// 2. the login page at [CaptivePortalLoginURL] gets a 200 response;
+//
+// 3. any other URL gets a 302 redirect to the login page.
+//
+// Use, e.g., the DNatter of the censor package to also redirect
+// traffic for arbitrary addresses to the captive portal.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewCaptivePortalStack() (*Stack, error) {
+	const portalPage = "<html><body>Please login to access the network.</body></html>\n"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case captivePortalCheckPaths[r.URL.Path]:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(portalPage))
+		case r.Host == "login.captive-portal.example" && r.URL.Path == "/login":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(portalPage))
+		default:
+			http.Redirect(w, r, CaptivePortalLoginURL, http.StatusFound)
+		}
+	})
+
+	return s.NewStack(&StackConfig{
+		DomainNames: []string{
+			"login.captive-portal.example",
+			"captive.apple.com",
+			"clients3.google.com",
+			"connectivitycheck.gstatic.com",
+			"www.msftconnecttest.com",
+			"www.msftncsi.com",
+		},
+		Addresses: []string{
+			"10.10.0.1",
+		},
+		HTTPHandler: handler,
+	})
+}
+
+// MustNewMeasurementBackendStack is like [*Scenario.NewMeasurementBackendStack] but panics on error.
+func (s *Scenario) MustNewMeasurementBackendStack(backend *MeasurementBackend) *Stack {
+	return runtimex.Try1(s.NewMeasurementBackendStack(backend))
+}
+
+// NewMeasurementBackendStack creates a new stack simulating
+// a measurement collector reachable at api.backend.example that
+// serves the given [*MeasurementBackend] over HTTPS.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewMeasurementBackendStack(backend *MeasurementBackend) (*Stack, error) {
+	return s.NewStack(&StackConfig{
+		DomainNames: []string{
+			"api.backend.example",
+		},
+		Addresses: []string{
+			"10.10.1.1",
+		},
+		HTTPSHandler: backend.newHandler(),
+	})
+}
+
+// OCSPResponderURL is the URL of the OCSP responder served by
+// the stack created by [*Scenario.MustNewOCSPResponderStack].
+const OCSPResponderURL = "http://ocsp.pki.example/"
+
+// MustNewOCSPResponderStack is like [*Scenario.NewOCSPResponderStack] but panics on error.
+func (s *Scenario) MustNewOCSPResponderStack() *Stack {
+	return runtimex.Try1(s.NewOCSPResponderStack())
+}
+
+// NewOCSPResponderStack creates a new stack simulating the OCSP
+// responder of the scenario PKI reachable at [OCSPResponderURL].
+//
+// Set the CertOCSPServer field of [*StackConfig] to advertise it and use
+// the SetOCSPStatus method of [*Scenario.PKI] to revoke certificates.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewOCSPResponderStack() (*Stack, error) {
+	return s.NewStack(&StackConfig{
+		DomainNames: []string{
+			"ocsp.pki.example",
+		},
+		Addresses: []string{
+			"10.10.2.1",
+		},
+		HTTPHandler: s.pki.OCSPHandler(),
+	})
+}
+
+// CRLDistributionPointURL is the URL of the CRL served by
+// the stack created by [*Scenario.MustNewCRLStack].
+const CRLDistributionPointURL = "http://crl.pki.example/root.crl"
+
+// MustNewCRLStack is like [*Scenario.NewCRLStack] but panics on error.
+func (s *Scenario) MustNewCRLStack() *Stack {
+	return runtimex.Try1(s.NewCRLStack())
+}
+
+// NewCRLStack creates a new stack simulating the server
+// distributing the CRL of the scenario PKI at [CRLDistributionPointURL].
+//
+// Set the CertCRLDistributionPoint field of [*StackConfig] to advertise
+// it and use the Revoke method of [*Scenario.PKI] to revoke certificates.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewCRLStack() (*Stack, error) {
+	return s.NewStack(&StackConfig{
+		DomainNames: []string{
+			"crl.pki.example",
+		},
+		Addresses: []string{
+			"10.10.2.2",
+		},
+		HTTPHandler: s.pki.CRLHandler(),
+	})
+}
+
+// MustNewCDNStacks is like [*Scenario.NewCDNStacks] but panics on error.
+func (s *Scenario) MustNewCDNStacks(config *StackConfig, replicaAddrs ...[]string) []*Stack {
+	return runtimex.Try1(s.NewCDNStacks(config, replicaAddrs...))
+}
+
+// NewCDNStacks creates a stack for each entry of replicaAddrs, thus modeling
+// a CDN or anycast-like deployment where several replicas share the same
+// domain names and handlers but have distinct addresses. Each replica uses
+// a copy of config where the Addresses field is replaced by the entry of
+// replicaAddrs, and all the addresses are registered in the scenario DNS.
+//
+// Because the simulated PKI caches certificates by common name, all the
+// replicas share the certificate generated for the first replica, which
+// is valid for the domain names but only lists the first replica addresses.
+//
+// The replicas are not attached to the scenario, so that it is possible
+// to attach them to different routers or to block a subset of them.
+//
+// On failure, the replicas created so far remain part of the scenario.
+//
+// This method IS NOT goroutine safe.
+func (s *Scenario) NewCDNStacks(config *StackConfig, replicaAddrs ...[]string) ([]*Stack, error) {
+	var stacks []*Stack
+	for _, addrs := range replicaAddrs {
+		replicaConfig := *config
+		replicaConfig.Addresses = addrs
+		stack, err := s.NewStack(&replicaConfig)
+		if err != nil {
+			return nil, err
+		}
+		stacks = append(stacks, stack)
+	}
+	return stacks, nil
+}